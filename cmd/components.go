@@ -4,13 +4,54 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/reporter"
 )
 
+// ValidateComponents checks the configuration of each component implementing
+// Validate(). Components are keyed by the configuration key they are
+// configured from (empty for squashed configurations). All problems are reported at once.
+func ValidateComponents(components map[string]interface{}) error {
+	keys := make([]string, 0, len(components))
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	problems := []string{}
+	for _, key := range keys {
+		checkerC, ok := components[key].(checker)
+		if !ok {
+			continue
+		}
+		err := checkerC.Validate()
+		if err == nil {
+			continue
+		}
+		errs := []error{err}
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			errs = joined.Unwrap()
+		}
+		for _, err := range errs {
+			if key == "" {
+				problems = append(problems, err.Error())
+			} else {
+				problems = append(problems, fmt.Sprintf("%s.%s", key, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
 // StartStopComponents activate/deactivate components in order.
 func StartStopComponents(r *reporter.Reporter, daemonComponent daemon.Component, otherComponents []interface{}) error {
 	components := append([]interface{}{r, daemonComponent}, otherComponents...)
@@ -48,3 +89,10 @@ type starter interface {
 type stopper interface {
 	Stop() error
 }
+
+// checker is implemented by components checking their configuration beyond
+// what struct tags can express. Each error should start with the faulty key,
+// relative to the configuration of the component.
+type checker interface {
+	Validate() error
+}
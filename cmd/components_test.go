@@ -121,3 +121,38 @@ func TestStartStop(t *testing.T) {
 		t.Errorf("StartStopComponents() (-got, +want):\n%s", diff)
 	}
 }
+
+type ComponentValidate struct {
+	err error
+}
+
+func (c ComponentValidate) Validate() error {
+	return c.err
+}
+
+func TestValidateComponents(t *testing.T) {
+	err := cmd.ValidateComponents(map[string]interface{}{
+		"http":  ComponentValidate{errors.New(`listen: "127.0.0.1:80" already used`)},
+		"flow":  ComponentValidate{errors.Join(errors.New("inputs[1].listen: bad"), errors.New("inputs[2].listen: bad"))},
+		"kafka": ComponentValidate{},
+		"core":  &ComponentNone{},
+		"":      ComponentValidate{errors.New("network-sources.test.url: bad")},
+	})
+	if err == nil {
+		t.Fatal("ValidateComponents() did not error")
+	}
+	expected := `invalid configuration:
+network-sources.test.url: bad
+flow.inputs[1].listen: bad
+flow.inputs[2].listen: bad
+http.listen: "127.0.0.1:80" already used`
+	if diff := helpers.Diff(err.Error(), expected); diff != "" {
+		t.Fatalf("ValidateComponents() (-got, +want):\n%s", diff)
+	}
+
+	if err := cmd.ValidateComponents(map[string]interface{}{
+		"kafka": ComponentValidate{},
+	}); err != nil {
+		t.Fatalf("ValidateComponents() error:\n%+v", err)
+	}
+}
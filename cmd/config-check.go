@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/benbjohnson/clock"
+	"github.com/spf13/cobra"
+
+	"akvorado/common/reporter"
+)
+
+// ConfigCheckOptions stores the command-line option values for the config
+// check command.
+var ConfigCheckOptions ConfigRelatedOptions
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration-related commands",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check SERVICE CONFIG",
+	Short: "Check the configuration of a service",
+	Long: `Parse and validate the configuration of a service without starting it.
+The checks are the same as the ones done by the service when using --check.
+On success, the resolved configuration is displayed with secrets masked. On
+error, a JSON summary is displayed.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service := args[0]
+		config, beforeDump, check, err := configurationForService(service)
+		if err != nil {
+			return err
+		}
+		options := ConfigCheckOptions
+		options.Path = args[1]
+		options.Dump = true
+		options.BeforeDump = beforeDump
+		dump := new(bytes.Buffer)
+		err = options.Parse(dump, service, config)
+		if err == nil {
+			err = check()
+		}
+		if err != nil {
+			output, _ := json.Marshal(struct {
+				Service string   `json:"service"`
+				Errors  []string `json:"errors"`
+			}{
+				Service: service,
+				Errors:  configurationErrors(err),
+			})
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", output)
+			return err
+		}
+		cmd.OutOrStdout().Write(dump.Bytes())
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configCheckCmd)
+	addConfigHTTPFlags(configCheckCmd, &ConfigCheckOptions)
+}
+
+// configurationForService returns an empty configuration for the provided
+// service, the function to call before dumping it, if any, and the function
+// checking the configuration of each component once parsed.
+func configurationForService(service string) (interface{}, func(), func() error, error) {
+	switch service {
+	case "inlet":
+		config := &InletConfiguration{}
+		return config, nil, func() error {
+			return checkService(config.Reporting, func(r *reporter.Reporter) error {
+				return inletStart(r, *config, clock.New(), true)
+			})
+		}, nil
+	case "console":
+		config := &ConsoleConfiguration{}
+		return config, nil, func() error {
+			return checkService(config.Reporting, func(r *reporter.Reporter) error {
+				return consoleStart(r, *config, true)
+			})
+		}, nil
+	case "demo-exporter":
+		config := &DemoExporterConfiguration{}
+		return config, nil, func() error {
+			return checkService(config.Reporting, func(r *reporter.Reporter) error {
+				return demoExporterStart(r, *config, true)
+			})
+		}, nil
+	case "orchestrator":
+		config := &OrchestratorConfiguration{}
+		return config, func() { overrideOrchestratorConfiguration(config) }, func() error {
+			return checkService(config.Reporting, func(r *reporter.Reporter) error {
+				return orchestratorStart(r, *config, true)
+			})
+		}, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown service %q", service)
+	}
+}
+
+// checkService initializes the components of a service in check mode.
+func checkService(config reporter.Configuration, start func(*reporter.Reporter) error) error {
+	r, err := reporter.New(config)
+	if err != nil {
+		return fmt.Errorf("unable to initialize reporter: %w", err)
+	}
+	return start(r)
+}
+
+// configurationErrors splits an error returned when parsing a configuration
+// into a list of problems.
+func configurationErrors(err error) []string {
+	problems := strings.Split(err.Error(), "\n")
+	if len(problems) > 1 && problems[0] == "invalid configuration:" {
+		problems = problems[1:]
+	}
+	return problems
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"akvorado/cmd"
+	"akvorado/common/helpers"
+)
+
+func TestConfigCheck(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		root := cmd.RootCmd
+		buf := new(bytes.Buffer)
+		root.SetOut(buf)
+		root.SetArgs([]string{
+			"config", "check", "orchestrator",
+			filepath.Join("testdata/configurations", "shipped", "in.yaml"),
+		})
+		if err := root.Execute(); err != nil {
+			t.Fatalf("`config check` error:\n%+v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "---\n") {
+			t.Fatalf("`config check` output should be YAML:\n%s", buf.String())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configFile, []byte("unknown: 1\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error:\n%+v", err)
+		}
+		root := cmd.RootCmd
+		buf := new(bytes.Buffer)
+		root.SetOut(buf)
+		root.SetErr(new(bytes.Buffer))
+		root.SetArgs([]string{"config", "check", "console", configFile})
+		if err := root.Execute(); err == nil {
+			t.Fatal("`config check` did not error")
+		}
+		var got struct {
+			Service string   `json:"service"`
+			Errors  []string `json:"errors"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("json.Unmarshal() error:\n%+v", err)
+		}
+		want := []string{`invalid key "unknown"`}
+		if got.Service != "console" {
+			t.Errorf("`config check` service: got %q, want %q", got.Service, "console")
+		}
+		if diff := helpers.Diff(got.Errors, want); diff != "" {
+			t.Errorf("`config check` errors (-got, +want):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid component", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configFile, []byte(`
+http:
+  listen: 127.0.0.1:8080
+  profiler-listen: 127.0.0.1:8080
+`), 0o644); err != nil {
+			t.Fatalf("WriteFile() error:\n%+v", err)
+		}
+		root := cmd.RootCmd
+		buf := new(bytes.Buffer)
+		root.SetOut(buf)
+		root.SetErr(new(bytes.Buffer))
+		root.SetArgs([]string{"config", "check", "console", configFile})
+		if err := root.Execute(); err == nil {
+			t.Fatal("`config check` did not error")
+		}
+		var got struct {
+			Errors []string `json:"errors"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("json.Unmarshal() error:\n%+v", err)
+		}
+		want := []string{`http.profiler-listen: "127.0.0.1:8080" already used by listen`}
+		if diff := helpers.Diff(got.Errors, want); diff != "" {
+			t.Errorf("`config check` errors (-got, +want):\n%s", diff)
+		}
+	})
+}
@@ -4,6 +4,9 @@
 package cmd
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -15,10 +18,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
 
 	"akvorado/common/helpers/yaml"
 
@@ -31,6 +38,33 @@ type ConfigRelatedOptions struct {
 	Path       string
 	Dump       bool
 	BeforeDump func()
+
+	// dumpSecrets disables masking secrets when dumping the configuration.
+	// This is only used in tests to check the complete configuration.
+	dumpSecrets bool
+
+	// Options used when fetching the configuration over HTTP
+	HTTPHeaders []string
+	HTTPCAFile  string
+	HTTPTimeout time.Duration
+	HTTPRetries int
+}
+
+// configRetryDelay is the delay between two attempts to fetch the
+// configuration over HTTP.
+const configRetryDelay = time.Second
+
+// addConfigHTTPFlags registers the flags related to fetching the
+// configuration over HTTP.
+func addConfigHTTPFlags(cmd *cobra.Command, options *ConfigRelatedOptions) {
+	cmd.Flags().StringArrayVar(&options.HTTPHeaders, "config-header", nil,
+		"Add an HTTP header (\"Name: value\") when fetching configuration")
+	cmd.Flags().StringVar(&options.HTTPCAFile, "config-ca-file", "",
+		"CA certificates to check the server when fetching configuration")
+	cmd.Flags().DurationVar(&options.HTTPTimeout, "config-timeout", 30*time.Second,
+		"Timeout for each attempt to fetch configuration")
+	cmd.Flags().IntVar(&options.HTTPRetries, "config-retries", 3,
+		"Number of retries when fetching configuration fails")
 }
 
 // Parse parses the configuration file (if present) and the
@@ -49,22 +83,32 @@ func (c ConfigRelatedOptions) Parse(out io.Writer, component string, config inte
 			if u.Fragment != "" {
 				u.Path = fmt.Sprintf("%s/%s", u.Path, u.Fragment)
 			}
-			resp, err := http.Get(u.String())
+			resp, err := c.fetch(u.String())
 			if err != nil {
 				return fmt.Errorf("unable to fetch configuration file: %w", err)
 			}
 			defer resp.Body.Close()
 			contentType := resp.Header.Get("Content-Type")
 			mediaType, _, err := mime.ParseMediaType(contentType)
-			if (mediaType != "application/x-yaml" && mediaType != "application/yaml") || err != nil {
-				return fmt.Errorf("received configuration file is not YAML (%s)", contentType)
+			if err != nil {
+				return fmt.Errorf("received configuration file has an invalid type (%s)", contentType)
 			}
 			input, err := io.ReadAll(resp.Body)
 			if err != nil {
 				return fmt.Errorf("unable to read configuration file: %w", err)
 			}
-			if err := yaml.Unmarshal(input, &rawConfig); err != nil {
-				return fmt.Errorf("unable to parse YAML configuration file: %w", err)
+			switch mediaType {
+			case "application/x-yaml", "application/yaml", "application/json":
+				// JSON is a subset of YAML
+				if err := yaml.Unmarshal(input, &rawConfig); err != nil {
+					return fmt.Errorf("unable to parse YAML configuration file: %w", err)
+				}
+			case "application/toml":
+				if err := toml.Unmarshal(input, &rawConfig); err != nil {
+					return fmt.Errorf("unable to parse TOML configuration file: %w", err)
+				}
+			default:
+				return fmt.Errorf("received configuration file is not YAML, JSON, or TOML (%s)", contentType)
 			}
 		} else {
 			cfgFile, err := filepath.EvalSymlinks(cfgFile)
@@ -75,12 +119,27 @@ func (c ConfigRelatedOptions) Parse(out io.Writer, component string, config inte
 			if dirname == "" {
 				dirname = "."
 			}
-			if err := yaml.UnmarshalWithInclude(os.DirFS(dirname), filename, &rawConfig); err != nil {
+			if strings.EqualFold(filepath.Ext(filename), ".toml") {
+				input, err := os.ReadFile(cfgFile)
+				if err != nil {
+					return fmt.Errorf("unable to read configuration file: %w", err)
+				}
+				if err := toml.Unmarshal(input, &rawConfig); err != nil {
+					return fmt.Errorf("unable to parse TOML configuration file: %w", err)
+				}
+			} else if err := yaml.UnmarshalWithInclude(os.DirFS(dirname), filename, &rawConfig); err != nil {
+				// JSON is a subset of YAML
 				return fmt.Errorf("unable to parse YAML configuration file: %w", err)
 			}
 		}
 	}
 
+	// Resolve references to files and environment variables
+	secrets := []string{}
+	if _, err := resolveReferences("", rawConfig, &secrets); err != nil {
+		return err
+	}
+
 	// Parse provided configuration
 	defaultHook, disableDefaultHook := DefaultHook()
 	zeroSliceHook, disableZeroSliceHook := ZeroSliceHook()
@@ -112,8 +171,15 @@ func (c ConfigRelatedOptions) Parse(out io.Writer, component string, config inte
 		// build a map "squid -> purple -> quirk ->
 		// 47". From AKVORADO_CFG_CMP_SQUID_3_PURPLE=47, we
 		// build "squid[3] -> purple -> 47"
+		value, resolved, err := resolveReference(kv[1])
+		if err != nil {
+			return fmt.Errorf("unable to resolve override %q: %w", kv[0], err)
+		}
+		if resolved && value != "" {
+			secrets = append(secrets, value)
+		}
 		var rawConfig interface{}
-		rawConfig = kv[1]
+		rawConfig = value
 		for i := len(kk) - 1; i > 2; i-- {
 			if index, err := strconv.Atoi(kk[i]); err == nil {
 				newRawConfig := make([]interface{}, index+1)
@@ -149,13 +215,28 @@ func (c ConfigRelatedOptions) Parse(out io.Writer, component string, config inte
 	if err := helpers.Validate.Struct(config); err != nil {
 		switch verr := err.(type) {
 		case validator.ValidationErrors:
-			return fmt.Errorf("invalid configuration:\n%w", verr)
+			problems := make([]string, len(verr))
+			for idx, ferr := range verr {
+				tag := ferr.Tag()
+				if ferr.Param() != "" {
+					tag = fmt.Sprintf("%s=%s", tag, ferr.Param())
+				}
+				problems[idx] = fmt.Sprintf("%s: failed on %q",
+					configurationPath(reflect.TypeOf(config), ferr.StructNamespace()), tag)
+			}
+			return fmt.Errorf("invalid configuration:\n%s", strings.Join(problems, "\n"))
 		default:
 			return fmt.Errorf("unexpected internal error: %w", verr)
 		}
 	}
 	if c.Dump {
-		output, err := yaml.Marshal(config)
+		var output []byte
+		var err error
+		if c.dumpSecrets {
+			output, err = yaml.Marshal(config)
+		} else {
+			output, err = yaml.MarshalWithoutSecrets(config, secrets...)
+		}
 		if err != nil {
 			return fmt.Errorf("unable to dump configuration: %w", err)
 		}
@@ -167,6 +248,220 @@ func (c ConfigRelatedOptions) Parse(out io.Writer, component string, config inte
 	return nil
 }
 
+// fetch retrieves the configuration from the provided URL. It retries on
+// network errors and server errors.
+func (c ConfigRelatedOptions) fetch(u string) (*http.Response, error) {
+	client := &http.Client{Timeout: c.HTTPTimeout}
+	if c.HTTPCAFile != "" {
+		caCert, err := os.ReadFile(c.HTTPCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, errors.New("cannot parse CA certificate")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+		client.Transport = transport
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range c.HTTPHeaders {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid HTTP header %q", header)
+		}
+		value, _, err := resolveReference(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve HTTP header %q: %w", name, err)
+		}
+		req.Header.Add(strings.TrimSpace(name), value)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		if attempt >= c.HTTPRetries {
+			return nil, err
+		}
+		time.Sleep(configRetryDelay)
+	}
+}
+
+// resolveReference resolves a value of the form ${file:/path} or
+// ${env:VARIABLE}. Other values are returned unchanged. The second returned
+// value tells if the value was a reference.
+func resolveReference(value string) (string, bool, error) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return value, false, nil
+	}
+	scheme, reference, ok := strings.Cut(value[2:len(value)-1], ":")
+	if !ok {
+		return "", false, fmt.Errorf("invalid reference %q", value)
+	}
+	switch scheme {
+	case "env":
+		result, ok := os.LookupEnv(reference)
+		if !ok {
+			return "", false, fmt.Errorf("environment variable %q is not set", reference)
+		}
+		return result, true, nil
+	case "file":
+		content, err := os.ReadFile(reference)
+		if err != nil {
+			return "", false, fmt.Errorf("unable to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	default:
+		return "", false, fmt.Errorf("unknown reference scheme %q in %q", scheme, value)
+	}
+}
+
+// resolveReferences walks the raw configuration and resolves references in
+// place. Resolved values are appended to secrets.
+func resolveReferences(path string, raw interface{}, secrets *[]string) (interface{}, error) {
+	switch raw := raw.(type) {
+	case string:
+		value, resolved, err := resolveReference(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %q: %w", path, err)
+		}
+		if resolved && value != "" {
+			*secrets = append(*secrets, value)
+		}
+		return value, nil
+	case gin.H:
+		return resolveReferences(path, map[string]interface{}(raw), secrets)
+	case map[string]interface{}:
+		for key, value := range raw {
+			value, err := resolveReferences(strings.TrimPrefix(fmt.Sprintf("%s.%s", path, key), "."), value, secrets)
+			if err != nil {
+				return nil, err
+			}
+			raw[key] = value
+		}
+	case []interface{}:
+		for idx, value := range raw {
+			value, err := resolveReferences(fmt.Sprintf("%s[%d]", path, idx), value, secrets)
+			if err != nil {
+				return nil, err
+			}
+			raw[idx] = value
+		}
+	}
+	return raw, nil
+}
+
+// configurationPath turns the namespace of a validation error into the path of
+// the key in the configuration file. The root structure and the squashed
+// structures are omitted.
+func configurationPath(root reflect.Type, namespace string) string {
+	segments := []string{}
+	depth, start := 0, 0
+	for idx, r := range namespace {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, namespace[start:idx])
+				start = idx + 1
+			}
+		}
+	}
+	segments = append(segments, namespace[start:])
+
+	path := []string{}
+	current := root
+	for _, segment := range segments[1:] {
+		name, index, _ := strings.Cut(segment, "[")
+		for current != nil && current.Kind() == reflect.Ptr {
+			current = current.Elem()
+		}
+		if current != nil && current.Kind() == reflect.Struct {
+			if field, ok := current.FieldByName(name); ok {
+				current = field.Type
+				if field.Anonymous && strings.Contains(field.Tag.Get("mapstructure"), "squash") {
+					continue
+				}
+			} else {
+				current = nil
+			}
+		} else {
+			current = nil
+		}
+		if index != "" {
+			index = "[" + index
+			if current != nil {
+				switch current.Kind() {
+				case reflect.Slice, reflect.Array, reflect.Map:
+					current = current.Elem()
+				default:
+					current = nil
+				}
+			}
+		}
+		path = append(path, kebabCase(name)+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// kebabCaseWords are words which are not split when turning a field name into
+// kebab-case.
+var kebabCaseWords = map[string]string{
+	"ClickHouse": "clickhouse",
+	"GeoIP":      "geoip",
+	"IPv4":       "ipv4",
+	"IPv6":       "ipv6",
+	"ASNs":       "asns",
+	"ASPaths":    "aspaths",
+}
+
+// kebabCase turns a field name into its kebab-case equivalent, as used in the
+// configuration file (SASLPassword becomes sasl-password, ClickHouse becomes
+// clickhouse).
+func kebabCase(name string) string {
+	var result strings.Builder
+	runes := []rune(name)
+	afterWord := false
+outer:
+	for idx := 0; idx < len(runes); idx++ {
+		for word, replacement := range kebabCaseWords {
+			if strings.HasPrefix(string(runes[idx:]), word) {
+				if idx > 0 {
+					result.WriteRune('-')
+				}
+				result.WriteString(replacement)
+				idx += len([]rune(word)) - 1
+				afterWord = true
+				continue outer
+			}
+		}
+		r := runes[idx]
+		if unicode.IsUpper(r) {
+			if idx > 0 && (afterWord || !unicode.IsUpper(runes[idx-1]) ||
+				(idx+1 < len(runes) && unicode.IsLower(runes[idx+1]))) {
+				result.WriteRune('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		afterWord = false
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
 // DefaultHook will reset the destination value to its default using
 // the Reset() method if present.
 func DefaultHook() (mapstructure.DecodeHookFunc, func()) {
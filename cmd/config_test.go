@@ -5,7 +5,9 @@ package cmd_test
 
 import (
 	"bytes"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -95,8 +97,46 @@ module1:
 	if err := c.Parse(out, "dummy", &parsed); err == nil {
 		t.Fatal("Parse() didn't error")
 	} else if diff := helpers.Diff(err.Error(), `invalid configuration:
-Key: 'dummyConfiguration.Module1.Topic' Error:Field validation for 'Topic' failed on the 'gte' tag
-Key: 'dummyConfiguration.Module1.Workers' Error:Field validation for 'Workers' failed on the 'gte' tag`); diff != "" {
+module1.topic: failed on "gte=3"
+module1.workers: failed on "gte=1"`); diff != "" {
+		t.Fatalf("Parse() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestValidationPath(t *testing.T) {
+	type geoIPConfiguration struct {
+		ASNDatabase []string `validate:"min=1"`
+	}
+	type clickHouseConfiguration struct {
+		IPv6PrefixLength int    `validate:"max=128"`
+		SASLPassword     string `validate:"min=3"`
+		CollectASNs      int    `validate:"min=1"`
+	}
+	type configuration struct {
+		ClickHouse clickHouseConfiguration
+		GeoIP      geoIPConfiguration
+	}
+	parsed := configuration{}
+	config := `---
+clickhouse:
+ ipv6-prefix-length: 130
+ sasl-password: a
+geoip: {}
+`
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configFile, []byte(config), 0o644)
+
+	c := cmd.ConfigRelatedOptions{
+		Path: configFile,
+	}
+	out := bytes.NewBuffer([]byte{})
+	if err := c.Parse(out, "dummy", &parsed); err == nil {
+		t.Fatal("Parse() didn't error")
+	} else if diff := helpers.Diff(err.Error(), `invalid configuration:
+clickhouse.ipv6-prefix-length: failed on "max=128"
+clickhouse.sasl-password: failed on "min=3"
+clickhouse.collect-asns: failed on "min=1"
+geoip.asn-database: failed on "min=1"`); diff != "" {
 		t.Fatalf("Parse() (-got, +want):\n%s", diff)
 	}
 }
@@ -187,6 +227,79 @@ module2:
 	}
 }
 
+func TestConfigurationFormats(t *testing.T) {
+	cases := []struct {
+		Filename string
+		Content  string
+	}{
+		{
+			Filename: "config.json",
+			Content: `{
+  "module1": {"topic": "flows"},
+  "module2": {
+    "details": {"workers": 5, "interval-value": "20m"},
+    "stuff": "bye",
+    "elements": [{"name": "first", "gauge": 67}, {"name": "second"}]
+  }
+}`,
+		}, {
+			Filename: "config.toml",
+			Content: `
+[module1]
+topic = "flows"
+
+[module2]
+stuff = "bye"
+
+[module2.details]
+workers = 5
+interval-value = "20m"
+
+[[module2.elements]]
+name = "first"
+gauge = 67
+
+[[module2.elements]]
+name = "second"
+`,
+		},
+	}
+	expected := dummyConfiguration{
+		Module1: dummyModule1Configuration{
+			Listen:  "127.0.0.1:8080",
+			Topic:   "flows",
+			Workers: 100,
+		},
+		Module2: dummyModule2Configuration{
+			MoreDetails: MoreDetails{
+				Stuff: "bye",
+			},
+			Details: dummyModule2DetailsConfiguration{
+				Workers:       5,
+				IntervalValue: 20 * time.Minute,
+			},
+			Elements: []dummyModule2ElementsConfiguration{
+				{"first", 67},
+				{"second", 0},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Filename, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), tc.Filename)
+			os.WriteFile(configFile, []byte(tc.Content), 0o644)
+			c := cmd.ConfigRelatedOptions{Path: configFile}
+			parsed := dummyConfiguration{}
+			if err := c.Parse(io.Discard, "dummy", &parsed); err != nil {
+				t.Fatalf("Parse() error:\n%+v", err)
+			}
+			if diff := helpers.Diff(parsed, expected); diff != "" {
+				t.Errorf("Parse() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestEnvOverride(t *testing.T) {
 	// Configuration file
 	config := `---
@@ -255,6 +368,77 @@ module2:
 	}
 }
 
+func TestReferences(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0o600)
+	t.Setenv("AKVORADO_TEST_TOPIC", "from-env")
+	t.Setenv("AKVORADO_CFG_DUMMY_MODULE2_ELEMENTS_0_NAME", fmt.Sprintf("${file:%s}", secretFile))
+	config := fmt.Sprintf(`---
+module1:
+ topic: ${env:AKVORADO_TEST_TOPIC}
+module2:
+ stuff: ${file:%s}
+`, secretFile)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configFile, []byte(config), 0o644)
+
+	c := cmd.ConfigRelatedOptions{
+		Path: configFile,
+		Dump: true,
+	}
+	parsed := dummyConfiguration{}
+	out := bytes.NewBuffer([]byte{})
+	if err := c.Parse(out, "dummy", &parsed); err != nil {
+		t.Fatalf("Parse() error:\n%+v", err)
+	}
+	if parsed.Module1.Topic != "from-env" {
+		t.Errorf("Parse() topic: got %q, expected %q", parsed.Module1.Topic, "from-env")
+	}
+	if parsed.Module2.Stuff != "from-file" {
+		t.Errorf("Parse() stuff: got %q, expected %q", parsed.Module2.Stuff, "from-file")
+	}
+	if parsed.Module2.Elements[0].Name != "from-file" {
+		t.Errorf("Parse() elements[0].name: got %q, expected %q", parsed.Module2.Elements[0].Name, "from-file")
+	}
+
+	var gotRaw map[string]gin.H
+	if err := yaml.Unmarshal(out.Bytes(), &gotRaw); err != nil {
+		t.Fatalf("Unmarshal() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(gotRaw["module1"]["topic"], "******"); diff != "" {
+		t.Errorf("Parse() dump (-got, +want):\n%s", diff)
+	}
+	if diff := helpers.Diff(gotRaw["module2"]["stuff"], "******"); diff != "" {
+		t.Errorf("Parse() dump (-got, +want):\n%s", diff)
+	}
+}
+
+func TestInvalidReferences(t *testing.T) {
+	cases := []struct {
+		Value string
+		Error string
+	}{
+		{"${vault:secret/kafka}", `cannot resolve "module1.topic": unknown reference scheme "vault" in "${vault:secret/kafka}"`},
+		{"${AKVORADO_TEST_MISSING}", `cannot resolve "module1.topic": invalid reference "${AKVORADO_TEST_MISSING}"`},
+		{"${env:AKVORADO_TEST_MISSING}", `cannot resolve "module1.topic": environment variable "AKVORADO_TEST_MISSING" is not set`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Value, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			os.WriteFile(configFile, []byte(fmt.Sprintf("module1:\n topic: %s\n", tc.Value)), 0o644)
+			c := cmd.ConfigRelatedOptions{Path: configFile}
+			parsed := dummyConfiguration{}
+			err := c.Parse(io.Discard, "dummy", &parsed)
+			if err == nil {
+				t.Fatal("Parse() did not error")
+			}
+			if diff := helpers.Diff(err.Error(), tc.Error); diff != "" {
+				t.Fatalf("Parse() error (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestHTTPConfiguration(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
@@ -309,6 +493,58 @@ module2:
 	}
 }
 
+func TestHTTPConfigurationWithAuthentication(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		fmt.Fprint(w, `---
+module1:
+ topic: flows
+`)
+	}))
+	defer ts.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: ts.Certificate().Raw,
+	}), 0o644)
+	t.Setenv("AKVORADO_TEST_TOKEN", "Bearer token")
+
+	c := cmd.ConfigRelatedOptions{
+		Path:        ts.URL,
+		HTTPHeaders: []string{"Authorization: ${env:AKVORADO_TEST_TOKEN}"},
+		HTTPCAFile:  caFile,
+		HTTPTimeout: 5 * time.Second,
+		HTTPRetries: 1,
+	}
+	parsed := dummyConfiguration{}
+	if err := c.Parse(io.Discard, "dummy", &parsed); err != nil {
+		t.Fatalf("Parse() error:\n%+v", err)
+	}
+	if parsed.Module1.Topic != "flows" {
+		t.Errorf("Parse() topic: got %q, expected %q", parsed.Module1.Topic, "flows")
+	}
+	if attempts != 2 {
+		t.Errorf("Parse() made %d attempts, expected 2", attempts)
+	}
+
+	// Without the CA, this should fail
+	c.HTTPCAFile = ""
+	c.HTTPRetries = 0
+	if err := c.Parse(io.Discard, "dummy", &parsed); err == nil {
+		t.Error("Parse() did not error without CA")
+	}
+}
+
 func TestUnused(t *testing.T) {
 	t.Run("ignored fields", func(t *testing.T) {
 		config := `---
@@ -24,7 +24,9 @@ containers started with the label "akvorado.conntrack.fix=1".`,
 	Args: cobra.NoArgs,
 	RunE: func(_ *cobra.Command, _ []string) error {
 		// This is a simplified service which is not configurable.
-		r, err := reporter.New(reporter.DefaultConfiguration())
+		config := reporter.DefaultConfiguration()
+		applyDebugFlag(&config)
+		r, err := reporter.New(config)
 		if err != nil {
 			return fmt.Errorf("unable to initialize reporter: %w", err)
 		}
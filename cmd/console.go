@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 
@@ -63,6 +64,7 @@ manage collected flows.`,
 		if err := ConsoleOptions.Parse(cmd.OutOrStdout(), "console", &config); err != nil {
 			return err
 		}
+		applyDebugFlag(&config.Reporting)
 
 		r, err := reporter.New(config.Reporting)
 		if err != nil {
@@ -76,6 +78,7 @@ func init() {
 	RootCmd.AddCommand(consoleCmd)
 	consoleCmd.Flags().BoolVarP(&ConsoleOptions.ConfigRelatedOptions.Dump, "dump", "D", false,
 		"Dump configuration before starting")
+	addConfigHTTPFlags(consoleCmd, &ConsoleOptions.ConfigRelatedOptions)
 	consoleCmd.Flags().BoolVarP(&ConsoleOptions.CheckMode, "check", "C", false,
 		"Check configuration, but does not start")
 }
@@ -125,6 +128,19 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 	addCommonHTTPHandlers(r, "console", httpComponent)
 	versionMetrics(r)
 
+	// Check the configuration of each component
+	configuredComponents := map[string]interface{}{
+		"reporting":  r,
+		"http":       httpComponent,
+		"clickhouse": clickhouseComponent,
+		"auth":       authenticationComponent,
+		"database":   databaseComponent,
+		"":           consoleComponent,
+	}
+	if err := ValidateComponents(configuredComponents); err != nil {
+		return err
+	}
+
 	// If we only asked for a check, stop here.
 	if checkOnly {
 		return nil
@@ -137,6 +153,14 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 		authenticationComponent,
 		databaseComponent,
 		consoleComponent,
+		NewConfigurationReloader(r, daemonComponent, config, configuredComponents, func() (interface{}, error) {
+			newConfig := ConsoleConfiguration{}
+			options := ConsoleOptions.ConfigRelatedOptions
+			options.Dump = false
+			err := options.Parse(io.Discard, "console", &newConfig)
+			applyDebugFlag(&newConfig.Reporting)
+			return newConfig, err
+		}),
 	}
 	return StartStopComponents(r, daemonComponent, components)
 }
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 
@@ -60,6 +61,7 @@ and answers SNMP requests.`,
 		if err := DemoExporterOptions.Parse(cmd.OutOrStdout(), "demo-exporter", &config); err != nil {
 			return err
 		}
+		applyDebugFlag(&config.Reporting)
 
 		r, err := reporter.New(config.Reporting)
 		if err != nil {
@@ -73,6 +75,7 @@ func init() {
 	RootCmd.AddCommand(demoExporterCmd)
 	demoExporterCmd.Flags().BoolVarP(&DemoExporterOptions.ConfigRelatedOptions.Dump, "dump", "D", false,
 		"Dump configuration before starting")
+	addConfigHTTPFlags(demoExporterCmd, &DemoExporterOptions.ConfigRelatedOptions)
 	demoExporterCmd.Flags().BoolVarP(&DemoExporterOptions.CheckMode, "check", "C", false,
 		"Check configuration, but does not start")
 }
@@ -118,6 +121,19 @@ func demoExporterStart(r *reporter.Reporter, config DemoExporterConfiguration, c
 	addCommonHTTPHandlers(r, "demo-exporter", httpComponent)
 	versionMetrics(r)
 
+	// Check the configuration of each component
+	configuredComponents := map[string]interface{}{
+		"reporting": r,
+		"http":      httpComponent,
+		"snmp":      snmpComponent,
+		"bmp":       bmpComponent,
+		"flows":     flowsComponent,
+		"":          demoExporterComponent,
+	}
+	if err := ValidateComponents(configuredComponents); err != nil {
+		return err
+	}
+
 	// If we only asked for a check, stop here.
 	if checkOnly {
 		return nil
@@ -130,6 +146,14 @@ func demoExporterStart(r *reporter.Reporter, config DemoExporterConfiguration, c
 		bmpComponent,
 		flowsComponent,
 		demoExporterComponent,
+		NewConfigurationReloader(r, daemonComponent, config, configuredComponents, func() (interface{}, error) {
+			newConfig := DemoExporterConfiguration{}
+			options := DemoExporterOptions.ConfigRelatedOptions
+			options.Dump = false
+			err := options.Parse(io.Discard, "demo-exporter", &newConfig)
+			applyDebugFlag(&newConfig.Reporting)
+			return newConfig, err
+		}),
 	}
 	return StartStopComponents(r, daemonComponent, components)
 }
@@ -34,9 +34,9 @@ func TestDemoExporter(t *testing.T) {
 
 	want := []string{
 		`invalid configuration:`,
-		`Key: 'DemoExporterConfiguration.SNMP.Interfaces' Error:Field validation for 'Interfaces' failed on the 'min' tag`,
-		`Key: 'DemoExporterConfiguration.Flows.Flows' Error:Field validation for 'Flows' failed on the 'min' tag`,
-		`Key: 'DemoExporterConfiguration.Flows.Target' Error:Field validation for 'Target' failed on the 'required' tag`,
+		`snmp.interfaces: failed on "min=1"`,
+		`flows.flows: failed on "required_without=ReplayFile"`,
+		`flows.target: failed on "required"`,
 	}
 	got := strings.Split(err.Error(), "\n")
 	if diff := helpers.Diff(got, want); diff != "" {
@@ -4,13 +4,24 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"net/http"
 
 	"github.com/spf13/cobra"
 )
 
+type healthcheckOptions struct {
+	HTTPS bool
+}
+
+// HealthcheckOptions stores the command-line option values for the
+// healthcheck command.
+var HealthcheckOptions healthcheckOptions
+
 func init() {
 	RootCmd.AddCommand(healthcheckCmd)
+	healthcheckCmd.Flags().BoolVar(&HealthcheckOptions.HTTPS, "https", false,
+		"Use HTTPS (the certificate is not verified)")
 }
 
 var healthcheckCmd = &cobra.Command{
@@ -18,7 +29,18 @@ var healthcheckCmd = &cobra.Command{
 	Short: "Check healthness",
 	Long:  `Check if Akvorado is alive using the builtin HTTP endpoint.`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		resp, err := http.Get("http://localhost:8080/api/v0/healthcheck")
+		url := "http://localhost:8080/api/v0/healthcheck"
+		client := http.DefaultClient
+		if HealthcheckOptions.HTTPS {
+			url = "https://localhost:8080/api/v0/healthcheck"
+			client = &http.Client{
+				Transport: &http.Transport{
+					// The certificate is unlikely to be valid for localhost.
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				},
+			}
+		}
+		resp, err := client.Get(url)
 		if err != nil {
 			return err
 		}
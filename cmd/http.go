@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 
 	"akvorado/common/httpserver"
 	"akvorado/common/reporter"
@@ -18,6 +19,14 @@ func addCommonHTTPHandlers(r *reporter.Reporter, service string, httpComponent *
 	httpComponent.AddHandler("/api/v0/metrics", r.MetricsHTTPHandler())
 	httpComponent.GinRouter.GET(fmt.Sprintf("/api/v0/%s/healthcheck", service), r.HealthcheckHTTPHandler)
 	httpComponent.GinRouter.GET("/api/v0/healthcheck", r.HealthcheckHTTPHandler)
+	httpComponent.GinRouter.GET(fmt.Sprintf("/api/v0/%s/ready", service), r.ReadinessHTTPHandler)
+	httpComponent.GinRouter.GET("/api/v0/ready", r.ReadinessHTTPHandler)
+	httpComponent.GinRouter.GET(fmt.Sprintf("/api/v0/%s/healthz", service), r.LivenessHTTPHandler)
+	httpComponent.GinRouter.GET("/api/v0/healthz", r.LivenessHTTPHandler)
 	httpComponent.GinRouter.GET(fmt.Sprintf("/api/v0/%s/version", service), versionHandler)
 	httpComponent.GinRouter.GET("/api/v0/version", versionHandler)
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		httpComponent.GinRouter.Handle(method, fmt.Sprintf("/api/v0/%s/loglevel", service), r.LogLevelHTTPHandler)
+		httpComponent.GinRouter.Handle(method, "/api/v0/loglevel", r.LogLevelHTTPHandler)
+	}
 }
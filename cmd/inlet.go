@@ -5,8 +5,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/gin-gonic/gin"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
@@ -56,6 +59,7 @@ func (c *InletConfiguration) Reset() {
 type inletOptions struct {
 	ConfigRelatedOptions
 	CheckMode bool
+	FixedTime string
 }
 
 // InletOptions stores the command-line option values for the inlet
@@ -74,12 +78,24 @@ enrichment and export to Kafka.`,
 		if err := InletOptions.Parse(cmd.OutOrStdout(), "inlet", &config); err != nil {
 			return err
 		}
+		applyDebugFlag(&config.Reporting)
+
+		clk := clock.New()
+		if InletOptions.FixedTime != "" {
+			fixedTime, err := time.Parse(time.RFC3339, InletOptions.FixedTime)
+			if err != nil {
+				return fmt.Errorf("unable to parse fixed time: %w", err)
+			}
+			mockClock := clock.NewMock()
+			mockClock.Set(fixedTime)
+			clk = mockClock
+		}
 
 		r, err := reporter.New(config.Reporting)
 		if err != nil {
 			return fmt.Errorf("unable to initialize reporter: %w", err)
 		}
-		return inletStart(r, config, InletOptions.CheckMode)
+		return inletStart(r, config, clk, InletOptions.CheckMode)
 	},
 }
 
@@ -87,11 +103,15 @@ func init() {
 	RootCmd.AddCommand(inletCmd)
 	inletCmd.Flags().BoolVarP(&InletOptions.ConfigRelatedOptions.Dump, "dump", "D", false,
 		"Dump configuration before starting")
+	addConfigHTTPFlags(inletCmd, &InletOptions.ConfigRelatedOptions)
 	inletCmd.Flags().BoolVarP(&InletOptions.CheckMode, "check", "C", false,
 		"Check configuration, but does not start")
+	inletCmd.Flags().StringVar(&InletOptions.FixedTime, "fixed-time", "",
+		"Freeze the clock at the provided RFC 3339 time (for reproducible replays)")
 }
 
-func inletStart(r *reporter.Reporter, config InletConfiguration, checkOnly bool) error {
+func inletStart(r *reporter.Reporter, config InletConfiguration, clk clock.Clock, checkOnly bool) error {
+	// Flow reception times and exporter expiration share the same clock
 	// Initialize the various components
 	daemonComponent, err := daemon.New(r)
 	if err != nil {
@@ -111,6 +131,7 @@ func inletStart(r *reporter.Reporter, config InletConfiguration, checkOnly bool)
 		Daemon: daemonComponent,
 		HTTP:   httpComponent,
 		Schema: schemaComponent,
+		Clock:  clk,
 	})
 	if err != nil {
 		return fmt.Errorf("unable to initialize flow component: %w", err)
@@ -142,6 +163,7 @@ func inletStart(r *reporter.Reporter, config InletConfiguration, checkOnly bool)
 		Kafka:    kafkaComponent,
 		HTTP:     httpComponent,
 		Schema:   schemaComponent,
+		Clock:    clk,
 	})
 	if err != nil {
 		return fmt.Errorf("unable to initialize core component: %w", err)
@@ -151,20 +173,47 @@ func inletStart(r *reporter.Reporter, config InletConfiguration, checkOnly bool)
 	addCommonHTTPHandlers(r, "inlet", httpComponent)
 	versionMetrics(r)
 
+	// Check the configuration of each component
+	configuredComponents := map[string]interface{}{
+		"reporting": r,
+		"http":      httpComponent,
+		"flow":      flowComponent,
+		"metadata":  metadataComponent,
+		"routing":   routingComponent,
+		"kafka":     kafkaComponent,
+		"core":      coreComponent,
+	}
+	if err := ValidateComponents(configuredComponents); err != nil {
+		return err
+	}
+
 	// If we only asked for a check, stop here.
 	if checkOnly {
 		return nil
 	}
 
-	// Start all the components.
+	// Start all the components. In dry-run mode, flows are not sent to
+	// Kafka and we do not need to connect to it.
 	components := []interface{}{
 		httpComponent,
 		metadataComponent,
 		routingComponent,
-		kafkaComponent,
+	}
+	if !config.Core.DryRun {
+		components = append(components, kafkaComponent)
+	}
+	components = append(components,
 		coreComponent,
 		flowComponent,
-	}
+		NewConfigurationReloader(r, daemonComponent, config, configuredComponents, func() (interface{}, error) {
+			newConfig := InletConfiguration{}
+			options := InletOptions.ConfigRelatedOptions
+			options.Dump = false
+			err := options.Parse(io.Discard, "inlet", &newConfig)
+			applyDebugFlag(&newConfig.Reporting)
+			return newConfig, err
+		}),
+	)
 	return StartStopComponents(r, daemonComponent, components)
 }
 
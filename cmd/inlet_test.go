@@ -7,14 +7,18 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/benbjohnson/clock"
+
+	"akvorado/common/helpers"
 	"akvorado/common/reporter"
+	"akvorado/inlet/metadata/provider/snmp"
 )
 
 func TestInletStart(t *testing.T) {
 	r := reporter.NewMock(t)
 	config := InletConfiguration{}
 	config.Reset()
-	if err := inletStart(r, config, true); err != nil {
+	if err := inletStart(r, config, clock.New(), true); err != nil {
 		t.Fatalf("inletStart() error:\n%+v", err)
 	}
 }
@@ -29,3 +33,34 @@ func TestInlet(t *testing.T) {
 		t.Errorf("`inlet` error:\n%+v", err)
 	}
 }
+
+func TestInletFixedTime(t *testing.T) {
+	defer func() { InletOptions.FixedTime = "" }()
+	root := RootCmd
+	buf := new(bytes.Buffer)
+	root.SetOut(buf)
+	root.SetArgs([]string{"inlet", "--check", "--fixed-time", "2024-01-01T10:00:00Z", "/dev/null"})
+	if err := root.Execute(); err != nil {
+		t.Errorf("`inlet --fixed-time` error:\n%+v", err)
+	}
+	root.SetArgs([]string{"inlet", "--check", "--fixed-time", "yesterday", "/dev/null"})
+	if err := root.Execute(); err == nil {
+		t.Error("`inlet --fixed-time yesterday` did not error")
+	}
+}
+
+func TestInletReloadIgnoredKeys(t *testing.T) {
+	config := InletConfiguration{}
+	config.Reset()
+	newConfig := InletConfiguration{}
+	newConfig.Reset()
+	newConfig.Metadata.Providers[0].Config.(snmp.Configuration).Communities.Set(
+		"192.0.2.0/24", []string{"private"})
+	newConfig.Metadata.Workers = 20
+
+	got := changedKeys("metadata", config.Metadata, newConfig.Metadata)
+	expected := []string{"metadata.providers[0].communities.192.0.2.0/24", "metadata.workers"}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("changedKeys() (-got, +want):\n%s", diff)
+	}
+}
@@ -6,6 +6,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/mitchellh/mapstructure"
@@ -76,21 +77,11 @@ components and centralizes configuration of the various other components.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config := OrchestratorConfiguration{}
 		OrchestratorOptions.Path = args[0]
-		OrchestratorOptions.BeforeDump = func() {
-			// Override some parts of the configuration
-			config.ClickHouse.Kafka.Configuration = config.Kafka.Configuration
-			for idx := range config.Inlet {
-				config.Inlet[idx].Kafka.Configuration = config.Kafka.Configuration
-				config.Inlet[idx].Schema = config.Schema
-			}
-			for idx := range config.Console {
-				config.Console[idx].ClickHouse = config.ClickHouse.Configuration
-				config.Console[idx].Schema = config.Schema
-			}
-		}
+		OrchestratorOptions.BeforeDump = func() { overrideOrchestratorConfiguration(&config) }
 		if err := OrchestratorOptions.Parse(cmd.OutOrStdout(), "orchestrator", &config); err != nil {
 			return err
 		}
+		applyDebugFlag(&config.Reporting)
 
 		r, err := reporter.New(config.Reporting)
 		if err != nil {
@@ -108,6 +99,20 @@ func init() {
 		"Check configuration, but does not start")
 }
 
+// overrideOrchestratorConfiguration overrides some parts of the configuration
+// of the other services with the configuration of the orchestrator.
+func overrideOrchestratorConfiguration(config *OrchestratorConfiguration) {
+	config.ClickHouse.Kafka.Configuration = config.Kafka.Configuration
+	for idx := range config.Inlet {
+		config.Inlet[idx].Kafka.Configuration = config.Kafka.Configuration
+		config.Inlet[idx].Schema = config.Schema
+	}
+	for idx := range config.Console {
+		config.Console[idx].ClickHouse = config.ClickHouse.Configuration
+		config.Console[idx].Schema = config.Schema
+	}
+}
+
 func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, checkOnly bool) error {
 	daemonComponent, err := daemon.New(r)
 	if err != nil {
@@ -171,6 +176,29 @@ func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, c
 	addCommonHTTPHandlers(r, "orchestrator", httpComponent)
 	versionMetrics(r)
 
+	// Check the configuration of each component
+	configuredComponents := map[string]interface{}{
+		"reporting":  r,
+		"http":       httpComponent,
+		"kafka":      kafkaComponent,
+		"geoip":      geoipComponent,
+		"clickhouse": clickhouseComponent,
+		"":           orchestratorComponent,
+		// Configurations served to the other services
+		"inlet": serviceConfigurations[InletConfiguration]{
+			orchestratorComponent, orchestrator.InletService,
+		},
+		"console": serviceConfigurations[ConsoleConfiguration]{
+			orchestratorComponent, orchestrator.ConsoleService,
+		},
+		"demo-exporter": serviceConfigurations[DemoExporterConfiguration]{
+			orchestratorComponent, orchestrator.DemoExporterService,
+		},
+	}
+	if err := ValidateComponents(configuredComponents); err != nil {
+		return err
+	}
+
 	// If we only asked for a check, stop here.
 	if checkOnly {
 		return nil
@@ -183,10 +211,61 @@ func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, c
 		clickhouseDBComponent,
 		clickhouseComponent,
 		kafkaComponent,
+		NewConfigurationReloader(r, daemonComponent, config, configuredComponents, func() (interface{}, error) {
+			newConfig := OrchestratorConfiguration{}
+			options := OrchestratorOptions.ConfigRelatedOptions
+			options.Dump = false
+			options.BeforeDump = func() { overrideOrchestratorConfiguration(&newConfig) }
+			err := options.Parse(io.Discard, "orchestrator", &newConfig)
+			applyDebugFlag(&newConfig.Reporting)
+			if err == nil {
+				err = checkSharedConfiguration(config, newConfig)
+			}
+			return newConfig, err
+		}),
 	}
 	return StartStopComponents(r, daemonComponent, components)
 }
 
+// checkSharedConfiguration rejects a new configuration changing the sections
+// copied into the configurations served to the other services. The
+// orchestrator components would keep using the old settings while the other
+// services would get the new ones.
+func checkSharedConfiguration(current, new OrchestratorConfiguration) error {
+	sections := []struct {
+		key          string
+		current, new interface{}
+	}{
+		{"kafka", current.Kafka, new.Kafka},
+		{"clickhouse", current.ClickHouse, new.ClickHouse},
+		{"schema", current.Schema, new.Schema},
+	}
+	for _, section := range sections {
+		if !sameConfiguration(section.current, section.new) {
+			return fmt.Errorf("%s configuration is shared with other services and requires a restart",
+				section.key)
+		}
+	}
+	return nil
+}
+
+// serviceConfigurations makes the configurations served by the orchestrator to
+// another service reloadable.
+type serviceConfigurations[T any] struct {
+	orchestrator *orchestrator.Component
+	service      orchestrator.ServiceType
+}
+
+// Reload replaces the configurations served to the service.
+func (sc serviceConfigurations[T]) Reload(configurations []T) error {
+	replacement := make([]interface{}, len(configurations))
+	for idx := range configurations {
+		replacement[idx] = configurations[idx]
+	}
+	sc.orchestrator.ReplaceConfigurations(sc.service, replacement)
+	return nil
+}
+
 // OrchestratorConfigurationUnmarshallerHook migrates GeoIP configuration from inlet
 // component to clickhouse component.
 func OrchestratorConfigurationUnmarshallerHook() mapstructure.DecodeHookFunc {
@@ -5,6 +5,9 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -12,9 +15,12 @@ import (
 	"strings"
 	"testing"
 
+	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/helpers/yaml"
+	"akvorado/common/httpserver"
 	"akvorado/common/reporter"
+	"akvorado/orchestrator"
 )
 
 func TestOrchestratorStart(t *testing.T) {
@@ -26,6 +32,56 @@ func TestOrchestratorStart(t *testing.T) {
 	}
 }
 
+func TestOrchestratorReloadServiceConfigurations(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+	orchestratorComponent, err := orchestrator.New(r, orchestrator.DefaultConfiguration(), orchestrator.Dependencies{
+		HTTP: h,
+	})
+	if err != nil {
+		t.Fatalf("orchestrator.New() error:\n%+v", err)
+	}
+	config := OrchestratorConfiguration{}
+	config.Reset()
+	newConfig := config
+	newConfig.Inlet = []InletConfiguration{config.Inlet[0]}
+	newConfig.Inlet[0].Kafka.Topic = "new-topic"
+	reloader := NewConfigurationReloader(r, daemon.NewMock(t), config, map[string]interface{}{
+		"inlet": serviceConfigurations[InletConfiguration]{
+			orchestratorComponent, orchestrator.InletService,
+		},
+	}, func() (interface{}, error) {
+		return newConfig, nil
+	})
+	if diff := helpers.Diff(reloader.Reload(), []string{"inlet"}); diff != "" {
+		t.Fatalf("Reload() (-got, +want):\n%s", diff)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v0/orchestrator/configuration/inlet", h.LocalAddr()))
+	if err != nil {
+		t.Fatalf("GET /api/v0/orchestrator/configuration/inlet error:\n%+v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "topic: new-topic") {
+		t.Fatalf("GET /api/v0/orchestrator/configuration/inlet does not contain new topic:\n%s", body)
+	}
+}
+
+func TestOrchestratorReloadSharedConfiguration(t *testing.T) {
+	config := OrchestratorConfiguration{}
+	config.Reset()
+	newConfig := OrchestratorConfiguration{}
+	newConfig.Reset()
+	if err := checkSharedConfiguration(config, newConfig); err != nil {
+		t.Fatalf("checkSharedConfiguration() error:\n%+v", err)
+	}
+	newConfig.Kafka.Topic = "new-topic"
+	if err := checkSharedConfiguration(config, newConfig); err == nil {
+		t.Fatal("checkSharedConfiguration() did not error")
+	}
+}
+
 func TestOrchestratorConfig(t *testing.T) {
 	tests, err := os.ReadDir("testdata/configurations")
 	if err != nil {
@@ -47,6 +103,9 @@ func TestOrchestratorConfig(t *testing.T) {
 			if err := yaml.Unmarshal(expected, &expectedYAML); err != nil {
 				t.Fatalf("yaml.Unmarshal(expected) error:\n%+v", err)
 			}
+			// Check the complete configuration, secrets included
+			OrchestratorOptions.dumpSecrets = true
+			defer func() { OrchestratorOptions.dumpSecrets = false }()
 			root := RootCmd
 			buf := new(bytes.Buffer)
 			root.SetOut(buf)
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/tomb.v2"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers/yaml"
+	"akvorado/common/reporter"
+)
+
+// ConfigurationReloader reloads the configuration when requested by the daemon
+// component (on SIGHUP) and applies the changes to the components
+// implementing a Reload() method accepting their new configuration. Other
+// changes are logged as requiring a restart.
+type ConfigurationReloader struct {
+	r          *reporter.Reporter
+	d          daemon.Component
+	t          tomb.Tomb
+	current    reflect.Value
+	components map[string]interface{}
+	parse      func() (interface{}, error)
+}
+
+// NewConfigurationReloader creates a new configuration reloader. config is the
+// running configuration, components are keyed by the configuration key they
+// are configured from (like for ValidateComponents) and parse should return a
+// freshly parsed configuration of the same type as config.
+func NewConfigurationReloader(r *reporter.Reporter, d daemon.Component, config interface{},
+	components map[string]interface{}, parse func() (interface{}, error),
+) *ConfigurationReloader {
+	current := reflect.New(reflect.TypeOf(config)).Elem()
+	current.Set(reflect.ValueOf(config))
+	return &ConfigurationReloader{
+		r:          r,
+		d:          d,
+		current:    current,
+		components: components,
+		parse:      parse,
+	}
+}
+
+// Start starts waiting for reload requests.
+func (c *ConfigurationReloader) Start() error {
+	c.t.Go(func() error {
+		for {
+			select {
+			case <-c.t.Dying():
+				return nil
+			case <-c.d.ReloadRequested():
+				c.Reload()
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops waiting for reload requests.
+func (c *ConfigurationReloader) Stop() error {
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
+
+// Reload parses the configuration again and applies the changes. It returns
+// the list of keys whose changes were applied.
+func (c *ConfigurationReloader) Reload() []string {
+	newConfig, err := c.parse()
+	if err != nil {
+		c.r.Err(err).Msg("unable to reload configuration")
+		return nil
+	}
+	newValue := reflect.ValueOf(newConfig)
+	if newValue.Type() != c.current.Type() {
+		c.r.Error().Msgf("unexpected configuration type %s", newValue.Type())
+		return nil
+	}
+
+	applied := []string{}
+	configType := c.current.Type()
+	for i := range configType.NumField() {
+		field := configType.Field(i)
+		currentField, newField := c.current.Field(i), newValue.Field(i)
+		if sameConfiguration(currentField.Interface(), newField.Interface()) {
+			continue
+		}
+		name := kebabCase(field.Name)
+		key := name
+		if strings.Contains(field.Tag.Get("mapstructure"), "squash") {
+			key = ""
+		}
+		if err := reloadComponent(c.components[key], newField); err != nil {
+			c.r.Warn().Err(err).
+				Str("key", name).
+				Strs("ignored", changedKeys(name, currentField.Interface(), newField.Interface())).
+				Msg("configuration change requires a restart")
+			continue
+		}
+		currentField.Set(newField)
+		applied = append(applied, name)
+		c.r.Info().Str("key", name).Msg("configuration change applied")
+	}
+	return applied
+}
+
+// reloadComponent calls the Reload() method of the provided component with the
+// new configuration.
+func reloadComponent(component interface{}, config reflect.Value) error {
+	if component == nil {
+		return errors.New("no reloadable component")
+	}
+	method := reflect.ValueOf(component).MethodByName("Reload")
+	if !method.IsValid() || method.Type().NumIn() != 1 || method.Type().NumOut() != 1 ||
+		method.Type().In(0) != config.Type() {
+		return errors.New("component is not reloadable")
+	}
+	if err, _ := method.Call([]reflect.Value{config})[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sameConfiguration tells if two configurations are identical once serialized.
+func sameConfiguration(current, new interface{}) bool {
+	currentYAML, err1 := yaml.Marshal(current)
+	newYAML, err2 := yaml.Marshal(new)
+	return err1 == nil && err2 == nil && string(currentYAML) == string(newYAML)
+}
+
+// changedKeys returns the keys whose value differs between two
+// configurations, using the provided prefix for the top-level key. It
+// descends into maps and into lists of the same length.
+func changedKeys(prefix string, current, new interface{}) []string {
+	var currentTree, newTree interface{}
+	currentYAML, err1 := yaml.Marshal(current)
+	newYAML, err2 := yaml.Marshal(new)
+	if err1 != nil || err2 != nil ||
+		yaml.Unmarshal(currentYAML, &currentTree) != nil ||
+		yaml.Unmarshal(newYAML, &newTree) != nil {
+		return []string{prefix}
+	}
+	return diffTrees(prefix, currentTree, newTree)
+}
+
+func diffTrees(prefix string, current, new interface{}) []string {
+	switch currentTree := current.(type) {
+	case map[string]interface{}:
+		newTree, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := []string{}
+		for k := range currentTree {
+			keys = append(keys, k)
+		}
+		for k := range newTree {
+			if _, ok := currentTree[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		result := []string{}
+		for _, k := range keys {
+			result = append(result, diffTrees(fmt.Sprintf("%s.%s", prefix, k), currentTree[k], newTree[k])...)
+		}
+		return result
+	case []interface{}:
+		newTree, ok := new.([]interface{})
+		if !ok || len(currentTree) != len(newTree) {
+			break
+		}
+		result := []string{}
+		for idx := range currentTree {
+			result = append(result, diffTrees(fmt.Sprintf("%s[%d]", prefix, idx), currentTree[idx], newTree[idx])...)
+		}
+		return result
+	}
+	if reflect.DeepEqual(current, new) {
+		return nil
+	}
+	return []string{prefix}
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cmd_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"akvorado/cmd"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+type reloadableConfiguration struct {
+	Value int
+}
+
+type reloadableComponent struct {
+	lock   sync.Mutex
+	config reloadableConfiguration
+}
+
+func (c *reloadableComponent) Reload(config reloadableConfiguration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if config.Value < 0 {
+		return errors.New("value cannot be negative")
+	}
+	c.config = config
+	return nil
+}
+
+func (c *reloadableComponent) Value() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.config.Value
+}
+
+type reloaderConfiguration struct {
+	Reloadable reloadableConfiguration
+	Squashed   reloadableConfiguration `mapstructure:",squash" yaml:",inline"`
+	Fixed      reloadableConfiguration
+	NoReload   reloadableConfiguration
+}
+
+func TestConfigurationReloader(t *testing.T) {
+	r := reporter.NewMock(t)
+	d := daemon.NewMock(t)
+	config := reloaderConfiguration{}
+	reloadable := &reloadableComponent{}
+	squashed := &reloadableComponent{}
+	var newConfigLock sync.Mutex
+	newConfig := config
+	reloader := cmd.NewConfigurationReloader(r, d, config, map[string]interface{}{
+		"reloadable": reloadable,
+		"":           squashed,
+		"no-reload":  &ComponentNone{},
+	}, func() (interface{}, error) {
+		newConfigLock.Lock()
+		defer newConfigLock.Unlock()
+		return newConfig, nil
+	})
+
+	// No change
+	if diff := helpers.Diff(reloader.Reload(), []string{}); diff != "" {
+		t.Fatalf("Reload() (-got, +want):\n%s", diff)
+	}
+
+	// Changes
+	newConfig.Reloadable.Value = 1
+	newConfig.Fixed.Value = 2
+	newConfig.NoReload.Value = 3
+	if diff := helpers.Diff(reloader.Reload(), []string{"reloadable"}); diff != "" {
+		t.Fatalf("Reload() (-got, +want):\n%s", diff)
+	}
+	if got := reloadable.Value(); got != 1 {
+		t.Fatalf("Reload() value %d, expected 1", got)
+	}
+
+	// Not reloadable changes are signaled again while a valid change is applied
+	newConfig.Squashed.Value = 4
+	if diff := helpers.Diff(reloader.Reload(), []string{"squashed"}); diff != "" {
+		t.Fatalf("Reload() (-got, +want):\n%s", diff)
+	}
+	if got := squashed.Value(); got != 4 {
+		t.Fatalf("Reload() value %d, expected 4", got)
+	}
+
+	// Refused change
+	newConfig.Reloadable.Value = -1
+	if diff := helpers.Diff(reloader.Reload(), []string{}); diff != "" {
+		t.Fatalf("Reload() (-got, +want):\n%s", diff)
+	}
+	if got := reloadable.Value(); got != 1 {
+		t.Fatalf("Reload() value %d, expected 1", got)
+	}
+
+	// Reload on request
+	helpers.StartStop(t, reloader)
+	newConfigLock.Lock()
+	newConfig.Reloadable.Value = 5
+	newConfigLock.Unlock()
+	d.RequestReload()
+	for range 100 {
+		if reloadable.Value() == 5 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := reloadable.Value(); got != 5 {
+		t.Fatalf("RequestReload() value %d, expected 5", got)
+	}
+}
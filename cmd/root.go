@@ -11,6 +11,9 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+
+	"akvorado/common/reporter"
+	"akvorado/common/reporter/logger"
 )
 
 var debug bool
@@ -38,3 +41,10 @@ func init() {
 	RootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false,
 		"Enable debug logs")
 }
+
+// applyDebugFlag forces the debug log level when requested on the command line.
+func applyDebugFlag(config *reporter.Configuration) {
+	if debug {
+		config.Logging.Level = logger.LevelDebug
+	}
+}
@@ -2,8 +2,8 @@
 // SPDX-License-Identifier: AGPL-3.0-only
 
 // Package daemon will handle daemon-related operations: readiness,
-// watchdog, exit, reexec... Currently, only exit is implemented as
-// other operations do not mean much when running in Docker.
+// watchdog, exit, reexec... Currently, only exit and reload requests are
+// implemented as other operations do not mean much when running in Docker.
 package daemon
 
 import (
@@ -25,6 +25,8 @@ type Component interface {
 	// Lifecycle
 	Terminated() <-chan struct{}
 	Terminate()
+	ReloadRequested() <-chan struct{}
+	RequestReload()
 }
 
 // realComponent is a non-mock implementation of the Component
@@ -48,6 +50,7 @@ func New(r *reporter.Reporter) (Component, error) {
 		r: r,
 		lifecycleComponent: lifecycleComponent{
 			terminateChannel: make(chan struct{}),
+			reloadChannel:    make(chan struct{}, 1),
 		},
 	}, nil
 }
@@ -70,22 +73,28 @@ func (c *realComponent) Start() error {
 			c.Terminate()
 		}(t)
 	}
-	// On signal, terminate
+	// On signal, terminate or reload
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals,
+		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals,
-			syscall.SIGINT, syscall.SIGTERM)
-		select {
-		case s := <-signals:
-			c.r.Debug().Stringer("signal", s).Msg("signal received")
-			switch s {
-			case syscall.SIGINT, syscall.SIGTERM:
-				c.r.Info().Msg("quitting")
-				c.Terminate()
-				signal.Stop(signals)
+		defer signal.Stop(signals)
+		for {
+			select {
+			case s := <-signals:
+				c.r.Debug().Stringer("signal", s).Msg("signal received")
+				switch s {
+				case syscall.SIGINT, syscall.SIGTERM:
+					c.r.Info().Msg("quitting")
+					c.Terminate()
+					return
+				case syscall.SIGHUP:
+					c.r.Info().Msg("reloading configuration")
+					c.RequestReload()
+				}
+			case <-c.Terminated():
+				return
 			}
-		case <-c.Terminated():
-			// Do nothing.
 		}
 	}()
 	return nil
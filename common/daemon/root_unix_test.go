@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	r := reporter.NewMock(t)
+	c, err := New(r)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	select {
+	case <-c.ReloadRequested():
+		// OK
+	case <-time.After(time.Second):
+		t.Fatalf("ReloadRequested() didn't receive a value after SIGHUP")
+	}
+	select {
+	case <-c.Terminated():
+		t.Fatalf("Terminated() was closed after SIGHUP")
+	default:
+		// OK
+	}
+}
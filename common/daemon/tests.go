@@ -23,6 +23,7 @@ func NewMock(t *testing.T) Component {
 	return &MockComponent{
 		lifecycleComponent: lifecycleComponent{
 			terminateChannel: make(chan struct{}),
+			reloadChannel:    make(chan struct{}, 1),
 		},
 	}
 }
@@ -24,6 +24,8 @@ type TLSConfiguration struct {
 	CertFile string `validate:"required_with=KeyFile"`
 	// KeyFile tells the location of the user key if any.
 	KeyFile string
+	// ServerName overrides the name used to check the remote certificate.
+	ServerName string
 }
 
 // MakeTLSConfig Create and *tls.Config from a TLSConfiguration.
@@ -34,6 +36,7 @@ func (config TLSConfiguration) MakeTLSConfig() (*tls.Config, error) {
 	}
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: !config.Verify,
+		ServerName:         config.ServerName,
 	}
 	// Read CA certificate if provided
 	if config.CAFile != "" {
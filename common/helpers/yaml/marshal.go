@@ -3,7 +3,12 @@
 
 package yaml
 
-import "gopkg.in/yaml.v3"
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Marshal serializes the value provided into a YAML document. The structure of
 // the generated document will reflect the structure of the value itself. Maps
@@ -11,3 +16,79 @@ import "gopkg.in/yaml.v3"
 func Marshal(in interface{}) (out []byte, err error) {
 	return yaml.Marshal(in)
 }
+
+// secretKeys is the list of substrings identifying a key whose value is secret.
+var secretKeys = []string{"password", "passphrase", "token"}
+
+// secretExactKeys is the list of keys whose value is secret. They are too
+// generic to be matched as substrings.
+var secretExactKeys = []string{"communities", "dsn"}
+
+// MarshalWithoutSecrets serializes the value provided into a YAML document,
+// like Marshal, but the non-empty values for keys containing one of the
+// secretKeys or equal to one of the secretExactKeys are masked. When such a
+// value is a list or a map, all the values it contains are masked. Values
+// matching one of the provided secrets are masked as well.
+func MarshalWithoutSecrets(in interface{}, secrets ...string) (out []byte, err error) {
+	var node yaml.Node
+	if err := node.Encode(in); err != nil {
+		return nil, err
+	}
+	maskSecrets(&node, secrets)
+	return yaml.Marshal(&node)
+}
+
+func maskSecrets(node *yaml.Node, secrets []string) {
+	mask := func(value *yaml.Node) {
+		value.Value = "******"
+		value.Tag = "!!str"
+		value.Style = 0
+	}
+	isSecretKey := func(key string) bool {
+		key = strings.ToLower(key)
+		for _, secretKey := range secretKeys {
+			if strings.Contains(key, secretKey) {
+				return true
+			}
+		}
+		return slices.Contains(secretExactKeys, key)
+	}
+	var maskAll func(value *yaml.Node)
+	maskAll = func(value *yaml.Node) {
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Value != "" {
+				mask(value)
+			}
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				maskAll(item)
+			}
+		case yaml.MappingNode:
+			for i := 1; i < len(value.Content); i += 2 {
+				maskAll(value.Content[i])
+			}
+		}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if isSecretKey(key.Value) {
+				maskAll(value)
+			} else if value.Kind == yaml.ScalarNode && value.Value != "" &&
+				slices.Contains(secrets, value.Value) {
+				mask(value)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, value := range node.Content {
+			if value.Kind == yaml.ScalarNode && slices.Contains(secrets, value.Value) {
+				mask(value)
+			}
+		}
+	}
+	for _, child := range node.Content {
+		maskSecrets(child, secrets)
+	}
+}
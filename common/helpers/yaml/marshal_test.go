@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package yaml_test
+
+import (
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/helpers/yaml"
+)
+
+func TestMarshalWithoutSecrets(t *testing.T) {
+	type security struct {
+		UserName                 string
+		AuthenticationPassphrase string
+	}
+	type sentry struct {
+		DSN         string
+		Environment string
+	}
+	input := struct {
+		Brokers            []string
+		SASLPassword       string
+		Password           string
+		Security           []security
+		Tokens             []string
+		Communities        map[string][]string
+		CollectCommunities bool
+		Sentry             sentry
+	}{
+		Brokers:      []string{"kafka:9092"},
+		SASLPassword: "secret",
+		Security:     []security{{"alfred", "hello"}},
+		Tokens:       []string{"token1", "token2"},
+		Communities: map[string][]string{
+			"::/0":                 {"public"},
+			"::ffff:192.0.2.0/120": {"private1", "private2"},
+		},
+		CollectCommunities: true,
+		Sentry:             sentry{"https://key@sentry.example.com/1", "production"},
+	}
+	got, err := yaml.MarshalWithoutSecrets(input)
+	if err != nil {
+		t.Fatalf("MarshalWithoutSecrets() error:\n%+v", err)
+	}
+	expected := `brokers:
+    - kafka:9092
+saslpassword: '******'
+password: ""
+security:
+    - username: alfred
+      authenticationpassphrase: '******'
+tokens:
+    - '******'
+    - '******'
+communities:
+    ::/0:
+        - '******'
+    ::ffff:192.0.2.0/120:
+        - '******'
+        - '******'
+collectcommunities: true
+sentry:
+    dsn: '******'
+    environment: production
+`
+	if diff := helpers.Diff(string(got), expected); diff != "" {
+		t.Fatalf("MarshalWithoutSecrets() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestMarshalWithoutSecretsExtra(t *testing.T) {
+	input := struct {
+		Key     string
+		Keys    []string
+		Comment string
+	}{
+		Key:     "secret1",
+		Keys:    []string{"secret2", "public"},
+		Comment: "public",
+	}
+	got, err := yaml.MarshalWithoutSecrets(input, "secret1", "secret2")
+	if err != nil {
+		t.Fatalf("MarshalWithoutSecrets() error:\n%+v", err)
+	}
+	expected := `key: '******'
+keys:
+    - '******'
+    - public
+comment: public
+`
+	if diff := helpers.Diff(string(got), expected); diff != "" {
+		t.Fatalf("MarshalWithoutSecrets() (-got, +want):\n%s", diff)
+	}
+}
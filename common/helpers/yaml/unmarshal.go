@@ -8,6 +8,7 @@ package yaml
 import (
 	"fmt"
 	"io/fs"
+	"path"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -22,7 +23,18 @@ func Unmarshal(in []byte, out interface{}) (err error) {
 // UnmarshalWithInclude decodes the first document found within the in byte
 // slice and assigns decoded values into the out value. It also accepts the
 // "!include" tag to include additional files contained in the provided fs.
+// Included files are relative to the including file.
 func UnmarshalWithInclude(fsys fs.FS, input string, out interface{}) (err error) {
+	return unmarshalWithInclude(fsys, input, out, nil)
+}
+
+func unmarshalWithInclude(fsys fs.FS, input string, out interface{}, including []string) (err error) {
+	for _, previous := range including {
+		if previous == input {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(including, " -> "), input)
+		}
+	}
+	including = append(including, input)
 	var outNode yaml.Node
 	in, err := fs.ReadFile(fsys, input)
 	if err != nil {
@@ -70,7 +82,8 @@ func UnmarshalWithInclude(fsys fs.FS, input string, out interface{}) (err error)
 			return fmt.Errorf("at line %d of %s, no content is allowed for !include", current.Line, input)
 		}
 		var outNode yaml.Node
-		if err := UnmarshalWithInclude(fsys, current.Value, &outNode); err != nil {
+		included := path.Join(path.Dir(input), current.Value)
+		if err := unmarshalWithInclude(fsys, included, &outNode, including); err != nil {
 			return fmt.Errorf("at line %d of %s: %w", current.Line, input, err)
 		}
 		*current = outNode
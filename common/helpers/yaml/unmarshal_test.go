@@ -5,6 +5,7 @@ package yaml_test
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"akvorado/common/helpers"
@@ -25,6 +26,9 @@ func TestUnmarshalWithIn(t *testing.T) {
 		"nested": gin.H{
 			"file1": gin.H{"name": "1.yaml"},
 		},
+		"sub": gin.H{
+			"file3": gin.H{"name": "sub/3.yaml"},
+		},
 		"list1": []string{"el1", "el2", "el3"},
 		"list2": []interface{}{gin.H{
 			"protocol": "tcp",
@@ -35,3 +39,16 @@ func TestUnmarshalWithIn(t *testing.T) {
 		t.Fatalf("UnmarshalWithInclude() (-got, +want):\n%s", diff)
 	}
 }
+
+func TestUnmarshalWithIncludeCycle(t *testing.T) {
+	fsys := os.DirFS("testdata")
+	var got interface{}
+	err := yaml.UnmarshalWithInclude(fsys, "cycle1.yaml", &got)
+	if err == nil {
+		t.Fatal("UnmarshalWithInclude() did not error")
+	}
+	expected := "include cycle: cycle1.yaml -> cycle2.yaml -> cycle1.yaml"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("UnmarshalWithInclude() error:\n%s\nexpected to contain:\n%s", err, expected)
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthConfiguration describes the authentication required for some paths.
+type AuthConfiguration struct {
+	// Prefixes is the list of path prefixes requiring authentication.
+	Prefixes []string `validate:"dive,startswith=/"`
+	// Users maps users to their bcrypt-hashed passwords for basic
+	// authentication.
+	Users map[string]string
+	// Tokens is the list of accepted bearer tokens.
+	Tokens []string
+}
+
+// validate checks the authentication configuration.
+func (config AuthConfiguration) validate() error {
+	if len(config.Prefixes) == 0 {
+		return nil
+	}
+	if len(config.Users) == 0 && len(config.Tokens) == 0 {
+		return errors.New("HTTP authentication requires at least one user or one token")
+	}
+	for user, hash := range config.Users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash for HTTP user %q: %w", user, err)
+		}
+	}
+	return nil
+}
+
+// authenticated tells if the request carries valid credentials.
+func (config AuthConfiguration) authenticated(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		for _, candidate := range config.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		hash, ok := config.Users[user]
+		if !ok {
+			return false
+		}
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	return false
+}
+
+// authHandler wraps an handler to require authentication for the configured
+// prefixes.
+func (c *Component) authHandler(next http.Handler) http.Handler {
+	config := c.config.Auth
+	if len(config.Prefixes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range config.Prefixes {
+			if !strings.HasPrefix(r.URL.Path, prefix) {
+				continue
+			}
+			if !config.authenticated(r) {
+				c.metrics.authFailures.WithLabelValues(prefix).Inc()
+				w.Header().Set("WWW-Authenticate", `Basic realm="akvorado"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			break
+		}
+		next.ServeHTTP(w, r)
+	})
+}
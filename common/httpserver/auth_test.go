@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestAuth(t *testing.T) {
+	r := reporter.NewMock(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error:\n%+v", err)
+	}
+	config := DefaultConfiguration()
+	config.Listen = "127.0.0.1:0"
+	config.Auth = AuthConfiguration{
+		Prefixes: []string{"/api/"},
+		Users:    map[string]string{"alfred": string(hash)},
+		Tokens:   []string{"token1"},
+	}
+	c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	for _, location := range []string{"/api/test", "/metrics"} {
+		c.AddHandler(location,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "Hello !")
+			}))
+	}
+	helpers.StartStop(t, c)
+
+	cases := []struct {
+		Description string
+		Path        string
+		Setup       func(*http.Request)
+		StatusCode  int
+	}{
+		{"unprotected", "/metrics", func(*http.Request) {}, 200},
+		{"no credentials", "/api/test", func(*http.Request) {}, 401},
+		{"valid user", "/api/test", func(r *http.Request) { r.SetBasicAuth("alfred", "secret") }, 200},
+		{"invalid password", "/api/test", func(r *http.Request) { r.SetBasicAuth("alfred", "nope") }, 401},
+		{"unknown user", "/api/test", func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, 401},
+		{"valid token", "/api/test", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token1") }, 200},
+		{"invalid token", "/api/test", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token2") }, 401},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", fmt.Sprintf("http://%s%s", c.LocalAddr(), tc.Path), nil)
+			tc.Setup(req)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s error:\n%+v", tc.Path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.StatusCode {
+				t.Fatalf("GET %s: got status code %d, expected %d", tc.Path, resp.StatusCode, tc.StatusCode)
+			}
+			if resp.StatusCode == 401 && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Fatalf("GET %s: missing WWW-Authenticate header", tc.Path)
+			}
+		})
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_common_httpserver_", "auth_")
+	expectedMetrics := map[string]string{
+		`auth_failures_total{path="/api/"}`: "4",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestAuthConfigurationValidate(t *testing.T) {
+	cases := []struct {
+		Description string
+		Config      AuthConfiguration
+		Error       bool
+	}{
+		{"empty", AuthConfiguration{}, false},
+		{"no credentials", AuthConfiguration{Prefixes: []string{"/api/"}}, true},
+		{"token", AuthConfiguration{Prefixes: []string{"/api/"}, Tokens: []string{"token"}}, false},
+		{"invalid hash", AuthConfiguration{
+			Prefixes: []string{"/api/"},
+			Users:    map[string]string{"alfred": "secret"},
+		}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := tc.Config.validate()
+			if err == nil && tc.Error {
+				t.Fatal("validate() did not error")
+			} else if err != nil && !tc.Error {
+				t.Fatalf("validate() error:\n%+v", err)
+			}
+		})
+	}
+}
@@ -15,12 +15,13 @@ import (
 	"akvorado/common/reporter"
 )
 
-// CacheByRequestPath is a middleware to cache the request using path as key
+// CacheByRequestPath is a middleware to cache the request using path (and
+// query string) as key
 func (c *Component) CacheByRequestPath(expire time.Duration) gin.HandlerFunc {
 	opts := c.commonCacheOptions()
 	opts = append(opts, cache.WithCacheStrategyByRequest(func(gc *gin.Context) (bool, cache.Strategy) {
 		return true, cache.Strategy{
-			CacheKey: gc.Request.URL.Path,
+			CacheKey: gc.Request.URL.RequestURI(),
 		}
 	}))
 	return cache.Cache(c.cacheStore, expire, opts...)
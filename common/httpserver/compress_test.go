@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpserver
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("hello world! ", 1000)
+	for _, enable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enable=%v", enable), func(t *testing.T) {
+			r := reporter.NewMock(t)
+			config := DefaultConfiguration()
+			config.Listen = "127.0.0.1:0"
+			config.Compression.Enable = enable
+			c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t)})
+			if err != nil {
+				t.Fatalf("New() error:\n%+v", err)
+			}
+			c.AddHandler("/large", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, large)
+			}))
+			c.AddHandler("/small", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "hello world!")
+			}))
+			c.AddHandler("/image", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				fmt.Fprint(w, large)
+			}))
+			c.AddHandler("/error", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, large, http.StatusNotFound)
+			}))
+			helpers.StartStop(t, c)
+
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			cases := []struct {
+				Path       string
+				Encoding   string
+				Compressed bool
+				StatusCode int
+			}{
+				{"/large", "gzip", true, 200},
+				{"/large", "br, deflate", true, 200},
+				{"/large", "", false, 200},
+				{"/large", "gzip;q=0", false, 200},
+				{"/small", "gzip", false, 200},
+				{"/image", "gzip", false, 200},
+				{"/error", "gzip", true, 404},
+			}
+			for _, tc := range cases {
+				req, _ := http.NewRequest("GET", fmt.Sprintf("http://%s%s", c.LocalAddr(), tc.Path), nil)
+				if tc.Encoding != "" {
+					req.Header.Set("Accept-Encoding", tc.Encoding)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("GET %s error:\n%+v", tc.Path, err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != tc.StatusCode {
+					t.Errorf("GET %s (%q): got status code %d, expected %d",
+						tc.Path, tc.Encoding, resp.StatusCode, tc.StatusCode)
+				}
+				compressed := tc.Compressed && enable
+				encoding := resp.Header.Get("Content-Encoding")
+				if compressed && encoding == "" {
+					t.Errorf("GET %s (%q): response not compressed", tc.Path, tc.Encoding)
+					continue
+				} else if !compressed && encoding != "" {
+					t.Errorf("GET %s (%q): response compressed with %s", tc.Path, tc.Encoding, encoding)
+					continue
+				}
+				var body io.Reader = resp.Body
+				if encoding == "gzip" {
+					body, err = gzip.NewReader(resp.Body)
+					if err != nil {
+						t.Fatalf("GET %s (%q): gzip.NewReader() error:\n%+v", tc.Path, tc.Encoding, err)
+					}
+				} else if encoding == "deflate" {
+					body, err = zlib.NewReader(resp.Body)
+					if err != nil {
+						t.Fatalf("GET %s (%q): zlib.NewReader() error:\n%+v", tc.Path, tc.Encoding, err)
+					}
+				}
+				got, err := io.ReadAll(body)
+				if err != nil {
+					t.Fatalf("GET %s (%q): ReadAll() error:\n%+v", tc.Path, tc.Encoding, err)
+				}
+				expected := large
+				if tc.Path == "/small" {
+					expected = "hello world!"
+				} else if tc.Path == "/error" {
+					expected = large + "\n"
+				}
+				if string(got) != expected {
+					t.Errorf("GET %s (%q): unexpected body", tc.Path, tc.Encoding)
+				}
+			}
+		})
+	}
+}
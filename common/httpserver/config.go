@@ -21,8 +21,20 @@ type Configuration struct {
 	Listen string `validate:"required,listen"`
 	// Profiler enables Go profiler as /debug
 	Profiler bool
+	// ProfilerListen defines an alternate listening string for the
+	// profiler. If empty, the profiler uses the main listening string.
+	ProfilerListen string `validate:"omitempty,listen"`
 	// Cache configuration
 	Cache CacheConfiguration
+	// TLS configuration
+	TLS TLSConfiguration
+	// Auth configuration
+	Auth AuthConfiguration
+	// Compression configuration
+	Compression CompressionConfiguration
+	// ShutdownTimeout is the time to wait for in-flight requests to
+	// complete when stopping.
+	ShutdownTimeout time.Duration `validate:"min=0"`
 }
 
 // CacheConfiguration describes the configuration of the internal HTTP cache.
@@ -100,6 +112,11 @@ func DefaultConfiguration() Configuration {
 		Cache: CacheConfiguration{
 			Config: DefaultMemoryCacheConfiguration(),
 		},
+		ShutdownTimeout: 5 * time.Second,
+		Compression: CompressionConfiguration{
+			Enable:  true,
+			MinSize: 1024,
+		},
 	}
 }
 
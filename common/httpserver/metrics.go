@@ -12,6 +12,8 @@ type metrics struct {
 	sizes     *reporter.HistogramVec
 	cacheHit  *reporter.CounterVec
 	cacheMiss *reporter.CounterVec
+
+	authFailures *reporter.CounterVec
 }
 
 func (c *Component) initMetrics() {
@@ -53,4 +55,10 @@ func (c *Component) initMetrics() {
 			Help: "Number of requests not served from cache",
 		}, []string{"path", "method"},
 	)
+	c.metrics.authFailures = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "auth_failures_total",
+			Help: "Number of requests rejected because of missing or invalid credentials.",
+		}, []string{"path"},
+	)
 }
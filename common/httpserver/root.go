@@ -6,10 +6,12 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"sync/atomic"
 	"time"
 
 	"github.com/chenyahui/gin-cache/persist"
@@ -34,6 +36,9 @@ type Component struct {
 	metrics metrics
 	address net.Addr
 
+	profilerMux     *http.ServeMux
+	profilerAddress net.Addr
+
 	// GinRouter is the router exposed for /api
 	GinRouter  *gin.Engine
 	cacheStore persist.CacheStore
@@ -46,6 +51,9 @@ type Dependencies struct {
 
 // New creates a new HTTP component.
 func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
+	if err := configuration.Auth.validate(); err != nil {
+		return nil, err
+	}
 	var err error
 	c := Component{
 		r:      r,
@@ -64,11 +72,16 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	c.GinRouter.Use(gin.Recovery())
 	c.AddHandler("/api/", c.GinRouter)
 	if configuration.Profiler {
-		c.mux.HandleFunc("/debug/pprof/", pprof.Index)
-		c.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		c.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		c.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		c.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux := c.mux
+		if configuration.ProfilerListen != "" {
+			c.profilerMux = http.NewServeMux()
+			mux = c.profilerMux
+		}
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	}
 	return &c, nil
 }
@@ -99,12 +112,22 @@ func (c *Component) AddHandler(location string, handler http.Handler) {
 	c.mux.Handle(location, handler)
 }
 
+// Validate checks the configuration of the HTTP component.
+func (c *Component) Validate() error {
+	if c.config.ProfilerListen == "" || c.config.ProfilerListen != c.config.Listen {
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(c.config.Listen); err == nil && port == "0" {
+		return nil
+	}
+	return fmt.Errorf("profiler-listen: %q already used by listen", c.config.ProfilerListen)
+}
+
 // Start starts the HTTP component.
 func (c *Component) Start() error {
 	if c.config.Listen == "" {
 		return nil
 	}
-	server := &http.Server{Handler: c.mux}
 
 	// Most of the time, if we have an error, it's here!
 	c.r.Info().Str("listen", c.config.Listen).Msg("starting HTTP server")
@@ -112,6 +135,39 @@ func (c *Component) Start() error {
 	if err != nil {
 		return fmt.Errorf("unable to listen to %v: %w", c.config.Listen, err)
 	}
+	if c.config.TLS.Enable {
+		tlsConfig, err := c.makeTLSConfig()
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	var profilerListener net.Listener
+	if c.profilerMux != nil {
+		c.r.Info().Str("listen", c.config.ProfilerListen).Msg("starting profiler HTTP server")
+		profilerListener, err = net.Listen("tcp", c.config.ProfilerListen)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("unable to listen to %v: %w", c.config.ProfilerListen, err)
+		}
+		c.profilerAddress = profilerListener.Addr()
+	}
+
+	// Request contexts are canceled when the shutdown timeout expires to
+	// let long-running handlers exit. Until then, in-flight requests
+	// (and the queries they run) are given a chance to complete.
+	baseCtx, cancel := context.WithCancel(context.Background())
+	var inflights atomic.Int64
+	handler := c.compressHandler(c.authHandler(c.mux))
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inflights.Add(1)
+			defer inflights.Add(-1)
+			handler.ServeHTTP(w, r)
+		}),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
 	c.address = listener.Addr()
 	server.Addr = listener.Addr().String()
 
@@ -124,15 +180,39 @@ func (c *Component) Start() error {
 		return nil
 	})
 
+	// Profiler is stopped abruptly as profiles may take a long time
+	if profilerListener != nil {
+		profilerServer := &http.Server{Handler: c.profilerMux}
+		c.t.Go(func() error {
+			if err := profilerServer.Serve(profilerListener); err != http.ErrServerClosed {
+				c.r.Err(err).Str("listen", c.config.ProfilerListen).Msg("unable to start profiler HTTP server")
+				return fmt.Errorf("unable to start profiler HTTP server: %w", err)
+			}
+			return nil
+		})
+		c.t.Go(func() error {
+			<-c.t.Dying()
+			profilerServer.Close()
+			return nil
+		})
+	}
+
 	// Gracefully stop when asked to
 	c.t.Go(func() error {
 		<-c.t.Dying()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := server.Shutdown(ctx); err != nil {
-			c.r.Err(err).Msg("unable to shutdown HTTP server")
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), c.config.ShutdownTimeout)
+		defer cancelShutdown()
+		requests := inflights.Load()
+		err := server.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			server.Close()
+			c.r.Err(err).
+				Int64("requests", inflights.Load()).
+				Msg("unable to shutdown HTTP server, requests interrupted")
 			return fmt.Errorf("unable to shutdown HTTP server: %w", err)
 		}
+		c.r.Info().Int64("requests", requests).Msg("HTTP server drained")
 		return nil
 	})
 	return nil
@@ -154,6 +234,20 @@ func (c *Component) LocalAddr() net.Addr {
 	return c.address
 }
 
+// Scheme returns the URL scheme to use to reach the HTTP server.
+func (c *Component) Scheme() string {
+	if c.config.TLS.Enable {
+		return "https"
+	}
+	return "http"
+}
+
+// ProfilerAddr returns the address the profiler HTTP server is listening to,
+// if any.
+func (c *Component) ProfilerAddr() net.Addr {
+	return c.profilerAddress
+}
+
 func init() {
 	// Disable proxy for client
 	http.DefaultTransport.(*http.Transport).Proxy = nil
@@ -5,9 +5,12 @@ package httpserver_test
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"testing"
+	"time"
 
+	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/httpserver"
 	"akvorado/common/reporter"
@@ -91,3 +94,159 @@ func TestGinRouterPanic(t *testing.T) {
 		},
 	})
 }
+
+func TestGracefulShutdown(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := httpserver.DefaultConfiguration()
+	config.Listen = "127.0.0.1:0"
+	config.ShutdownTimeout = 200 * time.Millisecond
+	h, err := httpserver.New(r, config, httpserver.Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	entered := make(chan bool)
+	release := make(chan bool)
+	canceled := make(chan bool)
+	h.AddHandler("/slow",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- true
+			// Like a database query, give up when the context is canceled
+			select {
+			case <-r.Context().Done():
+				fmt.Fprintf(w, "Canceled !")
+			case <-release:
+				fmt.Fprintf(w, "Hello !")
+			}
+		}))
+	if err := h.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		type result struct {
+			body string
+			err  error
+		}
+		results := make(chan result)
+		go func() {
+			resp, err := http.Get(fmt.Sprintf("http://%s/slow", h.LocalAddr()))
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			results <- result{string(body), err}
+		}()
+		<-entered
+		stopped := make(chan error)
+		go func() { stopped <- h.Stop() }()
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		got := <-results
+		if got.err != nil {
+			t.Fatalf("GET /slow error:\n%+v", got.err)
+		}
+		if got.body != "Hello !" {
+			t.Fatalf("GET /slow got %q", got.body)
+		}
+		if err := <-stopped; err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		h, err := httpserver.New(r, config, httpserver.Dependencies{Daemon: daemon.NewMock(t)})
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		h.AddHandler("/stream",
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				entered <- true
+				<-r.Context().Done()
+				close(canceled)
+			}))
+		if err := h.Start(); err != nil {
+			t.Fatalf("Start() error:\n%+v", err)
+		}
+		go http.Get(fmt.Sprintf("http://%s/stream", h.LocalAddr()))
+		<-entered
+		select {
+		case <-canceled:
+			t.Fatal("request context canceled before the shutdown")
+		default:
+		}
+		start := time.Now()
+		if err := h.Stop(); err == nil {
+			t.Fatal("Stop() did not error with a stuck request")
+		}
+		if elapsed := time.Since(start); elapsed < config.ShutdownTimeout {
+			t.Fatalf("Stop() did not wait for the shutdown timeout (%s)", elapsed)
+		}
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("request context not canceled")
+		}
+	})
+}
+
+func TestProfilerListen(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := httpserver.DefaultConfiguration()
+	config.Listen = "127.0.0.1:0"
+	config.ProfilerListen = "127.0.0.1:0"
+	h, err := httpserver.New(r, config, httpserver.Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, h)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:         "/debug/pprof/cmdline",
+			StatusCode:  404,
+			ContentType: "text/plain; charset=utf-8",
+			FirstLines:  []string{"404 page not found"},
+		},
+	})
+	resp, err := http.Get(fmt.Sprintf("http://%s/debug/pprof/cmdline", h.ProfilerAddr()))
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/cmdline error:\n%+v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("GET /debug/pprof/cmdline: got status code %d", resp.StatusCode)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		Listen         string
+		ProfilerListen string
+		Error          bool
+	}{
+		{":8080", "", false},
+		{":8080", ":6060", false},
+		{":8080", ":8080", true},
+		{"127.0.0.1:0", "127.0.0.1:0", false},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s-%s", tc.Listen, tc.ProfilerListen), func(t *testing.T) {
+			r := reporter.NewMock(t)
+			config := httpserver.DefaultConfiguration()
+			config.Listen = tc.Listen
+			config.ProfilerListen = tc.ProfilerListen
+			c, err := httpserver.New(r, config, httpserver.Dependencies{Daemon: daemon.NewMock(t)})
+			if err != nil {
+				t.Fatalf("New() error:\n%+v", err)
+			}
+			err = c.Validate()
+			if err == nil && tc.Error {
+				t.Fatal("Validate() did not error")
+			} else if err != nil && !tc.Error {
+				t.Fatalf("Validate() error:\n%+v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"akvorado/common/reporter"
+)
+
+// TLSConfiguration describes the TLS configuration of the HTTP server.
+type TLSConfiguration struct {
+	// Enable tells if the HTTP server should use TLS.
+	Enable bool `validate:"required_with=CertFile KeyFile ClientCAFile"`
+	// CertFile is the location of the server certificate.
+	CertFile string `validate:"required_if=Enable true"`
+	// KeyFile is the location of the server key. If empty, the key is
+	// expected to be in the certificate file.
+	KeyFile string
+	// ClientCAFile is the location of the CA certificate used to check
+	// client certificates. If not empty, clients have to present a valid
+	// certificate.
+	ClientCAFile string
+}
+
+// certificateLoader loads a certificate pair and reloads it when one of the
+// files is modified.
+type certificateLoader struct {
+	r        *reporter.Reporter
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// lastModTime returns the most recent modification time of the certificate and
+// key files.
+func (l *certificateLoader) lastModTime() (time.Time, error) {
+	var last time.Time
+	for _, file := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// load loads the certificate if the files have been modified since the last
+// load.
+func (l *certificateLoader) load() error {
+	modTime, err := l.lastModTime()
+	if err != nil {
+		return fmt.Errorf("cannot read HTTP server certificate: %w", err)
+	}
+	if l.cert != nil && !modTime.After(l.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load HTTP server certificate: %w", err)
+	}
+	l.cert = &cert
+	l.modTime = modTime
+	return nil
+}
+
+// GetCertificate returns the current certificate, reloading it if needed. If
+// the reload fails, the previous certificate is kept.
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.load(); err != nil {
+		l.r.Err(err).Msg("cannot reload HTTP server certificate, keeping the previous one")
+	}
+	return l.cert, nil
+}
+
+// makeTLSConfig builds the TLS configuration for the HTTP server.
+func (c *Component) makeTLSConfig() (*tls.Config, error) {
+	config := c.config.TLS
+	if config.KeyFile == "" {
+		config.KeyFile = config.CertFile
+	}
+	loader := &certificateLoader{
+		r:        c.r,
+		certFile: config.CertFile,
+		keyFile:  config.KeyFile,
+	}
+	if err := loader.load(); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		GetCertificate: loader.GetCertificate,
+	}
+	if config.ClientCAFile != "" {
+		caCert, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA certificate for HTTP server: %w", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, errors.New("cannot parse client CA certificate for HTTP server")
+		}
+		tlsConfig.ClientCAs = caCertPool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
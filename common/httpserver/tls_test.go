@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 with the
+// provided common name in the provided file.
+func writeCertificate(t *testing.T, file string, cn string, modTime time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error:\n%+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error:\n%+v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error:\n%+v", err)
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	content = append(content, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() error:\n%+v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error:\n%+v", err)
+	}
+	return cert
+}
+
+func TestTLS(t *testing.T) {
+	r := reporter.NewMock(t)
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	first := writeCertificate(t, certFile, "first", time.Now().Add(-time.Minute))
+
+	config := DefaultConfiguration()
+	config.Listen = "127.0.0.1:0"
+	config.TLS = TLSConfiguration{
+		Enable:   true,
+		CertFile: certFile,
+	}
+	c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	c.AddHandler("/test",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "Hello !")
+		}))
+	helpers.StartStop(t, c)
+	if scheme := c.Scheme(); scheme != "https" {
+		t.Fatalf("Scheme() == %q, expected %q", scheme, "https")
+	}
+
+	get := func(ca *x509.Certificate) string {
+		t.Helper()
+		pool := x509.NewCertPool()
+		pool.AddCert(ca)
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: pool},
+				DisableKeepAlives: true,
+			},
+		}
+		resp, err := client.Get(fmt.Sprintf("https://%s/test", c.LocalAddr()))
+		if err != nil {
+			t.Fatalf("GET /test error:\n%+v", err)
+		}
+		defer resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := get(first); got != "first" {
+		t.Fatalf("GET /test served certificate %q, expected %q", got, "first")
+	}
+
+	// Renew the certificate
+	second := writeCertificate(t, certFile, "second", time.Now())
+	if got := get(second); got != "second" {
+		t.Fatalf("GET /test served certificate %q, expected %q", got, "second")
+	}
+}
+
+func TestTLSMissingCertificate(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Listen = "127.0.0.1:0"
+	config.TLS = TLSConfiguration{
+		Enable:   true,
+		CertFile: filepath.Join(t.TempDir(), "cert.pem"),
+	}
+	c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if err := c.Start(); err == nil {
+		c.Stop()
+		t.Fatal("Start() did not error with a missing certificate")
+	}
+}
@@ -160,3 +160,13 @@ func NewConfig(config Configuration) (*sarama.Config, error) {
 	}
 	return kafkaConfig, nil
 }
+
+// ErrorWithHint adds a hint about the settings to check when the provided
+// error is due to an authentication failure. Otherwise, the error is returned
+// unmodified.
+func ErrorWithHint(err error) error {
+	if errors.Is(err, sarama.ErrSASLAuthenticationFailed) {
+		return fmt.Errorf("authentication to Kafka failed (check sasl-username, sasl-password and sasl-mechanism): %w", err)
+	}
+	return err
+}
@@ -4,6 +4,8 @@
 package kafka
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"akvorado/common/helpers"
@@ -77,6 +79,20 @@ func TestKafkaNewConfig(t *testing.T) {
 	}
 }
 
+func TestKafkaNewConfigTLSErrors(t *testing.T) {
+	config := DefaultConfiguration()
+	config.TLS.Enable = true
+	config.TLS.CAFile = "/does/not/exist.pem"
+	if _, err := NewConfig(config); err == nil {
+		t.Fatal("NewConfig() did not error on missing CA file")
+	}
+	config.TLS.CAFile = ""
+	config.TLS.CertFile = "/does/not/exist.pem"
+	if _, err := NewConfig(config); err == nil {
+		t.Fatal("NewConfig() did not error on missing certificate")
+	}
+}
+
 func TestTLSConfiguration(t *testing.T) {
 	helpers.TestConfigurationDecode(t, helpers.ConfigurationDecodeCases{
 		{
@@ -105,6 +121,29 @@ func TestTLSConfiguration(t *testing.T) {
 					},
 				},
 			},
+		}, {
+			Description: "TLS with server name",
+			Initial:     func() interface{} { return DefaultConfiguration() },
+			Configuration: func() interface{} {
+				return gin.H{
+					"tls": gin.H{
+						"enable":      true,
+						"server-name": "kafka.example.com",
+					},
+				}
+			},
+			Expected: Configuration{
+				Topic:   "flows",
+				Brokers: []string{"127.0.0.1:9092"},
+				Version: Version(sarama.V2_8_1_0),
+				TLS: TLSAndSASLConfiguration{
+					TLSConfiguration: helpers.TLSConfiguration{
+						Enable:     true,
+						Verify:     true,
+						ServerName: "kafka.example.com",
+					},
+				},
+			},
 		}, {
 			Description: "TLS SASL plain, skip cert verification",
 			Initial:     func() interface{} { return DefaultConfiguration() },
@@ -168,3 +207,16 @@ func TestTLSConfiguration(t *testing.T) {
 func TestMarshalUnmarshal(t *testing.T) {
 	saslAlgorithmMap.TestMarshalUnmarshal(t)
 }
+
+func TestErrorWithHint(t *testing.T) {
+	err := ErrorWithHint(sarama.Wrap(sarama.ErrOutOfBrokers, sarama.ErrSASLAuthenticationFailed))
+	if !errors.Is(err, sarama.ErrSASLAuthenticationFailed) {
+		t.Errorf("ErrorWithHint() does not wrap the original error: %s", err)
+	}
+	if !strings.HasPrefix(err.Error(), "authentication to Kafka failed") {
+		t.Errorf("ErrorWithHint() does not provide a hint: %s", err)
+	}
+	if err := ErrorWithHint(sarama.ErrOutOfBrokers); err != sarama.ErrOutOfBrokers {
+		t.Errorf("ErrorWithHint() modified an unrelated error: %s", err)
+	}
+}
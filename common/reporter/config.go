@@ -4,14 +4,21 @@
 package reporter
 
 import (
+	"time"
+
 	"akvorado/common/reporter/logger"
 	"akvorado/common/reporter/metrics"
+	"akvorado/common/reporter/sentry"
 )
 
 // Configuration contains the reporter configuration.
 type Configuration struct {
 	Logging logger.Configuration
 	Metrics metrics.Configuration
+	Sentry  sentry.Configuration
+	// StartupGracePeriod is the minimum duration during which the
+	// healthcheck is failing after startup
+	StartupGracePeriod time.Duration `validate:"min=0"`
 }
 
 // DefaultConfiguration is the default reporter configuration.
@@ -19,5 +26,6 @@ func DefaultConfiguration() Configuration {
 	return Configuration{
 		Logging: logger.DefaultConfiguration(),
 		Metrics: metrics.DefaultConfiguration(),
+		Sentry:  sentry.DefaultConfiguration(),
 	}
 }
@@ -5,6 +5,7 @@ package reporter
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -65,19 +66,52 @@ func (r *Reporter) RegisterHealthcheck(name string, hf HealthcheckFunc) {
 	r.healthchecksLock.Unlock()
 }
 
+// RegisterReadinessCheck registers a new readiness check. Unlike healthchecks,
+// readiness checks are only used to tell if the service is ready to do its
+// job (for example, if an external dependency is reachable). They are run by
+// the readiness endpoint, in addition to healthchecks.
+func (r *Reporter) RegisterReadinessCheck(name string, hf HealthcheckFunc) {
+	r.healthchecksLock.Lock()
+	r.readinessChecks[name] = hf
+	r.healthchecksLock.Unlock()
+}
+
 // RunHealthchecks execute all healthchecks in parallel and returns a
 // global status as well as a map from service names to returned
 // results.
 func (r *Reporter) RunHealthchecks(ctx context.Context) MultipleHealthcheckResults {
+	return r.runChecks(ctx, false)
+}
+
+// RunReadinessChecks execute all healthchecks and readiness checks in
+// parallel and returns a global status as well as a map from service names to
+// returned results.
+func (r *Reporter) RunReadinessChecks(ctx context.Context) MultipleHealthcheckResults {
+	return r.runChecks(ctx, true)
+}
+
+func (r *Reporter) runChecks(ctx context.Context, readiness bool) (results MultipleHealthcheckResults) {
 	var wg sync.WaitGroup
-	results := MultipleHealthcheckResults{
+	results = MultipleHealthcheckResults{
 		Status:  HealthcheckOK,
 		Details: map[string]HealthcheckResult{},
 	}
 
 	r.healthchecksLock.Lock()
 	defer r.healthchecksLock.Unlock()
-	runningHealthchecks := len(r.healthchecks)
+	// The returned value is named: the deferred call alters what is returned.
+	defer r.checkWarmup(&results)
+	checks := r.healthchecks
+	if readiness && len(r.readinessChecks) > 0 {
+		checks = make(map[string]HealthcheckFunc, len(r.healthchecks)+len(r.readinessChecks))
+		for name, hf := range r.healthchecks {
+			checks[name] = hf
+		}
+		for name, hf := range r.readinessChecks {
+			checks[name] = hf
+		}
+	}
+	runningHealthchecks := len(checks)
 	if runningHealthchecks == 0 {
 		return results
 	}
@@ -106,7 +140,7 @@ func (r *Reporter) RunHealthchecks(ctx context.Context) MultipleHealthcheckResul
 	}()
 
 	// One goroutine for each healthcheck
-	for name, healthcheckFunc := range r.healthchecks {
+	for name, healthcheckFunc := range checks {
 		wg.Add(1)
 		go func(name string, healthcheckFunc HealthcheckFunc) {
 			defer wg.Done()
@@ -125,7 +159,7 @@ func (r *Reporter) RunHealthchecks(ctx context.Context) MultipleHealthcheckResul
 	wg.Wait() // keep lock, we don't want something to change
 
 	// Check what we have
-	for name := range r.healthchecks {
+	for name := range checks {
 		if result, ok := results.Details[name]; ok {
 			if result.Status > results.Status {
 				results.Status = result.Status
@@ -139,6 +173,32 @@ func (r *Reporter) RunHealthchecks(ctx context.Context) MultipleHealthcheckResul
 	return results
 }
 
+// checkWarmup alters healthcheck results during the startup grace period.
+// Warmup ends once the grace period has elapsed and all healthchecks have
+// succeeded once. It should be called with healthchecksLock held.
+func (r *Reporter) checkWarmup(results *MultipleHealthcheckResults) {
+	if r.warmedUp || r.startupGracePeriod == 0 {
+		return
+	}
+	if remaining := r.startupGracePeriod - time.Since(r.started); remaining > 0 {
+		results.Details["startup"] = HealthcheckResult{
+			HealthcheckError,
+			fmt.Sprintf("warming up (%s remaining)", remaining.Round(time.Second)),
+		}
+		results.Status = HealthcheckError
+		return
+	}
+	if results.Status != HealthcheckOK {
+		results.Details["startup"] = HealthcheckResult{
+			HealthcheckError,
+			"warming up (waiting for all components to be ready)",
+		}
+		results.Status = HealthcheckError
+		return
+	}
+	r.warmedUp = true
+}
+
 // HealthcheckHTTPHandler is an HTTP handler return healthcheck results as JSON.
 func (r *Reporter) HealthcheckHTTPHandler(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
@@ -151,6 +211,26 @@ func (r *Reporter) HealthcheckHTTPHandler(c *gin.Context) {
 	c.JSON(httpStatus, results)
 }
 
+// ReadinessHTTPHandler is an HTTP handler returning healthcheck and readiness
+// check results as JSON.
+func (r *Reporter) ReadinessHTTPHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+	results := r.RunReadinessChecks(ctx)
+	httpStatus := http.StatusOK
+	if results.Status == HealthcheckError {
+		httpStatus = http.StatusServiceUnavailable
+	}
+	c.JSON(httpStatus, results)
+}
+
+// LivenessHTTPHandler is an HTTP handler telling the process is alive. It does
+// not run healthchecks: an unhealthy dependency should not get the process
+// restarted. Use ReadinessHTTPHandler for readiness.
+func (r *Reporter) LivenessHTTPHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthcheckResult{HealthcheckOK, "alive"})
+}
+
 // ChannelHealthcheckFunc is the function sent over a channel to signal liveness
 type ChannelHealthcheckFunc func(HealthcheckStatus, string)
 
@@ -154,3 +154,156 @@ func TestHealthcheckHTTPHandler(t *testing.T) {
 		t.Fatalf("GET /api/v0/healthcheck (-got, +want):\n%s", diff)
 	}
 }
+
+func TestLivenessHTTPHandler(t *testing.T) {
+	r := reporter.NewMock(t)
+	r.RegisterHealthcheck("hc1", func(ctx context.Context) reporter.HealthcheckResult {
+		return reporter.HealthcheckResult{reporter.HealthcheckError, "trying to be better"}
+	})
+
+	req := httptest.NewRequest("GET", "/api/v0/healthz", nil)
+	w := httptest.NewRecorder()
+	ginRouter := gin.Default()
+	ginRouter.GET("/api/v0/healthz", r.LivenessHTTPHandler)
+	ginRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /api/v0/healthz status code, got %d, expected %d",
+			w.Code, http.StatusOK)
+	}
+	var got gin.H
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("GET /api/v0/healthz error:\n%+v", err)
+	}
+	expected := gin.H{"status": "ok", "reason": "alive"}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("GET /api/v0/healthz (-got, +want):\n%s", diff)
+	}
+}
+
+func TestReadinessHTTPHandler(t *testing.T) {
+	r := reporter.NewMock(t)
+	r.RegisterHealthcheck("hc1", func(ctx context.Context) reporter.HealthcheckResult {
+		return reporter.HealthcheckResult{reporter.HealthcheckOK, "all well"}
+	})
+	r.RegisterReadinessCheck("rc1", func(ctx context.Context) reporter.HealthcheckResult {
+		return reporter.HealthcheckResult{reporter.HealthcheckError, "not connected"}
+	})
+
+	// Readiness checks are not run by healthchecks
+	if got := r.RunHealthchecks(context.Background()); got.Status != reporter.HealthcheckOK {
+		t.Errorf("RunHealthchecks() status: got %s, expected ok", got.Status)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v0/ready", nil)
+	w := httptest.NewRecorder()
+	ginRouter := gin.Default()
+	ginRouter.GET("/api/v0/ready", r.ReadinessHTTPHandler)
+	ginRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /api/v0/ready status code, got %d, expected %d",
+			w.Code, http.StatusServiceUnavailable)
+	}
+	var got gin.H
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("GET /api/v0/ready error:\n%+v", err)
+	}
+	expected := gin.H{
+		"status": "error",
+		"details": gin.H{
+			"hc1": gin.H{"status": "ok", "reason": "all well"},
+			"rc1": gin.H{"status": "error", "reason": "not connected"},
+		},
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("GET /api/v0/ready (-got, +want):\n%s", diff)
+	}
+}
+
+func TestStartupGracePeriod(t *testing.T) {
+	r, err := reporter.New(reporter.Configuration{StartupGracePeriod: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	var status reporter.HealthcheckStatus = reporter.HealthcheckError
+	r.RegisterHealthcheck("hc1", func(ctx context.Context) reporter.HealthcheckResult {
+		return reporter.HealthcheckResult{status, "hello"}
+	})
+
+	got := r.RunHealthchecks(context.Background())
+	if got.Status != reporter.HealthcheckError {
+		t.Errorf("RunHealthchecks() status = %s, expected error", got.Status)
+	}
+	if got.Details["startup"].Reason != "warming up (0s remaining)" {
+		t.Errorf("RunHealthchecks() startup reason = %q", got.Details["startup"].Reason)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	testHealthchecks(context.Background(), t, r,
+		reporter.MultipleHealthcheckResults{
+			Status: reporter.HealthcheckError,
+			Details: map[string]reporter.HealthcheckResult{
+				"hc1":     {reporter.HealthcheckError, "hello"},
+				"startup": {reporter.HealthcheckError, "warming up (waiting for all components to be ready)"},
+			},
+		})
+
+	status = reporter.HealthcheckOK
+	testHealthchecks(context.Background(), t, r,
+		reporter.MultipleHealthcheckResults{
+			Status: reporter.HealthcheckOK,
+			Details: map[string]reporter.HealthcheckResult{
+				"hc1": {reporter.HealthcheckOK, "hello"},
+			},
+		})
+
+	// Once warmed up, the grace period does not apply anymore
+	status = reporter.HealthcheckError
+	testHealthchecks(context.Background(), t, r,
+		reporter.MultipleHealthcheckResults{
+			Status: reporter.HealthcheckError,
+			Details: map[string]reporter.HealthcheckResult{
+				"hc1": {reporter.HealthcheckError, "hello"},
+			},
+		})
+}
+
+func TestStartupGracePeriodHealthy(t *testing.T) {
+	r, err := reporter.New(reporter.Configuration{StartupGracePeriod: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	r.RegisterHealthcheck("hc1", func(ctx context.Context) reporter.HealthcheckResult {
+		return reporter.HealthcheckResult{reporter.HealthcheckOK, "hello"}
+	})
+
+	// Even if all healthchecks are fine, we are not ready yet
+	got := r.RunHealthchecks(context.Background())
+	if got.Status != reporter.HealthcheckError {
+		t.Errorf("RunHealthchecks() status = %s, expected error", got.Status)
+	}
+	if got.Details["hc1"].Status != reporter.HealthcheckOK {
+		t.Errorf("RunHealthchecks() hc1 status = %s, expected ok", got.Details["hc1"].Status)
+	}
+	if _, ok := got.Details["startup"]; !ok {
+		t.Error("RunHealthchecks() no startup detail")
+	}
+
+	req := httptest.NewRequest("GET", "/api/v0/healthcheck", nil)
+	w := httptest.NewRecorder()
+	ginRouter := gin.Default()
+	ginRouter.GET("/api/v0/healthcheck", r.HealthcheckHTTPHandler)
+	ginRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /api/v0/healthcheck status code, got %d, expected %d",
+			w.Code, http.StatusServiceUnavailable)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	testHealthchecks(context.Background(), t, r,
+		reporter.MultipleHealthcheckResults{
+			Status: reporter.HealthcheckOK,
+			Details: map[string]reporter.HealthcheckResult{
+				"hc1": {reporter.HealthcheckOK, "hello"},
+			},
+		})
+}
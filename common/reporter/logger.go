@@ -4,9 +4,15 @@
 package reporter
 
 import (
+	"errors"
+	"net/http"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter/logger"
 )
 
 // Logger is an alias for zerolog.Logger
@@ -19,3 +25,41 @@ func BurstSampler(period time.Duration, burst uint32) zerolog.Sampler {
 		Burst:  burst,
 	}
 }
+
+// Reload applies a new configuration. Only the log level can be changed
+// without a restart.
+func (r *Reporter) Reload(config Configuration) error {
+	current, wanted := r.config, config
+	current.Logging.Level = wanted.Logging.Level
+	if current != wanted {
+		return errors.New("only logging.level can be changed without a restart")
+	}
+	logger.SetLevel(config.Logging.Level.Zerolog())
+	r.config = config
+	return nil
+}
+
+type logLevelParameters struct {
+	Level *zerolog.Level `json:"level" binding:"required"`
+}
+
+// LogLevelHTTPHandler is an HTTP handler returning the current log level. With
+// PUT, it changes the log level from the provided JSON object, if allowed by
+// the configuration.
+func (r *Reporter) LogLevelHTTPHandler(c *gin.Context) {
+	if c.Request.Method == http.MethodPut {
+		if !r.allowLevelChange {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Log level changes are not allowed."})
+			return
+		}
+		var params logLevelParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+			return
+		}
+		logger.SetLevel(*params.Level)
+		r.Info().Str("level", params.Level.String()).Msg("log level changed")
+	}
+	level := logger.GetLevel()
+	c.JSON(http.StatusOK, logLevelParameters{Level: &level})
+}
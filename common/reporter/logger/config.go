@@ -3,10 +3,144 @@
 
 package logger
 
-// Configuration if the configuration for logger. Currently, there is no configuration.
-type Configuration struct{}
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"akvorado/common/helpers/bimap"
+)
+
+// Configuration if the configuration for logger.
+type Configuration struct {
+	// Format is the output format of the logs.
+	Format Format
+	// Level is the minimum level of the logs to output. It can be changed
+	// without a restart.
+	Level Level
+	// Caller tells if the source file and line of the caller should be
+	// included in each log.
+	Caller bool
+	// AllowLevelChange tells if the log level can be changed with the HTTP
+	// API.
+	AllowLevelChange bool
+}
 
 // DefaultConfiguration is the default logging configuration.
 func DefaultConfiguration() Configuration {
-	return Configuration{}
+	return Configuration{
+		Format: FormatAuto,
+		Level:  LevelInfo,
+		Caller: true,
+	}
+}
+
+// Format is the output format of the logs.
+type Format int
+
+const (
+	// FormatAuto uses a human-readable format on a terminal and JSON
+	// otherwise.
+	FormatAuto Format = iota
+	// FormatConsole uses a human-readable format.
+	FormatConsole
+	// FormatJSON uses JSON.
+	FormatJSON
+)
+
+var formatMap = bimap.New(map[Format]string{
+	FormatAuto:    "auto",
+	FormatConsole: "console",
+	FormatJSON:    "json",
+})
+
+// MarshalText turns a log format to text.
+func (f Format) MarshalText() ([]byte, error) {
+	got, ok := formatMap.LoadValue(f)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns a log format to string.
+func (f Format) String() string {
+	got, _ := formatMap.LoadValue(f)
+	return got
+}
+
+// UnmarshalText provides a log format from a string.
+func (f *Format) UnmarshalText(input []byte) error {
+	got, ok := formatMap.LoadKey(string(input))
+	if ok {
+		*f = got
+		return nil
+	}
+	return errors.New("unknown log format")
+}
+
+// Level is the minimum level of the logs to output. The zero value is the info
+// level.
+type Level int
+
+const (
+	// LevelInfo outputs informational messages and above.
+	LevelInfo Level = iota
+	// LevelTrace outputs all messages.
+	LevelTrace
+	// LevelDebug outputs debug messages and above.
+	LevelDebug
+	// LevelWarn outputs warnings and above.
+	LevelWarn
+	// LevelError outputs errors and above.
+	LevelError
+)
+
+var levelMap = bimap.New(map[Level]string{
+	LevelInfo:  "info",
+	LevelTrace: "trace",
+	LevelDebug: "debug",
+	LevelWarn:  "warn",
+	LevelError: "error",
+})
+
+// MarshalText turns a log level to text.
+func (l Level) MarshalText() ([]byte, error) {
+	got, ok := levelMap.LoadValue(l)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns a log level to string.
+func (l Level) String() string {
+	got, _ := levelMap.LoadValue(l)
+	return got
+}
+
+// UnmarshalText provides a log level from a string.
+func (l *Level) UnmarshalText(input []byte) error {
+	got, ok := levelMap.LoadKey(string(input))
+	if ok {
+		*l = got
+		return nil
+	}
+	return errors.New("unknown log level")
+}
+
+// Zerolog returns the matching zerolog level.
+func (l Level) Zerolog() zerolog.Level {
+	switch l {
+	case LevelTrace:
+		return zerolog.TraceLevel
+	case LevelDebug:
+		return zerolog.DebugLevel
+	case LevelWarn:
+		return zerolog.WarnLevel
+	case LevelError:
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
 }
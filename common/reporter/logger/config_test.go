@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package logger
+
+import "testing"
+
+func TestFormatMarshalUnmarshal(t *testing.T) {
+	formatMap.TestMarshalUnmarshal(t)
+}
+
+func TestLevelMarshalUnmarshal(t *testing.T) {
+	levelMap.TestMarshalUnmarshal(t)
+}
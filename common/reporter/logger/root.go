@@ -3,8 +3,8 @@
 
 // Package logger handles logging for akvorado.
 //
-// This is a thin wrapper around zerolog. It is currently not
-// configurable as we don't need anything fancy yet for configuration.
+// This is a thin wrapper around zerolog. The output format, the level and the
+// presence of the caller can be configured.
 //
 // It also brings some conventions like the presence of "module" in
 // each context to be able to filter logs more easily. However, this
@@ -13,8 +13,11 @@
 package logger
 
 import (
+	"io"
+	"os"
 	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
@@ -27,21 +30,60 @@ type Logger struct {
 	zerolog.Logger
 }
 
-// New creates a new logger
-func New(_ Configuration) (Logger, error) {
+// New creates a new logger. As the level is global, it also sets the level of
+// all the other loggers. Logs are also written to the additional outputs.
+func New(config Configuration, outputs ...zerolog.LevelWriter) (Logger, error) {
 	// Initialize the logger
-	logger := log.Logger.Hook(contextHook{})
+	logger := log.Logger
+	var output io.Writer
+	switch config.Format {
+	case FormatConsole:
+		output = zerolog.ConsoleWriter{Out: os.Stderr}
+	case FormatJSON:
+		output = os.Stdout
+	case FormatAuto:
+		// The output of the global logger cannot be extended.
+		if len(outputs) > 0 {
+			output = os.Stdout
+			if isatty.IsTerminal(os.Stdout.Fd()) {
+				output = zerolog.ConsoleWriter{Out: os.Stderr}
+			}
+		}
+	}
+	if output != nil {
+		writers := []io.Writer{output}
+		for _, w := range outputs {
+			writers = append(writers, w)
+		}
+		logger = zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp().Logger()
+	}
+	logger = logger.Hook(contextHook{caller: config.Caller})
+	SetLevel(config.Level.Zerolog())
 	return Logger{logger}, nil
 }
 
-type contextHook struct{}
+// SetLevel changes the minimum level of the logs to output.
+func SetLevel(level zerolog.Level) {
+	zerolog.SetGlobalLevel(level)
+}
+
+// GetLevel returns the current minimum level of the logs to output.
+func GetLevel() zerolog.Level {
+	return zerolog.GlobalLevel()
+}
+
+type contextHook struct {
+	caller bool
+}
 
 // Run adds more context to an event, including "module" and "caller".
 func (h contextHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
 	callStack := stack.Callers()
 	callStack = callStack[3:] // Trial and error, there is a test to check it works
-	caller := callStack[0].SourceFile(true)
-	e.Str("caller", caller)
+	if h.caller {
+		caller := callStack[0].SourceFile(true)
+		e.Str("caller", caller)
+	}
 	for _, call := range callStack {
 		module := call.FunctionName()
 		if !strings.HasPrefix(module, stack.ModuleName) {
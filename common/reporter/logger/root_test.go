@@ -4,7 +4,12 @@
 package logger
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 func TestNew(t *testing.T) {
@@ -14,3 +19,48 @@ func TestNew(t *testing.T) {
 	}
 	logger.Info().Int("integer", 15).Msg("log message")
 }
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	previousLogger, previousLevel := log.Logger, zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		log.Logger = previousLogger
+		zerolog.SetGlobalLevel(previousLevel)
+	})
+	log.Logger = zerolog.New(&buf)
+
+	config := DefaultConfiguration()
+	logger, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	logger.Debug().Msg("debug message")
+	if got := buf.String(); got != "" {
+		t.Fatalf("Debug() at info level emitted %q", got)
+	}
+	logger.Info().Msg("info message")
+	if got := buf.String(); !strings.Contains(got, "info message") || !strings.Contains(got, `"caller"`) {
+		t.Fatalf("Info() at info level emitted %q", got)
+	}
+
+	buf.Reset()
+	SetLevel(zerolog.DebugLevel)
+	if got := GetLevel(); got != zerolog.DebugLevel {
+		t.Fatalf("GetLevel() == %s, expected debug", got)
+	}
+	logger.Debug().Msg("debug message")
+	if got := buf.String(); !strings.Contains(got, "debug message") {
+		t.Fatalf("Debug() at debug level emitted %q", got)
+	}
+
+	buf.Reset()
+	config.Caller = false
+	logger, err = New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	logger.Info().Msg("info message")
+	if got := buf.String(); strings.Contains(got, `"caller"`) || !strings.Contains(got, `"module"`) {
+		t.Fatalf("Info() without caller emitted %q", got)
+	}
+}
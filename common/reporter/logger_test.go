@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package reporter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+
+	"akvorado/common/reporter"
+	"akvorado/common/reporter/logger"
+)
+
+func TestReload(t *testing.T) {
+	previousLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	config := reporter.DefaultConfiguration()
+	r, err := reporter.New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if got := zerolog.GlobalLevel(); got != zerolog.InfoLevel {
+		t.Fatalf("GlobalLevel() == %s, expected info", got)
+	}
+
+	config.Logging.Level = logger.LevelWarn
+	if err := r.Reload(config); err != nil {
+		t.Fatalf("Reload() error:\n%+v", err)
+	}
+	if got := zerolog.GlobalLevel(); got != zerolog.WarnLevel {
+		t.Fatalf("GlobalLevel() == %s, expected warn", got)
+	}
+
+	config.Logging.Caller = false
+	if err := r.Reload(config); err == nil {
+		t.Fatal("Reload() did not error")
+	}
+}
+
+func TestLogLevelHTTPHandler(t *testing.T) {
+	previousLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	config := reporter.DefaultConfiguration()
+	config.Logging.AllowLevelChange = true
+	r, err := reporter.New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	router := gin.New()
+	router.GET("/api/v0/loglevel", r.LogLevelHTTPHandler)
+	router.PUT("/api/v0/loglevel", r.LogLevelHTTPHandler)
+
+	cases := []struct {
+		Method     string
+		Body       string
+		StatusCode int
+		Expected   string
+		Level      zerolog.Level
+	}{
+		{http.MethodGet, "", 200, `{"level":"info"}`, zerolog.InfoLevel},
+		{http.MethodPut, `{"level":"debug"}`, 200, `{"level":"debug"}`, zerolog.DebugLevel},
+		{http.MethodGet, "", 200, `{"level":"debug"}`, zerolog.DebugLevel},
+		{http.MethodPut, `{"level":"nope"}`, 400, "", zerolog.DebugLevel},
+		{http.MethodPut, `{}`, 400, "", zerolog.DebugLevel},
+		{http.MethodPut, `{"level":"error"}`, 200, `{"level":"error"}`, zerolog.ErrorLevel},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.Method, "/api/v0/loglevel", strings.NewReader(tc.Body))
+		router.ServeHTTP(w, req)
+		if w.Code != tc.StatusCode {
+			t.Errorf("%s %s: got status code %d, expected %d", tc.Method, tc.Body, w.Code, tc.StatusCode)
+		}
+		if tc.Expected != "" && w.Body.String() != tc.Expected {
+			t.Errorf("%s %s: got %s, expected %s", tc.Method, tc.Body, w.Body.String(), tc.Expected)
+		}
+		if got := zerolog.GlobalLevel(); got != tc.Level {
+			t.Errorf("%s %s: GlobalLevel() == %s, expected %s", tc.Method, tc.Body, got, tc.Level)
+		}
+	}
+}
+
+func TestLogLevelHTTPHandlerNotAllowed(t *testing.T) {
+	previousLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	r, err := reporter.New(reporter.DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	router := gin.New()
+	router.PUT("/api/v0/loglevel", r.LogLevelHTTPHandler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v0/loglevel", strings.NewReader(`{"level":"debug"}`))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("PUT: got status code %d, expected %d", w.Code, http.StatusForbidden)
+	}
+	if got := zerolog.GlobalLevel(); got != zerolog.InfoLevel {
+		t.Errorf("PUT: GlobalLevel() == %s, expected info", got)
+	}
+}
+
+func TestZeroConfigurationLevel(t *testing.T) {
+	previousLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	if _, err := reporter.New(reporter.Configuration{}); err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if got := zerolog.GlobalLevel(); got != zerolog.InfoLevel {
+		t.Errorf("GlobalLevel() == %s, expected info", got)
+	}
+}
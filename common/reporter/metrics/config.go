@@ -3,9 +3,11 @@
 
 package metrics
 
-// Configuration is currently empty as this sub-component is not
-// configurable yet.
-type Configuration struct{}
+// Configuration describes the configuration for the metrics sub-component.
+type Configuration struct {
+	// Prefix is prepended to the name of all metrics
+	Prefix string
+}
 
 // DefaultConfiguration is the default metrics configuration.
 func DefaultConfiguration() Configuration {
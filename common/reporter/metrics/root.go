@@ -34,8 +34,12 @@ type Metrics struct {
 // exporters. The provided prefix is used for system-wide metrics.
 func New(logger logger.Logger, configuration Configuration) (*Metrics, error) {
 	reg := prometheus.NewRegistry()
-	reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
-	reg.MustRegister(collectors.NewGoCollector(
+	var systemReg prometheus.Registerer = reg
+	if configuration.Prefix != "" {
+		systemReg = prometheus.WrapRegistererWithPrefix(configuration.Prefix, reg)
+	}
+	systemReg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	systemReg.MustRegister(collectors.NewGoCollector(
 		collectors.WithGoCollectorRuntimeMetrics(
 			collectors.GoRuntimeMetricsRule{Matcher: regexp.MustCompile("/.*")})))
 	m := Metrics{
@@ -48,10 +52,12 @@ func New(logger logger.Logger, configuration Configuration) (*Metrics, error) {
 	return &m, nil
 }
 
-// HTTPHandler returns an handler to server Prometheus metrics.
+// HTTPHandler returns an handler to server Prometheus metrics. The
+// OpenMetrics format is used when requested by the client.
 func (m *Metrics) HTTPHandler() http.Handler {
 	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
-		ErrorLog: promHTTPLogger{m.logger},
+		ErrorLog:          promHTTPLogger{m.logger},
+		EnableOpenMetrics: true,
 	})
 }
 
@@ -93,7 +99,7 @@ func (m *Metrics) Factory(skipCallstack int) *Factory {
 	defer m.factoryCacheLock.Unlock()
 	moduleName := getPrefix(module)
 	factory := Factory{
-		prefix:   moduleName,
+		prefix:   m.config.Prefix + moduleName,
 		registry: m.registry,
 	}
 	m.factoryCache[module] = &factory
@@ -102,7 +108,8 @@ func (m *Metrics) Factory(skipCallstack int) *Factory {
 
 // Desc allocates and initializes and new metric description. Like for
 // factory, names are prefixed with the module name. Unlike factory,
-// there is no cache.
+// there is no cache. The configured prefix is added when registering
+// the collector.
 func (m *Metrics) Desc(skipCallstack int, name, help string, variableLabels []string) *prometheus.Desc {
 	callStack := stack.Callers()
 	call := callStack[1+skipCallstack] // Trial and error, there is a test to check it works
@@ -113,6 +120,10 @@ func (m *Metrics) Desc(skipCallstack int, name, help string, variableLabels []st
 
 // Collector register a custom collector.
 func (m *Metrics) Collector(c prometheus.Collector) {
+	if m.config.Prefix != "" {
+		prometheus.WrapRegistererWithPrefix(m.config.Prefix, m.registry).MustRegister(c)
+		return
+	}
 	m.registry.MustRegister(c)
 }
 
@@ -122,5 +133,5 @@ func (m *Metrics) CollectorForCurrentModule(skipCallStack int, c prometheus.Coll
 	callStack := stack.Callers()
 	call := callStack[1+skipCallStack] // Should be the same as above !
 	prefix := getPrefix(call.FunctionName())
-	prometheus.WrapRegistererWithPrefix(prefix, m.registry).MustRegister(c)
+	prometheus.WrapRegistererWithPrefix(m.config.Prefix+prefix, m.registry).MustRegister(c)
 }
@@ -85,6 +85,34 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestOpenMetrics(t *testing.T) {
+	l, err := logger.New(logger.DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("logger.New() err:\n%+v", err)
+	}
+	m, err := metrics.New(l, metrics.DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("metrics.New() err:\n%+v", err)
+	}
+	counter := m.Factory(0).NewCounter(prometheus.CounterOpts{
+		Name: "counter2",
+		Help: "Some counter",
+	})
+	counter.Add(18)
+
+	req := httptest.NewRequest("GET", "/api/v0/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text")
+	w := httptest.NewRecorder()
+	m.HTTPHandler().ServeHTTP(w, req)
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/openmetrics-text") {
+		t.Fatalf("GET /api/v0/metrics Content-Type = %q, expected OpenMetrics", contentType)
+	}
+	got := strings.Split(w.Body.String(), "\n")
+	if got[len(got)-2] != "# EOF" {
+		t.Fatalf("GET /api/v0/metrics last line = %q, expected # EOF", got[len(got)-2])
+	}
+}
+
 func TestFactoryCache(t *testing.T) {
 	l, err := logger.New(logger.DefaultConfiguration())
 	if err != nil {
@@ -125,3 +153,63 @@ func TestRegisterTwice(t *testing.T) {
 		t.Fatalf("counter1 != counter2")
 	}
 }
+
+func TestPrefix(t *testing.T) {
+	l, err := logger.New(logger.DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("logger.New() err:\n%+v", err)
+	}
+	config := metrics.DefaultConfiguration()
+	config.Prefix = "myorg_"
+	m, err := metrics.New(l, config)
+	if err != nil {
+		t.Fatalf("metrics.New() err:\n%+v", err)
+	}
+
+	counter := m.Factory(0).NewCounter(prometheus.CounterOpts{
+		Name: "counter1",
+		Help: "Some counter",
+	})
+	counter.Add(18)
+	desc := m.Desc(0, "custom1", "Some custom metric", nil)
+	m.Collector(customCollector{desc})
+
+	req := httptest.NewRequest("GET", "/api/v0/metrics", nil)
+	w := httptest.NewRecorder()
+	m.HTTPHandler().ServeHTTP(w, req)
+	got := strings.Split(w.Body.String(), "\n")
+
+	for _, expected := range []string{
+		"# TYPE myorg_go_threads gauge",
+		"myorg_akvorado_common_reporter_metrics_test_counter1 18",
+		"myorg_akvorado_common_reporter_metrics_test_custom1 1",
+	} {
+		found := false
+		for _, line := range got {
+			if line == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("GET /api/v0/metrics missing: %s", expected)
+		}
+	}
+	for _, line := range got {
+		if strings.HasPrefix(line, "akvorado_") || strings.HasPrefix(line, "go_") {
+			t.Errorf("GET /api/v0/metrics unexpected unprefixed metric: %s", line)
+		}
+	}
+}
+
+type customCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c customCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c customCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1)
+}
@@ -3,14 +3,18 @@
 
 // Package reporter is a façade for reporting duties in akvorado.
 //
-// Such a façade currently includes logging and metrics.
+// Such a façade currently includes logging, metrics, and error reporting.
 package reporter
 
 import (
 	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
 
 	"akvorado/common/reporter/logger"
 	"akvorado/common/reporter/metrics"
+	"akvorado/common/reporter/sentry"
 )
 
 // Reporter contains the state for a reporter. It also supports the
@@ -18,15 +22,31 @@ import (
 type Reporter struct {
 	logger.Logger
 	metrics *metrics.Metrics
+	config  Configuration
 
 	healthchecks     map[string]HealthcheckFunc
+	readinessChecks  map[string]HealthcheckFunc
 	healthchecksLock sync.Mutex
+
+	allowLevelChange bool
+
+	started            time.Time
+	startupGracePeriod time.Duration
+	warmedUp           bool // protected by healthchecksLock
 }
 
 // New creates a new reporter from a configuration.
 func New(config Configuration) (*Reporter, error) {
-	// Initialize logger
-	l, err := logger.New(config.Logging)
+	// Initialize logger, with Sentry when configured
+	var outputs []zerolog.LevelWriter
+	s, err := sentry.New(config.Sentry)
+	if err != nil {
+		return nil, err
+	}
+	if s != nil {
+		outputs = append(outputs, s)
+	}
+	l, err := logger.New(config.Logging, outputs...)
 	if err != nil {
 		return nil, err
 	}
@@ -39,6 +59,13 @@ func New(config Configuration) (*Reporter, error) {
 	return &Reporter{
 		Logger:       l,
 		metrics:      m,
-		healthchecks: make(map[string]HealthcheckFunc),
+		config:       config,
+		healthchecks:    make(map[string]HealthcheckFunc),
+		readinessChecks: make(map[string]HealthcheckFunc),
+
+		allowLevelChange: config.Logging.AllowLevelChange,
+
+		started:            time.Now(),
+		startupGracePeriod: config.StartupGracePeriod,
 	}, nil
 }
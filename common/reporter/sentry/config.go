@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sentry
+
+// Configuration is the configuration for the Sentry integration.
+type Configuration struct {
+	// DSN is the Sentry DSN to send events to. When empty, nothing is sent.
+	DSN string
+	// Environment is the environment attached to each event.
+	Environment string
+}
+
+// DefaultConfiguration is the default configuration for the Sentry
+// integration. It is disabled.
+func DefaultConfiguration() Configuration {
+	return Configuration{}
+}
@@ -0,0 +1,247 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package sentry reports errors to Sentry.
+//
+// This is a minimal client for the store endpoint of Sentry. It is plugged as
+// an additional output of the logger: logs at the error level and above are
+// sent as events, with the stack trace of the caller. Events are sent in the
+// background and dropped when too many of them are pending.
+package sentry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"akvorado/common/reporter/stack"
+)
+
+// Client sends events to Sentry.
+type Client struct {
+	config   Configuration
+	endpoint string
+	auth     string
+	client   *http.Client
+	events   chan []byte
+	hostname string
+}
+
+// New creates a new Sentry client. When no DSN is configured, it returns nil.
+func New(config Configuration) (*Client, error) {
+	if config.DSN == "" {
+		return nil, nil
+	}
+	endpoint, key, err := parseDSN(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	c := &Client{
+		config:   config,
+		endpoint: endpoint,
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=akvorado, sentry_key=%s", key),
+		client:   &http.Client{Timeout: 5 * time.Second},
+		events:   make(chan []byte, 100),
+		hostname: hostname,
+	}
+	go c.run()
+	return c, nil
+}
+
+// parseDSN returns the store endpoint and the public key from a DSN.
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", errors.New("invalid Sentry DSN: unsupported scheme")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", errors.New("invalid Sentry DSN: missing public key")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	project := path[idx+1:]
+	if project == "" {
+		return "", "", errors.New("invalid Sentry DSN: missing project ID")
+	}
+	endpoint := fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:idx], project)
+	return endpoint, u.User.Username(), nil
+}
+
+// Write ignores logs without a level.
+func (c *Client) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel turns a log at the error level or above into an event and queues
+// it. The log is expected to be encoded as JSON.
+func (c *Client) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.ErrorLevel || level > zerolog.PanicLevel {
+		return len(p), nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return len(p), nil
+	}
+	body, err := json.Marshal(c.newEvent(level, fields, stack.Callers()))
+	if err != nil {
+		return len(p), nil
+	}
+	if level == zerolog.FatalLevel {
+		// The program exits just after.
+		c.send(body)
+		return len(p), nil
+	}
+	select {
+	case c.events <- body:
+	default:
+	}
+	return len(p), nil
+}
+
+// run sends the queued events.
+func (c *Client) run() {
+	for body := range c.events {
+		c.send(body)
+	}
+}
+
+// send sends an event to Sentry. Errors are ignored as logging them could
+// generate more events.
+func (c *Client) send(body []byte) {
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+type event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+}
+
+type exception struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []frame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// newEvent builds a Sentry event from the fields of a log and the stack trace
+// of the caller.
+func (c *Client) newEvent(level zerolog.Level, fields map[string]interface{}, trace stack.Trace) event {
+	id := make([]byte, 16)
+	rand.Read(id)
+	ev := event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		ServerName:  c.hostname,
+		Environment: c.config.Environment,
+		Tags:        map[string]string{},
+		Extra:       map[string]interface{}{},
+	}
+	if level > zerolog.ErrorLevel {
+		ev.Level = "fatal"
+	}
+	exc := exception{Type: "error"}
+	for key, value := range fields {
+		str, _ := value.(string)
+		switch key {
+		case zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.CallerFieldName:
+		case zerolog.MessageFieldName:
+			ev.Message = str
+		case "module":
+			ev.Logger = str
+			ev.Tags["module"] = str
+		case zerolog.ErrorFieldName:
+			exc.Value = str
+		case "panic":
+			exc.Type = "panic"
+			exc.Value = str
+		default:
+			ev.Extra[key] = value
+		}
+	}
+	if exc.Value == "" {
+		exc.Value = ev.Message
+	}
+	exc.Stacktrace.Frames = framesFromTrace(trace)
+	ev.Exception.Values = []exception{exc}
+	return ev
+}
+
+var clientPrefix = stack.ModuleName + "/common/reporter/sentry.(*Client)."
+
+// framesFromTrace turns a stack trace into Sentry frames. The frames from the
+// logging code are skipped. Sentry expects the oldest frame first.
+func framesFromTrace(trace stack.Trace) []frame {
+	for len(trace) > 0 {
+		name := trace[0].FunctionName()
+		if !strings.HasPrefix(name, "github.com/rs/zerolog") &&
+			!strings.HasPrefix(name, clientPrefix) {
+			break
+		}
+		trace = trace[1:]
+	}
+	frames := make([]frame, 0, len(trace))
+	for i := len(trace) - 1; i >= 0; i-- {
+		name := trace[i].FunctionName()
+		module, function := "", name
+		if idx := strings.Index(name[strings.LastIndex(name, "/")+1:], "."); idx >= 0 {
+			idx += strings.LastIndex(name, "/") + 1
+			module, function = name[:idx], name[idx+1:]
+		}
+		frames = append(frames, frame{
+			Function: function,
+			Module:   module,
+			Filename: trace[i].SourceFile(false),
+			Lineno:   trace[i].Line(),
+			InApp:    strings.HasPrefix(name, stack.ModuleName+"/"),
+		})
+	}
+	return frames
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sentry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"akvorado/common/helpers"
+)
+
+func TestParseDSN(t *testing.T) {
+	cases := []struct {
+		DSN      string
+		Endpoint string
+		Key      string
+		Error    bool
+	}{
+		{"https://abc@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/store/", "abc", false},
+		{"http://abc@sentry.example.com/sentry/42/", "http://sentry.example.com/sentry/api/42/store/", "abc", false},
+		{"https://o1.ingest.sentry.io/42", "", "", true},
+		{"https://abc@o1.ingest.sentry.io/", "", "", true},
+		{"ftp://abc@o1.ingest.sentry.io/42", "", "", true},
+	}
+	for _, tc := range cases {
+		endpoint, key, err := parseDSN(tc.DSN)
+		if err == nil && tc.Error {
+			t.Errorf("parseDSN(%q) did not error", tc.DSN)
+		} else if err != nil && !tc.Error {
+			t.Errorf("parseDSN(%q) error:\n%+v", tc.DSN, err)
+		} else if endpoint != tc.Endpoint || key != tc.Key {
+			t.Errorf("parseDSN(%q) == %q, %q, expected %q, %q", tc.DSN, endpoint, key, tc.Endpoint, tc.Key)
+		}
+	}
+}
+
+func TestNoDSN(t *testing.T) {
+	c, err := New(DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if c != nil {
+		t.Fatal("New() returned a client without a DSN")
+	}
+}
+
+func TestSendEvents(t *testing.T) {
+	received := make(chan event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/store/" {
+			t.Errorf("POST %s: unexpected path", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=abc") {
+			t.Errorf("POST %s: unexpected X-Sentry-Auth %q", r.URL.Path, auth)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var ev event
+		if err := json.Unmarshal(body, &ev); err != nil {
+			t.Errorf("POST %s: cannot decode event:\n%+v", r.URL.Path, err)
+		}
+		received <- ev
+	}))
+	defer server.Close()
+
+	c, err := New(Configuration{
+		DSN:         fmt.Sprintf("http://abc@%s/42", strings.TrimPrefix(server.URL, "http://")),
+		Environment: "test",
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	logger := zerolog.New(c).Hook(zerolog.HookFunc(func(e *zerolog.Event, _ zerolog.Level, _ string) {
+		e.Str("module", "akvorado/common/reporter/sentry")
+	}))
+	logger.Info().Msg("not sent")
+	logger.Error().Err(errors.New("boom")).Str("exporter", "192.0.2.1").Msg("something failed")
+
+	select {
+	case ev := <-received:
+		if len(ev.Exception.Values) != 1 || len(ev.Exception.Values[0].Stacktrace.Frames) == 0 {
+			t.Fatalf("received event without exception: %+v", ev)
+		}
+		frames := ev.Exception.Values[0].Stacktrace.Frames
+		last := frames[len(frames)-1]
+		if diff := helpers.Diff(last, frame{
+			Function: "TestSendEvents",
+			Module:   "akvorado/common/reporter/sentry",
+			Filename: "akvorado/common/reporter/sentry/root_test.go",
+			Lineno:   last.Lineno,
+			InApp:    true,
+		}); diff != "" {
+			t.Errorf("last frame (-got, +want):\n%s", diff)
+		}
+		ev.Exception.Values[0].Stacktrace.Frames = nil
+		got := struct {
+			Level, Logger, Message, Environment string
+			Tags                                map[string]string
+			Extra                               map[string]interface{}
+			Exception                           exception
+		}{ev.Level, ev.Logger, ev.Message, ev.Environment, ev.Tags, ev.Extra, ev.Exception.Values[0]}
+		expected := got
+		expected.Level = "error"
+		expected.Logger = "akvorado/common/reporter/sentry"
+		expected.Message = "something failed"
+		expected.Environment = "test"
+		expected.Tags = map[string]string{"module": "akvorado/common/reporter/sentry"}
+		expected.Extra = map[string]interface{}{"exporter": "192.0.2.1"}
+		expected.Exception = exception{Type: "error", Value: "boom"}
+		if diff := helpers.Diff(got, expected); diff != "" {
+			t.Errorf("received event (-got, +want):\n%s", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	select {
+	case ev := <-received:
+		t.Fatalf("unexpected event received: %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -96,6 +96,17 @@ func (pc Call) SourceFile(withLine bool) string {
 	return fmt.Sprintf("%s/%s", moduleName, file)
 }
 
+// Line returns the line number of the call point.
+func (pc Call) Line() int {
+	pcFix := uintptr(pc) - 1
+	fn := runtime.FuncForPC(pcFix)
+	if fn == nil {
+		return 0
+	}
+	_, line := fn.FileLine(pcFix)
+	return line
+}
+
 var (
 	ownPackageCall    = Callers()[0]
 	ownPackageName    = strings.SplitN(ownPackageCall.FunctionName(), ".", 2)[0] // akvorado/common/reporter/stack
@@ -41,6 +41,13 @@ func TestFunctionName(t *testing.T) {
 	}
 }
 
+func TestLine(t *testing.T) {
+	callers := stack.Callers()
+	if got := callers[0].Line(); got != 45 {
+		t.Fatalf("Line() == %d, expected 45", got)
+	}
+}
+
 func TestModuleName(t *testing.T) {
 	got := strings.Split(stack.ModuleName, "/")
 	expected := []string{"akvorado"}
@@ -9,12 +9,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"akvorado/common/reporter/logger"
 )
 
 // NewMock creates a new reporter for tests. Currently, this is the same as a production reporter.
 func NewMock(t testing.TB) *Reporter {
 	t.Helper()
-	r, err := New(Configuration{})
+	r, err := New(Configuration{Logging: logger.Configuration{Level: logger.LevelDebug, Caller: true}})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -156,6 +156,7 @@ const (
 	ColumnDstMAC
 	ColumnIPTTL
 	ColumnIPTos
+	ColumnIPDSCP
 	ColumnIPFragmentID
 	ColumnIPFragmentOffset
 	ColumnIPv6FlowLabel
@@ -172,6 +173,18 @@ const (
 	ColumnMPLS2ndLabel
 	ColumnMPLS3rdLabel
 	ColumnMPLS4thLabel
+	ColumnProtoInner
+	ColumnSrcAddrInner
+	ColumnDstAddrInner
+	ColumnSrcPortInner
+	ColumnDstPortInner
+	ColumnTags
+	ColumnFlowStartTime
+	ColumnFlowEndTime
+	ColumnFlowStartMilliseconds
+	ColumnFlowEndMilliseconds
+	ColumnSrcCustomer
+	ColumnDstCustomer
 
 	// ColumnLast points to after the last static column, custom dictionaries
 	// (dynamic columns) come after ColumnLast
@@ -182,6 +195,7 @@ const (
 	ColumnGroupL2 ColumnGroup = iota + 1
 	ColumnGroupNAT
 	ColumnGroupL3L4
+	ColumnGroupInner
 
 	ColumnGroupLast
 )
@@ -490,6 +504,15 @@ END`,
 			{Key: ColumnSrcMAC, Disabled: true, Group: ColumnGroupL2, ClickHouseType: "UInt64"},
 			{Key: ColumnIPTTL, Disabled: true, Group: ColumnGroupL3L4, ParserType: "uint", ClickHouseType: "UInt8"},
 			{Key: ColumnIPTos, Disabled: true, Group: ColumnGroupL3L4, ParserType: "uint", ClickHouseType: "UInt8"},
+			{
+				Key:             ColumnIPDSCP,
+				Depends:         []ColumnKey{ColumnIPTos},
+				Disabled:        true,
+				Group:           ColumnGroupL3L4,
+				ParserType:      "uint",
+				ClickHouseType:  "UInt8",
+				ClickHouseAlias: "bitShiftRight(IPTos, 2)",
+			},
 			{Key: ColumnIPFragmentID, Disabled: true, Group: ColumnGroupL3L4, ParserType: "uint", ClickHouseType: "UInt32"},
 			{Key: ColumnIPFragmentOffset, Disabled: true, Group: ColumnGroupL3L4, ParserType: "uint", ClickHouseType: "UInt16"},
 			{Key: ColumnIPv6FlowLabel, Disabled: true, Group: ColumnGroupL3L4, ParserType: "uint", ClickHouseType: "UInt32"},
@@ -570,6 +593,73 @@ END`,
 				ClickHouseAlias:    "MPLSLabels[4]",
 				ParserType:         "uint",
 			},
+			{
+				Key:                ColumnProtoInner,
+				Disabled:           true,
+				Group:              ColumnGroupInner,
+				ParserType:         "uint",
+				ClickHouseType:     "UInt8",
+				ClickHouseMainOnly: true,
+			},
+			{
+				Key:                ColumnSrcAddrInner,
+				Disabled:           true,
+				Group:              ColumnGroupInner,
+				ParserType:         "ip",
+				ClickHouseType:     "IPv6",
+				ClickHouseMainOnly: true,
+				ConsoleTruncateIP:  true,
+			},
+			{
+				Key:                ColumnSrcPortInner,
+				Disabled:           true,
+				Group:              ColumnGroupInner,
+				ParserType:         "uint",
+				ClickHouseType:     "UInt16",
+				ClickHouseMainOnly: true,
+			},
+			{
+				Key:                ColumnTags,
+				Disabled:           true,
+				ClickHouseMainOnly: true,
+				ClickHouseType:     "Array(LowCardinality(String))",
+			},
+			{
+				Key:                ColumnFlowStartTime,
+				Disabled:           true,
+				ClickHouseMainOnly: true,
+				ClickHouseType:     "DateTime64(3)",
+				ClickHouseCodec:    "ZSTD(1)",
+				ClickHouseTransformFrom: []Column{
+					{Key: ColumnFlowStartMilliseconds, ClickHouseType: "UInt64"},
+				},
+				ClickHouseTransformTo: "if(FlowStartMilliseconds = 0, toDateTime64(TimeReceived, 3), fromUnixTimestamp64Milli(toInt64(FlowStartMilliseconds)))",
+				ConsoleNotDimension:   true,
+			},
+			{
+				Key:                ColumnFlowEndTime,
+				Disabled:           true,
+				ClickHouseMainOnly: true,
+				ClickHouseType:     "DateTime64(3)",
+				ClickHouseCodec:    "ZSTD(1)",
+				ClickHouseTransformFrom: []Column{
+					{Key: ColumnFlowEndMilliseconds, ClickHouseType: "UInt64"},
+				},
+				ClickHouseTransformTo: "if(FlowEndMilliseconds = 0, toDateTime64(TimeReceived, 3), fromUnixTimestamp64Milli(toInt64(FlowEndMilliseconds)))",
+				ConsoleNotDimension:   true,
+			},
+			{
+				Key:            ColumnSrcCustomer,
+				Disabled:       true,
+				ParserType:     "string",
+				ClickHouseType: "LowCardinality(String)",
+			},
+			{
+				Key:            ColumnDstCustomer,
+				Disabled:       true,
+				ParserType:     "string",
+				ClickHouseType: "LowCardinality(String)",
+			},
 		},
 	}.finalize()
 }
@@ -662,6 +752,10 @@ func (schema Schema) finalize() Schema {
 	ncolumns = []Column{}
 	for _, column := range schema.columns {
 		pcolumns := []*Column{&column}
+		for idx := range column.ClickHouseTransformFrom {
+			// Source columns follow the state of the transformed column
+			column.ClickHouseTransformFrom[idx].Disabled = column.Disabled
+		}
 		for idx := range column.ClickHouseTransformFrom {
 			pcolumns = append(pcolumns, &column.ClickHouseTransformFrom[idx])
 		}
@@ -690,6 +784,9 @@ func (schema Schema) finalize() Schema {
 				case "Array(UInt32)":
 					column.ProtobufType = protoreflect.Uint32Kind
 					column.ProtobufRepeated = true
+				case "Array(LowCardinality(String))":
+					column.ProtobufType = protoreflect.StringKind
+					column.ProtobufRepeated = true
 				}
 			}
 		}
@@ -703,6 +800,11 @@ func (schema Schema) finalize() Schema {
 		if column.Key > maxKey {
 			maxKey = column.Key
 		}
+		for _, column := range column.ClickHouseTransformFrom {
+			if column.Key > maxKey {
+				maxKey = column.Key
+			}
+		}
 	}
 	schema.columnIndex = make([]*Column, maxKey+1)
 	for i, column := range schema.columns {
@@ -124,6 +124,38 @@ func (schema *Schema) ProtobufMarshal(bf *FlowMessage) []byte {
 	return result
 }
 
+// ProtobufLookupVarint returns the value of a varint previously appended to
+// the protobuf representation of a flow. It needs to go through the whole
+// message. It should not be used once the flow has been marshaled.
+func (schema *Schema) ProtobufLookupVarint(bf *FlowMessage, columnKey ColumnKey) (uint64, bool) {
+	column, ok := schema.LookupColumnByKey(columnKey)
+	if !ok || column.ProtobufIndex <= 0 || bf.protobuf == nil ||
+		!bf.protobufSet.Test(uint(column.ProtobufIndex)) {
+		return 0, false
+	}
+	b := bf.protobuf[maxSizeVarint:]
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+		if num == column.ProtobufIndex && typ == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, false
+			}
+			return value, true
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+	}
+	return 0, false
+}
+
 // ProtobufAppendVarint append a varint to the protobuf representation of a flow.
 func (schema *Schema) ProtobufAppendVarint(bf *FlowMessage, columnKey ColumnKey, value uint64) {
 	// Check if value is 0 to avoid a lookup.
@@ -110,6 +110,38 @@ message FlowMessagev5WRSGBXQDXZSUHZQE6QEHLI5JM {
 	}
 }
 
+func TestProtobufLookupVarint(t *testing.T) {
+	c := NewMock(t)
+	bf := &FlowMessage{}
+	if _, ok := c.ProtobufLookupVarint(bf, ColumnDstPort); ok {
+		t.Error("ProtobufLookupVarint() found a value in an empty flow")
+	}
+	c.ProtobufAppendVarint(bf, ColumnProto, 6)
+	c.ProtobufAppendBytes(bf, ColumnExporterName, []byte("exporter"))
+	c.ProtobufAppendVarint(bf, ColumnDstPort, 443)
+	c.ProtobufAppendVarint(bf, ColumnDstASPath, 65000)
+	c.ProtobufAppendVarint(bf, ColumnDstASPath, 65001)
+
+	cases := []struct {
+		Column   ColumnKey
+		Expected uint64
+		Found    bool
+	}{
+		{ColumnProto, 6, true},
+		{ColumnDstPort, 443, true},
+		{ColumnDstASPath, 65000, true},
+		{ColumnSrcPort, 0, false},
+		{ColumnExporterName, 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := c.ProtobufLookupVarint(bf, tc.Column)
+		if got != tc.Expected || ok != tc.Found {
+			t.Errorf("ProtobufLookupVarint(%s) == %d, %v but expected %d, %v",
+				tc.Column, got, ok, tc.Expected, tc.Found)
+		}
+	}
+}
+
 func TestProtobufMarshal(t *testing.T) {
 	c := NewMock(t)
 	exporterAddress := netip.MustParseAddr("::ffff:203.0.113.14")
@@ -32,6 +32,35 @@ func TestEnableDisableColumns(t *testing.T) {
 	}
 }
 
+func TestEnableTransformedColumn(t *testing.T) {
+	c, err := schema.New(schema.DefaultConfiguration())
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if column, ok := c.LookupColumnByKey(schema.ColumnFlowStartMilliseconds); !ok {
+		t.Fatal("FlowStartMilliseconds not found")
+	} else if !column.Disabled {
+		t.Fatal("FlowStartMilliseconds is not disabled")
+	}
+
+	config := schema.DefaultConfiguration()
+	config.Enabled = []schema.ColumnKey{schema.ColumnFlowStartTime}
+	c, err = schema.New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if column, ok := c.LookupColumnByKey(schema.ColumnFlowStartMilliseconds); !ok {
+		t.Fatal("FlowStartMilliseconds not found")
+	} else if column.Disabled {
+		t.Fatal("FlowStartMilliseconds is still disabled")
+	}
+	if column, ok := c.LookupColumnByKey(schema.ColumnFlowEndMilliseconds); !ok {
+		t.Fatal("FlowEndMilliseconds not found")
+	} else if !column.Disabled {
+		t.Fatal("FlowEndMilliseconds is not disabled")
+	}
+}
+
 func TestDisableForbiddenColumns(t *testing.T) {
 	config := schema.DefaultConfiguration()
 	config.Disabled = []schema.ColumnKey{schema.ColumnDst1stAS}
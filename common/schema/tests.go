@@ -96,6 +96,9 @@ func (schema *Schema) ProtobufDecode(t *testing.T, input []byte) *FlowMessage {
 		case "DstAddr":
 			ip, _ := netip.AddrFromSlice(message.GetFieldByNumber(k).([]byte))
 			flow.DstAddr = ip
+		case "NextHop":
+			ip, _ := netip.AddrFromSlice(message.GetFieldByNumber(k).([]byte))
+			flow.NextHop = ip
 		case "SrcAS":
 			flow.SrcAS = uint32(message.GetFieldByNumber(k).(uint32))
 		case "DstAS":
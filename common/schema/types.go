@@ -5,6 +5,7 @@ package schema
 
 import (
 	"net/netip"
+	"time"
 
 	"github.com/bits-and-blooms/bitset"
 	"google.golang.org/protobuf/encoding/protowire"
@@ -92,6 +93,12 @@ type FlowMessage struct {
 	SrcVlan uint16
 	DstVlan uint16
 
+	// Interface names and descriptions provided by the exporter (optional)
+	InIfName         string
+	InIfDescription  string
+	OutIfName        string
+	OutIfDescription string
+
 	// For geolocation or BMP
 	SrcAddr netip.Addr
 	DstAddr netip.Addr
@@ -105,6 +112,10 @@ type FlowMessage struct {
 	SrcNetMask uint8
 	DstNetMask uint8
 
+	// For latency measurements (not serialized)
+	ReceivedAt time.Time `json:"-"` // when the packet was received
+	QueuedAt   time.Time `json:"-"` // when the flow entered its current internal queue
+
 	// protobuf is the protobuf representation for the information not contained above.
 	protobuf      []byte
 	protobufSet   bitset.BitSet
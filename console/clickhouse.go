@@ -8,12 +8,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"text/template"
 	"time"
 
 	"akvorado/console/query"
+
+	"github.com/gin-gonic/gin"
 )
 
 // flowsTable describe a consolidated or unconsolidated flows table.
@@ -82,6 +85,28 @@ AND (engine LIKE '%MergeTree' OR engine = 'Distributed')
 	return nil
 }
 
+type flowsTableOutput struct {
+	Name       string    `json:"name"`
+	Resolution uint64    `json:"resolution"`
+	Oldest     time.Time `json:"oldest"`
+}
+
+// flowsTablesHandlerFunc returns the list of known flows tables with their
+// resolution in seconds and the oldest available data.
+func (c *Component) flowsTablesHandlerFunc(gc *gin.Context) {
+	c.flowsTablesLock.RLock()
+	output := make([]flowsTableOutput, 0, len(c.flowsTables))
+	for _, table := range c.flowsTables {
+		output = append(output, flowsTableOutput{
+			Name:       table.Name,
+			Resolution: uint64(table.Resolution.Seconds()),
+			Oldest:     table.Oldest,
+		})
+	}
+	c.flowsTablesLock.RUnlock()
+	gc.JSON(http.StatusOK, gin.H{"tables": output})
+}
+
 // finalizeQuery builds the finalized query. A single "context"
 // function is provided to return a `Context` struct with all the
 // information needed.
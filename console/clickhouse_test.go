@@ -10,11 +10,12 @@ import (
 
 	"akvorado/common/helpers"
 
+	"github.com/gin-gonic/gin"
 	"go.uber.org/mock/gomock"
 )
 
 func TestRefreshFlowsTables(t *testing.T) {
-	c, _, mockConn, _ := NewMock(t, DefaultConfiguration())
+	c, h, mockConn, _ := NewMock(t, DefaultConfiguration())
 	mockConn.EXPECT().
 		Select(gomock.Any(), gomock.Any(), `
 SELECT name
@@ -71,6 +72,20 @@ AND (engine LIKE '%MergeTree' OR engine = 'Distributed')
 	if diff := helpers.Diff(c.flowsTables, expected); diff != "" {
 		t.Fatalf("refreshFlowsTables() diff:\n%s", diff)
 	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL: "/api/v0/console/tables",
+			JSONOutput: gin.H{
+				"tables": []gin.H{
+					{"name": "flows", "resolution": 0, "oldest": "2022-04-10T15:45:10Z"},
+					{"name": "flows_1h0m0s", "resolution": 3600, "oldest": "2022-01-10T15:45:10Z"},
+					{"name": "flows_1m0s", "resolution": 60, "oldest": "2022-04-20T15:45:10Z"},
+					{"name": "flows_5m0s", "resolution": 300, "oldest": "2022-02-10T15:45:10Z"},
+				},
+			},
+		},
+	})
 }
 
 func TestFinalizeQuery(t *testing.T) {
@@ -27,6 +27,12 @@ type Configuration struct {
 	HomepageGraphTimeRange time.Duration `validate:"min=1m"`
 	// DimensionsLimit put an upper limit to the number of dimensions to return.
 	DimensionsLimit int `validate:"min=10"`
+	// MaxPoints put an upper limit to the number of points to return for a
+	// time series.
+	MaxPoints uint `validate:"min=5"`
+	// MaxTimeRange put an upper limit to the time range of a query. 0 means
+	// no limit.
+	MaxTimeRange time.Duration `validate:"min=0"`
 	// CacheTTL tells how long to keep the most costly requests in cache.
 	CacheTTL time.Duration `validate:"min=5s"`
 }
@@ -64,6 +70,7 @@ func DefaultConfiguration() Configuration {
 		},
 		HomepageTopWidgets:     []string{"src-as", "src-port", "protocol", "src-country", "etype"},
 		DimensionsLimit:        50,
+		MaxPoints:              2000,
 		CacheTTL:               3 * time.Hour,
 		HomepageGraphFilter:    "InIfBoundary = 'external'",
 		HomepageGraphTimeRange: 24 * time.Hour,
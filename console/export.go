@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+)
+
+type graphLineExportParameters struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv json"`
+}
+
+// exportFlushRows is the number of rows after which the exported data is
+// flushed to the client.
+const exportFlushRows = 1000
+
+// graphLineExportHandlerFunc streams the results of the /graph/line endpoint
+// as CSV or as JSON lines when requested with the format parameter. Rows are
+// sent as they are received from the database, without buffering. Otherwise,
+// it lets the next handlers answer.
+func (c *Component) graphLineExportHandlerFunc(gc *gin.Context) {
+	var params graphLineExportParameters
+	if err := gc.ShouldBindQuery(&params); err != nil {
+		gc.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if params.Format == "" {
+		return
+	}
+	defer gc.Abort()
+
+	ctx := c.t.Context(gc.Request.Context())
+	input, ok := c.bindGraphLineInput(gc)
+	if !ok {
+		return
+	}
+	sqlQuery := input.toSQL()
+	sqlQuery = c.finalizeQuery(sqlQuery)
+	gc.Header("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
+
+	rows, err := c.d.ClickHouseDB.Conn.Query(ctx, sqlQuery)
+	if err != nil {
+		c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
+		return
+	}
+	defer rows.Close()
+
+	dimensions := make([]string, len(input.Dimensions))
+	others := make([]string, len(input.Dimensions))
+	for idx, column := range input.Dimensions {
+		dimensions[idx] = column.String()
+		others[idx] = "Other"
+	}
+	var write func(result graphLineResult) error
+	var flush func()
+	switch params.Format {
+	case "csv":
+		gc.Header("Content-Type", "text/csv; charset=utf-8")
+		gc.Header("Content-Disposition", `attachment; filename="akvorado.csv"`)
+		w := csv.NewWriter(gc.Writer)
+		header := append([]string{"time", "axis"}, dimensions...)
+		header = append(header, input.Units)
+		w.Write(header)
+		write = func(result graphLineResult) error {
+			record := append([]string{
+				result.Time.UTC().Format(time.RFC3339),
+				input.axisName(int(result.Axis)),
+			}, result.Dimensions...)
+			record = append(record, strconv.FormatFloat(result.Xps, 'f', -1, 64))
+			return w.Write(record)
+		}
+		flush = w.Flush
+	case "json":
+		gc.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(gc.Writer)
+		write = func(result graphLineResult) error {
+			record := map[string]interface{}{
+				"time":      result.Time.UTC(),
+				"axis":      input.axisName(int(result.Axis)),
+				input.Units: result.Xps,
+			}
+			for idx, dimension := range dimensions {
+				record[dimension] = result.Dimensions[idx]
+			}
+			return encoder.Encode(record)
+		}
+		flush = func() {}
+	}
+	gc.Status(http.StatusOK)
+
+	count := 0
+	for rows.Next() {
+		var result graphLineResult
+		if err := rows.ScanStruct(&result); err != nil {
+			c.r.Err(err).Str("query", sqlQuery).Msg("unable to parse result")
+			return
+		}
+		// When filling 0 value, we may get an empty dimensions.
+		if len(result.Dimensions) == 0 {
+			result.Dimensions = others
+		}
+		if err := write(result); err != nil {
+			return
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			flush()
+			gc.Writer.Flush()
+		}
+	}
+	flush()
+	if err := rows.Err(); err != nil {
+		c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/helpers"
+)
+
+func TestGraphLineExport(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	base := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	results := []graphLineResult{
+		{1, base, 1000, []string{"router1", "provider1"}},
+		{1, base, 2000, []string{"router1", "provider2"}},
+		{1, base, 1900.5, []string{}},
+		{2, base, 100, []string{"router1", "provider1"}},
+	}
+	ctrl := gomock.NewController(t)
+	for range 2 {
+		mockRows := mocks.NewMockRows(ctrl)
+		mockConn.EXPECT().Query(gomock.Any(), gomock.Any()).Return(mockRows, nil)
+		for _, result := range results {
+			mockRows.EXPECT().Next().Return(true)
+			mockRows.EXPECT().ScanStruct(gomock.Any()).SetArg(0, result).Return(nil)
+		}
+		mockRows.EXPECT().Next().Return(false)
+		mockRows.EXPECT().Err().Return(nil)
+		mockRows.EXPECT().Close().Return(nil)
+	}
+
+	input := gin.H{
+		"start":         time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":           time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		"points":        100,
+		"limit":         20,
+		"dimensions":    []string{"ExporterName", "InIfProvider"},
+		"filter":        "DstCountry = 'FR'",
+		"units":         "l3bps",
+		"bidirectional": true,
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "CSV",
+			URL:         "/api/v0/console/graph/line?format=csv",
+			JSONInput:   input,
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"time,axis,ExporterName,InIfProvider,l3bps",
+				"2009-11-10T23:00:00Z,Direct,router1,provider1,1000",
+				"2009-11-10T23:00:00Z,Direct,router1,provider2,2000",
+				"2009-11-10T23:00:00Z,Direct,Other,Other,1900.5",
+				"2009-11-10T23:00:00Z,Reverse,router1,provider1,100",
+			},
+		}, {
+			Description: "JSON",
+			URL:         "/api/v0/console/graph/line?format=json",
+			JSONInput:   input,
+			ContentType: "application/x-ndjson",
+			FirstLines: []string{
+				`{"ExporterName":"router1","InIfProvider":"provider1","axis":"Direct","l3bps":1000,"time":"2009-11-10T23:00:00Z"}`,
+				`{"ExporterName":"router1","InIfProvider":"provider2","axis":"Direct","l3bps":2000,"time":"2009-11-10T23:00:00Z"}`,
+				`{"ExporterName":"Other","InIfProvider":"Other","axis":"Direct","l3bps":1900.5,"time":"2009-11-10T23:00:00Z"}`,
+				`{"ExporterName":"router1","InIfProvider":"provider1","axis":"Reverse","l3bps":100,"time":"2009-11-10T23:00:00Z"}`,
+			},
+		}, {
+			Description: "unknown format",
+			URL:         "/api/v0/console/graph/line?format=xml",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput: gin.H{
+				"message": "Key: 'graphLineExportParameters.Format' Error:Field validation for 'Format' failed on the 'oneof' tag",
+			},
+		},
+	})
+}
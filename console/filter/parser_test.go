@@ -313,6 +313,7 @@ output provider */ = 'telia'`,
 		{Input: `SrcMAC = 0000.5e00.5301`, Output: `SrcMAC = MACStringToNum('00:00:5e:00:53:01')`},
 		{Input: `ipttl > 50`, Output: `IPTTL > 50`},
 		{Input: `iptos = 0`, Output: `IPTos = 0`},
+		{Input: `ipdscp = 46`, Output: `IPDSCP = 46`},
 		{Input: `ipfragmentid != 0`, Output: `IPFragmentID != 0`},
 		{Input: `ipfragmentoffset = 3`, Output: `IPFragmentOffset = 3`},
 		{Input: `ipv6flowlabel = 0`, Output: `IPv6FlowLabel = 0`},
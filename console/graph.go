@@ -59,3 +59,31 @@ func (input graphCommonHandlerInput) sourceSelect() string {
 	}
 	return fmt.Sprintf("SELECT * REPLACE (%s) FROM {{ .Table }} SETTINGS asterisk_include_alias_columns = 1", strings.Join(truncated, ", "))
 }
+
+// checkTimeRange checks the time range of a query against the configured
+// maximum.
+func (c *Component) checkTimeRange(start, end time.Time) error {
+	if c.config.MaxTimeRange > 0 && end.Sub(start) > c.config.MaxTimeRange {
+		return fmt.Errorf("time range is set beyond maximum value (%s)", c.config.MaxTimeRange)
+	}
+	return nil
+}
+
+// checkPoints checks the number of points of a time series against the
+// configured maximum. When an explicit interval (in seconds) is provided, the
+// number of points is computed from it.
+func (c *Component) checkPoints(start, end time.Time, points *uint, interval uint) error {
+	if interval > 0 {
+		computed := uint64(end.Sub(start)/time.Second) / uint64(interval)
+		if computed > uint64(c.config.MaxPoints) {
+			return fmt.Errorf("interval is too small for the time range (%d points, maximum is %d)",
+				computed, c.config.MaxPoints)
+		}
+		*points = uint(max(computed, 1))
+		return nil
+	}
+	if *points > c.config.MaxPoints {
+		return fmt.Errorf("number of points is set beyond maximum value (%d)", c.config.MaxPoints)
+	}
+	return nil
+}
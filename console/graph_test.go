@@ -5,6 +5,9 @@ package console
 
 import (
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
 
 	"akvorado/common/helpers"
 	"akvorado/common/schema"
@@ -67,3 +70,105 @@ func TestSourceSelect(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckPoints(t *testing.T) {
+	c, _, _, _ := NewMock(t, DefaultConfiguration())
+	start := time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC)
+	cases := []struct {
+		Description string
+		End         time.Time
+		Points      uint
+		Interval    uint
+		Expected    uint
+		Error       bool
+	}{
+		{"points", start.Add(24 * time.Hour), 100, 0, 100, false},
+		{"too many points", start.Add(24 * time.Hour), 2001, 0, 0, true},
+		{"interval", start.Add(24 * time.Hour), 100, 3600, 24, false},
+		{"large interval", start.Add(time.Hour), 100, 86400, 1, false},
+		{"small interval", start.Add(24 * time.Hour), 100, 1, 0, true},
+		{"huge interval", start.Add(24 * time.Hour), 100, 1 << 55, 1, false},
+		{"interval without points", start.Add(24 * time.Hour), 0, 3600, 24, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			points := tc.Points
+			err := c.checkPoints(start, tc.End, &points, tc.Interval)
+			if err == nil && tc.Error {
+				t.Fatal("checkPoints() did not error")
+			} else if err != nil && !tc.Error {
+				t.Fatalf("checkPoints() error:\n%+v", err)
+			} else if err == nil && points != tc.Expected {
+				t.Fatalf("checkPoints() points == %d, expected %d", points, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestGraphLimits(t *testing.T) {
+	config := DefaultConfiguration()
+	config.MaxPoints = 100
+	config.MaxTimeRange = 24 * time.Hour
+	_, h, _, _ := NewMock(t, config)
+	start := time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC)
+	input := func(end time.Time, extra gin.H) gin.H {
+		result := gin.H{
+			"start":      start,
+			"end":        end,
+			"points":     100,
+			"limit":      10,
+			"dimensions": []string{"SrcAS"},
+			"units":      "l3bps",
+		}
+		for k, v := range extra {
+			result[k] = v
+		}
+		return result
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "line time range too large",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input(start.Add(48*time.Hour), nil),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Time range is set beyond maximum value (24h0m0s)"},
+		}, {
+			Description: "line too many points",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input(start.Add(time.Hour), gin.H{"points": 200}),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Number of points is set beyond maximum value (100)"},
+		}, {
+			Description: "line interval too small",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input(start.Add(time.Hour), gin.H{"interval": 1}),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Interval is too small for the time range (3600 points, maximum is 100)"},
+		}, {
+			Description: "line interval without points",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input(start.Add(time.Hour), gin.H{"interval": 1, "points": 0}),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Interval is too small for the time range (3600 points, maximum is 100)"},
+		}, {
+			Description: "line without points nor interval",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input(start.Add(time.Hour), gin.H{"points": 0}),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Key: 'graphLineHandlerInput.Points' Error:Field validation for 'Points' failed on the 'required_without' tag"},
+		}, {
+			Description: "sankey time range too large",
+			URL:         "/api/v0/console/graph/sankey",
+			JSONInput:   input(start.Add(48*time.Hour), gin.H{"dimensions": []string{"SrcAS", "DstAS"}}),
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Time range is set beyond maximum value (24h0m0s)"},
+		}, {
+			Description: "table interval time range too large",
+			URL:         "/api/v0/console/graph/table-interval",
+			JSONInput:   gin.H{"start": start, "end": start.Add(48 * time.Hour), "points": 100},
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Time range is set beyond maximum value (24h0m0s)"},
+		},
+	})
+}
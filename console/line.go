@@ -20,7 +20,8 @@ import (
 // graphLineHandlerInput describes the input for the /graph/line endpoint.
 type graphLineHandlerInput struct {
 	graphCommonHandlerInput
-	Points         uint `json:"points" binding:"required,min=5,max=2000"` // minimum number of points
+	Points         uint `json:"points" binding:"required_without=Interval,omitempty,min=5"` // minimum number of points
+	Interval       uint `json:"interval"`                                                   // interval in seconds, overrides points
 	Bidirectional  bool `json:"bidirectional"`
 	PreviousPeriod bool `json:"previous-period"`
 }
@@ -218,25 +219,66 @@ func (input graphLineHandlerInput) toSQL() string {
 	return strings.Join(parts, "\nUNION ALL\n")
 }
 
-func (c *Component) graphLineHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
+// bindGraphLineInput parses and validates the input for the /graph/line
+// endpoint. On error, it answers the request itself.
+func (c *Component) bindGraphLineInput(gc *gin.Context) (graphLineHandlerInput, bool) {
 	input := graphLineHandlerInput{graphCommonHandlerInput: graphCommonHandlerInput{schema: c.d.Schema}}
 	if err := gc.ShouldBindJSON(&input); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
 	if err := query.Columns(input.Dimensions).Validate(input.schema); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
 	if err := input.Filter.Validate(input.schema); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
 	if input.Limit > c.config.DimensionsLimit {
 		gc.JSON(http.StatusBadRequest,
 			gin.H{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
 				c.config.DimensionsLimit)})
+		return input, false
+	}
+	if err := c.checkTimeRange(input.Start, input.End); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return input, false
+	}
+	if err := c.checkPoints(input.Start, input.End, &input.Points, input.Interval); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return input, false
+	}
+	return input, true
+}
+
+// graphLineResult is a row returned by the query for the /graph/line endpoint.
+type graphLineResult = struct {
+	Axis       uint8     `ch:"axis"`
+	Time       time.Time `ch:"time"`
+	Xps        float64   `ch:"xps"`
+	Dimensions []string  `ch:"dimensions"`
+}
+
+// axisName returns the name of the provided axis.
+func (input graphLineHandlerInput) axisName(axis int) string {
+	switch axis {
+	case 1:
+		return "Direct"
+	case 2:
+		return "Reverse"
+	case 3, 4:
+		diff := input.End.Sub(input.Start)
+		_, name := nearestPeriod(diff)
+		return fmt.Sprintf("Previous %s", name)
+	}
+	return ""
+}
+
+func (c *Component) graphLineHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	input, ok := c.bindGraphLineInput(gc)
+	if !ok {
 		return
 	}
 
@@ -244,12 +286,7 @@ func (c *Component) graphLineHandlerFunc(gc *gin.Context) {
 	sqlQuery = c.finalizeQuery(sqlQuery)
 	gc.Header("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
 
-	results := []struct {
-		Axis       uint8     `ch:"axis"`
-		Time       time.Time `ch:"time"`
-		Xps        float64   `ch:"xps"`
-		Dimensions []string  `ch:"dimensions"`
-	}{}
+	results := []graphLineResult{}
 	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
 		c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
@@ -410,16 +447,7 @@ func (c *Component) graphLineHandlerFunc(gc *gin.Context) {
 	}
 
 	for _, axis := range output.Axis {
-		switch axis {
-		case 1:
-			output.AxisNames[axis] = "Direct"
-		case 2:
-			output.AxisNames[axis] = "Reverse"
-		case 3, 4:
-			diff := input.End.Sub(input.Start)
-			_, name := nearestPeriod(diff)
-			output.AxisNames[axis] = fmt.Sprintf("Previous %s", name)
-		}
+		output.AxisNames[axis] = input.axisName(axis)
 	}
 	gc.JSON(http.StatusOK, output)
 }
@@ -427,7 +455,7 @@ func (c *Component) graphLineHandlerFunc(gc *gin.Context) {
 type tableIntervalInput struct {
 	Start  time.Time `json:"start" binding:"required"`
 	End    time.Time `json:"end" binding:"required,gtfield=Start"`
-	Points uint      `json:"points" binding:"required,min=5,max=2000"` // minimum number of points
+	Points uint      `json:"points" binding:"required,min=5"` // minimum number of points
 }
 
 type tableIntervalOutput struct {
@@ -441,6 +469,14 @@ func (c *Component) getTableAndIntervalHandlerFunc(gc *gin.Context) {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
 		return
 	}
+	if err := c.checkTimeRange(input.Start, input.End); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := c.checkPoints(input.Start, input.End, &input.Points, 0); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
 	table, interval, _ := c.computeTableAndInterval(inputContext{
 		Points: input.Points,
 		Start:  input.Start,
@@ -1030,7 +1030,7 @@ func TestGetTableInterval(t *testing.T) {
 			},
 			StatusCode: 400,
 			JSONOutput: gin.H{
-				"message": `Key: 'tableIntervalInput.Points' Error:Field validation for 'Points' failed on the 'max' tag`,
+				"message": "Number of points is set beyond maximum value (2000)",
 			},
 		},
 	})
@@ -7,6 +7,8 @@ package query
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"akvorado/common/helpers"
@@ -115,6 +117,8 @@ func (qc Column) ToSQLSelect(sch *schema.Component) string {
 		strValue = `arrayStringConcat(MPLSLabels, ' ')`
 	case schema.ColumnDstASPath:
 		strValue = `arrayStringConcat(DstASPath, ' ')`
+	case schema.ColumnTags:
+		strValue = `arrayStringConcat(Tags, ' ')`
 	case schema.ColumnDstCommunities:
 		strValue = `arrayStringConcat(arrayConcat(arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':', toString(bitAnd(c, 0xffff))), DstCommunities), arrayMap(c -> concat(toString(bitAnd(bitShiftRight(c, 64), 0xffffffff)), ':', toString(bitAnd(bitShiftRight(c, 32), 0xffffffff)), ':', toString(bitAnd(c, 0xffffffff))), DstLargeCommunities)), ' ')`
 	case schema.ColumnSrcMAC, schema.ColumnDstMAC:
@@ -136,6 +140,20 @@ func (qc Column) ToSQLSelect(sch *schema.Component) string {
 			array[bit] = fmt.Sprintf("if(bitTest(%s, %d) = 1, '%s', '')", qc, bit, v[:1])
 		}
 		strValue = fmt.Sprintf("arrayStringConcat([%s], '')", strings.Join(array, ", "))
+	case schema.ColumnIPDSCP:
+		keys := make([]int, 0, len(dscpClassNames))
+		for codePoint := range dscpClassNames {
+			keys = append(keys, codePoint)
+		}
+		slices.Sort(keys)
+		codePoints := make([]string, len(keys))
+		names := make([]string, len(keys))
+		for idx, codePoint := range keys {
+			codePoints[idx] = strconv.Itoa(codePoint)
+			names[idx] = fmt.Sprintf("'%s'", dscpClassNames[codePoint])
+		}
+		strValue = fmt.Sprintf("transform(%s, [%s], [%s], toString(%s))",
+			qc, strings.Join(codePoints, ", "), strings.Join(names, ", "), qc)
 	case schema.ColumnDstPort, schema.ColumnSrcPort:
 		strValue = fmt.Sprintf(`replaceRegexpOne(multiIf(%s==6, concat(toString(%s), '/', dictGetOrDefault('%s', 'name', %s,'')), %s==17, concat(toString(%s), '/', dictGetOrDefault('%s', 'name', %s,'')), toString(%s)), '/$', '')`,
 			schema.ColumnProto, qc, schema.DictionaryTCP, qc, schema.ColumnProto, qc, schema.DictionaryUDP, qc, qc)
@@ -153,3 +171,31 @@ func (qc Column) ToSQLSelect(sch *schema.Component) string {
 	}
 	return strValue
 }
+
+// dscpClassNames maps DSCP code points to their standard class names. Other
+// code points are displayed numerically.
+var dscpClassNames = map[int]string{
+	0:  "CS0",
+	1:  "LE",
+	8:  "CS1",
+	10: "AF11",
+	12: "AF12",
+	14: "AF13",
+	16: "CS2",
+	18: "AF21",
+	20: "AF22",
+	22: "AF23",
+	24: "CS3",
+	26: "AF31",
+	28: "AF32",
+	30: "AF33",
+	32: "CS4",
+	34: "AF41",
+	36: "AF42",
+	38: "AF43",
+	40: "CS5",
+	44: "VA",
+	46: "EF",
+	48: "CS6",
+	56: "CS7",
+}
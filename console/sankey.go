@@ -110,6 +110,10 @@ func (c *Component) graphSankeyHandlerFunc(gc *gin.Context) {
 				c.config.DimensionsLimit)})
 		return
 	}
+	if err := c.checkTimeRange(input.Start, input.End); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
 
 	sqlQuery, err := input.toSQL()
 	if err != nil {
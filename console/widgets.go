@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"akvorado/common/helpers"
 	"akvorado/common/schema"
 )
 
@@ -97,27 +98,61 @@ func (c *Component) widgetFlowRateHandlerFunc(gc *gin.Context) {
 	})
 }
 
+// widgetExportersQuery describes the optional pagination of the list of
+// exporters. Exporters are sorted by name. After is the name of the last
+// returned exporter (the "next" value from the previous answer).
+type widgetExportersQuery struct {
+	Limit uint   `form:"limit" binding:"max=10000"`
+	After string `form:"after"`
+}
+
 func (c *Component) widgetExportersHandlerFunc(gc *gin.Context) {
 	ctx := c.t.Context(gc.Request.Context())
-	query := `SELECT ExporterName FROM exporters GROUP BY ExporterName ORDER BY ExporterName`
+	var input widgetExportersQuery
+	if err := gc.ShouldBindQuery(&input); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	where := ""
+	args := []interface{}{}
+	if input.After != "" {
+		where = "WHERE ExporterName > $1 "
+		args = append(args, input.After)
+	}
+	limit := ""
+	if input.Limit > 0 {
+		// Fetch one more to know if there are more results
+		limit = fmt.Sprintf(" LIMIT %d", input.Limit+1)
+	}
+	query := fmt.Sprintf(`SELECT ExporterName FROM exporters %sGROUP BY ExporterName ORDER BY ExporterName%s`,
+		where, limit)
 	gc.Header("X-SQL-Query", query)
 	// Do not increase counter for this one.
 
 	exporters := []struct {
 		ExporterName string
 	}{}
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &exporters, query)
+	err := c.d.ClickHouseDB.Conn.Select(ctx, &exporters, query, args...)
 	if err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
 		return
 	}
+	next := ""
+	if input.Limit > 0 && uint(len(exporters)) > input.Limit {
+		exporters = exporters[:input.Limit]
+		next = exporters[len(exporters)-1].ExporterName
+	}
 	exporterList := make([]string, len(exporters))
 	for idx, exporter := range exporters {
 		exporterList[idx] = exporter.ExporterName
 	}
 
-	gc.IndentedJSON(http.StatusOK, gin.H{"exporters": exporterList})
+	answer := gin.H{"exporters": exporterList}
+	if next != "" {
+		answer["next"] = next
+	}
+	gc.IndentedJSON(http.StatusOK, answer)
 }
 
 type topResult struct {
@@ -153,6 +153,58 @@ func TestWidgetExporters(t *testing.T) {
 	})
 }
 
+func TestWidgetExportersPagination(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(),
+				`SELECT ExporterName FROM exporters GROUP BY ExporterName ORDER BY ExporterName LIMIT 3`).
+			SetArg(1, []struct {
+				ExporterName string
+			}{
+				{"exporter1"},
+				{"exporter2"},
+				{"exporter3"},
+			}).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(),
+				`SELECT ExporterName FROM exporters WHERE ExporterName > $1 GROUP BY ExporterName ORDER BY ExporterName LIMIT 3`,
+				"exporter2").
+			SetArg(1, []struct {
+				ExporterName string
+			}{
+				{"exporter3"},
+			}).
+			Return(nil),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "first page",
+			URL:         "/api/v0/console/widget/exporters?limit=2",
+			JSONOutput: gin.H{
+				"exporters": []string{"exporter1", "exporter2"},
+				"next":      "exporter2",
+			},
+		}, {
+			Description: "last page",
+			URL:         "/api/v0/console/widget/exporters?limit=2&after=exporter2",
+			JSONOutput: gin.H{
+				"exporters": []string{"exporter3"},
+			},
+		}, {
+			Description: "limit too large",
+			URL:         "/api/v0/console/widget/exporters?limit=100000",
+			StatusCode:  400,
+			JSONOutput: gin.H{
+				"message": "Key: 'widgetExportersQuery.Limit' Error:Field validation for 'Limit' failed on the 'max' tag",
+			},
+		},
+	})
+}
+
 func TestWidgetTop(t *testing.T) {
 	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
 
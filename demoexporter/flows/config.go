@@ -13,13 +13,19 @@ type Configuration struct {
 	// SamplingRate defines the sampling rate for this device.
 	SamplingRate int `validate:"min=1"`
 	// Flows describe the flows we want to generate.
-	Flows []FlowConfiguration `validate:"min=1,dive"`
+	Flows []FlowConfiguration `validate:"required_without=ReplayFile,dive"`
 	// Target specify the IP address and port to generate flows to.
 	Target string `validate:"required,hostname_port"`
 	// Seed defines a seed to add to the random generator. Without
 	// one, all exporters will produce the same data if provided
 	// the same flows.
 	Seed int64
+	// ReplayFile is a PCAP file with NetFlow or IPFIX packets to replay in
+	// a loop instead of generating flows.
+	ReplayFile string
+	// ReplaySpeed is the speed factor to apply when replaying packets, 1
+	// meaning the original pace.
+	ReplaySpeed float64 `validate:"gt=0"`
 }
 
 // FlowConfiguration describes the configuration for a flow.
@@ -60,5 +66,6 @@ type FlowConfiguration struct {
 func DefaultConfiguration() Configuration {
 	return Configuration{
 		SamplingRate: 1000,
+		ReplaySpeed:  1,
 	}
 }
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flows
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"akvorado/common/reporter"
+)
+
+// replayPacket is an UDP payload to replay with its offset from the first
+// packet of the capture.
+type replayPacket struct {
+	offset  time.Duration
+	payload []byte
+}
+
+// readReplayFile extracts the UDP payloads from a PCAP file.
+func readReplayFile(path string) ([]replayPacket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %q: %w", path, err)
+	}
+	defer f.Close()
+	reader, err := pcapgo.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	source := gopacket.NewPacketSource(reader, reader.LinkType())
+	packets := []replayPacket{}
+	var first time.Time
+	for {
+		packet, err := source.NextPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+		}
+		udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok {
+			continue
+		}
+		timestamp := packet.Metadata().Timestamp
+		if len(packets) == 0 {
+			first = timestamp
+		}
+		packets = append(packets, replayPacket{
+			offset:  timestamp.Sub(first),
+			payload: udp.Payload,
+		})
+	}
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("no UDP packet in %q", path)
+	}
+	return packets, nil
+}
+
+// startReplay replays the packets from the configured PCAP file in a loop,
+// respecting the original pace modulated by the configured speed.
+func (c *Component) startReplay(conn net.Conn) error {
+	packets, err := readReplayFile(c.config.ReplayFile)
+	if err != nil {
+		return err
+	}
+	errLogger := c.r.Sample(reporter.BurstSampler(time.Minute, 10))
+
+	c.t.Go(func() error {
+		for {
+			start := c.d.Clock.Now()
+			for _, packet := range packets {
+				delay := time.Duration(float64(packet.offset)/c.config.ReplaySpeed) - c.d.Clock.Since(start)
+				if delay > 0 {
+					select {
+					case <-c.t.Dying():
+						return nil
+					case <-c.d.Clock.After(delay):
+					}
+				}
+				if _, err := conn.Write(packet.payload); err != nil {
+					c.metrics.errors.WithLabelValues(err.Error()).Inc()
+					errLogger.Err(err).Msg("unable to send UDP payload")
+				} else {
+					c.metrics.sent.WithLabelValues("replay").Inc()
+				}
+			}
+			// Pause before the next iteration
+			select {
+			case <-c.t.Dying():
+				return nil
+			case <-c.d.Clock.After(time.Second):
+			}
+		}
+	})
+	return nil
+}
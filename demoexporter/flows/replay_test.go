@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flows
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+// writeReplayFile writes a PCAP file with the provided UDP payloads, spaced
+// by the provided interval.
+func writeReplayFile(t *testing.T, interval time.Duration, payloads ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replay.pcap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error:\n%+v", err)
+	}
+	defer f.Close()
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("WriteFileHeader() error:\n%+v", err)
+	}
+	start := time.Date(2024, 3, 15, 9, 14, 12, 0, time.UTC)
+	for i, payload := range payloads {
+		eth := &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 1},
+			DstMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 2},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		ip := &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    net.ParseIP("192.0.2.1"),
+			DstIP:    net.ParseIP("192.0.2.2"),
+		}
+		udp := &layers.UDP{SrcPort: 12345, DstPort: 2055}
+		udp.SetNetworkLayerForChecksum(ip)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf,
+			gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+			eth, ip, udp, gopacket.Payload(payload)); err != nil {
+			t.Fatalf("SerializeLayers() error:\n%+v", err)
+		}
+		if err := w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     start.Add(time.Duration(i) * interval),
+			CaptureLength: len(buf.Bytes()),
+			Length:        len(buf.Bytes()),
+		}, buf.Bytes()); err != nil {
+			t.Fatalf("WritePacket() error:\n%+v", err)
+		}
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 0,
+	})
+	if err != nil {
+		t.Fatalf("ListenUDP() error:\n%+v", err)
+	}
+	defer receiver.Close()
+
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Target = receiver.LocalAddr().String()
+	config.ReplayFile = writeReplayFile(t, 500*time.Millisecond, "packet 1", "packet 2", "packet 3")
+	config.ReplaySpeed = 10
+	c, err := New(r, config, Dependencies{
+		Daemon: daemon.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	receiver.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	got := []string{}
+	for {
+		payload := make([]byte, 9000)
+		n, err := receiver.Read(payload)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			t.Fatalf("Read() error:\n%+v", err)
+		}
+		got = append(got, string(payload[:n]))
+	}
+	if diff := helpers.Diff(got, []string{"packet 1", "packet 2", "packet 3"}); diff != "" {
+		t.Fatalf("Read() (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_demoexporter_flows_", "sent_")
+	expectedMetrics := map[string]string{
+		`sent_packets_total{type="replay"}`: "3",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestReplayInvalidFile(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Target = "127.0.0.1:2055"
+	config.ReplayFile = filepath.Join(t.TempDir(), "missing.pcap")
+	// The file is only checked on start: the orchestrator validates the
+	// configuration on a different host.
+	if err := helpers.Validate.Struct(config); err != nil {
+		t.Fatalf("Validate() error:\n%+v", err)
+	}
+	c, err := New(r, config, Dependencies{
+		Daemon: daemon.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if err := c.Start(); err == nil {
+		t.Fatal("Start() did not error")
+	}
+}
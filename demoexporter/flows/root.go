@@ -73,6 +73,9 @@ func (c *Component) Start() error {
 	if err != nil {
 		return fmt.Errorf("cannot create socket to %q: %w", c.config.Target, err)
 	}
+	if c.config.ReplayFile != "" {
+		return c.startReplay(conn)
+	}
 
 	sequenceNumber := uint32(1)
 	start := c.d.Clock.Now()
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"akvorado/common/helpers/bimap"
+	"akvorado/common/schema"
+)
+
+// AnonymizationConfiguration describes how source and destination addresses
+// are anonymized before flows are exported.
+type AnonymizationConfiguration struct {
+	// Method is the anonymization method
+	Method AnonymizationMethod
+	// IPv4PrefixLength is the number of bits kept for IPv4 addresses when
+	// truncating
+	IPv4PrefixLength int `validate:"min=0,max=32"`
+	// IPv6PrefixLength is the number of bits kept for IPv6 addresses when
+	// truncating
+	IPv6PrefixLength int `validate:"min=0,max=128"`
+	// Passphrase is used to derive the key for prefix-preserving
+	// pseudonymization
+	Passphrase string
+}
+
+// DefaultAnonymizationConfiguration is the default configuration for
+// anonymization.
+func DefaultAnonymizationConfiguration() AnonymizationConfiguration {
+	return AnonymizationConfiguration{
+		Method:           AnonymizationNone,
+		IPv4PrefixLength: 24,
+		IPv6PrefixLength: 48,
+	}
+}
+
+// AnonymizationMethod describes how addresses are anonymized.
+type AnonymizationMethod int
+
+const (
+	// AnonymizationNone keeps addresses as is.
+	AnonymizationNone AnonymizationMethod = iota
+	// AnonymizationTruncate zeroes the host part of addresses.
+	AnonymizationTruncate
+	// AnonymizationCryptoPAn replaces addresses with prefix-preserving
+	// pseudonyms.
+	AnonymizationCryptoPAn
+)
+
+var anonymizationMethodMap = bimap.New(map[AnonymizationMethod]string{
+	AnonymizationNone:      "none",
+	AnonymizationTruncate:  "truncate",
+	AnonymizationCryptoPAn: "cryptopan",
+})
+
+// MarshalText turns an anonymization method to text.
+func (am AnonymizationMethod) MarshalText() ([]byte, error) {
+	got, ok := anonymizationMethodMap.LoadValue(am)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns an anonymization method to string.
+func (am AnonymizationMethod) String() string {
+	got, _ := anonymizationMethodMap.LoadValue(am)
+	return got
+}
+
+// UnmarshalText provides an anonymization method from a string.
+func (am *AnonymizationMethod) UnmarshalText(input []byte) error {
+	got, ok := anonymizationMethodMap.LoadKey(string(input))
+	if ok {
+		*am = got
+		return nil
+	}
+	return errors.New("unknown anonymization method")
+}
+
+// anonymizer transforms an address into an anonymized one.
+type anonymizer interface {
+	anonymize(netip.Addr) netip.Addr
+}
+
+// newAnonymizer returns the anonymizer matching the configuration. It returns
+// nil when anonymization is disabled.
+func newAnonymizer(config AnonymizationConfiguration) (anonymizer, error) {
+	switch config.Method {
+	case AnonymizationNone:
+		return nil, nil
+	case AnonymizationTruncate:
+		return truncateAnonymizer{
+			ipv4Bits: config.IPv4PrefixLength,
+			ipv6Bits: config.IPv6PrefixLength,
+		}, nil
+	case AnonymizationCryptoPAn:
+		if config.Passphrase == "" {
+			return nil, errors.New("a passphrase is required for cryptopan anonymization")
+		}
+		return newCryptoPAnAnonymizer(sha256.Sum256([]byte(config.Passphrase)))
+	}
+	return nil, errors.New("unknown anonymization method")
+}
+
+// checkAnonymizationSchema checks the schema is compatible with the
+// anonymization method. ClickHouse derives some columns from the source and
+// destination addresses (network attributes, geolocation). With Crypto-PAn,
+// the pseudonyms do not belong to the original networks and these columns
+// would be silently wrong. They have to be disabled. AS numbers are an
+// exception: the lookup is only a fallback when the inlet does not know the AS
+// number.
+func checkAnonymizationSchema(config AnonymizationConfiguration, sch *schema.Component) error {
+	if config.Method != AnonymizationCryptoPAn {
+		return nil
+	}
+	incompatible := []string{}
+	for _, column := range sch.Columns() {
+		if column.Key == schema.ColumnSrcAS || column.Key == schema.ColumnDstAS {
+			continue
+		}
+		if strings.Contains(column.ClickHouseGenerateFrom, "c_SrcNetworks[") ||
+			strings.Contains(column.ClickHouseGenerateFrom, "c_DstNetworks[") {
+			incompatible = append(incompatible, column.Name)
+		}
+	}
+	if len(incompatible) > 0 {
+		return fmt.Errorf("cryptopan anonymization requires to disable the following columns: %s",
+			strings.Join(incompatible, ", "))
+	}
+	return nil
+}
+
+// truncateAnonymizer keeps only the prefix of addresses.
+type truncateAnonymizer struct {
+	ipv4Bits int
+	ipv6Bits int
+}
+
+func (ta truncateAnonymizer) anonymize(addr netip.Addr) netip.Addr {
+	if addr.Is4In6() {
+		prefix, _ := addr.Unmap().Prefix(ta.ipv4Bits)
+		return netip.AddrFrom16(prefix.Addr().As16())
+	}
+	if addr.Is4() {
+		prefix, _ := addr.Prefix(ta.ipv4Bits)
+		return prefix.Addr()
+	}
+	prefix, _ := addr.Prefix(ta.ipv6Bits)
+	return prefix.Addr()
+}
+
+// cryptoPAnAnonymizer implements the prefix-preserving anonymization scheme
+// from Xu et al. (Crypto-PAn): two addresses sharing a prefix of n bits are
+// mapped to two addresses sharing a prefix of n bits as well.
+type cryptoPAnAnonymizer struct {
+	block cipher.Block
+	pad   [16]byte
+}
+
+// newCryptoPAnAnonymizer creates a new Crypto-PAn anonymizer. The first half
+// of the key is the AES key, the second half is used to build the pad.
+func newCryptoPAnAnonymizer(key [32]byte) (*cryptoPAnAnonymizer, error) {
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	ca := cryptoPAnAnonymizer{block: block}
+	block.Encrypt(ca.pad[:], key[16:])
+	return &ca, nil
+}
+
+func (ca *cryptoPAnAnonymizer) anonymize(addr netip.Addr) netip.Addr {
+	if addr.Is4In6() {
+		ip := addr.Unmap().As4()
+		ca.anonymizeBytes(ip[:])
+		return netip.AddrFrom16(netip.AddrFrom4(ip).As16())
+	}
+	if addr.Is4() {
+		ip := addr.As4()
+		ca.anonymizeBytes(ip[:])
+		return netip.AddrFrom4(ip)
+	}
+	ip := addr.As16()
+	ca.anonymizeBytes(ip[:])
+	return netip.AddrFrom16(ip)
+}
+
+// anonymizeBytes anonymizes the provided address in place. Each bit is
+// flipped depending on the first bit of the encryption of the preceding bits,
+// completed with the pad.
+func (ca *cryptoPAnAnonymizer) anonymizeBytes(ip []byte) {
+	var input, output [16]byte
+	flips := make([]byte, len(ip))
+	for i := 0; i < len(ip)*8; i++ {
+		input = ca.pad
+		copy(input[:i/8], ip[:i/8])
+		if bits := i % 8; bits != 0 {
+			mask := byte(0xff) << (8 - bits)
+			input[i/8] = ip[i/8]&mask | ca.pad[i/8]&^mask
+		}
+		ca.block.Encrypt(output[:], input[:])
+		flips[i/8] |= (output[0] >> 7) << (7 - i%8)
+	}
+	for i := range ip {
+		ip[i] ^= flips[i]
+	}
+}
+
+// anonymizeFlow anonymizes the source and destination addresses of the flow.
+func (c *Component) anonymizeFlow(flow *schema.FlowMessage) {
+	if flow.SrcAddr.IsValid() {
+		flow.SrcAddr = c.anonymizer.anonymize(flow.SrcAddr)
+	}
+	if flow.DstAddr.IsValid() {
+		flow.DstAddr = c.anonymizer.anonymize(flow.DstAddr)
+	}
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/helpers/yaml"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestTruncateAnonymizer(t *testing.T) {
+	ta := truncateAnonymizer{ipv4Bits: 24, ipv6Bits: 48}
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{"::ffff:192.0.2.142", "::ffff:192.0.2.0"},
+		{"192.0.2.142", "192.0.2.0"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::"},
+	}
+	for _, tc := range cases {
+		got := ta.anonymize(netip.MustParseAddr(tc.Input))
+		if diff := helpers.Diff(got, netip.MustParseAddr(tc.Expected)); diff != "" {
+			t.Errorf("anonymize(%q) (-got, +want):\n%s", tc.Input, diff)
+		}
+	}
+}
+
+func TestCryptoPAnAnonymizer(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "boojahyoo3vaeToong0Eijee7Ahz3yee")
+	ca, err := newCryptoPAnAnonymizer(key)
+	if err != nil {
+		t.Fatalf("newCryptoPAnAnonymizer() error:\n%+v", err)
+	}
+
+	// Pseudonyms should not change across versions
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{"128.11.68.132", "128.115.63.68"},
+		{"129.118.74.4", "129.121.74.3"},
+		{"192.102.249.13", "206.102.25.115"},
+		{"::ffff:128.11.68.132", "::ffff:128.115.63.68"},
+	}
+	for _, tc := range cases {
+		got := ca.anonymize(netip.MustParseAddr(tc.Input))
+		if diff := helpers.Diff(got, netip.MustParseAddr(tc.Expected)); diff != "" {
+			t.Errorf("anonymize(%q) (-got, +want):\n%s", tc.Input, diff)
+		}
+	}
+
+	// Common prefixes are preserved
+	commonPrefix := func(addr1, addr2 netip.Addr) int {
+		a1, a2 := addr1.As16(), addr2.As16()
+		for i := 0; i < 128; i++ {
+			if (a1[i/8]>>(7-i%8))&1 != (a2[i/8]>>(7-i%8))&1 {
+				return i
+			}
+		}
+		return 128
+	}
+	pairs := [][2]string{
+		{"141.223.7.43", "141.233.145.108"},
+		{"192.0.2.1", "192.0.2.254"},
+		{"192.0.2.1", "198.51.100.1"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234:ffff::1"},
+		{"2001:db8::1", "2001:db8::2"},
+	}
+	for _, pair := range pairs {
+		addr1, addr2 := netip.MustParseAddr(pair[0]), netip.MustParseAddr(pair[1])
+		got1, got2 := ca.anonymize(addr1), ca.anonymize(addr2)
+		if got1 == addr1 || got2 == addr2 {
+			t.Errorf("anonymize(%s, %s) did not change addresses", addr1, addr2)
+		}
+		if got, expected := commonPrefix(got1, got2), commonPrefix(addr1, addr2); got != expected {
+			t.Errorf("anonymize(%s, %s) common prefix == %d but expected %d", addr1, addr2, got, expected)
+		}
+	}
+}
+
+func TestAnonymizeFlow(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.Anonymization.Method = AnonymizationTruncate
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	flow := &schema.FlowMessage{
+		SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.142"),
+		DstAddr:         netip.MustParseAddr("2001:db8:1234:5678::1"),
+		ExporterAddress: netip.MustParseAddr("::ffff:203.0.113.14"),
+	}
+	c.anonymizeFlow(flow)
+	expected := &schema.FlowMessage{
+		SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.0"),
+		DstAddr:         netip.MustParseAddr("2001:db8:1234::"),
+		ExporterAddress: netip.MustParseAddr("::ffff:203.0.113.14"),
+	}
+	if diff := helpers.Diff(flow, expected); diff != "" {
+		t.Fatalf("anonymizeFlow() (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_core_", "anonymization_")
+	expectedMetrics := map[string]string{
+		`anonymization_enabled{method="truncate"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestAnonymizationConfiguration(t *testing.T) {
+	configuration := DefaultConfiguration()
+	configuration.Anonymization.Method = AnonymizationCryptoPAn
+	_, err := New(reporter.NewMock(t), configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error without a passphrase")
+	}
+
+	configuration.Anonymization.Passphrase = "secret passphrase"
+	_, err = New(reporter.NewMock(t), configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error with columns derived from addresses")
+	}
+	if !strings.Contains(err.Error(), "SrcNetName") || !strings.Contains(err.Error(), "DstCountry") {
+		t.Fatalf("New() error does not list incompatible columns:\n%+v", err)
+	}
+	schemaConfiguration := schema.DefaultConfiguration()
+	for _, column := range []schema.ColumnKey{
+		schema.ColumnSrcNetName, schema.ColumnDstNetName,
+		schema.ColumnSrcNetRole, schema.ColumnDstNetRole,
+		schema.ColumnSrcNetSite, schema.ColumnDstNetSite,
+		schema.ColumnSrcNetRegion, schema.ColumnDstNetRegion,
+		schema.ColumnSrcNetTenant, schema.ColumnDstNetTenant,
+		schema.ColumnSrcCountry, schema.ColumnDstCountry,
+		schema.ColumnSrcGeoCity, schema.ColumnDstGeoCity,
+		schema.ColumnSrcGeoState, schema.ColumnDstGeoState,
+	} {
+		schemaConfiguration.Disabled = append(schemaConfiguration.Disabled, column)
+	}
+	sch, err := schema.New(schemaConfiguration)
+	if err != nil {
+		t.Fatalf("schema.New() error:\n%+v", err)
+	}
+	if _, err := New(reporter.NewMock(t), configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: sch,
+	}); err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	out, err := yaml.MarshalWithoutSecrets(configuration)
+	if err != nil {
+		t.Fatalf("MarshalWithoutSecrets() error:\n%+v", err)
+	}
+	if strings.Contains(string(out), "secret passphrase") {
+		t.Fatalf("MarshalWithoutSecrets() did not mask the passphrase:\n%s", out)
+	}
+	if !strings.Contains(string(out), "method: cryptopan") {
+		t.Fatalf("MarshalWithoutSecrets() did not include the anonymization method:\n%s", out)
+	}
+}
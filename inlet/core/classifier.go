@@ -5,7 +5,9 @@ package core
 
 import (
 	"fmt"
+	"net/netip"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
@@ -22,6 +24,12 @@ var (
 	regexCache     = make(map[string]*regexp.Regexp)
 )
 
+// Global cache for subnets. No boundary.
+var (
+	subnetCacheLock sync.RWMutex
+	subnetCache     = make(map[string]netip.Prefix)
+)
+
 // ExporterClassifierRule defines a classification rule for a exporter.
 type ExporterClassifierRule struct {
 	program *vm.Program
@@ -249,6 +257,131 @@ func (scr InterfaceClassifierRule) MarshalText() ([]byte, error) {
 	return []byte(scr.String()), nil
 }
 
+// FlowClassifierRule defines a tagging rule for a flow.
+type FlowClassifierRule struct {
+	program *vm.Program
+}
+
+// flowInfo contains the information we want to expose about a flow.
+type flowInfo struct {
+	Exporter exporterInfo
+	InIf     interfaceInfo
+	OutIf    interfaceInfo
+	SrcAddr  string
+	DstAddr  string
+	SrcAS    uint32
+	DstAS    uint32
+	SrcPort  uint16
+	DstPort  uint16
+	Proto    uint8
+}
+
+// flowClassifierEnvironment defines the environment used by the flow classifier
+type flowClassifierEnvironment struct {
+	Format   func(string, ...any) string
+	Exporter exporterInfo
+	InIf     interfaceInfo
+	OutIf    interfaceInfo
+	SrcAddr  string
+	DstAddr  string
+	SrcAS    uint32
+	DstAS    uint32
+	SrcPort  uint16
+	DstPort  uint16
+	Proto    uint8
+	InSubnet func(string, string) (bool, error)
+	Tag      classifyStringFunc
+	TagRegex classifyStringRegexFunc
+}
+
+// exec executes the flow classifier with the provided flow. Tags are appended
+// to the provided slice. It returns true if the rule has tagged the flow.
+func (scr *FlowClassifierRule) exec(fi flowInfo, tags *[]string) (bool, error) {
+	matched := false
+	tag := func(input string) bool {
+		matched = true
+		if tag := normalize(input); tag != "" && !slices.Contains(*tags, tag) {
+			*tags = append(*tags, tag)
+		}
+		return true
+	}
+	env := flowClassifierEnvironment{
+		Format:   format,
+		Exporter: fi.Exporter,
+		InIf:     fi.InIf,
+		OutIf:    fi.OutIf,
+		SrcAddr:  fi.SrcAddr,
+		DstAddr:  fi.DstAddr,
+		SrcAS:    fi.SrcAS,
+		DstAS:    fi.DstAS,
+		SrcPort:  fi.SrcPort,
+		DstPort:  fi.DstPort,
+		Proto:    fi.Proto,
+		InSubnet: inSubnet,
+		Tag:      tag,
+		TagRegex: withRegex(tag),
+	}
+	if _, err := expr.Run(scr.program, env); err != nil {
+		return false, fmt.Errorf("unable to execute classifier %q: %w", scr, err)
+	}
+	return matched, nil
+}
+
+// UnmarshalText compiles a tagging rule for a flow.
+func (scr *FlowClassifierRule) UnmarshalText(text []byte) error {
+	regexValidator := regexValidator{}
+	subnetValidator := subnetValidator{}
+	program, err := expr.Compile(string(text),
+		expr.Env(flowClassifierEnvironment{}),
+		expr.AsBool(),
+		expr.Patch(&regexValidator),
+		expr.Patch(&subnetValidator))
+	if err != nil {
+		return fmt.Errorf("cannot compile flow classifier rule %q: %w", string(text), err)
+	}
+	if len(regexValidator.invalidRegexes) > 0 {
+		return fmt.Errorf("invalid regular expression %q", regexValidator.invalidRegexes[0])
+	}
+	if len(subnetValidator.invalidSubnets) > 0 {
+		return fmt.Errorf("invalid subnet %q", subnetValidator.invalidSubnets[0])
+	}
+	scr.program = program
+	return nil
+}
+
+// String turns a flow classifier rule into a string
+func (scr FlowClassifierRule) String() string {
+	return scr.program.Source().String()
+}
+
+// MarshalText turns a flow classifier rule into a string
+func (scr FlowClassifierRule) MarshalText() ([]byte, error) {
+	return []byte(scr.String()), nil
+}
+
+// inSubnet tells if the provided IP address is part of the provided subnet.
+func inSubnet(addr string, subnet string) (bool, error) {
+	subnetCacheLock.RLock()
+	prefix, ok := subnetCache[subnet]
+	subnetCacheLock.RUnlock()
+	if !ok {
+		var err error
+		prefix, err = netip.ParsePrefix(subnet)
+		if err != nil {
+			return false, fmt.Errorf("cannot parse subnet %q: %w", subnet, err)
+		}
+		prefix = prefix.Masked()
+		subnetCacheLock.Lock()
+		subnetCache[subnet] = prefix
+		subnetCacheLock.Unlock()
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false, nil
+	}
+	return prefix.Contains(ip.Unmap()), nil
+}
+
 // withRegex turns a function taking a string into a function taking a
 // string to match a regex with, a regex and a template to be expanded
 // with the result of the regex.
@@ -321,3 +454,28 @@ func (r *regexValidator) Visit(node *ast.Node) {
 		r.invalidRegexes = append(r.invalidRegexes, str.Value)
 	}
 }
+
+type subnetValidator struct {
+	invalidSubnets []string
+}
+
+func (r *subnetValidator) Visit(node *ast.Node) {
+	n, ok := (*node).(*ast.CallNode)
+	if !ok {
+		return
+	}
+	identifier, ok := n.Callee.(*ast.IdentifierNode)
+	if !ok {
+		return
+	}
+	if identifier.Value != "InSubnet" || len(n.Arguments) != 2 {
+		return
+	}
+	str, ok := n.Arguments[1].(*ast.StringNode)
+	if !ok {
+		return
+	}
+	if _, err := netip.ParsePrefix(str.Value); err != nil {
+		r.invalidSubnets = append(r.invalidSubnets, str.Value)
+	}
+}
@@ -6,7 +6,9 @@ package core
 import (
 	"testing"
 
+	"akvorado/common/daemon"
 	"akvorado/common/helpers"
+	"akvorado/common/reporter"
 	"akvorado/common/schema"
 )
 
@@ -257,6 +259,124 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")
 	}
 }
 
+func TestFlowClassifier(t *testing.T) {
+	cases := []struct {
+		Description     string
+		Program         string
+		FlowInfo        flowInfo
+		ExpectedTags    []string
+		ExpectedMatched bool
+		ExpectedErr     bool
+	}{
+		{
+			Description:  "trivial classifier",
+			Program:      "false",
+			ExpectedTags: []string{},
+		}, {
+			Description:     "constant classifier",
+			Program:         `Tag("Internal")`,
+			ExpectedTags:    []string{"internal"},
+			ExpectedMatched: true,
+		}, {
+			Description:     "several tags",
+			Program:         `Tag("transit") && Tag("cogent") && Tag("transit")`,
+			ExpectedTags:    []string{"transit", "cogent"},
+			ExpectedMatched: true,
+		}, {
+			Description:     "match on AS",
+			Program:         `SrcAS == 65000 && DstAS in [174, 1299] && Tag("transit")`,
+			FlowInfo:        flowInfo{SrcAS: 65000, DstAS: 1299},
+			ExpectedTags:    []string{"transit"},
+			ExpectedMatched: true,
+		}, {
+			Description:  "no match on AS",
+			Program:      `SrcAS == 65000 && Tag("transit")`,
+			FlowInfo:     flowInfo{SrcAS: 65001},
+			ExpectedTags: []string{},
+		}, {
+			Description:     "match on exporter and interface",
+			Program:         `Exporter.IP == "192.0.2.1" && InIf.Index == 10 && Tag(Format("customer-%s", InIf.Description))`,
+			FlowInfo:        flowInfo{Exporter: exporterInfo{IP: "192.0.2.1"}, InIf: interfaceInfo{Index: 10, Description: "Acme"}},
+			ExpectedTags:    []string{"customer-acme"},
+			ExpectedMatched: true,
+		}, {
+			Description:     "match on IPv4 subnet",
+			Program:         `InSubnet(SrcAddr, "10.0.0.0/8") && Tag("internal")`,
+			FlowInfo:        flowInfo{SrcAddr: "10.1.2.3"},
+			ExpectedTags:    []string{"internal"},
+			ExpectedMatched: true,
+		}, {
+			Description:     "match on IPv6 subnet",
+			Program:         `InSubnet(DstAddr, "2001:db8::/32") && Tag("internal")`,
+			FlowInfo:        flowInfo{DstAddr: "2001:db8::1"},
+			ExpectedTags:    []string{"internal"},
+			ExpectedMatched: true,
+		}, {
+			Description:  "no match on subnet",
+			Program:      `InSubnet(SrcAddr, "10.0.0.0/8") && Tag("internal")`,
+			FlowInfo:     flowInfo{SrcAddr: "192.0.2.1"},
+			ExpectedTags: []string{},
+		}, {
+			Description:     "regex",
+			Program:         `TagRegex(OutIf.Description, "^Peering: ([^ ]+)", "peering-$1")`,
+			FlowInfo:        flowInfo{OutIf: interfaceInfo{Description: "Peering: Google"}},
+			ExpectedTags:    []string{"peering-google"},
+			ExpectedMatched: true,
+		}, {
+			Description: "invalid subnet",
+			Program:     `InSubnet(SrcAddr, "10.0.0.0/33") && Tag("internal")`,
+			ExpectedErr: true,
+		}, {
+			Description: "incorrect typing",
+			Program:     `Tag(1)`,
+			ExpectedErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var scr FlowClassifierRule
+			err := scr.UnmarshalText([]byte(tc.Program))
+			if !tc.ExpectedErr && err != nil {
+				t.Fatalf("UnmarshalText(%q) error:\n%+v", tc.Program, err)
+			}
+			if tc.ExpectedErr && err != nil {
+				return
+			}
+			tags := []string{}
+			matched, err := scr.exec(tc.FlowInfo, &tags)
+			if !tc.ExpectedErr && err != nil {
+				t.Fatalf("exec(%q) error:\n%+v", tc.Program, err)
+			}
+			if tc.ExpectedErr && err == nil {
+				t.Fatalf("exec(%q) no error", tc.Program)
+			}
+			if matched != tc.ExpectedMatched {
+				t.Errorf("exec(%q) matched %v, expected %v", tc.Program, matched, tc.ExpectedMatched)
+			}
+			if diff := helpers.Diff(tags, tc.ExpectedTags); diff != "" {
+				t.Fatalf("exec(%q) (-got, +want):\n%s", tc.Program, diff)
+			}
+		})
+	}
+}
+
+func TestFlowClassifierWithoutTags(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	var rule FlowClassifierRule
+	if err := rule.UnmarshalText([]byte(`Tag("internal")`)); err != nil {
+		t.Fatalf("UnmarshalText() error:\n%+v", err)
+	}
+	configuration.FlowClassifiers = []FlowClassifierRule{rule}
+	_, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
+
 func TestRegexValidation(t *testing.T) {
 	cases := []struct {
 		Classifier string
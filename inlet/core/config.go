@@ -12,6 +12,7 @@ import (
 
 	"akvorado/common/helpers"
 	"akvorado/common/helpers/bimap"
+	"akvorado/common/schema"
 
 	"github.com/mitchellh/mapstructure"
 )
@@ -24,16 +25,52 @@ type Configuration struct {
 	ExporterClassifiers []ExporterClassifierRule
 	// InterfaceClassifiers defines rules for interface classification
 	InterfaceClassifiers []InterfaceClassifierRule
+	// FlowClassifiers defines rules to tag flows
+	FlowClassifiers []FlowClassifierRule
+	// FlowClassifiersMode tells if all flow classifiers are evaluated or if
+	// evaluation stops at the first matching one
+	FlowClassifiersMode FlowClassifiersMode
+	// FlowFilters defines rules to drop flows
+	FlowFilters FlowFiltersConfiguration
+	// Deduplication defines how to drop flows reported by several exporters
+	Deduplication DeduplicationConfiguration
+	// Anonymization defines how to anonymize addresses before export
+	Anonymization AnonymizationConfiguration
+	// CustomersFile is a CSV file mapping prefixes to customers, used to set
+	// the SrcCustomer and DstCustomer columns
+	CustomersFile string
 	// ClassifierCacheDuration defines the default TTL for classifier cache
 	ClassifierCacheDuration time.Duration `validate:"min=1s"`
+	// ExporterIdleTimeout defines how long an exporter is kept in the
+	// inventory after its last flow
+	ExporterIdleTimeout time.Duration `validate:"min=1s"`
 	// DefaultSamplingRate defines the default sampling rate to use when the information is missing
 	DefaultSamplingRate helpers.SubnetMap[uint]
 	// OverrideSamplingRate defines a sampling rate to use instead of the received on
 	OverrideSamplingRate helpers.SubnetMap[uint]
+	// SamplingRateUnit defines how the sampling rate reported by exporters
+	// should be interpreted
+	SamplingRateUnit helpers.SubnetMap[SamplingRateUnit]
+	// LearnSamplingRate enables learning the sampling rate of each exporter to
+	// use it when the information is missing
+	LearnSamplingRate bool
 	// ASNProviders defines the source used to get AS numbers
 	ASNProviders []ASNProvider `validate:"dive"`
 	// NetProviders defines the source used to get Prefix/Network Information
 	NetProviders []NetProvider `validate:"dive"`
+	// InterfaceProviders defines the source used to get interface names and descriptions
+	InterfaceProviders []InterfaceProvider `validate:"min=1,dive"`
+	// DropAddressFamilyMismatch drops flows whose source and destination
+	// addresses are not of the same family
+	DropAddressFamilyMismatch bool
+	// HTTPFlowsMaskedColumns lists the columns to mask when flows are
+	// exposed through the HTTP endpoint
+	HTTPFlowsMaskedColumns []schema.ColumnKey
+	// DryRun processes flows without sending them to Kafka
+	DryRun bool
+	// DryRunLoggedFlows is the maximum number of flows logged every minute
+	// in dry-run mode
+	DryRunLoggedFlows uint
 	// Old configuration settings
 	classifierCacheSize uint
 }
@@ -44,9 +81,18 @@ func DefaultConfiguration() Configuration {
 		Workers:                 1,
 		ExporterClassifiers:     []ExporterClassifierRule{},
 		InterfaceClassifiers:    []InterfaceClassifierRule{},
+		FlowClassifiers:         []FlowClassifierRule{},
 		ClassifierCacheDuration: 5 * time.Minute,
+		ExporterIdleTimeout:     10 * time.Minute,
 		ASNProviders:            []ASNProvider{ASNProviderFlow, ASNProviderRouting},
 		NetProviders:            []NetProvider{NetProviderFlow, NetProviderRouting},
+		InterfaceProviders:      []InterfaceProvider{InterfaceProviderMetadata},
+		FlowFilters: FlowFiltersConfiguration{
+			Include: []FlowFilterRule{},
+			Exclude: []FlowFilterRule{},
+		},
+		Deduplication: DefaultDeduplicationConfiguration(),
+		Anonymization: DefaultAnonymizationConfiguration(),
 	}
 }
 
@@ -55,6 +101,12 @@ type (
 	ASNProvider int
 	// NetProvider describes one network mask provider.
 	NetProvider int
+	// InterfaceProvider describes one interface name provider.
+	InterfaceProvider int
+	// SamplingRateUnit describes how to interpret a sampling rate.
+	SamplingRateUnit int
+	// FlowClassifiersMode describes how flow classifiers are evaluated.
+	FlowClassifiersMode int
 )
 
 const (
@@ -146,6 +198,121 @@ func (np *NetProvider) UnmarshalText(input []byte) error {
 	return errors.New("unknown provider")
 }
 
+const (
+	// InterfaceProviderFlow uses the interface name and description embedded in flows, if any
+	InterfaceProviderFlow InterfaceProvider = iota
+	// InterfaceProviderMetadata uses the metadata component
+	InterfaceProviderMetadata
+)
+
+var interfaceProviderMap = bimap.New(map[InterfaceProvider]string{
+	InterfaceProviderFlow:     "flow",
+	InterfaceProviderMetadata: "metadata",
+})
+
+// MarshalText turns an interface provider to text.
+func (ip InterfaceProvider) MarshalText() ([]byte, error) {
+	got, ok := interfaceProviderMap.LoadValue(ip)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns an interface provider to string.
+func (ip InterfaceProvider) String() string {
+	got, _ := interfaceProviderMap.LoadValue(ip)
+	return got
+}
+
+// UnmarshalText provides an interface provider from a string.
+func (ip *InterfaceProvider) UnmarshalText(input []byte) error {
+	got, ok := interfaceProviderMap.LoadKey(string(input))
+	if ok {
+		*ip = got
+		return nil
+	}
+	return errors.New("unknown provider")
+}
+
+const (
+	// SamplingRateOneInN means a sampling rate of N is one packet sampled
+	// out of N packets. Counters are multiplied by N.
+	SamplingRateOneInN SamplingRateUnit = iota
+	// SamplingRatePreScaled means counters are already multiplied by the
+	// sampling rate by the exporter. They should not be multiplied again.
+	SamplingRatePreScaled
+)
+
+var samplingRateUnitMap = bimap.New(map[SamplingRateUnit]string{
+	SamplingRateOneInN:    "one-in-n",
+	SamplingRatePreScaled: "pre-scaled",
+})
+
+// MarshalText turns a sampling rate unit to text.
+func (su SamplingRateUnit) MarshalText() ([]byte, error) {
+	got, ok := samplingRateUnitMap.LoadValue(su)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns a sampling rate unit to string.
+func (su SamplingRateUnit) String() string {
+	got, _ := samplingRateUnitMap.LoadValue(su)
+	return got
+}
+
+// UnmarshalText provides a sampling rate unit from a string.
+func (su *SamplingRateUnit) UnmarshalText(input []byte) error {
+	got, ok := samplingRateUnitMap.LoadKey(string(input))
+	if ok {
+		*su = got
+		return nil
+	}
+	return errors.New("unknown sampling rate unit")
+}
+
+const (
+	// FlowClassifiersAccumulate evaluates all flow classifiers and
+	// accumulates their tags.
+	FlowClassifiersAccumulate FlowClassifiersMode = iota
+	// FlowClassifiersFirstMatch stops evaluating flow classifiers after the
+	// first one tagging the flow.
+	FlowClassifiersFirstMatch
+)
+
+var flowClassifiersModeMap = bimap.New(map[FlowClassifiersMode]string{
+	FlowClassifiersAccumulate: "accumulate",
+	FlowClassifiersFirstMatch: "first-match",
+})
+
+// MarshalText turns a flow classifiers mode to text.
+func (fm FlowClassifiersMode) MarshalText() ([]byte, error) {
+	got, ok := flowClassifiersModeMap.LoadValue(fm)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown field")
+}
+
+// String turns a flow classifiers mode to string.
+func (fm FlowClassifiersMode) String() string {
+	got, _ := flowClassifiersModeMap.LoadValue(fm)
+	return got
+}
+
+// UnmarshalText provides a flow classifiers mode from a string.
+func (fm *FlowClassifiersMode) UnmarshalText(input []byte) error {
+	got, ok := flowClassifiersModeMap.LoadKey(string(input))
+	if ok {
+		*fm = got
+		return nil
+	}
+	return errors.New("unknown flow classifiers mode")
+}
+
 // ConfigurationUnmarshallerHook normalize core configuration:
 //   - replace ignore-asn-from-flow by asn-providers
 func ConfigurationUnmarshallerHook() mapstructure.DecodeHookFunc {
@@ -187,4 +354,5 @@ func ConfigurationUnmarshallerHook() mapstructure.DecodeHookFunc {
 func init() {
 	helpers.RegisterMapstructureUnmarshallerHook(ConfigurationUnmarshallerHook())
 	helpers.RegisterMapstructureUnmarshallerHook(helpers.SubnetMapUnmarshallerHook[uint]())
+	helpers.RegisterMapstructureUnmarshallerHook(helpers.SubnetMapUnmarshallerHook[SamplingRateUnit]())
 }
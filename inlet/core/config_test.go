@@ -5,8 +5,10 @@ package core
 
 import (
 	"testing"
+	"time"
 
 	"akvorado/common/helpers"
+	"akvorado/common/schema"
 
 	"github.com/gin-gonic/gin"
 )
@@ -112,6 +114,46 @@ func TestConfigurationUnmarshallerHook(t *testing.T) {
 				NetProviders: []NetProvider{NetProviderFlow, NetProviderRouting},
 			},
 			SkipValidation: true,
+		}, {
+			Description: "deduplication",
+			Initial:     func() interface{} { return Configuration{} },
+			Configuration: func() interface{} {
+				return gin.H{
+					"deduplication": gin.H{
+						"window":    "2s",
+						"columns":   []string{"SrcAddr", "DstAddr"},
+						"max-flows": 1000,
+					},
+				}
+			},
+			Expected: Configuration{
+				Deduplication: DeduplicationConfiguration{
+					Window:   2 * time.Second,
+					Columns:  []schema.ColumnKey{schema.ColumnSrcAddr, schema.ColumnDstAddr},
+					MaxFlows: 1000,
+				},
+			},
+			SkipValidation: true,
+		}, {
+			Description: "anonymization",
+			Initial:     func() interface{} { return Configuration{} },
+			Configuration: func() interface{} {
+				return gin.H{
+					"anonymization": gin.H{
+						"method":             "cryptopan",
+						"ipv4-prefix-length": 16,
+						"passphrase":         "secret",
+					},
+				}
+			},
+			Expected: Configuration{
+				Anonymization: AnonymizationConfiguration{
+					Method:           AnonymizationCryptoPAn,
+					IPv4PrefixLength: 16,
+					Passphrase:       "secret",
+				},
+			},
+			SkipValidation: true,
 		},
 	})
 }
@@ -119,4 +161,46 @@ func TestConfigurationUnmarshallerHook(t *testing.T) {
 func TestMarshalUnmarshal(t *testing.T) {
 	asnProviderMap.TestMarshalUnmarshal(t)
 	netProviderMap.TestMarshalUnmarshal(t)
+	flowClassifiersModeMap.TestMarshalUnmarshal(t)
+	anonymizationMethodMap.TestMarshalUnmarshal(t)
+}
+
+func TestValidate(t *testing.T) {
+	withConfig := func(update func(*Configuration)) Configuration {
+		config := DefaultConfiguration()
+		update(&config)
+		return config
+	}
+	cases := []struct {
+		Description   string
+		Configuration Configuration
+		Error         string
+	}{
+		{"default", DefaultConfiguration(), ""},
+		{"dry run", withConfig(func(c *Configuration) {
+			c.DryRun = true
+			c.DryRunLoggedFlows = 10
+		}), ""},
+		{"logged flows without dry run", withConfig(func(c *Configuration) {
+			c.DryRunLoggedFlows = 10
+		}), "dry-run-logged-flows: only used when dry-run is enabled"},
+		{"duplicate providers", withConfig(func(c *Configuration) {
+			c.ASNProviders = []ASNProvider{ASNProviderFlow, ASNProviderRouting, ASNProviderFlow}
+			c.InterfaceProviders = []InterfaceProvider{InterfaceProviderMetadata, InterfaceProviderMetadata}
+		}), `asn-providers[2]: "flow" already used by asn-providers[0]
+interface-providers[1]: "metadata" already used by interface-providers[0]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c := Component{config: tc.Configuration}
+			err := c.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Error); diff != "" {
+				t.Fatalf("Validate() (-got, +want):\n%s", diff)
+			}
+		})
+	}
 }
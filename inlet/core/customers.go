@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+// checkCustomersSchema checks the schema is able to store customers when a
+// customers file is configured.
+func checkCustomersSchema(path string, sch *schema.Component) error {
+	if path == "" {
+		return nil
+	}
+	for _, key := range []schema.ColumnKey{schema.ColumnSrcCustomer, schema.ColumnDstCustomer} {
+		if column, _ := sch.LookupColumnByKey(key); !column.Disabled {
+			return nil
+		}
+	}
+	return errors.New("customers-file requires to enable SrcCustomer or DstCustomer columns")
+}
+
+// loadCustomers reads a CSV file mapping prefixes to customers. The first line
+// is a header which should contain the "prefix" and "customer" columns. Other
+// columns are ignored. The longest matching prefix wins. It also returns the
+// number of prefixes read.
+func loadCustomers(path string) (*helpers.SubnetMap[string], int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot read header: %w", err)
+	}
+	prefixIdx, customerIdx := -1, -1
+	for idx, name := range header {
+		switch strings.TrimSpace(name) {
+		case "prefix":
+			prefixIdx = idx
+		case "customer":
+			customerIdx = idx
+		}
+	}
+	if prefixIdx == -1 || customerIdx == -1 {
+		return nil, 0, errors.New("header should contain prefix and customer columns")
+	}
+
+	customers, _ := helpers.NewSubnetMap[string](nil)
+	names := map[string]string{} // share identical customer names
+	prefixes := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) <= max(prefixIdx, customerIdx) {
+			return nil, 0, fmt.Errorf("line %d: missing columns", line)
+		}
+		customer := strings.TrimSpace(record[customerIdx])
+		if name, ok := names[customer]; ok {
+			customer = name
+		} else {
+			names[customer] = customer
+		}
+		prefix, err := helpers.SubnetMapParseKey(strings.TrimSpace(record[prefixIdx]))
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := customers.Set(prefix, customer); err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		prefixes++
+	}
+	return customers, prefixes, nil
+}
+
+// reloadCustomers loads the customers file and replaces the current customers
+// when successful.
+func (c *Component) reloadCustomers() error {
+	start := time.Now()
+	customers, prefixes, err := loadCustomers(c.config.CustomersFile)
+	if err != nil {
+		c.metrics.customersErrors.Inc()
+		return fmt.Errorf("cannot load customers from %q: %w", c.config.CustomersFile, err)
+	}
+	c.customers.Store(customers)
+	c.metrics.customersPrefixes.Set(float64(prefixes))
+	c.r.Info().
+		Str("path", c.config.CustomersFile).
+		Int("prefixes", prefixes).
+		Dur("duration", time.Since(start)).
+		Msg("customers loaded")
+	return nil
+}
+
+// watchCustomers loads the customers file and reloads it when it changes.
+func (c *Component) watchCustomers() error {
+	if err := c.reloadCustomers(); err != nil {
+		c.r.Err(err).Msg("cannot load customers")
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		c.r.Err(err).Msg("cannot setup watcher for customers file")
+		return fmt.Errorf("cannot setup watcher: %w", err)
+	}
+	path := filepath.Clean(c.config.CustomersFile)
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		c.r.Err(err).Msg("cannot watch customers file directory")
+		return fmt.Errorf("cannot watch customers file directory: %w", err)
+	}
+	c.t.Go(func() error {
+		errLogger := c.r.Sample(reporter.BurstSampler(10*time.Second, 1))
+		defer watcher.Close()
+		for {
+			select {
+			case <-c.t.Dying():
+				return nil
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return errors.New("file watcher died")
+				}
+				errLogger.Err(err).Msg("error from watcher")
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return errors.New("file watcher died")
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+				if filepath.Clean(event.Name) != path {
+					continue
+				}
+				if err := c.reloadCustomers(); err != nil {
+					errLogger.Err(err).Msg("cannot reload customers, keep previous ones")
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// enrichCustomers sets the source and destination customers of a flow.
+func (c *Component) enrichCustomers(flow *schema.FlowMessage) {
+	customers := c.customers.Load()
+	if customers == nil {
+		return
+	}
+	if customer, ok := customers.Lookup(flow.SrcAddr); ok {
+		c.d.Schema.ProtobufAppendBytes(flow, schema.ColumnSrcCustomer, []byte(customer))
+	}
+	if customer, ok := customers.Lookup(flow.DstAddr); ok {
+		c.d.Schema.ProtobufAppendBytes(flow, schema.ColumnDstCustomer, []byte(customer))
+	}
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow"
+)
+
+func TestLoadCustomers(t *testing.T) {
+	cases := []struct {
+		Description string
+		Content     string
+		Prefixes    int
+		Lookups     map[string]string
+		ExpectedErr bool
+	}{
+		{
+			Description: "IPv4 and IPv6 prefixes",
+			Content: `prefix,customer,service
+192.0.2.0/24,customer1,transit
+192.0.2.128/25,customer2,transit
+2001:db8:1::/48,customer3,hosting
+203.0.113.4,customer4,hosting
+`,
+			Prefixes: 4,
+			Lookups: map[string]string{
+				"::ffff:192.0.2.1":   "customer1",
+				"::ffff:192.0.2.129": "customer2",
+				"2001:db8:1::1":      "customer3",
+				"::ffff:203.0.113.4": "customer4",
+				"::ffff:203.0.113.5": "",
+				"2001:db8:2::1":      "",
+			},
+		}, {
+			Description: "columns in another order",
+			Content: `service,customer,prefix
+transit,customer1,192.0.2.0/24
+`,
+			Prefixes: 1,
+			Lookups: map[string]string{
+				"::ffff:192.0.2.1": "customer1",
+			},
+		}, {
+			Description: "missing customer column",
+			Content: `prefix,service
+192.0.2.0/24,transit
+`,
+			ExpectedErr: true,
+		}, {
+			Description: "invalid prefix",
+			Content: `prefix,customer
+192.0.2.0/24,customer1
+192.0.2.0/33,customer2
+`,
+			ExpectedErr: true,
+		}, {
+			Description: "missing columns",
+			Content: `prefix,service,customer
+192.0.2.0/24,transit
+`,
+			ExpectedErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "customers.csv")
+			if err := os.WriteFile(path, []byte(tc.Content), 0o644); err != nil {
+				t.Fatalf("WriteFile() error:\n%+v", err)
+			}
+			customers, prefixes, err := loadCustomers(path)
+			if tc.ExpectedErr {
+				if err == nil {
+					t.Fatal("loadCustomers() did not error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadCustomers() error:\n%+v", err)
+			}
+			if prefixes != tc.Prefixes {
+				t.Errorf("loadCustomers() prefixes: got %d, expected %d", prefixes, tc.Prefixes)
+			}
+			got := map[string]string{}
+			for addr := range tc.Lookups {
+				got[addr], _ = customers.Lookup(netip.MustParseAddr(addr))
+			}
+			if diff := helpers.Diff(got, tc.Lookups); diff != "" {
+				t.Errorf("Lookup() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCustomers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "customers.csv")
+	if err := os.WriteFile(path, []byte("prefix,customer\n192.0.2.0/24,customer1\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.CustomersFile = path
+	sch, err := schema.New(schema.Configuration{
+		Enabled: []schema.ColumnKey{schema.ColumnSrcCustomer, schema.ColumnDstCustomer},
+	})
+	if err != nil {
+		t.Fatalf("schema.New() error:\n%+v", err)
+	}
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flow.NewMock(t, r, flow.DefaultConfiguration()),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: sch,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	fl := &schema.FlowMessage{
+		SrcAddr: netip.MustParseAddr("::ffff:192.0.2.10"),
+		DstAddr: netip.MustParseAddr("::ffff:198.51.100.10"),
+	}
+	c.enrichCustomers(fl)
+	expected := map[schema.ColumnKey]interface{}{
+		schema.ColumnSrcCustomer: []byte("customer1"),
+	}
+	if diff := helpers.Diff(fl.ProtobufDebug, expected); diff != "" {
+		t.Fatalf("enrichCustomers() (-got, +want):\n%s", diff)
+	}
+
+	// Replace the file
+	tmpPath := filepath.Join(dir, "customers.tmp")
+	if err := os.WriteFile(tmpPath, []byte("prefix,customer\n192.0.2.0/24,customer1\n198.51.100.0/24,customer2\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		t.Fatalf("Rename() error:\n%+v", err)
+	}
+	for range 50 {
+		if gotMetrics := r.GetMetrics("akvorado_inlet_core_", "customers_prefixes"); gotMetrics["customers_prefixes"] == "2" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fl = &schema.FlowMessage{
+		SrcAddr: netip.MustParseAddr("::ffff:192.0.2.10"),
+		DstAddr: netip.MustParseAddr("::ffff:198.51.100.10"),
+	}
+	c.enrichCustomers(fl)
+	expected = map[schema.ColumnKey]interface{}{
+		schema.ColumnSrcCustomer: []byte("customer1"),
+		schema.ColumnDstCustomer: []byte("customer2"),
+	}
+	if diff := helpers.Diff(fl.ProtobufDebug, expected); diff != "" {
+		t.Fatalf("enrichCustomers() after reload (-got, +want):\n%s", diff)
+	}
+
+	// An invalid file is ignored
+	if err := os.WriteFile(path, []byte("prefix,customer\nnot a prefix,customer3\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	gotMetrics := r.GetMetrics("akvorado_inlet_core_", "customers_")
+	if gotMetrics["customers_prefixes"] != "2" || gotMetrics["customers_errors_total"] == "0" {
+		t.Fatalf("Metrics after invalid file: %v", gotMetrics)
+	}
+}
+
+func TestCustomersDisabledColumns(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.CustomersFile = "/nonexistent/customers.csv"
+	_, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flow.NewMock(t, r, flow.DefaultConfiguration()),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
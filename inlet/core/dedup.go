@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"container/list"
+	"encoding/binary"
+	"net/netip"
+	"sync"
+	"time"
+
+	"akvorado/common/schema"
+)
+
+// DeduplicationConfiguration describes how flows reported by several exporters
+// are deduplicated.
+type DeduplicationConfiguration struct {
+	// Window is the duration during which a flow with the same key reported
+	// by another exporter is considered as a duplicate. 0 disables
+	// deduplication.
+	Window time.Duration `validate:"min=0"`
+	// Columns is the list of columns used to identify a flow
+	Columns []schema.ColumnKey `validate:"min=1"`
+	// MaxFlows is the maximum number of flows to remember
+	MaxFlows int `validate:"min=1"`
+}
+
+// DefaultDeduplicationConfiguration is the default configuration for
+// deduplication.
+func DefaultDeduplicationConfiguration() DeduplicationConfiguration {
+	return DeduplicationConfiguration{
+		Window: 0,
+		Columns: []schema.ColumnKey{
+			schema.ColumnSrcAddr,
+			schema.ColumnDstAddr,
+			schema.ColumnProto,
+			schema.ColumnSrcPort,
+			schema.ColumnDstPort,
+		},
+		MaxFlows: 100_000,
+	}
+}
+
+// deduplicationColumns are the columns that can be used to identify a flow
+// for deduplication. The associated function appends the value of the column
+// to the provided key.
+var deduplicationColumns = map[schema.ColumnKey]func(*schema.Component, *schema.FlowMessage, []byte) []byte{
+	schema.ColumnSrcAddr: func(_ *schema.Component, f *schema.FlowMessage, key []byte) []byte {
+		addr := f.SrcAddr.As16()
+		return append(key, addr[:]...)
+	},
+	schema.ColumnDstAddr: func(_ *schema.Component, f *schema.FlowMessage, key []byte) []byte {
+		addr := f.DstAddr.As16()
+		return append(key, addr[:]...)
+	},
+	schema.ColumnSrcAS: func(_ *schema.Component, f *schema.FlowMessage, key []byte) []byte {
+		return binary.AppendUvarint(key, uint64(f.SrcAS))
+	},
+	schema.ColumnDstAS: func(_ *schema.Component, f *schema.FlowMessage, key []byte) []byte {
+		return binary.AppendUvarint(key, uint64(f.DstAS))
+	},
+	schema.ColumnEType:   deduplicationVarintColumn(schema.ColumnEType),
+	schema.ColumnProto:   deduplicationVarintColumn(schema.ColumnProto),
+	schema.ColumnSrcPort: deduplicationVarintColumn(schema.ColumnSrcPort),
+	schema.ColumnDstPort: deduplicationVarintColumn(schema.ColumnDstPort),
+}
+
+func deduplicationVarintColumn(column schema.ColumnKey) func(*schema.Component, *schema.FlowMessage, []byte) []byte {
+	return func(sch *schema.Component, f *schema.FlowMessage, key []byte) []byte {
+		value, _ := sch.ProtobufLookupVarint(f, column)
+		return binary.AppendUvarint(key, value)
+	}
+}
+
+// deduplicator remembers the last seen flows, up to a maximum number of flows.
+// The least recently seen flows are forgotten first.
+type deduplicator struct {
+	lock     sync.Mutex
+	window   time.Duration
+	maxFlows int
+	entries  map[string]*list.Element
+	order    *list.List // most recently seen first
+}
+
+type deduplicatorEntry struct {
+	key      string
+	exporter netip.Addr
+	seen     time.Time
+}
+
+func newDeduplicator(window time.Duration, maxFlows int) *deduplicator {
+	return &deduplicator{
+		window:   window,
+		maxFlows: maxFlows,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// duplicate tells if a flow with the same key was reported by another exporter
+// during the window. Otherwise, the flow is remembered.
+func (d *deduplicator) duplicate(t time.Time, key string, exporter netip.Addr) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if element, ok := d.entries[key]; ok {
+		d.order.MoveToFront(element)
+		entry := element.Value.(*deduplicatorEntry)
+		if t.Sub(entry.seen) < d.window {
+			return entry.exporter != exporter
+		}
+		entry.exporter = exporter
+		entry.seen = t
+		return false
+	}
+	d.entries[key] = d.order.PushFront(&deduplicatorEntry{
+		key:      key,
+		exporter: exporter,
+		seen:     t,
+	})
+	if d.order.Len() > d.maxFlows {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(*deduplicatorEntry).key)
+	}
+	return false
+}
+
+// size returns the number of remembered flows.
+func (d *deduplicator) size() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.order.Len()
+}
+
+// usesAS tells if deduplication needs AS numbers.
+func (config DeduplicationConfiguration) usesAS() bool {
+	for _, column := range config.Columns {
+		if column == schema.ColumnSrcAS || column == schema.ColumnDstAS {
+			return true
+		}
+	}
+	return false
+}
+
+// deduplicateFlow tells if the flow is a duplicate of a flow reported by
+// another exporter.
+func (c *Component) deduplicateFlow(t time.Time, exporterStr string, flow *schema.FlowMessage) bool {
+	key := make([]byte, 0, 64)
+	for _, column := range c.config.Deduplication.Columns {
+		key = deduplicationColumns[column](c.d.Schema, flow, key)
+	}
+	if c.deduplicator.duplicate(t, string(key), flow.ExporterAddress) {
+		c.metrics.flowsDeduplicated.WithLabelValues(exporterStr).Inc()
+		return true
+	}
+	return false
+}
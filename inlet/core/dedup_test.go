@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := newDeduplicator(time.Second, 2)
+	exporter1 := netip.MustParseAddr("::ffff:192.0.2.1")
+	exporter2 := netip.MustParseAddr("::ffff:192.0.2.2")
+	now := time.Now()
+
+	cases := []struct {
+		Description string
+		Time        time.Time
+		Key         string
+		Exporter    netip.Addr
+		Duplicate   bool
+	}{
+		{"first occurrence", now, "flow1", exporter1, false},
+		{"same exporter", now.Add(100 * time.Millisecond), "flow1", exporter1, false},
+		{"other exporter", now.Add(200 * time.Millisecond), "flow1", exporter2, true},
+		{"other exporter after window", now.Add(1200 * time.Millisecond), "flow1", exporter2, false},
+		{"first exporter after window", now.Add(1300 * time.Millisecond), "flow1", exporter1, true},
+		{"other flow", now.Add(1300 * time.Millisecond), "flow2", exporter1, false},
+		{"evicting oldest flow", now.Add(1300 * time.Millisecond), "flow3", exporter1, false},
+		{"evicted flow", now.Add(1400 * time.Millisecond), "flow1", exporter1, false},
+	}
+	for _, tc := range cases {
+		if got := d.duplicate(tc.Time, tc.Key, tc.Exporter); got != tc.Duplicate {
+			t.Errorf("duplicate(%s) == %v but expected %v", tc.Description, got, tc.Duplicate)
+		}
+	}
+	if got := d.size(); got != 2 {
+		t.Errorf("size() == %d but expected 2", got)
+	}
+}
+
+func TestDeduplicateFlow(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.Deduplication.Window = time.Second
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	newFlow := func(exporter string, dstPort uint64) *schema.FlowMessage {
+		flow := &schema.FlowMessage{
+			ExporterAddress: netip.MustParseAddr(exporter),
+			SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.1"),
+			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.1"),
+		}
+		c.d.Schema.ProtobufAppendVarint(flow, schema.ColumnProto, 6)
+		c.d.Schema.ProtobufAppendVarint(flow, schema.ColumnSrcPort, 34567)
+		c.d.Schema.ProtobufAppendVarint(flow, schema.ColumnDstPort, dstPort)
+		return flow
+	}
+	now := time.Now()
+	if c.deduplicateFlow(now, "192.0.2.1", newFlow("::ffff:192.0.2.1", 443)) {
+		t.Error("deduplicateFlow() should keep the first flow")
+	}
+	if !c.deduplicateFlow(now, "192.0.2.2", newFlow("::ffff:192.0.2.2", 443)) {
+		t.Error("deduplicateFlow() should drop the same flow from another exporter")
+	}
+	if c.deduplicateFlow(now, "192.0.2.2", newFlow("::ffff:192.0.2.2", 80)) {
+		t.Error("deduplicateFlow() should keep a different flow from another exporter")
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_core_", "deduplicat")
+	expectedMetrics := map[string]string{
+		`deduplicated_flows_total{exporter="192.0.2.2"}`: "1",
+		`deduplication_cache_size_items`:                 "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDeduplicationInvalidColumn(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.Deduplication.Window = time.Second
+	configuration.Deduplication.Columns = []schema.ColumnKey{schema.ColumnExporterName}
+	_, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
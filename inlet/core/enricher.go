@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"akvorado/common/schema"
+	"akvorado/inlet/kafka"
+	"akvorado/inlet/metadata/provider"
 )
 
 // exporterAndInterfaceInfo aggregates both exporter info and interface info
@@ -18,30 +20,33 @@ type exporterAndInterfaceInfo struct {
 	Interface interfaceInfo
 }
 
-// enrichFlow adds more data to a flow.
-func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *schema.FlowMessage) (skip bool) {
+// enrichFlow adds more data to a flow. It also returns the exporter
+// information and the tags used to select the Kafka topic.
+func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *schema.FlowMessage) (route kafka.Route, skip bool) {
 	var flowExporterName string
 	var flowInIfName, flowInIfDescription, flowOutIfName, flowOutIfDescription string
 	var flowInIfSpeed, flowOutIfSpeed, flowInIfIndex, flowOutIfIndex uint32
 	var flowInIfVlan, flowOutIfVlan uint16
 
-	t := time.Now() // only call it once
+	t := c.d.Clock.Now() // only call it once
 	expClassification := exporterClassification{}
 	inIfClassification := interfaceClassification{}
 	outIfClassification := interfaceClassification{}
 
 	if flow.InIf != 0 {
-		answer, ok := c.d.Metadata.Lookup(t, exporterIP, uint(flow.InIf))
+		answer, ok := c.lookupInterface(t, exporterIP, flow.InIf, flow.InIfName, flow.InIfDescription)
 		if !ok {
 			c.metrics.flowsErrors.WithLabelValues(exporterStr, "SNMP cache miss").Inc()
 			skip = true
 		} else {
-			flowExporterName = answer.Exporter.Name
-			expClassification.Region = answer.Exporter.Region
-			expClassification.Role = answer.Exporter.Role
-			expClassification.Tenant = answer.Exporter.Tenant
-			expClassification.Site = answer.Exporter.Site
-			expClassification.Group = answer.Exporter.Group
+			if answer.Exporter.Name != "" {
+				flowExporterName = answer.Exporter.Name
+				expClassification.Region = answer.Exporter.Region
+				expClassification.Role = answer.Exporter.Role
+				expClassification.Tenant = answer.Exporter.Tenant
+				expClassification.Site = answer.Exporter.Site
+				expClassification.Group = answer.Exporter.Group
+			}
 			flowInIfIndex = flow.InIf
 			flowInIfName = answer.Interface.Name
 			flowInIfDescription = answer.Interface.Description
@@ -54,7 +59,7 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 	}
 
 	if flow.OutIf != 0 {
-		answer, ok := c.d.Metadata.Lookup(t, exporterIP, uint(flow.OutIf))
+		answer, ok := c.lookupInterface(t, exporterIP, flow.OutIf, flow.OutIfName, flow.OutIfDescription)
 		if !ok {
 			// Only register a cache miss if we don't have one.
 			// TODO: maybe we could do one SNMP query for both interfaces.
@@ -63,12 +68,14 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 				skip = true
 			}
 		} else {
-			flowExporterName = answer.Exporter.Name
-			expClassification.Region = answer.Exporter.Region
-			expClassification.Role = answer.Exporter.Role
-			expClassification.Tenant = answer.Exporter.Tenant
-			expClassification.Site = answer.Exporter.Site
-			expClassification.Group = answer.Exporter.Group
+			if answer.Exporter.Name != "" {
+				flowExporterName = answer.Exporter.Name
+				expClassification.Region = answer.Exporter.Region
+				expClassification.Role = answer.Exporter.Role
+				expClassification.Tenant = answer.Exporter.Tenant
+				expClassification.Site = answer.Exporter.Site
+				expClassification.Group = answer.Exporter.Group
+			}
 			flowOutIfIndex = flow.OutIf
 			flowOutIfName = answer.Interface.Name
 			flowOutIfDescription = answer.Interface.Description
@@ -80,30 +87,59 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 		}
 	}
 
+	// When interfaces are described by the flow, we may not know the exporter name.
+	if flowExporterName == "" {
+		flowExporterName = exporterStr
+	}
+
 	// We need at least one of them.
 	if flow.OutIf == 0 && flow.InIf == 0 {
 		c.metrics.flowsErrors.WithLabelValues(exporterStr, "input and output interfaces missing").Inc()
 		skip = true
 	}
 
-	if samplingRate, ok := c.config.OverrideSamplingRate.Lookup(exporterIP); ok && samplingRate > 0 {
+	// Source and destination should be of the same family
+	if flow.SrcAddr.IsValid() && flow.DstAddr.IsValid() &&
+		flow.SrcAddr.Is4In6() != flow.DstAddr.Is4In6() {
+		c.metrics.flowsErrors.WithLabelValues(exporterStr, "address family mismatch").Inc()
+		if c.config.DropAddressFamilyMismatch {
+			skip = true
+		}
+	}
+
+	samplingConfig := c.samplingConfig.Load()
+	samplingRateSource := "reported"
+	if samplingRate, ok := samplingConfig.OverrideSamplingRate.Lookup(exporterIP); ok && samplingRate > 0 {
 		flow.SamplingRate = uint32(samplingRate)
+		samplingRateSource = "override"
+	} else if flow.SamplingRate > 0 {
+		if unit, _ := samplingConfig.SamplingRateUnit.Lookup(exporterIP); unit == SamplingRatePreScaled {
+			// Counters already take the sampling rate into account
+			flow.SamplingRate = 1
+		}
+		c.learnSamplingRate(exporterStr, flow.SamplingRate)
 	}
 	if flow.SamplingRate == 0 {
-		if samplingRate, ok := c.config.DefaultSamplingRate.Lookup(exporterIP); ok && samplingRate > 0 {
+		if samplingRate, ok := samplingConfig.DefaultSamplingRate.Lookup(exporterIP); ok && samplingRate > 0 {
 			flow.SamplingRate = uint32(samplingRate)
+			samplingRateSource = "default"
+		} else if samplingRate, ok := c.learnedSamplingRate(exporterStr); ok {
+			flow.SamplingRate = samplingRate
+			samplingRateSource = "learned"
 		} else {
 			c.metrics.flowsErrors.WithLabelValues(exporterStr, "sampling rate missing").Inc()
+			samplingRateSource = "missing"
 			skip = true
 		}
 	}
+	c.metrics.samplingRateSource.WithLabelValues(exporterStr, samplingRateSource).Inc()
 
 	if skip {
 		return
 	}
 
 	// Classification
-	if !c.classifyExporter(t, exporterStr, flowExporterName, flow, expClassification) ||
+	if !c.classifyExporter(t, exporterStr, flowExporterName, flow, &expClassification) ||
 		!c.classifyInterface(t, exporterStr, flowExporterName, flow,
 			flowOutIfIndex, flowOutIfName, flowOutIfDescription, flowOutIfSpeed, flowOutIfVlan, outIfClassification,
 			false) ||
@@ -111,7 +147,54 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 			flowInIfIndex, flowInIfName, flowInIfDescription, flowInIfSpeed, flowInIfVlan, inIfClassification,
 			true) {
 		// Flow is rejected
-		return true
+		return route, true
+	}
+	route.Exporter = kafka.Exporter{
+		IP:     exporterStr,
+		Name:   flowExporterName,
+		Group:  expClassification.Group,
+		Role:   expClassification.Role,
+		Site:   expClassification.Site,
+		Region: expClassification.Region,
+		Tenant: expClassification.Tenant,
+	}
+
+	var fi flowInfo
+	filters := len(c.config.FlowFilters.Include) > 0 || len(c.config.FlowFilters.Exclude) > 0
+	if filters || len(c.config.FlowClassifiers) > 0 {
+		srcPort, _ := c.d.Schema.ProtobufLookupVarint(flow, schema.ColumnSrcPort)
+		dstPort, _ := c.d.Schema.ProtobufLookupVarint(flow, schema.ColumnDstPort)
+		proto, _ := c.d.Schema.ProtobufLookupVarint(flow, schema.ColumnProto)
+		fi = flowInfo{
+			Exporter: exporterInfo{IP: exporterStr, Name: flowExporterName},
+			InIf: interfaceInfo{
+				Index:       flowInIfIndex,
+				Name:        flowInIfName,
+				Description: flowInIfDescription,
+				Speed:       flowInIfSpeed,
+				VLAN:        flowInIfVlan,
+			},
+			OutIf: interfaceInfo{
+				Index:       flowOutIfIndex,
+				Name:        flowOutIfName,
+				Description: flowOutIfDescription,
+				Speed:       flowOutIfSpeed,
+				VLAN:        flowOutIfVlan,
+			},
+			SrcAddr: flow.SrcAddr.Unmap().String(),
+			DstAddr: flow.DstAddr.Unmap().String(),
+			SrcPort: uint16(srcPort),
+			DstPort: uint16(dstPort),
+			Proto:   uint8(proto),
+		}
+	}
+
+	// Drop flows as early as possible to avoid useless routing lookups.
+	if filters && c.earlyFilters && !c.filterFlow(flowExporterName, fi) {
+		return route, true
+	}
+	if c.deduplicator != nil && c.earlyDeduplication && c.deduplicateFlow(t, exporterStr, flow) {
+		return route, true
 	}
 
 	ctx := c.t.Context(context.Background())
@@ -148,10 +231,43 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 	c.d.Schema.ProtobufAppendBytes(flow, schema.ColumnExporterName, []byte(flowExporterName))
 	c.d.Schema.ProtobufAppendVarint(flow, schema.ColumnInIfSpeed, uint64(flowInIfSpeed))
 	c.d.Schema.ProtobufAppendVarint(flow, schema.ColumnOutIfSpeed, uint64(flowOutIfSpeed))
+	c.enrichCustomers(flow)
+
+	fi.SrcAS = flow.SrcAS
+	fi.DstAS = flow.DstAS
+	if filters && !c.earlyFilters && !c.filterFlow(flowExporterName, fi) {
+		return route, true
+	}
+	if c.deduplicator != nil && !c.earlyDeduplication && c.deduplicateFlow(t, exporterStr, flow) {
+		return route, true
+	}
+	if len(c.config.FlowClassifiers) > 0 {
+		route.Tags = c.classifyFlow(flowExporterName, flow, fi)
+	}
 
 	return
 }
 
+// lookupInterface retrieves the metadata for an interface, depending on user preferences.
+func (c *Component) lookupInterface(t time.Time, exporterIP netip.Addr, ifIndex uint32, flowName, flowDescription string) (provider.Answer, bool) {
+	for _, p := range c.config.InterfaceProviders {
+		switch p {
+		case InterfaceProviderFlow:
+			if flowName != "" {
+				return provider.Answer{
+					Interface: provider.Interface{
+						Name:        flowName,
+						Description: flowDescription,
+					},
+				}, true
+			}
+		case InterfaceProviderMetadata:
+			return c.d.Metadata.Lookup(t, exporterIP, uint(ifIndex))
+		}
+	}
+	return provider.Answer{}, false
+}
+
 // getASNumber retrieves the AS number for a flow, depending on user preferences.
 func (c *Component) getASNumber(flowAS, bmpAS uint32) (asn uint32) {
 	for _, provider := range c.config.ASNProviders {
@@ -222,21 +338,22 @@ func (c *Component) writeExporter(flow *schema.FlowMessage, classification expor
 	return true
 }
 
-func (c *Component) classifyExporter(t time.Time, ip string, name string, flow *schema.FlowMessage, classification exporterClassification) bool {
+func (c *Component) classifyExporter(t time.Time, ip string, name string, flow *schema.FlowMessage, classification *exporterClassification) bool {
 	// we already have the info provided by the metadata component
-	if (classification != exporterClassification{}) {
-		return c.writeExporter(flow, classification)
+	if (*classification != exporterClassification{}) {
+		return c.writeExporter(flow, *classification)
 	}
 	if len(c.config.ExporterClassifiers) == 0 {
 		return true
 	}
 	si := exporterInfo{IP: ip, Name: name}
-	if classification, ok := c.classifierExporterCache.Get(t, si); ok {
-		return c.writeExporter(flow, classification)
+	if cached, ok := c.classifierExporterCache.Get(t, si); ok {
+		*classification = cached
+		return c.writeExporter(flow, cached)
 	}
 
 	for idx, rule := range c.config.ExporterClassifiers {
-		if err := rule.exec(si, &classification); err != nil {
+		if err := rule.exec(si, classification); err != nil {
 			c.classifierErrLogger.Err(err).
 				Str("type", "exporter").
 				Int("index", idx).
@@ -250,8 +367,8 @@ func (c *Component) classifyExporter(t time.Time, ip string, name string, flow *
 		}
 		break
 	}
-	c.classifierExporterCache.Put(t, si, classification)
-	return c.writeExporter(flow, classification)
+	c.classifierExporterCache.Put(t, si, *classification)
+	return c.writeExporter(flow, *classification)
 }
 
 func (c *Component) writeInterface(flow *schema.FlowMessage, classification interfaceClassification, directionIn bool) bool {
@@ -345,6 +462,35 @@ func (c *Component) classifyInterface(
 	return c.writeInterface(fl, classification, directionIn)
 }
 
+// classifyFlow executes the flow classifiers and appends the tags to the flow.
+// It returns the tags.
+func (c *Component) classifyFlow(exporterName string, flow *schema.FlowMessage, fi flowInfo) []string {
+	tags := []string{}
+	for idx, rule := range c.config.FlowClassifiers {
+		matched, err := rule.exec(fi, &tags)
+		if err != nil {
+			c.classifierErrLogger.Err(err).
+				Str("type", "flow").
+				Int("index", idx).
+				Str("exporter", exporterName).
+				Msg("error executing classifier")
+			c.metrics.classifierErrors.WithLabelValues("flow", strconv.Itoa(idx)).Inc()
+			continue
+		}
+		if !matched {
+			continue
+		}
+		c.metrics.classifierFlowMatches.WithLabelValues(strconv.Itoa(idx)).Inc()
+		if c.config.FlowClassifiersMode == FlowClassifiersFirstMatch {
+			break
+		}
+	}
+	for _, tag := range tags {
+		c.d.Schema.ProtobufAppendBytes(flow, schema.ColumnTags, []byte(tag))
+	}
+	return tags
+}
+
 func isPrivateAS(as uint32) bool {
 	// See https://www.iana.org/assignments/iana-as-numbers-special-registry/iana-as-numbers-special-registry.xhtml
 	if as == 0 || as == 23456 {
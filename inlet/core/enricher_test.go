@@ -26,10 +26,12 @@ import (
 
 func TestEnrich(t *testing.T) {
 	cases := []struct {
-		Name          string
-		Configuration gin.H
-		InputFlow     func() *schema.FlowMessage
-		OutputFlow    *schema.FlowMessage
+		Name            string
+		Configuration   gin.H
+		Schema          schema.Configuration
+		InputFlow       func() *schema.FlowMessage
+		OutputFlow      *schema.FlowMessage
+		ExpectedMetrics map[string]string
 	}{
 		{
 			Name:          "no rule",
@@ -83,6 +85,33 @@ func TestEnrich(t *testing.T) {
 					schema.ColumnOutIfSpeed:       1000,
 				},
 			},
+		}, {
+			Name: "no rule, pre-scaled sampling rate",
+			Configuration: gin.H{"samplingrateunit": gin.H{
+				"192.0.2.0/24":   "one-in-n",
+				"192.0.2.128/25": "pre-scaled",
+			}},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        1000,
+					schema.ColumnOutIfSpeed:       1000,
+				},
+			},
 		}, {
 			Name:          "no rule, no sampling rate, default is one value",
 			Configuration: gin.H{"defaultsamplingrate": 500},
@@ -531,6 +560,248 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 					schema.ColumnDstNetMask:                    27,
 				},
 			},
+		}, {
+			Name: "flow classifiers, accumulate",
+			Configuration: gin.H{
+				"flowclassifiers": []string{
+					`InSubnet(SrcAddr, "192.0.2.128/25") && Tag("internal")`,
+					`DstAS == 174 && Tag("transit") && Tag("cogent")`,
+					`InIf.Index == 100 && Tag("Transit")`,
+					`SrcAS == 65000 && Tag("customer")`,
+				},
+			},
+			Schema: schema.Configuration{Enabled: []schema.ColumnKey{schema.ColumnTags}},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.142"),
+					DstAddr:         netip.MustParseAddr("::ffff:192.0.2.10"),
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.142"),
+				DstAddr:         netip.MustParseAddr("::ffff:192.0.2.10"),
+				SrcAS:           1299,
+				DstAS:           174,
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:                  "192_0_2_142",
+					schema.ColumnInIfName:                      "Gi0/0/100",
+					schema.ColumnOutIfName:                     "Gi0/0/200",
+					schema.ColumnInIfDescription:               "Interface 100",
+					schema.ColumnOutIfDescription:              "Interface 200",
+					schema.ColumnInIfSpeed:                     1000,
+					schema.ColumnOutIfSpeed:                    1000,
+					schema.ColumnDstASPath:                     []uint32{64200, 1299, 174},
+					schema.ColumnDstCommunities:                []uint32{100, 200, 400},
+					schema.ColumnDstLargeCommunitiesASN:        []int32{64200},
+					schema.ColumnDstLargeCommunitiesLocalData1: []int32{2},
+					schema.ColumnDstLargeCommunitiesLocalData2: []int32{3},
+					schema.ColumnSrcNetMask:                    27,
+					schema.ColumnDstNetMask:                    27,
+					schema.ColumnTags:                          []string{"internal", "transit", "cogent"},
+				},
+			},
+			ExpectedMetrics: map[string]string{
+				`classifier_flow_matches_total{index="0"}`: "1",
+				`classifier_flow_matches_total{index="1"}`: "1",
+				`classifier_flow_matches_total{index="2"}`: "1",
+				`classifier_flow_matches_total{index="3"}`: "0",
+			},
+		}, {
+			Name: "flow classifiers, first match",
+			Configuration: gin.H{
+				"flowclassifiers": []string{
+					`InSubnet(DstAddr, "2001:db8::/32") && Tag("internal")`,
+					`OutIf.Name == "Gi0/0/200" && Tag("peering")`,
+					`Tag("other")`,
+				},
+				"flowclassifiersmode": "first-match",
+			},
+			Schema: schema.Configuration{Enabled: []schema.ColumnKey{schema.ColumnTags}},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        1000,
+					schema.ColumnOutIfSpeed:       1000,
+					schema.ColumnTags:             []string{"peering"},
+				},
+			},
+			ExpectedMetrics: map[string]string{
+				`classifier_flow_matches_total{index="0"}`: "0",
+				`classifier_flow_matches_total{index="1"}`: "1",
+				`classifier_flow_matches_total{index="2"}`: "0",
+			},
+		}, {
+			Name: "flow classifiers, error",
+			Configuration: gin.H{
+				"flowclassifiers": []string{
+					`InIf.Index % (OutIf.Index - OutIf.Index) == 0 && Tag("never")`,
+					`OutIf.Name == "Gi0/0/200" && Tag("peering")`,
+				},
+			},
+			Schema: schema.Configuration{Enabled: []schema.ColumnKey{schema.ColumnTags}},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        1000,
+					schema.ColumnOutIfSpeed:       1000,
+					schema.ColumnTags:             []string{"peering"},
+				},
+			},
+			ExpectedMetrics: map[string]string{
+				`classifier_flow_matches_total{index="0"}`: "0",
+				`classifier_flow_matches_total{index="1"}`: "1",
+			},
+		}, {
+			Name: "flow filters, exclude",
+			Configuration: gin.H{
+				"flowfilters": gin.H{
+					"exclude": []string{
+						`Proto == 17 && DstPort == 53`,
+						`InSubnet(SrcAddr, "192.0.2.0/24") && InSubnet(DstAddr, "192.0.2.0/24")`,
+					},
+				},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.142"),
+					DstAddr:         netip.MustParseAddr("::ffff:192.0.2.10"),
+				}
+			},
+			OutputFlow: nil,
+			ExpectedMetrics: map[string]string{
+				`filtered_flows_total{rule="exclude[0]"}`: "0",
+				`filtered_flows_total{rule="exclude[1]"}`: "1",
+			},
+		}, {
+			Name: "flow filters, include",
+			Configuration: gin.H{
+				"flowfilters": gin.H{
+					"include": []string{`DstAS == 65000`, `InIf.Index == 300`},
+				},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.142"),
+					DstAddr:         netip.MustParseAddr("::ffff:192.0.2.10"),
+				}
+			},
+			OutputFlow: nil,
+			ExpectedMetrics: map[string]string{
+				`filtered_flows_total{rule="include"}`: "1",
+			},
+		}, {
+			Name:          "address family mismatch",
+			Configuration: gin.H{},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.10"),
+					DstAddr:         netip.MustParseAddr("2001:db8::10"),
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.10"),
+				DstAddr:         netip.MustParseAddr("2001:db8::10"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        1000,
+					schema.ColumnOutIfSpeed:       1000,
+				},
+			},
+			ExpectedMetrics: map[string]string{
+				`flows_errors_total{error="address family mismatch",exporter="192.0.2.142"}`: "2",
+			},
+		}, {
+			Name:          "address family mismatch, drop",
+			Configuration: gin.H{"dropaddressfamilymismatch": true},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.10"),
+					DstAddr:         netip.MustParseAddr("2001:db8::10"),
+				}
+			},
+			OutputFlow: nil,
+			ExpectedMetrics: map[string]string{
+				`flows_errors_total{error="address family mismatch",exporter="192.0.2.142"}`: "2",
+			},
+		}, {
+			Name:          "interface names from flow, fallback to metadata",
+			Configuration: gin.H{"interfaceproviders": []string{"flow", "metadata"}},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					InIfName:        "et-0/0/1",
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "et-0/0/1",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnOutIfSpeed:       1000,
+				},
+			},
 		},
 	}
 	for _, tc := range cases {
@@ -546,6 +817,10 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 			httpComponent := httpserver.NewMock(t, r)
 			routingComponent := routing.NewMock(t, r)
 			routingComponent.PopulateRIB(t)
+			schemaComponent, err := schema.New(tc.Schema)
+			if err != nil {
+				t.Fatalf("schema.New() error:\n%+v", err)
+			}
 
 			// Prepare a configuration
 			configuration := DefaultConfiguration()
@@ -565,7 +840,7 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 				Kafka:    kafkaComponent,
 				HTTP:     httpComponent,
 				Routing:  routingComponent,
-				Schema:   schema.NewMock(t),
+				Schema:   schemaComponent,
 			})
 			if err != nil {
 				t.Fatalf("New() error:\n%+v", err)
@@ -603,7 +878,7 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 			} else {
 				time.Sleep(100 * time.Millisecond)
 			}
-			gotMetrics := r.GetMetrics("akvorado_inlet_core_", "-processing_", "flows_", "received_", "forwarded_")
+			gotMetrics := r.GetMetrics("akvorado_inlet_core_", "-processing_", "flows_", "received_", "forwarded_", "classifier_flow_", "filtered_")
 			expectedMetrics := map[string]string{
 				`flows_errors_total{error="SNMP cache miss",exporter="192.0.2.142"}`: "1",
 				`flows_http_clients`:                           "0",
@@ -612,6 +887,9 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 			if tc.OutputFlow != nil {
 				expectedMetrics[`forwarded_flows_total{exporter="192.0.2.142"}`] = "1"
 			}
+			for k, v := range tc.ExpectedMetrics {
+				expectedMetrics[k] = v
+			}
 			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 				t.Fatalf("Metrics (-got, +want):\n%s", diff)
 			}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exporterRateWindow is the duration used to compute the flow rate of an
+// exporter.
+const exporterRateWindow = time.Minute
+
+// exporterInventory keeps track of the exporters sending flows. The lock only
+// protects the map: the state of each exporter is updated with atomic
+// operations to not serialize the workers.
+type exporterInventory struct {
+	lock      sync.RWMutex
+	exporters map[netip.Addr]*exporterState
+}
+
+// exporterState is the state of one exporter in the inventory. Times are
+// stored as nanoseconds since the epoch.
+type exporterState struct {
+	firstSeen    time.Time
+	lastSeen     atomic.Int64
+	flows        atomic.Uint64
+	samplingRate atomic.Uint32
+
+	windowStart atomic.Int64  // start of the current rate window
+	windowFlows atomic.Uint64 // number of flows at the start of the current rate window
+	flowRate    atomic.Uint64 // flow rate during the last complete window (as float64 bits)
+}
+
+func newExporterInventory() *exporterInventory {
+	return &exporterInventory{
+		exporters: make(map[netip.Addr]*exporterState),
+	}
+}
+
+// get returns the state of an exporter, creating it if needed.
+func (i *exporterInventory) get(now time.Time, exporter netip.Addr) *exporterState {
+	i.lock.RLock()
+	state, ok := i.exporters[exporter]
+	i.lock.RUnlock()
+	if ok {
+		return state
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	state, ok = i.exporters[exporter]
+	if !ok {
+		state = &exporterState{firstSeen: now}
+		state.windowStart.Store(now.UnixNano())
+		i.exporters[exporter] = state
+	}
+	return state
+}
+
+// Observe records a flow received from an exporter.
+func (i *exporterInventory) Observe(now time.Time, exporter netip.Addr, samplingRate uint32) {
+	state := i.get(now, exporter)
+	nowNano := now.UnixNano()
+	flows := state.flows.Add(1)
+	if start := state.windowStart.Load(); nowNano-start >= int64(exporterRateWindow) &&
+		state.windowStart.CompareAndSwap(start, nowNano) {
+		// The current flow belongs to the new window.
+		previous := state.windowFlows.Swap(flows - 1)
+		elapsed := time.Duration(nowNano - start)
+		state.flowRate.Store(math.Float64bits(float64(flows-1-previous) / elapsed.Seconds()))
+	}
+	state.lastSeen.Store(nowNano)
+	if samplingRate > 0 {
+		state.samplingRate.Store(samplingRate)
+	}
+}
+
+// DeleteLastSeenBefore removes the exporters not seen since the provided time.
+func (i *exporterInventory) DeleteLastSeenBefore(before time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for exporter, state := range i.exporters {
+		if state.lastSeen.Load() < before.UnixNano() {
+			delete(i.exporters, exporter)
+		}
+	}
+}
+
+// exporterInventoryEntry is one exporter as returned by the HTTP endpoint.
+type exporterInventoryEntry struct {
+	Address           string    `json:"address,omitempty"`
+	FirstSeen         time.Time `json:"first-seen"`
+	LastSeen          time.Time `json:"last-seen"`
+	Flows             uint64    `json:"flows"`
+	FlowRate          float64   `json:"flow-rate"`
+	SamplingRate      uint32    `json:"sampling-rate"`
+	Decoder           string    `json:"decoder,omitempty"`
+	Version           uint32    `json:"version,omitempty"`
+	DecodedPackets    uint64    `json:"decoded-packets"`
+	DecodingErrors    uint64    `json:"decoding-errors"`
+	DecodingErrorRate float64   `json:"decoding-error-rate"`
+}
+
+// ExportersHTTPHandler returns the list of exporters currently sending flows.
+// Exporters only known by the flow component (for example, because their
+// packets cannot be decoded) are included as well.
+func (c *Component) ExportersHTTPHandler(gc *gin.Context) {
+	now := c.d.Clock.Now()
+	entries := map[netip.Addr]*exporterInventoryEntry{}
+	c.exporters.lock.RLock()
+	for exporter, state := range c.exporters.exporters {
+		flows := state.flows.Load()
+		flowRate := math.Float64frombits(state.flowRate.Load())
+		windowStart := state.windowStart.Load()
+		if elapsed := time.Duration(now.UnixNano() - windowStart); elapsed >= exporterRateWindow {
+			// The current window is complete but no flow came to close it.
+			flowRate = float64(flows-state.windowFlows.Load()) / elapsed.Seconds()
+		}
+		entries[netip.AddrFrom16(exporter.As16())] = &exporterInventoryEntry{
+			FirstSeen:    state.firstSeen.UTC(),
+			LastSeen:     time.Unix(0, state.lastSeen.Load()).UTC(),
+			Flows:        flows,
+			FlowRate:     flowRate,
+			SamplingRate: state.samplingRate.Load(),
+		}
+	}
+	c.exporters.lock.RUnlock()
+
+	// Add decoding statistics from the flow component
+	for exporter, stats := range c.d.Flow.AllExporterStats() {
+		exporter = netip.AddrFrom16(exporter.As16())
+		entry, ok := entries[exporter]
+		if !ok {
+			entry = &exporterInventoryEntry{
+				FirstSeen: stats.FirstSeen.UTC(),
+				LastSeen:  stats.LastSeen.UTC(),
+			}
+			entries[exporter] = entry
+		}
+		entry.Decoder = stats.Decoder
+		entry.Version = stats.Version
+		entry.DecodedPackets = stats.Packets
+		entry.DecodingErrors = stats.Errors
+		if total := stats.Packets + stats.Errors; total > 0 {
+			entry.DecodingErrorRate = float64(stats.Errors) / float64(total)
+		}
+	}
+
+	addresses := make([]netip.Addr, 0, len(entries))
+	for exporter := range entries {
+		addresses = append(addresses, exporter)
+	}
+	slices.SortFunc(addresses, netip.Addr.Compare)
+	result := make([]exporterInventoryEntry, len(addresses))
+	for idx, exporter := range addresses {
+		result[idx] = *entries[exporter]
+		if !c.maskExporter {
+			result[idx].Address = exporter.Unmap().String()
+		}
+	}
+	gc.JSON(http.StatusOK, gin.H{"exporters": result})
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow"
+)
+
+func TestExporterInventory(t *testing.T) {
+	r := reporter.NewMock(t)
+	mockClock := clock.NewMock()
+	configuration := DefaultConfiguration()
+	flowComponent := flow.NewMock(t, r, flow.DefaultConfiguration())
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flowComponent,
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: schema.NewMock(t),
+		Clock:  mockClock,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	exporter1 := netip.MustParseAddr("::ffff:192.0.2.1")
+	exporter2 := netip.MustParseAddr("::ffff:192.0.2.2")
+	for range 6 {
+		c.exporters.Observe(mockClock.Now(), exporter1, 1000)
+	}
+	time.Sleep(10 * time.Millisecond)
+	mockClock.Add(30 * time.Second)
+	c.exporters.Observe(mockClock.Now(), exporter2, 0)
+	mockClock.Add(30 * time.Second)
+	c.exporters.Observe(mockClock.Now(), exporter1, 0)
+	flowComponent.InjectExporterStats(exporter1, flow.ExporterStats{
+		Decoder: "netflow",
+		Version: 9,
+		Packets: 3,
+		Errors:  1,
+	})
+	// This one only sends undecodable packets
+	flowComponent.InjectExporterStats(netip.MustParseAddr("::ffff:192.0.2.3"), flow.ExporterStats{
+		Decoder:   "sflow",
+		Errors:    2,
+		FirstSeen: time.Unix(10, 0),
+		LastSeen:  time.Unix(40, 0),
+	})
+
+	helpers.TestHTTPEndpoints(t, c.d.HTTP.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:         "/api/v0/inlet/exporters",
+			ContentType: "application/json; charset=utf-8",
+			JSONOutput: gin.H{
+				"exporters": []gin.H{
+					{
+						"address":             "192.0.2.1",
+						"first-seen":          "1970-01-01T00:00:00Z",
+						"last-seen":           "1970-01-01T00:01:00Z",
+						"flows":               7,
+						"flow-rate":           0.1,
+						"sampling-rate":       1000,
+						"decoder":             "netflow",
+						"version":             9,
+						"decoded-packets":     3,
+						"decoding-errors":     1,
+						"decoding-error-rate": 0.25,
+					}, {
+						"address":             "192.0.2.2",
+						"first-seen":          "1970-01-01T00:00:30Z",
+						"last-seen":           "1970-01-01T00:00:30Z",
+						"flows":               1,
+						"flow-rate":           0,
+						"sampling-rate":       0,
+						"decoded-packets":     0,
+						"decoding-errors":     0,
+						"decoding-error-rate": 0,
+					}, {
+						"address":             "192.0.2.3",
+						"first-seen":          "1970-01-01T00:00:10Z",
+						"last-seen":           "1970-01-01T00:00:40Z",
+						"flows":               0,
+						"flow-rate":           0,
+						"sampling-rate":       0,
+						"decoder":             "sflow",
+						"decoded-packets":     0,
+						"decoding-errors":     2,
+						"decoding-error-rate": 1,
+					},
+				},
+			},
+		},
+	})
+
+	// Two ticks are needed for the exporters to expire
+	mockClock.Add(configuration.ExporterIdleTimeout - time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	mockClock.Add(configuration.ExporterIdleTimeout)
+	time.Sleep(10 * time.Millisecond)
+	c.exporters.lock.RLock()
+	_, ok1 := c.exporters.exporters[exporter1]
+	_, ok2 := c.exporters.exporters[exporter2]
+	c.exporters.lock.RUnlock()
+	if ok1 || ok2 {
+		t.Fatalf("exporters were not expired (%v, %v)", ok1, ok2)
+	}
+	if stats := flowComponent.AllExporterStats(); len(stats) != 0 {
+		t.Fatalf("decoding statistics were not expired (%d left)", len(stats))
+	}
+}
+
+func TestExporterInventoryMasked(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.HTTPFlowsMaskedColumns = []schema.ColumnKey{schema.ColumnExporterAddress}
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flow.NewMock(t, r, flow.DefaultConfiguration()),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: schema.NewMock(t),
+		Clock:  clock.NewMock(),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+	c.exporters.Observe(c.d.Clock.Now(), netip.MustParseAddr("::ffff:192.0.2.1"), 1000)
+
+	helpers.TestHTTPEndpoints(t, c.d.HTTP.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:         "/api/v0/inlet/exporters",
+			ContentType: "application/json; charset=utf-8",
+			JSONOutput: gin.H{
+				"exporters": []gin.H{
+					{
+						"first-seen":          "1970-01-01T00:00:00Z",
+						"last-seen":           "1970-01-01T00:00:00Z",
+						"flows":               1,
+						"flow-rate":           0,
+						"sampling-rate":       1000,
+						"decoded-packets":     0,
+						"decoding-errors":     0,
+						"decoding-error-rate": 0,
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// FlowFiltersConfiguration defines the rules to drop flows. When Include is
+// not empty, flows not matching any of its rules are dropped. Flows matching
+// any of the rules of Exclude are dropped.
+type FlowFiltersConfiguration struct {
+	// Include defines the rules for flows to keep
+	Include []FlowFilterRule
+	// Exclude defines the rules for flows to drop
+	Exclude []FlowFilterRule
+}
+
+// FlowFilterRule defines a filtering rule for a flow.
+type FlowFilterRule struct {
+	program *vm.Program
+	usesAS  bool // the rule needs AS numbers (and therefore routing information)
+}
+
+// flowFilterEnvironment defines the environment used by the flow filters
+type flowFilterEnvironment struct {
+	Exporter exporterInfo
+	InIf     interfaceInfo
+	OutIf    interfaceInfo
+	SrcAddr  string
+	DstAddr  string
+	SrcAS    uint32
+	DstAS    uint32
+	SrcPort  uint16
+	DstPort  uint16
+	Proto    uint8
+	InSubnet func(string, string) (bool, error)
+}
+
+// match tells if the provided flow matches the filter.
+func (ffr *FlowFilterRule) match(fi flowInfo) (bool, error) {
+	env := flowFilterEnvironment{
+		Exporter: fi.Exporter,
+		InIf:     fi.InIf,
+		OutIf:    fi.OutIf,
+		SrcAddr:  fi.SrcAddr,
+		DstAddr:  fi.DstAddr,
+		SrcAS:    fi.SrcAS,
+		DstAS:    fi.DstAS,
+		SrcPort:  fi.SrcPort,
+		DstPort:  fi.DstPort,
+		Proto:    fi.Proto,
+		InSubnet: inSubnet,
+	}
+	result, err := expr.Run(ffr.program, env)
+	if err != nil {
+		return false, fmt.Errorf("unable to execute filter %q: %w", ffr, err)
+	}
+	return result.(bool), nil
+}
+
+// UnmarshalText compiles a filtering rule for a flow.
+func (ffr *FlowFilterRule) UnmarshalText(text []byte) error {
+	subnetValidator := subnetValidator{}
+	asDetector := asDetector{}
+	program, err := expr.Compile(string(text),
+		expr.Env(flowFilterEnvironment{}),
+		expr.AsBool(),
+		expr.Patch(&subnetValidator),
+		expr.Patch(&asDetector))
+	if err != nil {
+		return fmt.Errorf("cannot compile flow filter rule %q: %w", string(text), err)
+	}
+	if len(subnetValidator.invalidSubnets) > 0 {
+		return fmt.Errorf("invalid subnet %q", subnetValidator.invalidSubnets[0])
+	}
+	ffr.program = program
+	ffr.usesAS = asDetector.found
+	return nil
+}
+
+// String turns a flow filter rule into a string
+func (ffr FlowFilterRule) String() string {
+	return ffr.program.Source().String()
+}
+
+// MarshalText turns a flow filter rule into a string
+func (ffr FlowFilterRule) MarshalText() ([]byte, error) {
+	return []byte(ffr.String()), nil
+}
+
+// asDetector detects if a rule uses AS numbers.
+type asDetector struct {
+	found bool
+}
+
+func (d *asDetector) Visit(node *ast.Node) {
+	if n, ok := (*node).(*ast.IdentifierNode); ok && (n.Value == "SrcAS" || n.Value == "DstAS") {
+		d.found = true
+	}
+}
+
+// usesAS tells if one of the filters needs AS numbers.
+func (config FlowFiltersConfiguration) usesAS() bool {
+	for _, rules := range [][]FlowFilterRule{config.Include, config.Exclude} {
+		for _, rule := range rules {
+			if rule.usesAS {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterFlow tells if a flow should be kept according to the flow filters.
+// Flows are kept when a filter cannot be evaluated.
+func (c *Component) filterFlow(exporterName string, fi flowInfo) bool {
+	match := func(name string, rule FlowFilterRule) (matched bool, ok bool) {
+		matched, err := rule.match(fi)
+		if err != nil {
+			c.classifierErrLogger.Err(err).
+				Str("type", "filter").
+				Str("rule", name).
+				Str("exporter", exporterName).
+				Msg("error executing filter")
+			c.metrics.classifierErrors.WithLabelValues("filter", name).Inc()
+			return false, false
+		}
+		return matched, true
+	}
+	for idx, rule := range c.config.FlowFilters.Exclude {
+		name := fmt.Sprintf("exclude[%d]", idx)
+		if matched, ok := match(name, rule); ok && matched {
+			c.metrics.flowsFiltered.WithLabelValues(name).Inc()
+			return false
+		}
+	}
+	if len(c.config.FlowFilters.Include) == 0 {
+		return true
+	}
+	for idx, rule := range c.config.FlowFilters.Include {
+		name := fmt.Sprintf("include[%d]", idx)
+		if matched, ok := match(name, rule); !ok || matched {
+			return true
+		}
+	}
+	c.metrics.flowsFiltered.WithLabelValues("include").Inc()
+	return false
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"testing"
+)
+
+func TestFlowFilter(t *testing.T) {
+	cases := []struct {
+		Description string
+		Program     string
+		FlowInfo    flowInfo
+		Expected    bool
+		ExpectedErr bool
+		UsesAS      bool
+	}{
+		{
+			Description: "trivial filter",
+			Program:     "false",
+		}, {
+			Description: "match on protocol and port",
+			Program:     `Proto == 17 && DstPort == 53`,
+			FlowInfo:    flowInfo{Proto: 17, DstPort: 53},
+			Expected:    true,
+		}, {
+			Description: "no match on protocol",
+			Program:     `Proto == 17 && DstPort == 53`,
+			FlowInfo:    flowInfo{Proto: 6, DstPort: 53},
+		}, {
+			Description: "match on port range",
+			Program:     `SrcPort in 8000..8080`,
+			FlowInfo:    flowInfo{SrcPort: 8043},
+			Expected:    true,
+		}, {
+			Description: "no match on port range",
+			Program:     `SrcPort in 8000..8080`,
+			FlowInfo:    flowInfo{SrcPort: 8443},
+		}, {
+			Description: "match on subnet pair",
+			Program:     `InSubnet(SrcAddr, "10.0.0.0/24") && InSubnet(DstAddr, "10.0.0.0/24")`,
+			FlowInfo:    flowInfo{SrcAddr: "10.0.0.1", DstAddr: "10.0.0.2"},
+			Expected:    true,
+		}, {
+			Description: "no match on subnet pair",
+			Program:     `InSubnet(SrcAddr, "10.0.0.0/24") && InSubnet(DstAddr, "10.0.0.0/24")`,
+			FlowInfo:    flowInfo{SrcAddr: "10.0.0.1", DstAddr: "10.0.1.2"},
+		}, {
+			Description: "match on interface and AS",
+			Program:     `InIf.Name == "Gi0/0/1" && DstAS == 65000`,
+			FlowInfo:    flowInfo{InIf: interfaceInfo{Name: "Gi0/0/1"}, DstAS: 65000},
+			Expected:    true,
+			UsesAS:      true,
+		}, {
+			Description: "no match on AS",
+			Program:     `SrcAS in [65000, 65001]`,
+			FlowInfo:    flowInfo{SrcAS: 65002},
+			UsesAS:      true,
+		}, {
+			Description: "invalid subnet",
+			Program:     `InSubnet(SrcAddr, "10.0.0.0")`,
+			ExpectedErr: true,
+		}, {
+			Description: "incorrect typing",
+			Program:     `DstPort`,
+			ExpectedErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var ffr FlowFilterRule
+			err := ffr.UnmarshalText([]byte(tc.Program))
+			if !tc.ExpectedErr && err != nil {
+				t.Fatalf("UnmarshalText(%q) error:\n%+v", tc.Program, err)
+			}
+			if tc.ExpectedErr && err != nil {
+				return
+			}
+			if ffr.usesAS != tc.UsesAS {
+				t.Errorf("UnmarshalText(%q) usesAS == %v but expected %v", tc.Program, ffr.usesAS, tc.UsesAS)
+			}
+			got, err := ffr.match(tc.FlowInfo)
+			if !tc.ExpectedErr && err != nil {
+				t.Fatalf("match(%q) error:\n%+v", tc.Program, err)
+			}
+			if tc.ExpectedErr && err == nil {
+				t.Fatalf("match(%q) no error", tc.Program)
+			}
+			if got != tc.Expected {
+				t.Fatalf("match(%q) == %v but expected %v", tc.Program, got, tc.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	commonkafka "akvorado/common/kafka"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow"
+	"akvorado/inlet/kafka"
+	"akvorado/inlet/metadata"
+	"akvorado/inlet/routing"
+)
+
+func TestRealKafka(t *testing.T) {
+	client, brokers := commonkafka.SetupKafkaBroker(t)
+	r := reporter.NewMock(t)
+
+	// Prepare all components.
+	daemonComponent := daemon.NewMock(t)
+	metadataComponent := metadata.NewMock(t, r, metadata.DefaultConfiguration(),
+		metadata.Dependencies{Daemon: daemonComponent})
+	flowComponent := flow.NewMock(t, r, flow.DefaultConfiguration())
+	httpComponent := httpserver.NewMock(t, r)
+	routingComponent := routing.NewMock(t, r)
+	sch := schema.NewMock(t)
+
+	kafkaConfiguration := kafka.DefaultConfiguration()
+	kafkaConfiguration.Topic = fmt.Sprintf("test-topic-%d", rand.Int())
+	kafkaConfiguration.Brokers = brokers
+	kafkaConfiguration.Version = commonkafka.Version(sarama.V2_8_1_0)
+	kafkaConfiguration.FlushInterval = 100 * time.Millisecond
+	kafkaComponent, err := kafka.New(r, kafkaConfiguration, kafka.Dependencies{
+		Daemon: daemonComponent,
+		Schema: sch,
+	})
+	if err != nil {
+		t.Fatalf("kafka.New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, kafkaComponent)
+
+	// Instantiate and start core
+	c, err := New(r, DefaultConfiguration(), Dependencies{
+		Daemon:   daemonComponent,
+		Flow:     flowComponent,
+		Metadata: metadataComponent,
+		Kafka:    kafkaComponent,
+		HTTP:     httpComponent,
+		Routing:  routingComponent,
+		Schema:   sch,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	flowMessage := func() *schema.FlowMessage {
+		msg := &schema.FlowMessage{
+			TimeReceived:    200,
+			SamplingRate:    1000,
+			ExporterAddress: netip.MustParseAddr("192.0.2.142"),
+			InIf:            434,
+			OutIf:           677,
+			SrcAddr:         netip.MustParseAddr("67.43.156.77"),
+			DstAddr:         netip.MustParseAddr("2.125.160.216"),
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   6765,
+				schema.ColumnPackets: 4,
+				schema.ColumnProto:   6,
+			},
+		}
+		for k, v := range msg.ProtobufDebug {
+			sch.ProtobufAppendVarint(msg, k, uint64(v.(int)))
+		}
+		return msg
+	}
+
+	// The first flow is a cache miss for the metadata component.
+	flowComponent.Inject(flowMessage())
+	time.Sleep(50 * time.Millisecond)
+	flowComponent.Inject(flowMessage())
+
+	time.Sleep(500 * time.Millisecond)
+	got := kafkaComponent.ConsumeLastFlows(t, client, 1)
+	if len(got) != 1 {
+		t.Fatalf("ConsumeLastFlows() returned %d flows instead of 1", len(got))
+	}
+	expected := flowMessage()
+	expected.ProtobufDebug[schema.ColumnInIfName] = "Gi0/0/434"
+	expected.ProtobufDebug[schema.ColumnOutIfName] = "Gi0/0/677"
+	expected.ProtobufDebug[schema.ColumnInIfDescription] = "Interface 434"
+	expected.ProtobufDebug[schema.ColumnOutIfDescription] = "Interface 677"
+	expected.ProtobufDebug[schema.ColumnInIfSpeed] = 1000
+	expected.ProtobufDebug[schema.ColumnOutIfSpeed] = 1000
+	expected.ProtobufDebug[schema.ColumnExporterName] = "192_0_2_142"
+	kafkaComponent.CheckFlow(t, got[0], expected)
+}
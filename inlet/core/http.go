@@ -5,21 +5,28 @@ package core
 
 import (
 	"net/http"
+	"net/netip"
 	"sync/atomic"
 	"time"
 
 	"akvorado/common/helpers"
+	"akvorado/common/schema"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
 )
 
 type flowsParameters struct {
-	Limit uint64 `form:"limit"`
+	Limit    uint64  `form:"limit"`
+	Exporter string  `form:"exporter"`
+	Rate     float64 `form:"rate,default=100" binding:"min=0"`
 }
 
 // FlowsHTTPHandler streams a JSON copy of all flows just after
 // sending them to Kafka. Under load, some flows may not be sent. This
-// is intended for debug only.
+// is intended for debug only. Flows can be filtered by exporter and
+// are rate-limited. When requested, flows are sent as Server-Sent
+// Events.
 func (c *Component) FlowsHTTPHandler(gc *gin.Context) {
 	var params flowsParameters
 	var count uint64
@@ -27,10 +34,40 @@ func (c *Component) FlowsHTTPHandler(gc *gin.Context) {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
 		return
 	}
-	format := gc.NegotiateFormat("application/json", "application/x-protobuf")
+	var exporter netip.Addr
+	if params.Exporter != "" {
+		var err error
+		exporter, err = netip.ParseAddr(params.Exporter)
+		if err != nil {
+			gc.JSON(http.StatusBadRequest, gin.H{"message": "Invalid exporter address."})
+			return
+		}
+		exporter = exporter.Unmap()
+	}
+	var limiter *rate.Limiter
+	if params.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(params.Rate), max(1, int(params.Rate)))
+	}
+	// The protobuf representation cannot be masked.
+	var format string
+	if len(c.config.HTTPFlowsMaskedColumns) == 0 {
+		format = gc.NegotiateFormat("application/json", "application/x-protobuf", "text/event-stream")
+	} else {
+		format = gc.NegotiateFormat("application/json", "text/event-stream")
+	}
+	if format == "" {
+		gc.JSON(http.StatusNotAcceptable, gin.H{"message": "Requested format is not available."})
+		return
+	}
+	if format == "text/event-stream" {
+		gc.Header("Content-Type", format)
+		gc.Header("Cache-Control", "no-cache")
+		gc.Writer.WriteHeader(http.StatusOK)
+		gc.Writer.Flush()
+	}
 
-	atomic.AddUint32(&c.httpFlowClients, 1)
-	defer atomic.AddUint32(&c.httpFlowClients, ^uint32(0))
+	flows := c.subscribeHTTPFlows()
+	defer c.unsubscribeHTTPFlows(flows)
 
 	// Flush from time to time
 	var tickerChan <-chan time.Time
@@ -44,7 +81,16 @@ func (c *Component) FlowsHTTPHandler(gc *gin.Context) {
 			return
 		case <-gc.Request.Context().Done():
 			return
-		case msg := <-c.httpFlowChannel:
+		case msg := <-flows:
+			if exporter.IsValid() && msg.ExporterAddress.Unmap() != exporter {
+				continue
+			}
+			if limiter != nil && !limiter.Allow() {
+				continue
+			}
+			if len(c.config.HTTPFlowsMaskedColumns) > 0 {
+				msg = c.maskFlow(msg)
+			}
 			switch format {
 			case "application/json":
 				if params.Limit == 1 {
@@ -56,6 +102,9 @@ func (c *Component) FlowsHTTPHandler(gc *gin.Context) {
 			case "application/x-protobuf":
 				gc.Set("Content-Type", format)
 				gc.Writer.Write(msg.Bytes())
+			case "text/event-stream":
+				gc.SSEvent("flow", msg)
+				gc.Writer.Flush()
 			}
 
 			count++
@@ -67,3 +116,57 @@ func (c *Component) FlowsHTTPHandler(gc *gin.Context) {
 		}
 	}
 }
+
+// subscribeHTTPFlows returns a new channel receiving a copy of the flows sent
+// to Kafka. Each HTTP client gets its own channel.
+func (c *Component) subscribeHTTPFlows() chan *schema.FlowMessage {
+	flows := make(chan *schema.FlowMessage, 10)
+	c.httpFlowLock.Lock()
+	c.httpFlowSubscribers[flows] = struct{}{}
+	c.httpFlowLock.Unlock()
+	atomic.AddUint32(&c.httpFlowClients, 1)
+	return flows
+}
+
+// unsubscribeHTTPFlows stops sending flows to the provided channel.
+func (c *Component) unsubscribeHTTPFlows(flows chan *schema.FlowMessage) {
+	atomic.AddUint32(&c.httpFlowClients, ^uint32(0))
+	c.httpFlowLock.Lock()
+	delete(c.httpFlowSubscribers, flows)
+	c.httpFlowLock.Unlock()
+}
+
+// maskableColumns are the columns that can be masked in the flows exposed
+// through the HTTP endpoint and the dry-run logs.
+var maskableColumns = map[schema.ColumnKey]func(*schema.FlowMessage){
+	schema.ColumnTimeReceived:     func(f *schema.FlowMessage) { f.TimeReceived = 0 },
+	schema.ColumnSamplingRate:     func(f *schema.FlowMessage) { f.SamplingRate = 0 },
+	schema.ColumnExporterAddress:  func(f *schema.FlowMessage) { f.ExporterAddress = netip.Addr{} },
+	schema.ColumnSrcVlan:          func(f *schema.FlowMessage) { f.SrcVlan = 0 },
+	schema.ColumnDstVlan:          func(f *schema.FlowMessage) { f.DstVlan = 0 },
+	schema.ColumnInIfName:         func(f *schema.FlowMessage) { f.InIfName = "" },
+	schema.ColumnOutIfName:        func(f *schema.FlowMessage) { f.OutIfName = "" },
+	schema.ColumnInIfDescription:  func(f *schema.FlowMessage) { f.InIfDescription = "" },
+	schema.ColumnOutIfDescription: func(f *schema.FlowMessage) { f.OutIfDescription = "" },
+	schema.ColumnSrcAddr:          func(f *schema.FlowMessage) { f.SrcAddr = netip.Addr{} },
+	schema.ColumnDstAddr:          func(f *schema.FlowMessage) { f.DstAddr = netip.Addr{} },
+	schema.ColumnNextHop:          func(f *schema.FlowMessage) { f.NextHop = netip.Addr{} },
+	schema.ColumnSrcAS:            func(f *schema.FlowMessage) { f.SrcAS = 0 },
+	schema.ColumnDstAS:            func(f *schema.FlowMessage) { f.DstAS = 0 },
+	schema.ColumnSrcNetMask:       func(f *schema.FlowMessage) { f.SrcNetMask = 0 },
+	schema.ColumnDstNetMask:       func(f *schema.FlowMessage) { f.DstNetMask = 0 },
+
+	// The exporter name is only present in the protobuf representation, which
+	// is not available when masking.
+	schema.ColumnExporterName: func(*schema.FlowMessage) {},
+}
+
+// maskFlow returns a copy of the provided flow with the configured columns
+// masked.
+func (c *Component) maskFlow(flow *schema.FlowMessage) *schema.FlowMessage {
+	masked := *flow
+	for _, column := range c.config.HTTPFlowsMaskedColumns {
+		maskableColumns[column](&masked)
+	}
+	return &masked
+}
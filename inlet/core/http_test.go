@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow"
+)
+
+func TestMaskFlow(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.HTTPFlowsMaskedColumns = []schema.ColumnKey{
+		schema.ColumnSrcAddr, schema.ColumnExporterAddress,
+		schema.ColumnInIfName, schema.ColumnInIfDescription,
+	}
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	flow := &schema.FlowMessage{
+		SamplingRate:    1000,
+		ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+		SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.10"),
+		DstAddr:         netip.MustParseAddr("::ffff:203.0.113.20"),
+		InIfName:        "Gi0/0/0/1",
+		InIfDescription: "Transit: Cogent",
+		OutIfName:       "Gi0/0/0/2",
+	}
+	got := c.maskFlow(flow)
+	expected := &schema.FlowMessage{
+		SamplingRate: 1000,
+		DstAddr:      netip.MustParseAddr("::ffff:203.0.113.20"),
+		OutIfName:    "Gi0/0/0/2",
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("maskFlow() (-got, +want):\n%s", diff)
+	}
+	if flow.SrcAddr != netip.MustParseAddr("::ffff:203.0.113.10") {
+		t.Fatal("maskFlow() modified the original flow")
+	}
+}
+
+func TestMaskFlowUnknownColumn(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.HTTPFlowsMaskedColumns = []schema.ColumnKey{schema.ColumnInIfBoundary}
+	_, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
+
+func TestFlowsHTTPMaskedFormat(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.HTTPFlowsMaskedColumns = []schema.ColumnKey{schema.ColumnExporterName}
+	h := httpserver.NewMock(t, r)
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flow.NewMock(t, r, flow.DefaultConfiguration()),
+		HTTP:   h,
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/api/v0/inlet/flows", h.LocalAddr()), nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest() error:\n%+v", err)
+	}
+	req.Header.Set("accept", "application/x-protobuf")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/v0/inlet/flows:\n%+v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotAcceptable {
+		t.Fatalf("GET /api/v0/inlet/flows status code %d", resp.StatusCode)
+	}
+}
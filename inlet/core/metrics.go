@@ -4,20 +4,39 @@
 package core
 
 import (
+	"fmt"
+	"strconv"
 	"sync/atomic"
 
 	"akvorado/common/reporter"
 )
 
 type metrics struct {
-	flowsReceived    *reporter.CounterVec
-	flowsForwarded   *reporter.CounterVec
-	flowsErrors      *reporter.CounterVec
-	flowsHTTPClients reporter.GaugeFunc
+	flowsReceived     *reporter.CounterVec
+	flowsForwarded    *reporter.CounterVec
+	flowsDryRun       *reporter.CounterVec
+	flowsErrors       *reporter.CounterVec
+	flowsHTTPClients  reporter.GaugeFunc
+	flowsFiltered     *reporter.CounterVec
+	flowsDeduplicated *reporter.CounterVec
+
+	deduplicationCacheSize reporter.GaugeFunc
+	anonymizationEnabled   *reporter.GaugeVec
+
+	queueWait       reporter.Histogram
+	pipelineLatency reporter.Histogram
+
+	learnedSamplingRate   *reporter.GaugeVec
+	estimatedSamplingRate *reporter.CounterVec
+	samplingRateSource    *reporter.CounterVec
 
 	classifierExporterCacheSize  reporter.CounterFunc
 	classifierInterfaceCacheSize reporter.CounterFunc
 	classifierErrors             *reporter.CounterVec
+	classifierFlowMatches        *reporter.CounterVec
+
+	customersPrefixes reporter.Gauge
+	customersErrors   reporter.Counter
 }
 
 func (c *Component) initMetrics() {
@@ -35,6 +54,13 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"exporter"},
 	)
+	c.metrics.flowsDryRun = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "dry_run_flows_total",
+			Help: "Number of flows processed but not forwarded to Kafka in dry-run mode.",
+		},
+		[]string{"exporter"},
+	)
 	c.metrics.flowsErrors = c.r.CounterVec(
 		reporter.CounterOpts{
 			Name: "flows_errors_total",
@@ -42,6 +68,49 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"exporter", "error"},
 	)
+	c.metrics.flowsFiltered = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "filtered_flows_total",
+			Help: "Number of flows dropped by a flow filter.",
+		},
+		[]string{"rule"},
+	)
+	for idx := range c.config.FlowFilters.Exclude {
+		c.metrics.flowsFiltered.WithLabelValues(fmt.Sprintf("exclude[%d]", idx))
+	}
+	if len(c.config.FlowFilters.Include) > 0 {
+		c.metrics.flowsFiltered.WithLabelValues("include")
+	}
+	c.metrics.flowsDeduplicated = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "deduplicated_flows_total",
+			Help: "Number of flows dropped as duplicates of flows from another exporter.",
+		},
+		[]string{"exporter"},
+	)
+	if c.deduplicator != nil {
+		c.metrics.deduplicationCacheSize = c.r.GaugeFunc(
+			reporter.GaugeOpts{
+				Name: "deduplication_cache_size_items",
+				Help: "Number of flows remembered for deduplication.",
+			},
+			func() float64 {
+				return float64(c.deduplicator.size())
+			},
+		)
+	}
+	c.metrics.anonymizationEnabled = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "anonymization_enabled",
+			Help: "Whether source and destination addresses are anonymized before export.",
+		},
+		[]string{"method"},
+	)
+	anonymizationEnabled := 0.
+	if c.anonymizer != nil {
+		anonymizationEnabled = 1
+	}
+	c.metrics.anonymizationEnabled.WithLabelValues(c.config.Anonymization.Method.String()).Set(anonymizationEnabled)
 	c.metrics.flowsHTTPClients = c.r.GaugeFunc(
 		reporter.GaugeOpts{
 			Name: "flows_http_clients",
@@ -52,6 +121,44 @@ func (c *Component) initMetrics() {
 		},
 	)
 
+	c.metrics.queueWait = c.r.Histogram(
+		reporter.HistogramOpts{
+			Name:    "queue_wait_seconds",
+			Help:    "Time flows wait to be picked by a worker.",
+			Buckets: []float64{.0001, .001, .01, .1, 1, 10},
+		},
+	)
+	c.metrics.pipelineLatency = c.r.Histogram(
+		reporter.HistogramOpts{
+			Name:    "pipeline_latency_seconds",
+			Help:    "Time between the reception of a flow and its publication to Kafka.",
+			Buckets: []float64{.001, .01, .1, .5, 1, 5, 10, 30},
+		},
+	)
+
+	c.metrics.learnedSamplingRate = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "learned_sampling_rate",
+			Help: "Sampling rate learned for an exporter.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.estimatedSamplingRate = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "estimated_sampling_rate_flows_total",
+			Help: "Number of flows using a learned sampling rate.",
+		},
+		[]string{"exporter"},
+	)
+
+	c.metrics.samplingRateSource = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "sampling_rate_source_flows_total",
+			Help: "Number of flows by source of their sampling rate.",
+		},
+		[]string{"exporter", "source"},
+	)
+
 	c.metrics.classifierExporterCacheSize = c.r.CounterFunc(
 		reporter.CounterOpts{
 			Name: "classifier_exporter_cache_size_items",
@@ -76,4 +183,24 @@ func (c *Component) initMetrics() {
 			Help: "Number of errors when evaluating a classifer",
 		},
 		[]string{"type", "index"})
+	c.metrics.classifierFlowMatches = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "classifier_flow_matches_total",
+			Help: "Number of flows tagged by a flow classifier",
+		},
+		[]string{"index"})
+	for idx := range c.config.FlowClassifiers {
+		// Unused rules should be visible
+		c.metrics.classifierFlowMatches.WithLabelValues(strconv.Itoa(idx))
+	}
+	c.metrics.customersPrefixes = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "customers_prefixes",
+			Help: "Number of prefixes loaded from the customers file",
+		})
+	c.metrics.customersErrors = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "customers_errors_total",
+			Help: "Number of errors when loading the customers file",
+		})
 }
@@ -5,14 +5,19 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"gopkg.in/tomb.v2"
 
 	"akvorado/common/daemon"
+	"akvorado/common/helpers"
 	"akvorado/common/helpers/cache"
+	"akvorado/common/helpers/yaml"
 	"akvorado/common/httpserver"
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
@@ -31,14 +36,40 @@ type Component struct {
 
 	metrics metrics
 
-	healthy            chan reporter.ChannelHealthcheckFunc
-	httpFlowClients    uint32 // for dumping flows
-	httpFlowChannel    chan *schema.FlowMessage
-	httpFlowFlushDelay time.Duration
+	healthy             chan reporter.ChannelHealthcheckFunc
+	httpFlowClients     uint32 // for dumping flows
+	httpFlowLock        sync.RWMutex
+	httpFlowSubscribers map[chan *schema.FlowMessage]struct{}
+	httpFlowFlushDelay  time.Duration
 
 	classifierExporterCache  *cache.Cache[exporterInfo, exporterClassification]
 	classifierInterfaceCache *cache.Cache[exporterAndInterfaceInfo, interfaceClassification]
 	classifierErrLogger      reporter.Logger
+	dryRunLogger             reporter.Logger
+	maskExporter             bool // do not expose the exporter in logs and endpoints
+
+	samplingRates  *samplingRateLearner
+	samplingConfig atomic.Pointer[samplingConfiguration]
+
+	deduplicator *deduplicator
+	anonymizer   anonymizer
+	exporters    *exporterInventory
+
+	// Filters and deduplication not needing AS numbers are applied before
+	// looking up routing information.
+	earlyFilters       bool
+	earlyDeduplication bool
+
+	// Customers, loaded from the customers file
+	customers atomic.Pointer[helpers.SubnetMap[string]]
+}
+
+// samplingConfiguration contains the settings used to compute the sampling
+// rate of a flow. They can be changed without a restart.
+type samplingConfiguration struct {
+	DefaultSamplingRate  helpers.SubnetMap[uint]
+	OverrideSamplingRate helpers.SubnetMap[uint]
+	SamplingRateUnit     helpers.SubnetMap[SamplingRateUnit]
 }
 
 // Dependencies define the dependencies of the HTTP component.
@@ -50,32 +81,123 @@ type Dependencies struct {
 	Kafka    *kafka.Component
 	HTTP     *httpserver.Component
 	Schema   *schema.Component
+	Clock    clock.Clock
 }
 
 // New creates a new core component.
 func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
+	if dependencies.Clock == nil {
+		dependencies.Clock = clock.New()
+	}
 	c := Component{
 		r:      r,
 		d:      &dependencies,
 		config: configuration,
 
-		healthy:            make(chan reporter.ChannelHealthcheckFunc),
-		httpFlowClients:    0,
-		httpFlowChannel:    make(chan *schema.FlowMessage, 10),
-		httpFlowFlushDelay: time.Second,
+		healthy:             make(chan reporter.ChannelHealthcheckFunc),
+		httpFlowClients:     0,
+		httpFlowSubscribers: make(map[chan *schema.FlowMessage]struct{}),
+		httpFlowFlushDelay:  time.Second,
 
 		classifierExporterCache:  cache.New[exporterInfo, exporterClassification](),
 		classifierInterfaceCache: cache.New[exporterAndInterfaceInfo, interfaceClassification](),
 		classifierErrLogger:      r.Sample(reporter.BurstSampler(10*time.Second, 3)),
+		dryRunLogger:             r.Sample(reporter.BurstSampler(time.Minute, uint32(configuration.DryRunLoggedFlows))),
+
+		samplingRates: newSamplingRateLearner(),
+		exporters:     newExporterInventory(),
+	}
+	c.samplingConfig.Store(&samplingConfiguration{
+		DefaultSamplingRate:  configuration.DefaultSamplingRate,
+		OverrideSamplingRate: configuration.OverrideSamplingRate,
+		SamplingRateUnit:     configuration.SamplingRateUnit,
+	})
+	for _, column := range c.config.HTTPFlowsMaskedColumns {
+		if _, ok := maskableColumns[column]; !ok {
+			return nil, fmt.Errorf("column %q cannot be masked", column)
+		}
+		if column == schema.ColumnExporterAddress || column == schema.ColumnExporterName {
+			c.maskExporter = true
+		}
+	}
+	if len(c.config.HTTPFlowsMaskedColumns) > 0 && c.d.Flow != nil {
+		// Undecodable packets may contain the masked columns as well.
+		c.d.Flow.MaskDecodeErrors(c.maskExporter)
+	}
+	if c.config.Deduplication.Window > 0 {
+		for _, column := range c.config.Deduplication.Columns {
+			if _, ok := deduplicationColumns[column]; !ok {
+				return nil, fmt.Errorf("column %q cannot be used for deduplication", column)
+			}
+		}
+		c.deduplicator = newDeduplicator(c.config.Deduplication.Window, c.config.Deduplication.MaxFlows)
+	}
+	c.earlyFilters = !c.config.FlowFilters.usesAS()
+	c.earlyDeduplication = !c.config.Deduplication.usesAS()
+	anonymizer, err := newAnonymizer(c.config.Anonymization)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymization configuration: %w", err)
+	}
+	if err := checkAnonymizationSchema(c.config.Anonymization, c.d.Schema); err != nil {
+		return nil, fmt.Errorf("invalid anonymization configuration: %w", err)
+	}
+	c.anonymizer = anonymizer
+	if err := checkCustomersSchema(c.config.CustomersFile, c.d.Schema); err != nil {
+		return nil, err
+	}
+	if len(c.config.FlowClassifiers) > 0 {
+		if column, _ := c.d.Schema.LookupColumnByKey(schema.ColumnTags); column.Disabled {
+			return nil, errors.New("flow-classifiers requires to enable the Tags column")
+		}
 	}
 	c.d.Daemon.Track(&c.t, "inlet/core")
 	c.initMetrics()
 	return &c, nil
 }
 
+// Validate checks the configuration of the core component. A provider cannot
+// be listed twice and dry-run-logged-flows is only used in dry-run mode.
+func (c *Component) Validate() error {
+	errs := []error{}
+	if c.config.DryRunLoggedFlows > 0 && !c.config.DryRun {
+		errs = append(errs, errors.New("dry-run-logged-flows: only used when dry-run is enabled"))
+	}
+	errs = append(errs, checkDuplicateProviders("asn-providers", c.config.ASNProviders)...)
+	errs = append(errs, checkDuplicateProviders("net-providers", c.config.NetProviders)...)
+	errs = append(errs, checkDuplicateProviders("interface-providers", c.config.InterfaceProviders)...)
+	return errors.Join(errs...)
+}
+
+// checkDuplicateProviders returns an error for each provider already present
+// earlier in the provided list.
+func checkDuplicateProviders[T interface {
+	comparable
+	fmt.Stringer
+}](key string, providers []T) []error {
+	errs := []error{}
+	seen := map[T]int{}
+	for idx, provider := range providers {
+		if previous, ok := seen[provider]; ok {
+			errs = append(errs, fmt.Errorf("%s[%d]: %q already used by %s[%d]",
+				key, idx, provider, key, previous))
+			continue
+		}
+		seen[provider] = idx
+	}
+	return errs
+}
+
 // Start starts the core component.
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting core component")
+	if c.config.DryRun {
+		c.r.Warn().Msg("dry-run mode enabled, flows are not sent to Kafka")
+	}
+	if c.config.CustomersFile != "" {
+		if err := c.watchCustomers(); err != nil {
+			return err
+		}
+	}
 	for i := range c.config.Workers {
 		workerID := i
 		c.t.Go(func() error {
@@ -89,16 +211,32 @@ func (c *Component) Start() error {
 			select {
 			case <-c.t.Dying():
 				return nil
-			case <-time.After(c.config.ClassifierCacheDuration):
-				before := time.Now().Add(-c.config.ClassifierCacheDuration)
+			case <-c.d.Clock.After(c.config.ClassifierCacheDuration):
+				before := c.d.Clock.Now().Add(-c.config.ClassifierCacheDuration)
 				c.classifierExporterCache.DeleteLastAccessedBefore(before)
 				c.classifierInterfaceCache.DeleteLastAccessedBefore(before)
 			}
 		}
 	})
 
+	// Exporter inventory expiration
+	c.t.Go(func() error {
+		for {
+			select {
+			case <-c.t.Dying():
+				return nil
+			case <-c.d.Clock.After(c.config.ExporterIdleTimeout):
+				before := c.d.Clock.Now().Add(-c.config.ExporterIdleTimeout)
+				c.exporters.DeleteLastSeenBefore(before)
+				c.expireLearnedSamplingRates(before)
+				c.d.Flow.ExpireExporterStats(c.config.ExporterIdleTimeout)
+			}
+		}
+	})
+
 	c.r.RegisterHealthcheck("core", c.channelHealthcheck())
 	c.d.HTTP.GinRouter.GET("/api/v0/inlet/flows", c.FlowsHTTPHandler)
+	c.d.HTTP.GinRouter.GET("/api/v0/inlet/exporters", c.ExportersHTTPHandler)
 	return nil
 }
 
@@ -121,29 +259,64 @@ func (c *Component) runWorker(workerID int) error {
 				return nil
 			}
 
+			if !flow.QueuedAt.IsZero() {
+				c.metrics.queueWait.Observe(time.Since(flow.QueuedAt).Seconds())
+			}
 			exporter := flow.ExporterAddress.Unmap().String()
 			c.metrics.flowsReceived.WithLabelValues(exporter).Inc()
 
 			// Enrichment
 			ip := flow.ExporterAddress
-			if skip := c.enrichFlow(ip, exporter, flow); skip {
+			kafkaRoute, skip := c.enrichFlow(ip, exporter, flow)
+			c.exporters.Observe(c.d.Clock.Now(), ip, flow.SamplingRate)
+			if skip {
 				continue
 			}
 
+			// Anonymize addresses once enrichment does not need them anymore
+			if c.anonymizer != nil {
+				c.anonymizeFlow(flow)
+			}
+
 			// Serialize flow to Protobuf
 			buf := c.d.Schema.ProtobufMarshal(flow)
 
-			// Forward to Kafka. This could block and buf is now owned by the
-			// Kafka subsystem!
-			c.metrics.flowsForwarded.WithLabelValues(exporter).Inc()
-			c.d.Kafka.Send(exporter, buf)
+			if c.config.DryRun {
+				c.metrics.flowsDryRun.WithLabelValues(exporter).Inc()
+				if c.config.DryRunLoggedFlows > 0 {
+					logged := flow
+					if len(c.config.HTTPFlowsMaskedColumns) > 0 {
+						logged = c.maskFlow(flow)
+					}
+					event := c.dryRunLogger.Info()
+					if !c.maskExporter {
+						event = event.Str("exporter", exporter)
+					}
+					event.Int("size", len(buf)).
+						Interface("flow", logged).
+						Msg("dry-run flow")
+				}
+			} else {
+				// Forward to Kafka. This could block and buf is now owned by the
+				// Kafka subsystem!
+				if c.d.Kafka.SendRouted(kafkaRoute, buf) {
+					c.metrics.flowsForwarded.WithLabelValues(exporter).Inc()
+				}
+				if !flow.ReceivedAt.IsZero() {
+					c.metrics.pipelineLatency.Observe(time.Since(flow.ReceivedAt).Seconds())
+				}
+			}
 
 			// If we have HTTP clients, send to them too
 			if atomic.LoadUint32(&c.httpFlowClients) > 0 {
-				select {
-				case c.httpFlowChannel <- flow: // OK
-				default: // Overflow, best effort and ignore
+				c.httpFlowLock.RLock()
+				for subscriber := range c.httpFlowSubscribers {
+					select {
+					case subscriber <- flow: // OK
+					default: // Overflow, best effort and ignore
+					}
 				}
+				c.httpFlowLock.RUnlock()
 			}
 
 		}
@@ -153,7 +326,6 @@ func (c *Component) runWorker(workerID int) error {
 // Stop stops the core component.
 func (c *Component) Stop() error {
 	defer func() {
-		close(c.httpFlowChannel)
 		close(c.healthy)
 		c.r.Info().Msg("core component stopped")
 	}()
@@ -162,6 +334,34 @@ func (c *Component) Stop() error {
 	return c.t.Wait()
 }
 
+// Reload applies a new configuration. Only the settings related to the
+// sampling rate can be changed without a restart.
+func (c *Component) Reload(configuration Configuration) error {
+	current, wanted := c.config, configuration
+	for _, config := range []*Configuration{&current, &wanted} {
+		config.DefaultSamplingRate = helpers.SubnetMap[uint]{}
+		config.OverrideSamplingRate = helpers.SubnetMap[uint]{}
+		config.SamplingRateUnit = helpers.SubnetMap[SamplingRateUnit]{}
+	}
+	currentYAML, err := yaml.Marshal(current)
+	if err != nil {
+		return err
+	}
+	wantedYAML, err := yaml.Marshal(wanted)
+	if err != nil {
+		return err
+	}
+	if string(currentYAML) != string(wantedYAML) {
+		return errors.New("only default-sampling-rate, override-sampling-rate, and sampling-rate-unit can be changed without a restart")
+	}
+	c.samplingConfig.Store(&samplingConfiguration{
+		DefaultSamplingRate:  configuration.DefaultSamplingRate,
+		OverrideSamplingRate: configuration.OverrideSamplingRate,
+		SamplingRateUnit:     configuration.SamplingRateUnit,
+	})
+	return nil
+}
+
 func (c *Component) channelHealthcheck() reporter.HealthcheckFunc {
 	return reporter.ChannelHealthcheck(c.t.Context(nil), c.healthy)
 }
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/benbjohnson/clock"
 	"github.com/gin-gonic/gin"
 
 	"akvorado/common/daemon"
@@ -111,15 +112,20 @@ func TestCore(t *testing.T) {
 		flowComponent.Inject(flowMessage("192.0.2.143", 434, 679))
 
 		time.Sleep(20 * time.Millisecond)
-		gotMetrics := r.GetMetrics("akvorado_inlet_core_", "-flows_processing_")
+		gotMetrics := r.GetMetrics("akvorado_inlet_core_", "-flows_processing_", "-queue_wait_", "-pipeline_latency_")
 		expectedMetrics := map[string]string{
-			`classifier_exporter_cache_size_items`:                               "0",
-			`classifier_interface_cache_size_items`:                              "0",
-			`flows_errors_total{error="SNMP cache miss",exporter="192.0.2.142"}`: "1",
-			`flows_errors_total{error="SNMP cache miss",exporter="192.0.2.143"}`: "3",
-			`received_flows_total{exporter="192.0.2.142"}`:                       "1",
-			`received_flows_total{exporter="192.0.2.143"}`:                       "3",
-			`flows_http_clients`:                                                 "0",
+			`anonymization_enabled{method="none"}`:                                       "0",
+			`classifier_exporter_cache_size_items`:                                       "0",
+			`classifier_interface_cache_size_items`:                                      "0",
+			`customers_errors_total`:                                                     "0",
+			`customers_prefixes`:                                                         "0",
+			`flows_errors_total{error="SNMP cache miss",exporter="192.0.2.142"}`:         "1",
+			`flows_errors_total{error="SNMP cache miss",exporter="192.0.2.143"}`:         "3",
+			`received_flows_total{exporter="192.0.2.142"}`:                               "1",
+			`received_flows_total{exporter="192.0.2.143"}`:                               "3",
+			`flows_http_clients`:                                                         "0",
+			`sampling_rate_source_flows_total{exporter="192.0.2.142",source="reported"}`: "1",
+			`sampling_rate_source_flows_total{exporter="192.0.2.143",source="reported"}`: "3",
 		}
 		if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 			t.Fatalf("Metrics (-got, +want):\n%s", diff)
@@ -200,6 +206,31 @@ func TestCore(t *testing.T) {
 		}
 	})
 
+	// Test latency metrics
+	t.Run("latency", func(t *testing.T) {
+		input := flowMessage("192.0.2.142", 434, 677)
+		input.ReceivedAt = time.Now().Add(-2 * time.Second)
+		input.QueuedAt = time.Now().Add(-200 * time.Millisecond)
+		kafkaProducer.ExpectInputAndSucceed()
+		flowComponent.Inject(input)
+		time.Sleep(20 * time.Millisecond)
+
+		gotMetrics := r.GetMetrics("akvorado_inlet_core_", "queue_wait_seconds_count", "pipeline_latency_seconds_count",
+			`queue_wait_seconds_bucket{le="0.1"}`, `queue_wait_seconds_bucket{le="1"}`,
+			`pipeline_latency_seconds_bucket{le="1"}`, `pipeline_latency_seconds_bucket{le="5"}`)
+		expectedMetrics := map[string]string{
+			`queue_wait_seconds_bucket{le="0.1"}`:     "0",
+			`queue_wait_seconds_bucket{le="1"}`:       "1",
+			`queue_wait_seconds_count`:                "1",
+			`pipeline_latency_seconds_bucket{le="1"}`: "0",
+			`pipeline_latency_seconds_bucket{le="5"}`: "1",
+			`pipeline_latency_seconds_count`:          "1",
+		}
+		if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+			t.Fatalf("Metrics (-got, +want):\n%s", diff)
+		}
+	})
+
 	// Test the healthcheck function
 	t.Run("healthcheck", func(t *testing.T) {
 		got := r.RunHealthchecks(context.Background())
@@ -264,6 +295,11 @@ func TestCore(t *testing.T) {
 				"DstVlan":    0,
 				"GotASPath":  false,
 				"DstAS":      0,
+
+				"InIfName":         "",
+				"InIfDescription":  "",
+				"OutIfName":        "",
+				"OutIfDescription": "",
 			}
 			if diff := helpers.Diff(got, expected); diff != "" {
 				t.Fatalf("GET /api/v0/inlet/flows (-got, +want):\n%s", diff)
@@ -320,6 +356,47 @@ func TestCore(t *testing.T) {
 		}
 	})
 
+	// Test several HTTP flow clients at the same time
+	time.Sleep(10 * time.Millisecond)
+	t.Run("http flows with several clients", func(t *testing.T) {
+		readers := []*bufio.Reader{}
+		for range 2 {
+			resp, err := http.Get(fmt.Sprintf("http://%s/api/v0/inlet/flows?limit=4", c.d.HTTP.LocalAddr()))
+			if err != nil {
+				t.Fatalf("GET /api/v0/inlet/flows:\n%+v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatalf("GET /api/v0/inlet/flows status code %d", resp.StatusCode)
+			}
+			readers = append(readers, bufio.NewReader(resp.Body))
+		}
+
+		// Produce some flows
+		for range 6 {
+			kafkaProducer.ExpectInputAndSucceed()
+			flowComponent.Inject(flowMessage("192.0.2.142", 434, 677))
+		}
+
+		// Each client should get 4 flows
+		for idx, reader := range readers {
+			count := 0
+			for {
+				_, err := reader.ReadString('\n')
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("GET /api/v0/inlet/flows error while reading:\n%+v", err)
+				}
+				count++
+			}
+			if count != 4 {
+				t.Fatalf("GET /api/v0/inlet/flows for client %d got %d flows, expected 4", idx, count)
+			}
+		}
+	})
+
 	// Test HTTP flow clients using protobuf
 	time.Sleep(10 * time.Millisecond)
 	t.Run("http flows with protobuf", func(t *testing.T) {
@@ -359,4 +436,161 @@ func TestCore(t *testing.T) {
 			t.Errorf("HTTP message (-got, +want):\n%s", diff)
 		}
 	})
+
+	// Test HTTP flow clients using SSE with an exporter filter
+	time.Sleep(10 * time.Millisecond)
+	t.Run("http flows with SSE", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet,
+			fmt.Sprintf("http://%s/api/v0/inlet/flows?limit=2&exporter=192.0.2.143", c.d.HTTP.LocalAddr()), nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest() error:\n%+v", err)
+		}
+		req.Header.Set("accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/v0/inlet/flows:\n%+v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("GET /api/v0/inlet/flows status code %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Fatalf("GET /api/v0/inlet/flows content type %q", got)
+		}
+
+		// Produce some flows, only some of them from the right exporter
+		for i := range 6 {
+			kafkaProducer.ExpectInputAndSucceed()
+			if i%2 == 0 {
+				flowComponent.Inject(flowMessage("192.0.2.142", 434, 677))
+			} else {
+				flowComponent.Inject(flowMessage("192.0.2.143", 434, 677))
+			}
+		}
+
+		// Check the events
+		reader := bufio.NewReader(resp.Body)
+		count := 0
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("GET /api/v0/inlet/flows error while reading:\n%+v", err)
+			}
+			if line == "event:flow\n" {
+				count++
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			var got struct{ ExporterAddress netip.Addr }
+			if err := json.Unmarshal([]byte(data), &got); err != nil {
+				t.Fatalf("GET /api/v0/inlet/flows cannot decode event:\n%+v", err)
+			}
+			if got.ExporterAddress.Unmap() != netip.MustParseAddr("192.0.2.143") {
+				t.Fatalf("GET /api/v0/inlet/flows got flow from %s", got.ExporterAddress)
+			}
+		}
+		if count != 2 {
+			t.Fatalf("GET /api/v0/inlet/flows got %d events instead of 2", count)
+		}
+	})
+
+	t.Run("http flows with invalid exporter", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/api/v0/inlet/flows?exporter=nope", c.d.HTTP.LocalAddr()))
+		if err != nil {
+			t.Fatalf("GET /api/v0/inlet/flows:\n%+v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 400 {
+			t.Fatalf("GET /api/v0/inlet/flows status code %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestClassifierCacheExpiration(t *testing.T) {
+	r := reporter.NewMock(t)
+	mockClock := clock.NewMock()
+	configuration := DefaultConfiguration()
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Flow:   flow.NewMock(t, r, flow.DefaultConfiguration()),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: schema.NewMock(t),
+		Clock:  mockClock,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	c.classifierExporterCache.Put(mockClock.Now(),
+		exporterInfo{IP: "192.0.2.1", Name: "exporter1"},
+		exporterClassification{Region: "europe"})
+	helpers.StartStop(t, c)
+
+	time.Sleep(10 * time.Millisecond)
+	mockClock.Add(configuration.ClassifierCacheDuration / 2)
+	time.Sleep(10 * time.Millisecond)
+	if size := c.classifierExporterCache.Size(); size != 1 {
+		t.Fatalf("classifierExporterCache.Size() == %d, expected 1", size)
+	}
+	// Two ticks are needed for the entry to be old enough
+	for range 2 {
+		mockClock.Add(configuration.ClassifierCacheDuration)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if size := c.classifierExporterCache.Size(); size != 0 {
+		t.Fatalf("classifierExporterCache.Size() == %d, expected 0", size)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	r := reporter.NewMock(t)
+	daemonComponent := daemon.NewMock(t)
+	flowComponent := flow.NewMock(t, r, flow.DefaultConfiguration())
+	// No message is expected by the Kafka mock
+	kafkaComponent, _ := kafka.NewMock(t, r, kafka.DefaultConfiguration())
+	configuration := DefaultConfiguration()
+	configuration.DryRun = true
+	configuration.DryRunLoggedFlows = 1
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemonComponent,
+		Flow:   flowComponent,
+		Metadata: metadata.NewMock(t, r, metadata.DefaultConfiguration(),
+			metadata.Dependencies{Daemon: daemonComponent}),
+		Kafka:   kafkaComponent,
+		HTTP:    httpserver.NewMock(t, r),
+		Routing: routing.NewMock(t, r),
+		Schema:  schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	flowMessage := func() *schema.FlowMessage {
+		return &schema.FlowMessage{
+			SamplingRate:    1000,
+			ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+			InIf:            434,
+			OutIf:           677,
+		}
+	}
+	// The first flow is a cache miss
+	flowComponent.Inject(flowMessage())
+	time.Sleep(20 * time.Millisecond)
+	flowComponent.Inject(flowMessage())
+	time.Sleep(20 * time.Millisecond)
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_core_", "received_", "forwarded_", "dry_run_")
+	expectedMetrics := map[string]string{
+		`received_flows_total{exporter="192.0.2.142"}`: "2",
+		`dry_run_flows_total{exporter="192.0.2.142"}`:  "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
 }
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// samplingRateLearningThreshold is the number of consecutive flows with the
+// same sampling rate we need to see before considering the sampling rate as
+// learned for an exporter.
+const samplingRateLearningThreshold = 10
+
+// samplingRateLearner keeps track of the sampling rates reported by each
+// exporter to be able to use them when a flow does not come with one.
+type samplingRateLearner struct {
+	lock      sync.RWMutex
+	exporters map[string]*learnedSamplingRate
+}
+
+// learnedSamplingRate is the learning state for one exporter.
+type learnedSamplingRate struct {
+	candidate uint32 // last reported sampling rate
+	count     uint   // number of consecutive flows with candidate rate
+	learned   uint32 // learned sampling rate (0 if none)
+	lastSeen  atomic.Int64
+}
+
+func newSamplingRateLearner() *samplingRateLearner {
+	return &samplingRateLearner{
+		exporters: make(map[string]*learnedSamplingRate),
+	}
+}
+
+// Observe records a sampling rate reported by an exporter. It returns true
+// if the learned sampling rate has changed.
+func (l *samplingRateLearner) Observe(now time.Time, exporter string, samplingRate uint32) bool {
+	// Fast path: nothing to change. The candidate has to match too, otherwise
+	// the consecutive count of another candidate has to be reset.
+	l.lock.RLock()
+	state, ok := l.exporters[exporter]
+	if ok && state.learned == samplingRate && state.candidate == samplingRate {
+		state.lastSeen.Store(now.UnixNano())
+		l.lock.RUnlock()
+		return false
+	}
+	l.lock.RUnlock()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state, ok = l.exporters[exporter]
+	if !ok {
+		state = &learnedSamplingRate{}
+		l.exporters[exporter] = state
+	}
+	state.lastSeen.Store(now.UnixNano())
+	if state.learned != 0 && state.learned == samplingRate {
+		// Back to the learned sampling rate
+		state.candidate = samplingRate
+		state.count = samplingRateLearningThreshold
+		return false
+	}
+	if state.candidate != samplingRate {
+		state.candidate = samplingRate
+		state.count = 0
+	}
+	if state.count < samplingRateLearningThreshold {
+		state.count++
+	}
+	if state.count == samplingRateLearningThreshold && state.learned != samplingRate {
+		state.learned = samplingRate
+		return true
+	}
+	return false
+}
+
+// Lookup returns the learned sampling rate for an exporter.
+func (l *samplingRateLearner) Lookup(exporter string) (uint32, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	state, ok := l.exporters[exporter]
+	if !ok || state.learned == 0 {
+		return 0, false
+	}
+	return state.learned, true
+}
+
+// DeleteLastSeenBefore forgets the exporters which did not report a sampling
+// rate since the provided time. It returns the removed exporters.
+func (l *samplingRateLearner) DeleteLastSeenBefore(before time.Time) []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	deleted := []string{}
+	for exporter, state := range l.exporters {
+		if state.lastSeen.Load() < before.UnixNano() {
+			delete(l.exporters, exporter)
+			deleted = append(deleted, exporter)
+		}
+	}
+	return deleted
+}
+
+// learnSamplingRate records the sampling rate reported by an exporter, if
+// learning is enabled.
+func (c *Component) learnSamplingRate(exporter string, samplingRate uint32) {
+	if !c.config.LearnSamplingRate {
+		return
+	}
+	if c.samplingRates.Observe(c.d.Clock.Now(), exporter, samplingRate) {
+		c.metrics.learnedSamplingRate.WithLabelValues(exporter).Set(float64(samplingRate))
+	}
+}
+
+// learnedSamplingRate returns the sampling rate learned for an exporter, if
+// learning is enabled. As this is an estimation, flows using it are counted.
+func (c *Component) learnedSamplingRate(exporter string) (uint32, bool) {
+	if !c.config.LearnSamplingRate {
+		return 0, false
+	}
+	samplingRate, ok := c.samplingRates.Lookup(exporter)
+	if ok {
+		c.metrics.estimatedSamplingRate.WithLabelValues(exporter).Inc()
+	}
+	return samplingRate, ok
+}
+
+// expireLearnedSamplingRates forgets the sampling rates of idle exporters.
+func (c *Component) expireLearnedSamplingRates(before time.Time) {
+	for _, exporter := range c.samplingRates.DeleteLastSeenBefore(before) {
+		c.metrics.learnedSamplingRate.DeleteLabelValues(exporter)
+	}
+}
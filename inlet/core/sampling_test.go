@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestSamplingRateLearner(t *testing.T) {
+	l := newSamplingRateLearner()
+	now := time.Now()
+	if _, ok := l.Lookup("192.0.2.1"); ok {
+		t.Fatal("Lookup() should not have learned anything")
+	}
+
+	// Not enough observations
+	for range samplingRateLearningThreshold - 1 {
+		if l.Observe(now, "192.0.2.1", 1000) {
+			t.Fatal("Observe() should not have learned anything")
+		}
+	}
+	if _, ok := l.Lookup("192.0.2.1"); ok {
+		t.Fatal("Lookup() should not have learned anything")
+	}
+
+	// A different sampling rate resets the counter
+	l.Observe(now, "192.0.2.1", 2000)
+	for range samplingRateLearningThreshold - 1 {
+		l.Observe(now, "192.0.2.1", 1000)
+	}
+	if _, ok := l.Lookup("192.0.2.1"); ok {
+		t.Fatal("Lookup() should not have learned anything")
+	}
+	if !l.Observe(now, "192.0.2.1", 1000) {
+		t.Fatal("Observe() should have learned the sampling rate")
+	}
+	if got, ok := l.Lookup("192.0.2.1"); !ok || got != 1000 {
+		t.Fatalf("Lookup() == %d, %v, expected 1000, true", got, ok)
+	}
+	if l.Observe(now, "192.0.2.1", 1000) {
+		t.Fatal("Observe() should not have changed the learned sampling rate")
+	}
+
+	// Other exporter
+	if _, ok := l.Lookup("192.0.2.2"); ok {
+		t.Fatal("Lookup() should not have learned anything")
+	}
+
+	// Switch to another sampling rate
+	for range samplingRateLearningThreshold - 1 {
+		l.Observe(now, "192.0.2.1", 500)
+	}
+	if got, _ := l.Lookup("192.0.2.1"); got != 1000 {
+		t.Fatalf("Lookup() == %d, expected 1000", got)
+	}
+	if !l.Observe(now, "192.0.2.1", 500) {
+		t.Fatal("Observe() should have learned the new sampling rate")
+	}
+	if got, _ := l.Lookup("192.0.2.1"); got != 500 {
+		t.Fatalf("Lookup() == %d, expected 500", got)
+	}
+
+	// The learned sampling rate interrupts the learning of another one
+	for range samplingRateLearningThreshold - 1 {
+		l.Observe(now, "192.0.2.1", 250)
+	}
+	l.Observe(now, "192.0.2.1", 500)
+	if l.Observe(now, "192.0.2.1", 250) {
+		t.Fatal("Observe() should not have learned a non-consecutive sampling rate")
+	}
+	if got, _ := l.Lookup("192.0.2.1"); got != 500 {
+		t.Fatalf("Lookup() == %d, expected 500", got)
+	}
+
+	// Expiration
+	l.Observe(now.Add(time.Minute), "192.0.2.2", 1000)
+	deleted := l.DeleteLastSeenBefore(now.Add(30 * time.Second))
+	if diff := helpers.Diff(deleted, []string{"192.0.2.1"}); diff != "" {
+		t.Fatalf("DeleteLastSeenBefore() (-got, +want):\n%s", diff)
+	}
+	if _, ok := l.Lookup("192.0.2.1"); ok {
+		t.Fatal("Lookup() should have forgotten the sampling rate")
+	}
+	if len(l.exporters) != 1 {
+		t.Fatalf("DeleteLastSeenBefore() kept %d exporters, expected 1", len(l.exporters))
+	}
+}
+
+func TestLearnSamplingRate(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		r := reporter.NewMock(t)
+		configuration := DefaultConfiguration()
+		configuration.LearnSamplingRate = enabled
+		c, err := New(r, configuration, Dependencies{
+			Daemon: daemon.NewMock(t),
+			Schema: schema.NewMock(t),
+		})
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		for range samplingRateLearningThreshold {
+			c.learnSamplingRate("192.0.2.1", 1000)
+		}
+		got, ok := c.learnedSamplingRate("192.0.2.1")
+		if ok != enabled {
+			t.Fatalf("learnedSamplingRate() == %d, %v, expected %v", got, ok, enabled)
+		}
+		if !enabled {
+			continue
+		}
+		if got != 1000 {
+			t.Fatalf("learnedSamplingRate() == %d, expected 1000", got)
+		}
+		gotMetrics := r.GetMetrics("akvorado_inlet_core_", "learned_", "estimated_")
+		expectedMetrics := map[string]string{
+			`learned_sampling_rate{exporter="192.0.2.1"}`:               "1000",
+			`estimated_sampling_rate_flows_total{exporter="192.0.2.1"}`: "1",
+		}
+		if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+			t.Fatalf("Metrics (-got, +want):\n%s", diff)
+		}
+
+		c.expireLearnedSamplingRates(time.Now().Add(time.Minute))
+		if _, ok := c.learnedSamplingRate("192.0.2.1"); ok {
+			t.Fatal("learnedSamplingRate() should have expired")
+		}
+		gotMetrics = r.GetMetrics("akvorado_inlet_core_", "learned_")
+		if diff := helpers.Diff(gotMetrics, map[string]string{}); diff != "" {
+			t.Fatalf("Metrics after expiration (-got, +want):\n%s", diff)
+		}
+	}
+}
+
+func TestReloadSamplingRate(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.OverrideSamplingRate = *helpers.MustNewSubnetMap(map[string]uint{
+		"192.0.2.0/24": 1000,
+	})
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	exporter := netip.MustParseAddr("::ffff:192.0.2.1")
+	if got, _ := c.samplingConfig.Load().OverrideSamplingRate.Lookup(exporter); got != 1000 {
+		t.Fatalf("Lookup() == %d, expected 1000", got)
+	}
+
+	// Change of sampling rate
+	configuration.OverrideSamplingRate = *helpers.MustNewSubnetMap(map[string]uint{
+		"192.0.2.0/24": 2000,
+	})
+	if err := c.Reload(configuration); err != nil {
+		t.Fatalf("Reload() error:\n%+v", err)
+	}
+	if got, _ := c.samplingConfig.Load().OverrideSamplingRate.Lookup(exporter); got != 2000 {
+		t.Fatalf("Lookup() == %d, expected 2000", got)
+	}
+
+	// Change of something else
+	configuration.Workers = 10
+	configuration.OverrideSamplingRate = helpers.SubnetMap[uint]{}
+	if err := c.Reload(configuration); err == nil {
+		t.Fatal("Reload() did not error")
+	}
+	if got, _ := c.samplingConfig.Load().OverrideSamplingRate.Lookup(exporter); got != 2000 {
+		t.Fatalf("Lookup() == %d, expected 2000", got)
+	}
+}
+
+func TestSamplingRateSource(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.LearnSamplingRate = true
+	configuration.OverrideSamplingRate = *helpers.MustNewSubnetMap(map[string]uint{
+		"192.0.2.0/30": 1000,
+	})
+	configuration.DefaultSamplingRate = *helpers.MustNewSubnetMap(map[string]uint{
+		"192.0.2.4/30": 2000,
+	})
+	c, err := New(r, configuration, Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	for range samplingRateLearningThreshold {
+		c.learnSamplingRate("192.0.2.9", 4000)
+	}
+
+	cases := []struct {
+		Exporter     string
+		SamplingRate uint32
+		Expected     uint32
+	}{
+		{"192.0.2.1", 0, 1000},
+		{"192.0.2.1", 500, 1000},
+		{"192.0.2.5", 0, 2000},
+		{"192.0.2.5", 500, 500},
+		{"192.0.2.9", 0, 4000},
+		{"192.0.2.13", 0, 0},
+	}
+	for _, tc := range cases {
+		flow := &schema.FlowMessage{SamplingRate: tc.SamplingRate}
+		c.enrichFlow(netip.MustParseAddr("::ffff:"+tc.Exporter), tc.Exporter, flow)
+		if flow.SamplingRate != tc.Expected {
+			t.Errorf("enrichFlow(%s, %d) sampling rate == %d, expected %d",
+				tc.Exporter, tc.SamplingRate, flow.SamplingRate, tc.Expected)
+		}
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_core_", "sampling_rate_source_")
+	expectedMetrics := map[string]string{
+		`sampling_rate_source_flows_total{exporter="192.0.2.1",source="override"}`: "2",
+		`sampling_rate_source_flows_total{exporter="192.0.2.5",source="default"}`:  "1",
+		`sampling_rate_source_flows_total{exporter="192.0.2.5",source="reported"}`: "1",
+		`sampling_rate_source_flows_total{exporter="192.0.2.9",source="learned"}`:  "1",
+		`sampling_rate_source_flows_total{exporter="192.0.2.13",source="missing"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
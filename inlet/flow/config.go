@@ -10,6 +10,7 @@ import (
 	"akvorado/inlet/flow/decoder"
 	"akvorado/inlet/flow/input"
 	"akvorado/inlet/flow/input/file"
+	"akvorado/inlet/flow/input/grpc"
 	"akvorado/inlet/flow/input/udp"
 )
 
@@ -18,8 +19,15 @@ type Configuration struct {
 	// Inputs define a list of input modules to enable
 	Inputs []InputConfiguration `validate:"dive"`
 	// RateLimit defines a rate limit on the number of flows per
-	// second. The limit is per-exporter.
-	RateLimit rate.Limit `validate:"isdefault|min=100"`
+	// second. The limit is per-exporter. It can be a single value or a map
+	// from subnets to values. Exporters without a limit are not limited.
+	RateLimit helpers.SubnetMap[rate.Limit]
+	// RecentDecodeErrors is the number of recent decoding errors to keep
+	// for the HTTP endpoint. Use 0 to disable.
+	RecentDecodeErrors uint
+	// SplitBiflows tells to split IPFIX biflows (RFC 5103) into two
+	// unidirectional flows. When disabled, only the forward direction is kept.
+	SplitBiflows bool
 }
 
 // DefaultConfiguration represents the default configuration for the flow component
@@ -34,6 +42,8 @@ func DefaultConfiguration() Configuration {
 			Decoder:         "sflow",
 			Config:          udp.DefaultConfiguration(),
 		}},
+		RecentDecodeErrors: 100,
+		SplitBiflows:       true,
 	}
 }
 
@@ -58,9 +68,11 @@ func (ic InputConfiguration) MarshalYAML() (interface{}, error) {
 var inputs = map[string](func() input.Configuration){
 	"udp":  udp.DefaultConfiguration,
 	"file": file.DefaultConfiguration,
+	"grpc": grpc.DefaultConfiguration,
 }
 
 func init() {
 	helpers.RegisterMapstructureUnmarshallerHook(
 		helpers.ParametrizedConfigurationUnmarshallerHook(InputConfiguration{}, inputs))
+	helpers.RegisterMapstructureUnmarshallerHook(helpers.SubnetMapUnmarshallerHook[rate.Limit]())
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
 
 	"akvorado/common/helpers/yaml"
 
@@ -262,6 +263,42 @@ func TestDecodeConfiguration(t *testing.T) {
 					},
 				}},
 			},
+		}, {
+			Description: "rate limit as a single value",
+			Initial:     func() interface{} { return Configuration{} },
+			Configuration: func() interface{} {
+				return gin.H{
+					"inputs":     []gin.H{{"type": "file", "paths": []string{"file1"}}},
+					"rate-limit": 1000,
+				}
+			},
+			Expected: Configuration{
+				Inputs: []InputConfiguration{{
+					Config: &file.Configuration{Paths: []string{"file1"}},
+				}},
+				RateLimit: *helpers.MustNewSubnetMap(map[string]rate.Limit{"::/0": 1000}),
+			},
+		}, {
+			Description: "rate limit per subnet",
+			Initial:     func() interface{} { return Configuration{} },
+			Configuration: func() interface{} {
+				return gin.H{
+					"inputs": []gin.H{{"type": "file", "paths": []string{"file1"}}},
+					"rate-limit": gin.H{
+						"192.0.2.0/24":  1000,
+						"2001:db8::/64": 500,
+					},
+				}
+			},
+			Expected: Configuration{
+				Inputs: []InputConfiguration{{
+					Config: &file.Configuration{Paths: []string{"file1"}},
+				}},
+				RateLimit: *helpers.MustNewSubnetMap(map[string]rate.Limit{
+					"192.0.2.0/24":  1000,
+					"2001:db8::/64": 500,
+				}),
+			},
 		},
 	})
 }
@@ -293,7 +330,9 @@ func TestMarshalYAML(t *testing.T) {
 		t.Fatalf("Marshal() error:\n%+v", err)
 	}
 	expected := `inputs:
-    - decoder: netflow
+    - additionallisten: []
+      binddevice: ""
+      decoder: netflow
       listen: 192.0.2.11:2055
       queuesize: 1000
       receivebuffer: 0
@@ -301,7 +340,9 @@ func TestMarshalYAML(t *testing.T) {
       type: udp
       usesrcaddrforexporteraddr: false
       workers: 3
-    - decoder: sflow
+    - additionallisten: []
+      binddevice: ""
+      decoder: sflow
       listen: 192.0.2.11:6343
       queuesize: 1000
       receivebuffer: 0
@@ -309,9 +350,50 @@ func TestMarshalYAML(t *testing.T) {
       type: udp
       usesrcaddrforexporteraddr: true
       workers: 3
-ratelimit: 0
+ratelimit: {}
+recentdecodeerrors: 0
+splitbiflows: false
 `
 	if diff := helpers.Diff(strings.Split(string(got), "\n"), strings.Split(expected, "\n")); diff != "" {
 		t.Fatalf("Marshal() (-got, +want):\n%s", diff)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	udpConfig := func(listen string, additional ...string) InputConfiguration {
+		return InputConfiguration{
+			Decoder: "netflow",
+			Config: &udp.Configuration{
+				Listen:           listen,
+				AdditionalListen: additional,
+				Workers:          1,
+			},
+		}
+	}
+	cases := []struct {
+		Description string
+		Inputs      []InputConfiguration
+		Error       string
+	}{
+		{"distinct", []InputConfiguration{udpConfig(":2055"), udpConfig(":6343")}, ""},
+		{"random ports", []InputConfiguration{udpConfig(":0"), udpConfig(":0")}, ""},
+		{"duplicate", []InputConfiguration{udpConfig(":2055"), udpConfig(":2055")},
+			`inputs[1].listen: ":2055" already used by inputs[0].listen`},
+		{"duplicate additional", []InputConfiguration{udpConfig(":2055", ":2056"), udpConfig(":6343", ":2056", ":2055")},
+			`inputs[1].additional-listen[0]: ":2056" already used by inputs[0].additional-listen[0]
+inputs[1].additional-listen[1]: ":2055" already used by inputs[0].listen`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c := Component{config: Configuration{Inputs: tc.Inputs}}
+			err := c.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Error); diff != "" {
+				t.Fatalf("Validate() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
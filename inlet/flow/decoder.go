@@ -4,11 +4,14 @@
 package flow
 
 import (
+	"fmt"
 	"net/netip"
+	"time"
 
 	"akvorado/common/schema"
 	"akvorado/inlet/flow/decoder"
 	"akvorado/inlet/flow/decoder/netflow"
+	"akvorado/inlet/flow/decoder/protobuf"
 	"akvorado/inlet/flow/decoder/sflow"
 )
 
@@ -22,15 +25,26 @@ type wrappedDecoder struct {
 func (wd *wrappedDecoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 	defer func() {
 		if r := recover(); r != nil {
+			wd.c.errLogger.Error().
+				Str("decoder", wd.orig.Name()).
+				Str("panic", fmt.Sprintf("%+v", r)).
+				Msg("panic while decoding flow")
 			wd.c.metrics.decoderErrors.WithLabelValues(wd.orig.Name()).
 				Inc()
+			wd.c.recordDecoding(decoder.DecodeIP(in.Source), wd.orig.Name(), in.Payload, false)
+			wd.c.recordDecodeError(wd.orig.Name(), in, fmt.Errorf("panic: %v", r))
 		}
 	}()
+	if in.TimeReceived.IsZero() {
+		// Inputs without a reception time (like the file input) rely on our clock
+		in.TimeReceived = wd.c.d.Clock.Now()
+	}
 	decoded := wd.orig.Decode(in)
 
 	if decoded == nil {
 		wd.c.metrics.decoderErrors.WithLabelValues(wd.orig.Name()).
 			Inc()
+		wd.c.recordDecoding(decoder.DecodeIP(in.Source), wd.orig.Name(), in.Payload, false)
 		return nil
 	}
 
@@ -41,8 +55,24 @@ func (wd *wrappedDecoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 		}
 	}
 
+	// Decoding statistics are keyed on the exporter address, like the
+	// exporter inventory of the core component. Packets without flows (like
+	// templates) are attributed to the source address.
+	exporter := decoder.DecodeIP(in.Source)
+	if len(decoded) > 0 && decoded[0].ExporterAddress.IsValid() {
+		exporter = decoded[0].ExporterAddress
+	}
+
+	// Decoded flows are about to be put in the input queue.
+	now := time.Now()
+	for _, f := range decoded {
+		f.ReceivedAt = in.TimeReceived
+		f.QueuedAt = now
+	}
+
 	wd.c.metrics.decoderStats.WithLabelValues(wd.orig.Name()).
 		Inc()
+	wd.c.recordDecoding(exporter, wd.orig.Name(), in.Payload, true)
 	return decoded
 }
 
@@ -61,6 +91,7 @@ func (c *Component) wrapDecoder(d decoder.Decoder, useSrcAddrForExporterAddr boo
 }
 
 var decoders = map[string]decoder.NewDecoderFunc{
-	"netflow": netflow.New,
-	"sflow":   sflow.New,
+	"netflow":  netflow.New,
+	"sflow":    sflow.New,
+	"protobuf": protobuf.New,
 }
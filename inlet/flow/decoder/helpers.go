@@ -83,6 +83,17 @@ func ParseL4(sch *schema.Component, bf *schema.FlowMessage, data []byte, proto u
 				uint64(binary.BigEndian.Uint16(data[2:4])))
 		}
 	}
+	if !sch.IsDisabled(schema.ColumnGroupInner) {
+		if proto == 17 && len(data) > 8 && binary.BigEndian.Uint16(data[2:4]) == 4789 {
+			// VXLAN
+			if len(data) > 16 {
+				parseInnerEthernet(sch, bf, data[16:])
+			}
+		} else if proto == 47 {
+			// GRE
+			parseInnerGRE(sch, bf, data)
+		}
+	}
 	if !sch.IsDisabled(schema.ColumnGroupL3L4) {
 		if proto == 6 {
 			// TCP
@@ -164,6 +175,99 @@ func ParseEthernet(sch *schema.Component, bf *schema.FlowMessage, data []byte) u
 	return 0
 }
 
+// parseInnerGRE parses a GRE header and the encapsulated packet.
+func parseInnerGRE(sch *schema.Component, bf *schema.FlowMessage, data []byte) {
+	if len(data) < 4 {
+		return
+	}
+	flags := binary.BigEndian.Uint16(data[0:2])
+	if flags&0x7 != 0 {
+		// Only version 0 is supported
+		return
+	}
+	offset := 4
+	for _, flag := range []uint16{0x8000, 0x2000, 0x1000} {
+		// Checksum, key and sequence number
+		if flags&flag != 0 {
+			offset += 4
+		}
+	}
+	if len(data) < offset {
+		return
+	}
+	switch binary.BigEndian.Uint16(data[2:4]) {
+	case 0x6558:
+		parseInnerEthernet(sch, bf, data[offset:])
+	case helpers.ETypeIPv4, helpers.ETypeIPv6:
+		parseInnerIP(sch, bf, data[offset:])
+	}
+}
+
+// parseInnerEthernet parses an encapsulated Ethernet frame.
+func parseInnerEthernet(sch *schema.Component, bf *schema.FlowMessage, data []byte) {
+	if len(data) < 14 {
+		return
+	}
+	etherType := binary.BigEndian.Uint16(data[12:14])
+	data = data[14:]
+	if etherType == 0x8100 {
+		// 802.1q
+		if len(data) < 4 {
+			return
+		}
+		etherType = binary.BigEndian.Uint16(data[2:4])
+		data = data[4:]
+	}
+	if etherType == helpers.ETypeIPv4 || etherType == helpers.ETypeIPv6 {
+		parseInnerIP(sch, bf, data)
+	}
+}
+
+// parseInnerIP parses an encapsulated IP packet. Only addresses, protocol and
+// ports are extracted.
+func parseInnerIP(sch *schema.Component, bf *schema.FlowMessage, data []byte) {
+	var proto uint8
+	var srcAddr, dstAddr netip.Addr
+	if len(data) < 1 {
+		return
+	}
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return
+		}
+		proto = data[9]
+		srcAddr = DecodeIP(data[12:16])
+		dstAddr = DecodeIP(data[16:20])
+		ihl := int((data[0] & 0xf) * 4)
+		fragoffset := binary.BigEndian.Uint16(data[6:8]) & 0x1fff
+		if len(data) >= ihl && fragoffset == 0 {
+			data = data[ihl:]
+		} else {
+			data = data[:0]
+		}
+	case 6:
+		if len(data) < 40 {
+			return
+		}
+		proto = data[6]
+		srcAddr = DecodeIP(data[8:24])
+		dstAddr = DecodeIP(data[24:40])
+		data = data[40:]
+	default:
+		return
+	}
+	sch.ProtobufAppendVarint(bf, schema.ColumnProtoInner, uint64(proto))
+	sch.ProtobufAppendIP(bf, schema.ColumnSrcAddrInner, srcAddr)
+	sch.ProtobufAppendIP(bf, schema.ColumnDstAddrInner, dstAddr)
+	if (proto == 6 || proto == 17) && len(data) > 4 {
+		sch.ProtobufAppendVarint(bf, schema.ColumnSrcPortInner,
+			uint64(binary.BigEndian.Uint16(data[0:2])))
+		sch.ProtobufAppendVarint(bf, schema.ColumnDstPortInner,
+			uint64(binary.BigEndian.Uint16(data[2:4])))
+	}
+}
+
 // DecodeIP decodes an IP address
 func DecodeIP(b []byte) netip.Addr {
 	if ip, ok := netip.AddrFromSlice(b); ok {
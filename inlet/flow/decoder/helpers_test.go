@@ -66,3 +66,83 @@ func TestDecodeVLANAndIPv6(t *testing.T) {
 		t.Fatalf("ParseEthernet() (-got, +want):\n%s", diff)
 	}
 }
+
+func TestDecodeVXLAN(t *testing.T) {
+	sch := schema.NewMock(t).EnableAllColumns()
+	pcap := helpers.ReadPcapL2(t, filepath.Join("testdata", "vxlan-ipv4.pcap"))
+	bf := &schema.FlowMessage{}
+	l := ParseEthernet(sch, bf, pcap)
+	if l != 96 {
+		t.Errorf("ParseEthernet() returned %d, expected 96", l)
+	}
+	expected := schema.FlowMessage{
+		SrcAddr: netip.MustParseAddr("::ffff:192.0.2.1"),
+		DstAddr: netip.MustParseAddr("::ffff:192.0.2.2"),
+		ProtobufDebug: map[schema.ColumnKey]interface{}{
+			schema.ColumnEType:        helpers.ETypeIPv4,
+			schema.ColumnProto:        17,
+			schema.ColumnSrcPort:      54321,
+			schema.ColumnDstPort:      4789,
+			schema.ColumnIPTTL:        64,
+			schema.ColumnSrcMAC:       0x000102030405,
+			schema.ColumnDstMAC:       0x000102030406,
+			schema.ColumnProtoInner:   6,
+			schema.ColumnSrcAddrInner: netip.MustParseAddr("::ffff:10.0.0.1"),
+			schema.ColumnDstAddrInner: netip.MustParseAddr("::ffff:10.0.0.2"),
+			schema.ColumnSrcPortInner: 34567,
+			schema.ColumnDstPortInner: 443,
+		},
+	}
+	if diff := helpers.Diff(bf, expected); diff != "" {
+		t.Fatalf("ParseEthernet() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeGRE(t *testing.T) {
+	sch := schema.NewMock(t).EnableAllColumns()
+	pcap := helpers.ReadPcapL2(t, filepath.Join("testdata", "gre-ipv6.pcap"))
+	bf := &schema.FlowMessage{}
+	l := ParseEthernet(sch, bf, pcap)
+	if l != 80 {
+		t.Errorf("ParseEthernet() returned %d, expected 80", l)
+	}
+	expected := schema.FlowMessage{
+		SrcAddr: netip.MustParseAddr("::ffff:192.0.2.1"),
+		DstAddr: netip.MustParseAddr("::ffff:192.0.2.2"),
+		ProtobufDebug: map[schema.ColumnKey]interface{}{
+			schema.ColumnEType:        helpers.ETypeIPv4,
+			schema.ColumnProto:        47,
+			schema.ColumnIPTTL:        64,
+			schema.ColumnSrcMAC:       0x000102030405,
+			schema.ColumnDstMAC:       0x000102030406,
+			schema.ColumnProtoInner:   17,
+			schema.ColumnSrcAddrInner: netip.MustParseAddr("2001:db8::1"),
+			schema.ColumnDstAddrInner: netip.MustParseAddr("2001:db8::2"),
+			schema.ColumnSrcPortInner: 5353,
+			schema.ColumnDstPortInner: 53,
+		},
+	}
+	if diff := helpers.Diff(bf, expected); diff != "" {
+		t.Fatalf("ParseEthernet() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeInnerDisabled(t *testing.T) {
+	sch := schema.NewMock(t)
+	pcap := helpers.ReadPcapL2(t, filepath.Join("testdata", "vxlan-ipv4.pcap"))
+	bf := &schema.FlowMessage{}
+	ParseEthernet(sch, bf, pcap)
+	expected := schema.FlowMessage{
+		SrcAddr: netip.MustParseAddr("::ffff:192.0.2.1"),
+		DstAddr: netip.MustParseAddr("::ffff:192.0.2.2"),
+		ProtobufDebug: map[schema.ColumnKey]interface{}{
+			schema.ColumnEType:   helpers.ETypeIPv4,
+			schema.ColumnProto:   17,
+			schema.ColumnSrcPort: 54321,
+			schema.ColumnDstPort: 4789,
+		},
+	}
+	if diff := helpers.Diff(bf, expected); diff != "" {
+		t.Fatalf("ParseEthernet() (-got, +want):\n%s", diff)
+	}
+}
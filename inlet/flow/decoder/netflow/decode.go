@@ -7,6 +7,7 @@ package netflow
 import (
 	"encoding/binary"
 	"net/netip"
+	"strings"
 
 	"akvorado/common/helpers"
 	"akvorado/common/schema"
@@ -21,7 +22,7 @@ import (
 // values in the sub-range of 1-127 are compatible with field types used by
 // NetFlow version 9 [RFC3954]."
 
-func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUptime uint64) []*schema.FlowMessage {
+func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUptime, bootTime uint64) []*schema.FlowMessage {
 	flowMessageSet := []*schema.FlowMessage{}
 
 	for _, record := range packet.Records {
@@ -50,6 +51,8 @@ func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUpti
 		if nd.useTsFromFirstSwitched {
 			bf.TimeReceived = ts - sysUptime + uint64(record.First)
 		}
+		nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnFlowStartMilliseconds, bootTime+uint64(record.First))
+		nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnFlowEndMilliseconds, bootTime+uint64(record.Last))
 		if bf.SamplingRate == 0 {
 			bf.SamplingRate = 1
 		}
@@ -58,7 +61,7 @@ func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUpti
 	return flowMessageSet
 }
 
-func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets []interface{}, samplingRateSys *samplingRateSystem, ts, sysUptime uint64) []*schema.FlowMessage {
+func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets []interface{}, samplingRateSys *samplingRateSystem, interfaceSys *interfaceSystem, ts, sysUptime, bootTime uint64) []*schema.FlowMessage {
 	flowMessageSet := []*schema.FlowMessage{}
 
 	// Look for sampling rate in option data flowsets
@@ -93,13 +96,24 @@ func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets
 				if samplingRate > 0 {
 					samplingRateSys.SetSamplingRate(version, obsDomainID, samplerID, samplingRate)
 				}
+				nd.decodeInterfaceOptions(version, interfaceSys, record)
 			}
 		case netflow.DataFlowSet:
 			for _, record := range tFlowSet.Records {
-				flow := nd.decodeRecord(version, obsDomainID, samplingRateSys, record.Values, ts, sysUptime)
+				flow := nd.decodeRecord(version, obsDomainID, samplingRateSys, interfaceSys, record.Values, ts, sysUptime, bootTime)
 				if flow != nil {
 					flowMessageSet = append(flowMessageSet, flow)
 				}
+				// RFC 5103: a biflow is split into two unidirectional flows
+				if !nd.splitBiflows {
+					continue
+				}
+				if reverse := reverseFields(record.Values); reverse != nil {
+					flow := nd.decodeRecord(version, obsDomainID, samplingRateSys, interfaceSys, reverse, ts, sysUptime, bootTime)
+					if flow != nil {
+						flowMessageSet = append(flowMessageSet, flow)
+					}
+				}
 			}
 		}
 	}
@@ -107,10 +121,48 @@ func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets
 	return flowMessageSet
 }
 
-func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, samplingRateSys *samplingRateSystem, fields []netflow.DataField, ts, sysUptime uint64) *schema.FlowMessage {
+// decodeInterfaceOptions extracts interface names and descriptions from an
+// option record.
+func (nd *Decoder) decodeInterfaceOptions(version uint16, interfaceSys *interfaceSystem, record netflow.OptionsDataRecord) {
+	var (
+		ifIndex uint32
+		info    interfaceInfo
+	)
+	for _, field := range record.ScopesValues {
+		v, ok := field.Value.([]byte)
+		if !ok || field.PenProvided {
+			continue
+		}
+		// For NetFlow v9, scope type 2 is "interface"
+		if (version == 9 && field.Type == 2) || (version == 10 && field.Type == netflow.IPFIX_FIELD_ingressInterface) {
+			ifIndex = uint32(decodeUNumber(v))
+		}
+	}
+	for _, field := range record.OptionsValues {
+		v, ok := field.Value.([]byte)
+		if !ok || field.PenProvided {
+			continue
+		}
+		switch field.Type {
+		case netflow.IPFIX_FIELD_ingressInterface:
+			ifIndex = uint32(decodeUNumber(v))
+		case netflow.IPFIX_FIELD_interfaceName:
+			info.name = decodeString(v)
+		case netflow.IPFIX_FIELD_interfaceDescription:
+			info.description = decodeString(v)
+		}
+	}
+	if ifIndex > 0 && info.name != "" {
+		interfaceSys.SetInterface(ifIndex, info)
+	}
+}
+
+func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, samplingRateSys *samplingRateSystem, interfaceSys *interfaceSystem, fields []netflow.DataField, ts, sysUptime, bootTime uint64) *schema.FlowMessage {
 	var etype, dstPort, srcPort uint16
 	var proto, icmpType, icmpCode uint8
 	var foundIcmpTypeCode bool
+	var flowStart, flowEnd, flowStartUptime, flowEndUptime uint64
+	var foundFlowStartUptime, foundFlowEndUptime bool
 	bf := &schema.FlowMessage{}
 	dataLinkFrameSectionIdx := -1
 	for idx, field := range fields {
@@ -172,6 +224,10 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, samplingRate
 			bf.InIf = uint32(decodeUNumber(v))
 		case netflow.IPFIX_FIELD_egressInterface:
 			bf.OutIf = uint32(decodeUNumber(v))
+		case netflow.IPFIX_FIELD_interfaceName:
+			bf.InIfName = decodeString(v)
+		case netflow.IPFIX_FIELD_interfaceDescription:
+			bf.InIfDescription = decodeString(v)
 
 		// RFC7133: process it later to not override other fields
 		case netflow.IPFIX_FIELD_dataLinkFrameSize:
@@ -187,6 +243,26 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, samplingRate
 		case netflow.IPFIX_FIELD_forwardingStatus:
 			nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnForwardingStatus, decodeUNumber(v))
 		default:
+			// Flow timing
+			switch field.Type {
+			case netflow.IPFIX_FIELD_flowStartSysUpTime:
+				flowStartUptime = decodeUNumber(v)
+				foundFlowStartUptime = true
+			case netflow.IPFIX_FIELD_flowEndSysUpTime:
+				flowEndUptime = decodeUNumber(v)
+				foundFlowEndUptime = true
+			case netflow.IPFIX_FIELD_flowStartSeconds:
+				flowStart = decodeUNumber(v) * 1000
+			case netflow.IPFIX_FIELD_flowEndSeconds:
+				flowEnd = decodeUNumber(v) * 1000
+			case netflow.IPFIX_FIELD_flowStartMilliseconds:
+				flowStart = decodeUNumber(v)
+			case netflow.IPFIX_FIELD_flowEndMilliseconds:
+				flowEnd = decodeUNumber(v)
+			case netflow.IPFIX_FIELD_systemInitTimeMilliseconds:
+				bootTime = decodeUNumber(v)
+			}
+
 			if nd.useTsFromFirstSwitched {
 				switch field.Type {
 				case netflow.NFV9_FIELD_FIRST_SWITCHED:
@@ -298,12 +374,122 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, samplingRate
 		}
 	}
 	nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnEType, uint64(etype))
+	// Timestamps relative to the system uptime need the boot time of the
+	// exporter. For IPFIX, it is only known when the record contains it.
+	if bootTime > 0 {
+		if flowStart == 0 && foundFlowStartUptime {
+			flowStart = bootTime + flowStartUptime
+		}
+		if flowEnd == 0 && foundFlowEndUptime {
+			flowEnd = bootTime + flowEndUptime
+		}
+	}
+	nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnFlowStartMilliseconds, flowStart)
+	nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnFlowEndMilliseconds, flowEnd)
+	if bf.InIf > 0 && bf.InIfName == "" {
+		if info, ok := interfaceSys.GetInterface(bf.InIf); ok {
+			bf.InIfName = info.name
+			bf.InIfDescription = info.description
+		}
+	}
+	if bf.OutIf > 0 && bf.OutIfName == "" {
+		if info, ok := interfaceSys.GetInterface(bf.OutIf); ok {
+			bf.OutIfName = info.name
+			bf.OutIfDescription = info.description
+		}
+	}
 	if bf.SamplingRate == 0 {
 		bf.SamplingRate = samplingRateSys.GetSamplingRate(version, obsDomainID, 0)
 	}
 	return bf
 }
 
+// reversePEN is the private enterprise number used for reverse information
+// elements in biflows (RFC 5103).
+const reversePEN = 29305
+
+// reverseSwappedFields are the information elements to swap to get the
+// reverse direction of a biflow.
+var reverseSwappedFields = map[uint16]uint16{
+	netflow.IPFIX_FIELD_sourceIPv4Address:           netflow.IPFIX_FIELD_destinationIPv4Address,
+	netflow.IPFIX_FIELD_destinationIPv4Address:      netflow.IPFIX_FIELD_sourceIPv4Address,
+	netflow.IPFIX_FIELD_sourceIPv6Address:           netflow.IPFIX_FIELD_destinationIPv6Address,
+	netflow.IPFIX_FIELD_destinationIPv6Address:      netflow.IPFIX_FIELD_sourceIPv6Address,
+	netflow.IPFIX_FIELD_sourceIPv4PrefixLength:      netflow.IPFIX_FIELD_destinationIPv4PrefixLength,
+	netflow.IPFIX_FIELD_destinationIPv4PrefixLength: netflow.IPFIX_FIELD_sourceIPv4PrefixLength,
+	netflow.IPFIX_FIELD_sourceIPv6PrefixLength:      netflow.IPFIX_FIELD_destinationIPv6PrefixLength,
+	netflow.IPFIX_FIELD_destinationIPv6PrefixLength: netflow.IPFIX_FIELD_sourceIPv6PrefixLength,
+	netflow.IPFIX_FIELD_sourceTransportPort:         netflow.IPFIX_FIELD_destinationTransportPort,
+	netflow.IPFIX_FIELD_destinationTransportPort:    netflow.IPFIX_FIELD_sourceTransportPort,
+	netflow.IPFIX_FIELD_bgpSourceAsNumber:           netflow.IPFIX_FIELD_bgpDestinationAsNumber,
+	netflow.IPFIX_FIELD_bgpDestinationAsNumber:      netflow.IPFIX_FIELD_bgpSourceAsNumber,
+	netflow.IPFIX_FIELD_ingressInterface:            netflow.IPFIX_FIELD_egressInterface,
+	netflow.IPFIX_FIELD_egressInterface:             netflow.IPFIX_FIELD_ingressInterface,
+	netflow.IPFIX_FIELD_sourceMacAddress:            netflow.IPFIX_FIELD_destinationMacAddress,
+	netflow.IPFIX_FIELD_destinationMacAddress:       netflow.IPFIX_FIELD_sourceMacAddress,
+}
+
+// reverseFields returns the fields for the reverse direction of a biflow. It
+// returns nil if the record is not a biflow or if there is no traffic in the
+// reverse direction.
+func reverseFields(fields []netflow.DataField) []netflow.DataField {
+	reversed := map[uint16]netflow.DataField{}
+	reversedOrder := []uint16{}
+	var reverseTraffic bool
+	for _, field := range fields {
+		if !field.PenProvided || field.Pen != reversePEN {
+			continue
+		}
+		v, ok := field.Value.([]byte)
+		if !ok {
+			continue
+		}
+		switch field.Type {
+		case netflow.IPFIX_FIELD_octetDeltaCount, netflow.IPFIX_FIELD_packetDeltaCount:
+			if decodeUNumber(v) > 0 {
+				reverseTraffic = true
+			}
+		}
+		if _, ok := reversed[field.Type]; !ok {
+			reversedOrder = append(reversedOrder, field.Type)
+		}
+		reversed[field.Type] = netflow.DataField{Type: field.Type, Value: v}
+	}
+	if !reverseTraffic {
+		return nil
+	}
+
+	result := make([]netflow.DataField, 0, len(fields))
+	for _, field := range fields {
+		if field.PenProvided {
+			continue
+		}
+		switch field.Type {
+		case netflow.IPFIX_FIELD_octetDeltaCount, netflow.IPFIX_FIELD_packetDeltaCount,
+			netflow.IPFIX_FIELD_postOctetDeltaCount, netflow.IPFIX_FIELD_postPacketDeltaCount,
+			netflow.IPFIX_FIELD_initiatorOctets, netflow.IPFIX_FIELD_responderOctets,
+			netflow.IPFIX_FIELD_ipNextHopIPv4Address, netflow.IPFIX_FIELD_bgpNextHopIPv4Address,
+			netflow.IPFIX_FIELD_ipNextHopIPv6Address, netflow.IPFIX_FIELD_bgpNextHopIPv6Address:
+			// Counters are provided by reverse fields, next hop is unknown
+			continue
+		}
+		if reverseField, ok := reversed[field.Type]; ok {
+			field = reverseField
+			delete(reversed, field.Type)
+		}
+		if swapped, ok := reverseSwappedFields[field.Type]; ok {
+			field.Type = swapped
+		}
+		result = append(result, field)
+	}
+	for _, fieldType := range reversedOrder {
+		if field, ok := reversed[fieldType]; ok {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
 func decodeUNumber(b []byte) uint64 {
 	var o uint64
 	l := len(b)
@@ -330,6 +516,10 @@ func decodeUNumber(b []byte) uint64 {
 	return o
 }
 
+func decodeString(b []byte) string {
+	return strings.TrimRight(string(b), "\x00")
+}
+
 func decodeIPFromBytes(b []byte) netip.Addr {
 	if ip, ok := netip.AddrFromSlice(b); ok {
 		return netip.AddrFrom16(ip.As16())
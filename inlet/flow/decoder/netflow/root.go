@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net/netip"
 	"strconv"
 	"sync"
@@ -31,6 +32,7 @@ type Decoder struct {
 	systemsLock sync.RWMutex
 	templates   map[string]*templateSystem
 	sampling    map[string]*samplingRateSystem
+	interfaces  map[string]*interfaceSystem
 
 	metrics struct {
 		errors             *reporter.CounterVec
@@ -41,6 +43,7 @@ type Decoder struct {
 	}
 	useTsFromNetflowsPacket bool
 	useTsFromFirstSwitched  bool
+	splitBiflows            bool
 }
 
 // New instantiates a new netflow decoder.
@@ -51,8 +54,10 @@ func New(r *reporter.Reporter, dependencies decoder.Dependencies, option decoder
 		errLogger:               r.Sample(reporter.BurstSampler(30*time.Second, 3)),
 		templates:               map[string]*templateSystem{},
 		sampling:                map[string]*samplingRateSystem{},
+		interfaces:              map[string]*interfaceSystem{},
 		useTsFromNetflowsPacket: option.TimestampSource == decoder.TimestampSourceNetflowPacket,
 		useTsFromFirstSwitched:  option.TimestampSource == decoder.TimestampSourceNetflowFirstSwitched,
+		splitBiflows:            option.SplitBiflows,
 	}
 
 	nd.metrics.errors = nd.r.CounterVec(
@@ -168,15 +173,42 @@ func (s *samplingRateSystem) SetSamplingRate(version uint16, obsDomainID uint32,
 	}] = samplingRate
 }
 
+// interfaceInfo is the name and description of an interface as provided by an
+// exporter.
+type interfaceInfo struct {
+	name        string
+	description string
+}
+
+type interfaceSystem struct {
+	lock       sync.RWMutex
+	interfaces map[uint32]interfaceInfo
+}
+
+func (s *interfaceSystem) GetInterface(ifIndex uint32) (interfaceInfo, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	info, ok := s.interfaces[ifIndex]
+	return info, ok
+}
+
+func (s *interfaceSystem) SetInterface(ifIndex uint32, info interfaceInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.interfaces[ifIndex] = info
+}
+
 // Decode decodes a Netflow payload.
 func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 	if len(in.Payload) < 2 {
+		nd.d.ReportError(in, errors.New("packet too short"))
 		return nil
 	}
 	key := in.Source.String()
 	nd.systemsLock.RLock()
 	templates, tok := nd.templates[key]
 	sampling, sok := nd.sampling[key]
+	interfaces, iok := nd.interfaces[key]
 	nd.systemsLock.RUnlock()
 	if !tok {
 		templates = &templateSystem{
@@ -196,9 +228,18 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 		nd.sampling[key] = sampling
 		nd.systemsLock.Unlock()
 	}
+	if !iok {
+		interfaces = &interfaceSystem{
+			interfaces: map[uint32]interfaceInfo{},
+		}
+		nd.systemsLock.Lock()
+		nd.interfaces[key] = interfaces
+		nd.systemsLock.Unlock()
+	}
 
 	var (
 		sysUptime      uint64
+		bootTime       uint64 // in milliseconds since epoch, 0 when unknown
 		versionStr     string
 		flowSets       []interface{}
 		obsDomainID    uint32
@@ -214,6 +255,7 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 		if err := netflowlegacy.DecodeMessage(buf, &packetNFv5); err != nil {
 			nd.metrics.errors.WithLabelValues(key, "NetFlow v5 decoding error").Inc()
 			nd.errLogger.Err(err).Str("exporter", key).Msg("error while decoding NetFlow v5")
+			nd.d.ReportError(in, err)
 			return nil
 		}
 		versionStr = "5"
@@ -224,14 +266,18 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 			ts = uint64(packetNFv5.UnixSecs)
 			sysUptime = uint64(packetNFv5.SysUptime)
 		}
-		flowMessageSet = nd.decodeNFv5(&packetNFv5, ts, sysUptime)
+		bootTime = uint64(packetNFv5.UnixSecs)*1000 + uint64(packetNFv5.UnixNSecs)/1_000_000 -
+			uint64(packetNFv5.SysUptime)
+		flowMessageSet = nd.decodeNFv5(&packetNFv5, ts, sysUptime, bootTime)
 	case 9:
 		var packetNFv9 netflow.NFv9Packet
 		if err := netflow.DecodeMessageNetFlow(buf, templates, &packetNFv9); err != nil {
 			nd.metrics.errors.WithLabelValues(key, "NetFlow v9 decoding error").Inc()
 			if !errors.Is(err, netflow.ErrorTemplateNotFound) {
 				nd.errLogger.Err(err).Str("exporter", key).Msg("error while decoding NetFlow v9")
+				nd.d.ReportError(in, err)
 			} else {
+				// Expected until the exporter sends its templates
 				nd.errLogger.Debug().Str("exporter", key).Msg("template not received yet")
 			}
 			return nil
@@ -243,14 +289,17 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 			ts = uint64(packetNFv9.UnixSeconds)
 			sysUptime = uint64(packetNFv9.SystemUptime)
 		}
-		flowMessageSet = nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, sampling, ts, sysUptime)
+		bootTime = uint64(packetNFv9.UnixSeconds)*1000 - uint64(packetNFv9.SystemUptime)
+		flowMessageSet = nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, sampling, interfaces, ts, sysUptime, bootTime)
 	case 10:
 		var packetIPFIX netflow.IPFIXPacket
 		if err := netflow.DecodeMessageIPFIX(buf, templates, &packetIPFIX); err != nil {
 			nd.metrics.errors.WithLabelValues(key, "IPFIX decoding error").Inc()
 			if !errors.Is(err, netflow.ErrorTemplateNotFound) {
 				nd.errLogger.Err(err).Str("exporter", key).Msg("error while decoding IPFIX")
+				nd.d.ReportError(in, err)
 			} else {
+				// Expected until the exporter sends its templates
 				nd.errLogger.Debug().Str("exporter", key).Msg("template not received yet")
 			}
 			return nil
@@ -261,10 +310,11 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 		if nd.useTsFromNetflowsPacket {
 			ts = uint64(packetIPFIX.ExportTime)
 		}
-		flowMessageSet = nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, sampling, ts, sysUptime)
+		flowMessageSet = nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, sampling, interfaces, ts, sysUptime, bootTime)
 	default:
 		nd.metrics.stats.WithLabelValues(key, "unknown").
 			Inc()
+		nd.d.ReportError(in, fmt.Errorf("unknown version %d", version))
 		return nil
 	}
 	nd.metrics.stats.WithLabelValues(key, versionStr).Inc()
@@ -4,6 +4,7 @@
 package netflow
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"net/netip"
@@ -14,6 +15,8 @@ import (
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
 	"akvorado/inlet/flow/decoder"
+
+	"github.com/netsampler/goflow2/v2/decoders/netflow"
 )
 
 func TestDecode(t *testing.T) {
@@ -111,14 +114,16 @@ func TestDecode(t *testing.T) {
 			SrcNetMask:      24,
 			DstNetMask:      14,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            1500,
-				schema.ColumnPackets:          1,
-				schema.ColumnEType:            helpers.ETypeIPv4,
-				schema.ColumnProto:            6,
-				schema.ColumnSrcPort:          443,
-				schema.ColumnDstPort:          19624,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnTCPFlags:         16,
+				schema.ColumnBytes:                 1500,
+				schema.ColumnPackets:               1,
+				schema.ColumnEType:                 helpers.ETypeIPv4,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               443,
+				schema.ColumnDstPort:               19624,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnTCPFlags:              16,
+				schema.ColumnFlowStartMilliseconds: 1647285925050,
+				schema.ColumnFlowEndMilliseconds:   1647285925050,
 			},
 		}, {
 			SamplingRate:    30000,
@@ -131,14 +136,16 @@ func TestDecode(t *testing.T) {
 			SrcNetMask:      24,
 			DstNetMask:      14,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            1500,
-				schema.ColumnPackets:          1,
-				schema.ColumnEType:            helpers.ETypeIPv4,
-				schema.ColumnProto:            6,
-				schema.ColumnSrcPort:          443,
-				schema.ColumnDstPort:          2444,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnTCPFlags:         16,
+				schema.ColumnBytes:                 1500,
+				schema.ColumnPackets:               1,
+				schema.ColumnEType:                 helpers.ETypeIPv4,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               443,
+				schema.ColumnDstPort:               2444,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnTCPFlags:              16,
+				schema.ColumnFlowStartMilliseconds: 1647285925050,
+				schema.ColumnFlowEndMilliseconds:   1647285925050,
 			},
 		}, {
 			SamplingRate:    30000,
@@ -151,14 +158,16 @@ func TestDecode(t *testing.T) {
 			SrcNetMask:      20,
 			DstNetMask:      18,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            1400,
-				schema.ColumnPackets:          1,
-				schema.ColumnEType:            helpers.ETypeIPv4,
-				schema.ColumnProto:            6,
-				schema.ColumnSrcPort:          443,
-				schema.ColumnDstPort:          53697,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnTCPFlags:         16,
+				schema.ColumnBytes:                 1400,
+				schema.ColumnPackets:               1,
+				schema.ColumnEType:                 helpers.ETypeIPv4,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               443,
+				schema.ColumnDstPort:               53697,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnTCPFlags:              16,
+				schema.ColumnFlowStartMilliseconds: 1647285925051,
+				schema.ColumnFlowEndMilliseconds:   1647285925051,
 			},
 		}, {
 			SamplingRate:    30000,
@@ -171,14 +180,16 @@ func TestDecode(t *testing.T) {
 			SrcNetMask:      16,
 			DstNetMask:      14,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            1448,
-				schema.ColumnPackets:          1,
-				schema.ColumnEType:            helpers.ETypeIPv4,
-				schema.ColumnProto:            6,
-				schema.ColumnSrcPort:          443,
-				schema.ColumnDstPort:          52300,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnTCPFlags:         16,
+				schema.ColumnBytes:                 1448,
+				schema.ColumnPackets:               1,
+				schema.ColumnEType:                 helpers.ETypeIPv4,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               443,
+				schema.ColumnDstPort:               52300,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnTCPFlags:              16,
+				schema.ColumnFlowStartMilliseconds: 1647285925052,
+				schema.ColumnFlowEndMilliseconds:   1647285925052,
 			},
 		},
 	}
@@ -255,12 +266,14 @@ func TestDecodeSamplingRate(t *testing.T) {
 			SrcVlan:         701,
 			NextHop:         netip.MustParseAddr("::ffff:0.0.0.0"),
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnPackets: 1,
-				schema.ColumnBytes:   160,
-				schema.ColumnProto:   6,
-				schema.ColumnSrcPort: 13245,
-				schema.ColumnDstPort: 10907,
-				schema.ColumnEType:   helpers.ETypeIPv4,
+				schema.ColumnPackets:               1,
+				schema.ColumnBytes:                 160,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               13245,
+				schema.ColumnDstPort:               10907,
+				schema.ColumnEType:                 helpers.ETypeIPv4,
+				schema.ColumnFlowStartMilliseconds: 1691746198012,
+				schema.ColumnFlowEndMilliseconds:   1691746198012,
 			},
 		},
 	}
@@ -299,17 +312,19 @@ func TestDecodeMultipleSamplingRates(t *testing.T) {
 			InIf:            97,
 			OutIf:           6,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnPackets:          18,
-				schema.ColumnBytes:            1348,
-				schema.ColumnProto:            6,
-				schema.ColumnSrcPort:          443,
-				schema.ColumnDstPort:          52616,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnIPTTL:            127,
-				schema.ColumnIPTos:            64,
-				schema.ColumnIPv6FlowLabel:    252813,
-				schema.ColumnTCPFlags:         16,
-				schema.ColumnEType:            helpers.ETypeIPv6,
+				schema.ColumnPackets:               18,
+				schema.ColumnBytes:                 1348,
+				schema.ColumnProto:                 6,
+				schema.ColumnSrcPort:               443,
+				schema.ColumnDstPort:               52616,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnIPTTL:                 127,
+				schema.ColumnIPTos:                 64,
+				schema.ColumnIPv6FlowLabel:         252813,
+				schema.ColumnTCPFlags:              16,
+				schema.ColumnEType:                 helpers.ETypeIPv6,
+				schema.ColumnFlowStartMilliseconds: 1701360969980,
+				schema.ColumnFlowEndMilliseconds:   1701360974891,
 			},
 		},
 		{
@@ -323,16 +338,18 @@ func TestDecodeMultipleSamplingRates(t *testing.T) {
 			InIf:            103,
 			OutIf:           6,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnPackets:          4,
-				schema.ColumnBytes:            579,
-				schema.ColumnProto:            17,
-				schema.ColumnSrcPort:          2121,
-				schema.ColumnDstPort:          2121,
-				schema.ColumnForwardingStatus: 64,
-				schema.ColumnIPTTL:            57,
-				schema.ColumnIPTos:            40,
-				schema.ColumnIPv6FlowLabel:    570164,
-				schema.ColumnEType:            helpers.ETypeIPv6,
+				schema.ColumnPackets:               4,
+				schema.ColumnBytes:                 579,
+				schema.ColumnProto:                 17,
+				schema.ColumnSrcPort:               2121,
+				schema.ColumnDstPort:               2121,
+				schema.ColumnForwardingStatus:      64,
+				schema.ColumnIPTTL:                 57,
+				schema.ColumnIPTos:                 40,
+				schema.ColumnIPv6FlowLabel:         570164,
+				schema.ColumnEType:                 helpers.ETypeIPv6,
+				schema.ColumnFlowStartMilliseconds: 1701360971632,
+				schema.ColumnFlowEndMilliseconds:   1701360973502,
 			},
 		},
 	}
@@ -361,12 +378,14 @@ func TestDecodeICMP(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("2001:db8::"),
 			DstAddr:         netip.MustParseAddr("2001:db8::1"),
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:      104,
-				schema.ColumnDstPort:    32768,
-				schema.ColumnEType:      34525,
-				schema.ColumnICMPv6Type: 128, // Code: 0
-				schema.ColumnPackets:    1,
-				schema.ColumnProto:      58,
+				schema.ColumnBytes:                 104,
+				schema.ColumnDstPort:               32768,
+				schema.ColumnEType:                 34525,
+				schema.ColumnICMPv6Type:            128, // Code: 0
+				schema.ColumnPackets:               1,
+				schema.ColumnProto:                 58,
+				schema.ColumnFlowStartMilliseconds: 1685867993216,
+				schema.ColumnFlowEndMilliseconds:   1685867993216,
 			},
 		},
 		{
@@ -374,12 +393,14 @@ func TestDecodeICMP(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("2001:db8::1"),
 			DstAddr:         netip.MustParseAddr("2001:db8::"),
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:      104,
-				schema.ColumnDstPort:    33024,
-				schema.ColumnEType:      34525,
-				schema.ColumnICMPv6Type: 129, // Code: 0
-				schema.ColumnPackets:    1,
-				schema.ColumnProto:      58,
+				schema.ColumnBytes:                 104,
+				schema.ColumnDstPort:               33024,
+				schema.ColumnEType:                 34525,
+				schema.ColumnICMPv6Type:            129, // Code: 0
+				schema.ColumnPackets:               1,
+				schema.ColumnProto:                 58,
+				schema.ColumnFlowStartMilliseconds: 1685867993216,
+				schema.ColumnFlowEndMilliseconds:   1685867993216,
 			},
 		},
 		{
@@ -387,12 +408,14 @@ func TestDecodeICMP(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.4"),
 			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.5"),
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:      84,
-				schema.ColumnDstPort:    2048,
-				schema.ColumnEType:      2048,
-				schema.ColumnICMPv4Type: 8, // Code: 0
-				schema.ColumnPackets:    1,
-				schema.ColumnProto:      1,
+				schema.ColumnBytes:                 84,
+				schema.ColumnDstPort:               2048,
+				schema.ColumnEType:                 2048,
+				schema.ColumnICMPv4Type:            8, // Code: 0
+				schema.ColumnPackets:               1,
+				schema.ColumnProto:                 1,
+				schema.ColumnFlowStartMilliseconds: 1685867995034,
+				schema.ColumnFlowEndMilliseconds:   1685867995034,
 			},
 		},
 		{
@@ -405,6 +428,8 @@ func TestDecodeICMP(t *testing.T) {
 				schema.ColumnPackets: 1,
 				schema.ColumnProto:   1,
 				// Type/Code  = 0
+				schema.ColumnFlowStartMilliseconds: 1685867995034,
+				schema.ColumnFlowEndMilliseconds:   1685867995034,
 			},
 		},
 	}
@@ -460,8 +485,14 @@ func TestDecodeDataLink(t *testing.T) {
 
 func TestDecodeWithoutTemplate(t *testing.T) {
 	r := reporter.NewMock(t)
+	reported := 0
 	nfdecoder := New(r,
-		decoder.Dependencies{Schema: schema.NewMock(t).EnableAllColumns()},
+		decoder.Dependencies{
+			Schema: schema.NewMock(t).EnableAllColumns(),
+			ErrorCallback: func(decoder.RawFlow, error) {
+				reported++
+			},
+		},
 		decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
 	data := helpers.ReadPcapL4(t, filepath.Join("testdata", "datalink-data.pcap"))
 	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
@@ -470,6 +501,11 @@ func TestDecodeWithoutTemplate(t *testing.T) {
 	if diff := helpers.Diff(got, expectedFlows); diff != "" {
 		t.Fatalf("Decode() (-got, +want):\n%s", diff)
 	}
+
+	// Missing templates are expected and not reported as errors
+	if reported != 0 {
+		t.Fatalf("Decode() reported %d errors, expected 0", reported)
+	}
 }
 
 func TestDecodeMPLS(t *testing.T) {
@@ -488,15 +524,17 @@ func TestDecodeMPLS(t *testing.T) {
 			SamplingRate:    10,
 			OutIf:           16,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            89,
-				schema.ColumnPackets:          1,
-				schema.ColumnEType:            helpers.ETypeIPv6,
-				schema.ColumnForwardingStatus: 66,
-				schema.ColumnIPTTL:            255,
-				schema.ColumnProto:            17,
-				schema.ColumnSrcPort:          49153,
-				schema.ColumnDstPort:          862,
-				schema.ColumnMPLSLabels:       []uint32{20005, 524250},
+				schema.ColumnBytes:                 89,
+				schema.ColumnPackets:               1,
+				schema.ColumnEType:                 helpers.ETypeIPv6,
+				schema.ColumnForwardingStatus:      66,
+				schema.ColumnIPTTL:                 255,
+				schema.ColumnProto:                 17,
+				schema.ColumnSrcPort:               49153,
+				schema.ColumnDstPort:               862,
+				schema.ColumnMPLSLabels:            []uint32{20005, 524250},
+				schema.ColumnFlowStartMilliseconds: 1699893330381,
+				schema.ColumnFlowEndMilliseconds:   1699893330381,
 			},
 		}, {
 			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
@@ -506,15 +544,191 @@ func TestDecodeMPLS(t *testing.T) {
 			SamplingRate:    10,
 			OutIf:           17,
 			ProtobufDebug: map[schema.ColumnKey]interface{}{
-				schema.ColumnBytes:            890,
-				schema.ColumnPackets:          10,
-				schema.ColumnEType:            helpers.ETypeIPv6,
-				schema.ColumnForwardingStatus: 66,
-				schema.ColumnIPTTL:            255,
-				schema.ColumnProto:            17,
-				schema.ColumnSrcPort:          49153,
-				schema.ColumnDstPort:          862,
-				schema.ColumnMPLSLabels:       []uint32{20006, 524275},
+				schema.ColumnBytes:                 890,
+				schema.ColumnPackets:               10,
+				schema.ColumnEType:                 helpers.ETypeIPv6,
+				schema.ColumnForwardingStatus:      66,
+				schema.ColumnIPTTL:                 255,
+				schema.ColumnProto:                 17,
+				schema.ColumnSrcPort:               49153,
+				schema.ColumnDstPort:               862,
+				schema.ColumnMPLSLabels:            []uint32{20006, 524275},
+				schema.ColumnFlowStartMilliseconds: 1699893297901,
+				schema.ColumnFlowEndMilliseconds:   1699893381901,
+			},
+		},
+	}
+	for _, f := range got {
+		f.TimeReceived = 0
+	}
+
+	if diff := helpers.Diff(got, expectedFlows); diff != "" {
+		t.Fatalf("Decode() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeBiflow(t *testing.T) {
+	expectedFlows := []*schema.FlowMessage{
+		{
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.10"),
+			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.20"),
+			NextHop:         netip.MustParseAddr("::ffff:192.0.2.1"),
+			InIf:            10,
+			OutIf:           20,
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   1500,
+				schema.ColumnPackets: 10,
+				schema.ColumnEType:   helpers.ETypeIPv4,
+				schema.ColumnProto:   6,
+				schema.ColumnSrcPort: 34567,
+				schema.ColumnDstPort: 443,
+			},
+		}, {
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:203.0.113.20"),
+			DstAddr:         netip.MustParseAddr("::ffff:198.51.100.10"),
+			InIf:            20,
+			OutIf:           10,
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   45000,
+				schema.ColumnPackets: 35,
+				schema.ColumnEType:   helpers.ETypeIPv4,
+				schema.ColumnProto:   6,
+				schema.ColumnSrcPort: 443,
+				schema.ColumnDstPort: 34567,
+			},
+		},
+	}
+	data := helpers.ReadPcapL4(t, filepath.Join("testdata", "biflow.pcap"))
+
+	for _, split := range []bool{true, false} {
+		t.Run(fmt.Sprintf("split %v", split), func(t *testing.T) {
+			r := reporter.NewMock(t)
+			nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t)}, decoder.Option{
+				TimestampSource: decoder.TimestampSourceUDP,
+				SplitBiflows:    split,
+			})
+			got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+			for _, f := range got {
+				f.TimeReceived = 0
+			}
+
+			expected := expectedFlows
+			if !split {
+				expected = expectedFlows[:1]
+			}
+			if diff := helpers.Diff(got, expected); diff != "" {
+				t.Fatalf("Decode() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeInterfaceNames(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t)}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
+
+	data := helpers.ReadPcapL4(t, filepath.Join("testdata", "interfaces.pcap"))
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+
+	expectedFlows := []*schema.FlowMessage{
+		{
+			ExporterAddress:  netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:          netip.MustParseAddr("::ffff:198.51.100.10"),
+			DstAddr:          netip.MustParseAddr("::ffff:203.0.113.20"),
+			InIf:             10,
+			OutIf:            20,
+			InIfName:         "et-0/0/10",
+			InIfDescription:  "Transit: Cogent",
+			OutIfName:        "et-0/0/20",
+			OutIfDescription: "Core",
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   1500,
+				schema.ColumnPackets: 10,
+				schema.ColumnEType:   helpers.ETypeIPv4,
+			},
+		}, {
+			// Unknown output interface
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.11"),
+			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.21"),
+			InIf:            10,
+			OutIf:           30,
+			InIfName:        "et-0/0/10",
+			InIfDescription: "Transit: Cogent",
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   1000,
+				schema.ColumnPackets: 5,
+				schema.ColumnEType:   helpers.ETypeIPv4,
+			},
+		},
+	}
+	for _, f := range got {
+		f.TimeReceived = 0
+	}
+
+	if diff := helpers.Diff(got, expectedFlows); diff != "" {
+		t.Fatalf("Decode() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeVariableLength(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t)}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
+
+	// The template contains applicationName (variable length, short form)
+	// and an enterprise-specific field (variable length, long form) before
+	// the protocol.
+	data := helpers.ReadPcapL4(t, filepath.Join("testdata", "varlen.pcap"))
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+
+	expectedFlows := []*schema.FlowMessage{
+		{
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.10"),
+			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.20"),
+			InIf:            10,
+			OutIf:           20,
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   1500,
+				schema.ColumnPackets: 10,
+				schema.ColumnEType:   helpers.ETypeIPv4,
+				schema.ColumnProto:   6,
+			},
+		},
+	}
+	for _, f := range got {
+		f.TimeReceived = 0
+	}
+
+	if diff := helpers.Diff(got, expectedFlows); diff != "" {
+		t.Fatalf("Decode() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeTCPFlags(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t).EnableAllColumns()}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
+
+	data := helpers.ReadPcapL4(t, filepath.Join("testdata", "tcpflags.pcap"))
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+
+	expectedFlows := []*schema.FlowMessage{
+		{
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.10"),
+			DstAddr:         netip.MustParseAddr("::ffff:203.0.113.20"),
+			InIf:            10,
+			OutIf:           20,
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:    60,
+				schema.ColumnPackets:  1,
+				schema.ColumnEType:    helpers.ETypeIPv4,
+				schema.ColumnProto:    6,
+				schema.ColumnSrcPort:  443,
+				schema.ColumnDstPort:  51234,
+				schema.ColumnTCPFlags: 0x12, // SYN+ACK
 			},
 		},
 	}
@@ -561,13 +775,15 @@ func TestDecodeNFv5(t *testing.T) {
 					SrcNetMask:      19,
 					DstNetMask:      24,
 					ProtobufDebug: map[schema.ColumnKey]interface{}{
-						schema.ColumnBytes:    133,
-						schema.ColumnPackets:  1,
-						schema.ColumnEType:    helpers.ETypeIPv4,
-						schema.ColumnProto:    6,
-						schema.ColumnSrcPort:  30104,
-						schema.ColumnDstPort:  11963,
-						schema.ColumnTCPFlags: 0x18,
+						schema.ColumnBytes:                 133,
+						schema.ColumnPackets:               1,
+						schema.ColumnEType:                 helpers.ETypeIPv4,
+						schema.ColumnProto:                 6,
+						schema.ColumnSrcPort:               30104,
+						schema.ColumnDstPort:               11963,
+						schema.ColumnTCPFlags:              0x18,
+						schema.ColumnFlowStartMilliseconds: 1680626664000,
+						schema.ColumnFlowEndMilliseconds:   1680626664000,
 					},
 				},
 			}
@@ -629,3 +845,223 @@ func TestDecodeTimestampFromFirstSwitched(t *testing.T) {
 		}
 	}
 }
+
+// nfv9Packet builds a NetFlow v9 packet for the provided observation domain
+// with the provided flowsets. Each flowset is a flowset ID followed by its
+// content.
+func nfv9Packet(obsDomainID uint32, flowSets ...[]byte) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, 9)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(flowSets)))
+	packet = binary.BigEndian.AppendUint32(packet, 1000)       // sysUptime
+	packet = binary.BigEndian.AppendUint32(packet, 1700000000) // unixSecs
+	packet = binary.BigEndian.AppendUint32(packet, 1)          // sequence
+	packet = binary.BigEndian.AppendUint32(packet, obsDomainID)
+	for _, flowSet := range flowSets {
+		content := flowSet[2:]
+		for len(content)%4 != 0 {
+			content = append(content, 0)
+		}
+		packet = append(packet, flowSet[:2]...)
+		packet = binary.BigEndian.AppendUint16(packet, uint16(4+len(content)))
+		packet = append(packet, content...)
+	}
+	return packet
+}
+
+func TestDecodeMultipleObservationDomains(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t)}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
+	source := net.ParseIP("127.0.0.1")
+
+	// Both domains use template 256 with a different layout: domain 1 sends
+	// source address and bytes on 4 bytes, domain 2 sends bytes on 8 bytes,
+	// then source address.
+	template1 := []byte{
+		0, 0, // template flowset
+		1, 0, 0, 2, // template 256, 2 fields
+		0, 8, 0, 4, // IPV4_SRC_ADDR
+		0, 1, 0, 4, // IN_BYTES
+	}
+	template2 := []byte{
+		0, 0, // template flowset
+		1, 0, 0, 2, // template 256, 2 fields
+		0, 1, 0, 8, // IN_BYTES
+		0, 8, 0, 4, // IPV4_SRC_ADDR
+	}
+	data1 := []byte{
+		1, 0, // template 256
+		192, 0, 2, 1,
+		0, 0, 0, 100,
+	}
+	data2 := []byte{
+		1, 0, // template 256
+		0, 0, 0, 0, 0, 0, 0, 200,
+		192, 0, 2, 2,
+	}
+	nfdecoder.Decode(decoder.RawFlow{Payload: nfv9Packet(1, template1), Source: source})
+	nfdecoder.Decode(decoder.RawFlow{Payload: nfv9Packet(2, template2), Source: source})
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: nfv9Packet(1, data1), Source: source})
+	got = append(got, nfdecoder.Decode(decoder.RawFlow{Payload: nfv9Packet(2, data2), Source: source})...)
+
+	for _, f := range got {
+		f.TimeReceived = 0
+	}
+	expectedFlows := []*schema.FlowMessage{
+		{
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.1"),
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes: 100,
+				schema.ColumnEType: helpers.ETypeIPv4,
+			},
+		}, {
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.2"),
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes: 200,
+				schema.ColumnEType: helpers.ETypeIPv4,
+			},
+		},
+	}
+	if diff := helpers.Diff(got, expectedFlows); diff != "" {
+		t.Fatalf("Decode() (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics(
+		"akvorado_inlet_flow_decoder_netflow_",
+		"templates_",
+	)
+	expectedMetrics := map[string]string{
+		`templates_total{exporter="127.0.0.1",obs_domain_id="1",template_id="256",type="template",version="9"}`: "1",
+		`templates_total{exporter="127.0.0.1",obs_domain_id="2",template_id="256",type="template",version="9"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeIPv6RoundTrip(t *testing.T) {
+	r := reporter.NewMock(t)
+	sch := schema.NewMock(t).EnableAllColumns()
+	nfdecoder := New(r, decoder.Dependencies{Schema: sch}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP})
+
+	var got []*schema.FlowMessage
+	for _, pcap := range []string{
+		"multiplesamplingrates-options-template.pcap",
+		"multiplesamplingrates-options-data.pcap",
+		"multiplesamplingrates-template.pcap",
+		"multiplesamplingrates-data.pcap",
+	} {
+		data := helpers.ReadPcapL4(t, filepath.Join("testdata", pcap))
+		got = nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+	}
+	if len(got) == 0 {
+		t.Fatal("Decode() returned no flow")
+	}
+
+	// Addresses should survive serialization to protobuf
+	for _, flow := range got {
+		decoded := sch.ProtobufDecode(t, sch.ProtobufMarshal(flow))
+		gotAddresses := []netip.Addr{decoded.ExporterAddress, decoded.SrcAddr, decoded.DstAddr, decoded.NextHop}
+		expectedAddresses := []netip.Addr{flow.ExporterAddress, flow.SrcAddr, flow.DstAddr, flow.NextHop}
+		if diff := helpers.Diff(gotAddresses, expectedAddresses); diff != "" {
+			t.Fatalf("ProtobufDecode() (-got, +want):\n%s", diff)
+		}
+		if !decoded.SrcAddr.Is6() || decoded.SrcAddr.Is4In6() {
+			t.Fatalf("ProtobufDecode() source address %s is not IPv6", decoded.SrcAddr)
+		}
+	}
+}
+
+func TestDecodeFlowTiming(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r, decoder.Dependencies{Schema: schema.NewMock(t).EnableAllColumns()},
+		decoder.Option{TimestampSource: decoder.TimestampSourceUDP}).(*Decoder)
+	field := func(fieldType uint16, value uint64) netflow.DataField {
+		return netflow.DataField{Type: fieldType, Value: binary.BigEndian.AppendUint64(nil, value)}
+	}
+
+	cases := []struct {
+		Description   string
+		Version       uint16
+		BootTime      uint64
+		Fields        []netflow.DataField
+		ExpectedStart uint64
+		ExpectedEnd   uint64
+	}{
+		{
+			Description: "absolute milliseconds",
+			Version:     10,
+			Fields: []netflow.DataField{
+				field(netflow.IPFIX_FIELD_flowStartMilliseconds, 1_700_000_000_123),
+				field(netflow.IPFIX_FIELD_flowEndMilliseconds, 1_700_000_001_456),
+			},
+			ExpectedStart: 1_700_000_000_123,
+			ExpectedEnd:   1_700_000_001_456,
+		}, {
+			Description: "absolute seconds",
+			Version:     10,
+			Fields: []netflow.DataField{
+				field(netflow.IPFIX_FIELD_flowStartSeconds, 1_700_000_000),
+				field(netflow.IPFIX_FIELD_flowEndSeconds, 1_700_000_001),
+			},
+			ExpectedStart: 1_700_000_000_000,
+			ExpectedEnd:   1_700_000_001_000,
+		}, {
+			Description: "NetFlow v9 uptime",
+			Version:     9,
+			BootTime:    1_700_000_000_000,
+			Fields: []netflow.DataField{
+				field(netflow.NFV9_FIELD_FIRST_SWITCHED, 1000),
+				field(netflow.NFV9_FIELD_LAST_SWITCHED, 2500),
+			},
+			ExpectedStart: 1_700_000_001_000,
+			ExpectedEnd:   1_700_000_002_500,
+		}, {
+			Description: "IPFIX uptime with system init time",
+			Version:     10,
+			Fields: []netflow.DataField{
+				field(netflow.IPFIX_FIELD_systemInitTimeMilliseconds, 1_700_000_000_000),
+				field(netflow.IPFIX_FIELD_flowStartSysUpTime, 0),
+				field(netflow.IPFIX_FIELD_flowEndSysUpTime, 2500),
+			},
+			ExpectedStart: 1_700_000_000_000,
+			ExpectedEnd:   1_700_000_002_500,
+		}, {
+			Description: "IPFIX uptime without system init time",
+			Version:     10,
+			Fields: []netflow.DataField{
+				field(netflow.IPFIX_FIELD_flowStartSysUpTime, 1000),
+				field(netflow.IPFIX_FIELD_flowEndSysUpTime, 2500),
+			},
+		}, {
+			Description: "no timing",
+			Version:     10,
+			Fields: []netflow.DataField{
+				field(netflow.IPFIX_FIELD_octetDeltaCount, 1000),
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			flow := nfdecoder.decodeRecord(tc.Version, 0,
+				&samplingRateSystem{rates: map[samplingRateKey]uint32{}},
+				&interfaceSystem{interfaces: map[uint32]interfaceInfo{}},
+				tc.Fields, 1_700_000_010, 0, tc.BootTime)
+			got := []interface{}{
+				flow.ProtobufDebug[schema.ColumnFlowStartMilliseconds],
+				flow.ProtobufDebug[schema.ColumnFlowEndMilliseconds],
+			}
+			expected := []interface{}{nil, nil}
+			if tc.ExpectedStart != 0 {
+				expected[0] = tc.ExpectedStart
+			}
+			if tc.ExpectedEnd != 0 {
+				expected[1] = tc.ExpectedEnd
+			}
+			if diff := helpers.Diff(got, expected); diff != "" {
+				t.Fatalf("decodeRecord() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
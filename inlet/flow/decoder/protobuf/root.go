@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package protobuf handles decoding of flows already decoded by the sender and
+// encoded as FlowRecord messages (see flow.proto).
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow/decoder"
+)
+
+// Field numbers from flow.proto.
+const (
+	fieldTimeReceived     protowire.Number = 1
+	fieldSamplingRate     protowire.Number = 2
+	fieldExporterAddress  protowire.Number = 3
+	fieldInIf             protowire.Number = 4
+	fieldOutIf            protowire.Number = 5
+	fieldSrcAddr          protowire.Number = 6
+	fieldDstAddr          protowire.Number = 7
+	fieldNextHop          protowire.Number = 8
+	fieldSrcAS            protowire.Number = 9
+	fieldDstAS            protowire.Number = 10
+	fieldSrcNetMask       protowire.Number = 11
+	fieldDstNetMask       protowire.Number = 12
+	fieldEType            protowire.Number = 13
+	fieldProto            protowire.Number = 14
+	fieldSrcPort          protowire.Number = 15
+	fieldDstPort          protowire.Number = 16
+	fieldBytes            protowire.Number = 17
+	fieldPackets          protowire.Number = 18
+	fieldForwardingStatus protowire.Number = 19
+)
+
+// Decoder contains the state for the protobuf decoder.
+type Decoder struct {
+	r         *reporter.Reporter
+	d         decoder.Dependencies
+	errLogger reporter.Logger
+
+	metrics struct {
+		errors *reporter.CounterVec
+		stats  *reporter.CounterVec
+	}
+}
+
+// New instantiates a new protobuf decoder.
+func New(r *reporter.Reporter, dependencies decoder.Dependencies, _ decoder.Option) decoder.Decoder {
+	nd := &Decoder{
+		r:         r,
+		d:         dependencies,
+		errLogger: r.Sample(reporter.BurstSampler(30*time.Second, 3)),
+	}
+
+	nd.metrics.errors = nd.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "errors_total",
+			Help: "Flow records not successfully decoded.",
+		},
+		[]string{"exporter", "error"},
+	)
+	nd.metrics.stats = nd.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "flows_total",
+			Help: "Flow records processed.",
+		},
+		[]string{"exporter"},
+	)
+
+	return nd
+}
+
+// Decode decodes a FlowRecord message.
+func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
+	key := in.Source.String()
+	bf := &schema.FlowMessage{}
+	if err := nd.decode(bf, in.Payload); err != nil {
+		nd.metrics.errors.WithLabelValues(key, "protobuf decoding error").Inc()
+		nd.errLogger.Err(err).Str("exporter", key).Msg("error while decoding flow record")
+		nd.d.ReportError(in, err)
+		return nil
+	}
+	nd.metrics.stats.WithLabelValues(key).Inc()
+
+	if bf.TimeReceived == 0 {
+		bf.TimeReceived = uint64(in.TimeReceived.UTC().Unix())
+	}
+	if !bf.ExporterAddress.IsValid() {
+		bf.ExporterAddress = decoder.DecodeIP(in.Source)
+	}
+	return []*schema.FlowMessage{bf}
+}
+
+// decode parses the provided FlowRecord message into the flow message.
+func (nd *Decoder) decode(bf *schema.FlowMessage, payload []byte) error {
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		payload = payload[n:]
+
+		switch num {
+		case fieldExporterAddress, fieldSrcAddr, fieldDstAddr, fieldNextHop:
+			if typ != protowire.BytesType {
+				return fmt.Errorf("unexpected wire type %d for field %d", typ, num)
+			}
+			v, n := protowire.ConsumeBytes(payload)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			payload = payload[n:]
+			addr, err := decodeAddr(v)
+			if err != nil {
+				return fmt.Errorf("field %d: %w", num, err)
+			}
+			switch num {
+			case fieldExporterAddress:
+				bf.ExporterAddress = addr
+			case fieldSrcAddr:
+				bf.SrcAddr = addr
+			case fieldDstAddr:
+				bf.DstAddr = addr
+			case fieldNextHop:
+				bf.NextHop = addr
+			}
+		case fieldTimeReceived, fieldSamplingRate, fieldInIf, fieldOutIf,
+			fieldSrcAS, fieldDstAS, fieldSrcNetMask, fieldDstNetMask,
+			fieldEType, fieldProto, fieldSrcPort, fieldDstPort,
+			fieldBytes, fieldPackets, fieldForwardingStatus:
+			if typ != protowire.VarintType {
+				return fmt.Errorf("unexpected wire type %d for field %d", typ, num)
+			}
+			v, n := protowire.ConsumeVarint(payload)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			payload = payload[n:]
+			switch num {
+			case fieldTimeReceived:
+				bf.TimeReceived = v
+			case fieldSamplingRate:
+				bf.SamplingRate = uint32(v)
+			case fieldInIf:
+				bf.InIf = uint32(v)
+			case fieldOutIf:
+				bf.OutIf = uint32(v)
+			case fieldSrcAS:
+				bf.SrcAS = uint32(v)
+			case fieldDstAS:
+				bf.DstAS = uint32(v)
+			case fieldSrcNetMask:
+				bf.SrcNetMask = uint8(v)
+			case fieldDstNetMask:
+				bf.DstNetMask = uint8(v)
+			case fieldEType:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnEType, v)
+			case fieldProto:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnProto, v)
+			case fieldSrcPort:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnSrcPort, v)
+			case fieldDstPort:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnDstPort, v)
+			case fieldBytes:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnBytes, v)
+			case fieldPackets:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnPackets, v)
+			case fieldForwardingStatus:
+				nd.d.Schema.ProtobufAppendVarint(bf, schema.ColumnForwardingStatus, v)
+			}
+		default:
+			// Unknown fields are skipped for forward compatibility.
+			n := protowire.ConsumeFieldValue(num, typ, payload)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			payload = payload[n:]
+		}
+	}
+	return nil
+}
+
+// decodeAddr decodes an IPv4 or IPv6 address. An empty slice is an unset
+// address.
+func decodeAddr(b []byte) (netip.Addr, error) {
+	switch len(b) {
+	case 0:
+		return netip.Addr{}, nil
+	case 4, 16:
+		return decoder.DecodeIP(b), nil
+	default:
+		return netip.Addr{}, errors.New("address should be 4 or 16 bytes")
+	}
+}
+
+// Name returns the name of the decoder.
+func (nd *Decoder) Name() string {
+	return "protobuf"
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package protobuf
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow/decoder"
+)
+
+func TestDecode(t *testing.T) {
+	r := reporter.NewMock(t)
+	var reported []error
+	pbdecoder := New(r, decoder.Dependencies{
+		Schema: schema.NewMock(t),
+		ErrorCallback: func(_ decoder.RawFlow, err error) {
+			reported = append(reported, err)
+		},
+	}, decoder.Option{})
+	now := time.Date(2024, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Description string
+		Fields      map[string]interface{}
+		Expected    *schema.FlowMessage
+	}{
+		{
+			Description: "complete record",
+			Fields: map[string]interface{}{
+				"time_received":     uint64(1715500000),
+				"sampling_rate":     uint32(1000),
+				"exporter_address":  []byte{192, 0, 2, 1},
+				"in_if":             uint32(10),
+				"out_if":            uint32(20),
+				"src_addr":          []byte(netip.MustParseAddr("2001:db8::1").AsSlice()),
+				"dst_addr":          []byte(netip.MustParseAddr("2001:db8::2").AsSlice()),
+				"next_hop":          []byte(netip.MustParseAddr("2001:db8::3").AsSlice()),
+				"src_as":            uint32(65001),
+				"dst_as":            uint32(65002),
+				"src_net_mask":      uint32(48),
+				"dst_net_mask":      uint32(56),
+				"etype":             uint32(helpers.ETypeIPv6),
+				"proto":             uint32(6),
+				"src_port":          uint32(443),
+				"dst_port":          uint32(34211),
+				"bytes":             uint64(1500),
+				"packets":           uint64(1),
+				"forwarding_status": uint32(64),
+			},
+			Expected: &schema.FlowMessage{
+				TimeReceived:    1715500000,
+				SamplingRate:    1000,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.1"),
+				InIf:            10,
+				OutIf:           20,
+				SrcAddr:         netip.MustParseAddr("2001:db8::1"),
+				DstAddr:         netip.MustParseAddr("2001:db8::2"),
+				NextHop:         netip.MustParseAddr("2001:db8::3"),
+				SrcAS:           65001,
+				DstAS:           65002,
+				SrcNetMask:      48,
+				DstNetMask:      56,
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnEType:            helpers.ETypeIPv6,
+					schema.ColumnProto:            6,
+					schema.ColumnSrcPort:          443,
+					schema.ColumnDstPort:          34211,
+					schema.ColumnBytes:            1500,
+					schema.ColumnPackets:          1,
+					schema.ColumnForwardingStatus: 64,
+				},
+			},
+		}, {
+			Description: "minimal record",
+			Fields: map[string]interface{}{
+				"src_addr": []byte{198, 51, 100, 1},
+				"bytes":    uint64(100),
+				"packets":  uint64(2),
+			},
+			Expected: &schema.FlowMessage{
+				TimeReceived:    uint64(now.Unix()),
+				ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+				SrcAddr:         netip.MustParseAddr("::ffff:198.51.100.1"),
+				ProtobufDebug: map[schema.ColumnKey]interface{}{
+					schema.ColumnBytes:   100,
+					schema.ColumnPackets: 2,
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			got := pbdecoder.Decode(decoder.RawFlow{
+				TimeReceived: now,
+				Payload:      EncodeFlowRecord(t, tc.Fields),
+				Source:       net.ParseIP("127.0.0.1"),
+			})
+			if diff := helpers.Diff(got, []*schema.FlowMessage{tc.Expected}); diff != "" {
+				t.Fatalf("Decode() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+
+	// Invalid records
+	for _, payload := range [][]byte{
+		{0x1a, 0x02, 0x01, 0x02},  // 2-byte exporter address
+		{0x08},                    // truncated varint
+		{0x08 | 0x02, 0x01, 0x01}, // wrong wire type for time_received
+	} {
+		if got := pbdecoder.Decode(decoder.RawFlow{
+			TimeReceived: now,
+			Payload:      payload,
+			Source:       net.ParseIP("127.0.0.1"),
+		}); got != nil {
+			t.Errorf("Decode(%v) == %v but expected nil", payload, got)
+		}
+	}
+	if len(reported) != 3 {
+		t.Errorf("Decode() reported %d errors, expected 3", len(reported))
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_decoder_protobuf_")
+	expectedMetrics := map[string]string{
+		`flows_total{exporter="127.0.0.1"}`:                                  "2",
+		`errors_total{error="protobuf decoding error",exporter="127.0.0.1"}`: "3",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
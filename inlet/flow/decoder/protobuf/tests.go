@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build !release
+
+package protobuf
+
+import (
+	_ "embed" // for flow.proto
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+//go:embed flow.proto
+var flowProto string
+
+// EncodeFlowRecord encodes a FlowRecord message using the provided field
+// values, indexed by their names in flow.proto.
+func EncodeFlowRecord(t testing.TB, fields map[string]interface{}) []byte {
+	t.Helper()
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"flow.proto": flowProto,
+		}),
+	}
+	descs, err := parser.ParseFiles("flow.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles(%q) error:\n%+v", "flow.proto", err)
+	}
+	descriptor := descs[0].FindMessage("akvorado.inlet.flow.v0.FlowRecord")
+	if descriptor == nil {
+		t.Fatal("cannot find FlowRecord descriptor")
+	}
+	message := dynamic.NewMessage(descriptor)
+	for name, value := range fields {
+		if err := message.TrySetFieldByName(name, value); err != nil {
+			t.Fatalf("TrySetFieldByName(%q) error:\n%+v", name, err)
+		}
+	}
+	payload, err := message.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error:\n%+v", err)
+	}
+	return payload
+}
@@ -28,11 +28,24 @@ type Decoder interface {
 type Option struct {
 	// TimestampSource is a selector for how to set the TimeReceived.
 	TimestampSource TimestampSource
+	// SplitBiflows tells to emit a second flow for the reverse direction of
+	// a biflow.
+	SplitBiflows bool
 }
 
 // Dependencies are the dependencies for the decoder
 type Dependencies struct {
 	Schema *schema.Component
+	// ErrorCallback is called when a packet cannot be decoded. It is
+	// optional.
+	ErrorCallback func(in RawFlow, err error)
+}
+
+// ReportError reports an error while decoding the provided raw flow.
+func (d Dependencies) ReportError(in RawFlow, err error) {
+	if d.ErrorCallback != nil {
+		d.ErrorCallback(in, err)
+	}
 }
 
 // RawFlow is an undecoded flow.
@@ -92,6 +92,7 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*schema.FlowMessage {
 	if err := sflow.DecodeMessageVersion(buf, &packet); err != nil {
 		nd.metrics.errors.WithLabelValues(key, "sFlow decoding error").Inc()
 		nd.errLogger.Err(err).Str("exporter", key).Msg("error while decoding sFlow")
+		nd.d.ReportError(in, err)
 		return nil
 	}
 
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/inlet/flow/decoder"
+)
+
+// decodeErrorPayloadLength is the maximum number of bytes of the payload kept
+// for a decoding error.
+const decodeErrorPayloadLength = 128
+
+// decodeError is a decoding error as returned by the HTTP endpoint.
+type decodeError struct {
+	Time     time.Time `json:"time"`
+	Exporter string    `json:"exporter"`
+	Decoder  string    `json:"decoder"`
+	Error    string    `json:"error"`
+	Payload  string    `json:"payload"`
+}
+
+// decodeErrors is a ring buffer of the most recent decoding errors.
+type decodeErrors struct {
+	lock    sync.Mutex
+	entries []decodeError
+	next    int
+	full    bool
+
+	maskPayload  bool // do not expose payloads
+	maskExporter bool // do not expose exporters
+}
+
+func newDecodeErrors(size uint) *decodeErrors {
+	return &decodeErrors{entries: make([]decodeError, size)}
+}
+
+// Add adds a new error, evicting the oldest one if the buffer is full.
+func (de *decodeErrors) Add(entry decodeError) {
+	de.lock.Lock()
+	defer de.lock.Unlock()
+	if len(de.entries) == 0 {
+		return
+	}
+	de.entries[de.next] = entry
+	de.next++
+	if de.next == len(de.entries) {
+		de.next = 0
+		de.full = true
+	}
+}
+
+// List returns the errors, from the oldest to the most recent one.
+func (de *decodeErrors) List() []decodeError {
+	de.lock.Lock()
+	defer de.lock.Unlock()
+	var result []decodeError
+	if !de.full {
+		result = append([]decodeError{}, de.entries[:de.next]...)
+	} else {
+		result = append(append([]decodeError{}, de.entries[de.next:]...), de.entries[:de.next]...)
+	}
+	for idx := range result {
+		if de.maskPayload {
+			result[idx].Payload = ""
+		}
+		if de.maskExporter {
+			result[idx].Exporter = ""
+		}
+	}
+	return result
+}
+
+// Mask tells to not expose the payloads of the packets which could not be
+// decoded, as well as their exporters when exporter is true.
+func (de *decodeErrors) Mask(exporter bool) {
+	de.lock.Lock()
+	defer de.lock.Unlock()
+	de.maskPayload = true
+	de.maskExporter = exporter
+}
+
+// recordDecodeError records an error while decoding the provided raw flow.
+func (c *Component) recordDecodeError(decoderName string, in decoder.RawFlow, err error) {
+	exporter, _ := netip.AddrFromSlice(in.Source.To16())
+	payload := in.Payload
+	if len(payload) > decodeErrorPayloadLength {
+		payload = payload[:decodeErrorPayloadLength]
+	}
+	c.decodeErrors.Add(decodeError{
+		Time:     in.TimeReceived.UTC(),
+		Exporter: exporter.Unmap().String(),
+		Decoder:  decoderName,
+		Error:    err.Error(),
+		Payload:  hex.EncodeToString(payload),
+	})
+}
+
+// MaskDecodeErrors tells to not expose the payloads of the packets which could
+// not be decoded as they may contain masked columns. When exporter is true,
+// exporters are not exposed either.
+func (c *Component) MaskDecodeErrors(exporter bool) {
+	c.decodeErrors.Mask(exporter)
+}
+
+// DecodeErrorsHTTPHandler returns the most recent decoding errors.
+func (c *Component) DecodeErrorsHTTPHandler(gc *gin.Context) {
+	gc.JSON(http.StatusOK, gin.H{"errors": c.decodeErrors.List()})
+}
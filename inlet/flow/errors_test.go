@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/decoder/netflow"
+)
+
+func TestDecodeErrorsRing(t *testing.T) {
+	de := newDecodeErrors(3)
+	if got := de.List(); len(got) != 0 {
+		t.Fatalf("List() == %v, expected nothing", got)
+	}
+	for _, e := range []string{"error 1", "error 2", "error 3", "error 4", "error 5"} {
+		de.Add(decodeError{Error: e})
+	}
+	got := []string{}
+	for _, e := range de.List() {
+		got = append(got, e.Error)
+	}
+	if diff := helpers.Diff(got, []string{"error 3", "error 4", "error 5"}); diff != "" {
+		t.Fatalf("List() (-got, +want):\n%s", diff)
+	}
+
+	de = newDecodeErrors(0)
+	de.Add(decodeError{Error: "error 1"})
+	if got := de.List(); len(got) != 0 {
+		t.Fatalf("List() == %v, expected nothing", got)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.RecentDecodeErrors = 2
+	c := NewMock(t, r, config)
+	dec := c.wrapDecoder(
+		netflow.New(r, decoder.Dependencies{
+			Schema: c.d.Schema,
+			ErrorCallback: func(in decoder.RawFlow, err error) {
+				c.recordDecodeError("netflow", in, err)
+			},
+		}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP}),
+		false)
+
+	received := time.Date(2024, 3, 14, 10, 0, 0, 0, time.UTC)
+	for _, payload := range [][]byte{
+		{0},
+		{0, 10, 0, 0},
+		append([]byte{0, 7}, bytes.Repeat([]byte{0xff}, 200)...),
+	} {
+		dec.Decode(decoder.RawFlow{
+			TimeReceived: received,
+			Payload:      payload,
+			Source:       net.ParseIP("192.0.2.1"),
+		})
+	}
+
+	helpers.TestHTTPEndpoints(t, c.d.HTTP.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:         "/api/v0/inlet/flow/decode-errors",
+			ContentType: "application/json; charset=utf-8",
+			JSONOutput: gin.H{
+				"errors": []gin.H{
+					{
+						"time":     "2024-03-14T10:00:00Z",
+						"exporter": "192.0.2.1",
+						"decoder":  "netflow",
+						"error":    "IPFIX header unexpected EOF",
+						"payload":  "000a0000",
+					}, {
+						"time":     "2024-03-14T10:00:00Z",
+						"exporter": "192.0.2.1",
+						"decoder":  "netflow",
+						"error":    "unknown version 7",
+						"payload":  "0007" + strings.Repeat("ff", 126),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestDecodeErrorsMasked(t *testing.T) {
+	de := newDecodeErrors(3)
+	de.Add(decodeError{Exporter: "192.0.2.1", Error: "error 1", Payload: "000a0000"})
+	de.Mask(false)
+	expected := []decodeError{{Exporter: "192.0.2.1", Error: "error 1"}}
+	if diff := helpers.Diff(de.List(), expected); diff != "" {
+		t.Fatalf("List() (-got, +want):\n%s", diff)
+	}
+	de.Mask(true)
+	expected = []decodeError{{Error: "error 1"}}
+	if diff := helpers.Diff(de.List(), expected); diff != "" {
+		t.Fatalf("List() (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sync/atomic"
+	"time"
+)
+
+// ExporterStats contains the decoding statistics for one exporter.
+type ExporterStats struct {
+	// Decoder is the name of the decoder used for the last packet.
+	Decoder string
+	// Version is the protocol version of the last packet (5, 9, or 10 for
+	// NetFlow/IPFIX, 5 for sFlow).
+	Version uint32
+	// Packets is the number of successfully decoded packets.
+	Packets uint64
+	// Errors is the number of packets which could not be decoded.
+	Errors uint64
+	// FirstSeen is when the first packet was received.
+	FirstSeen time.Time
+	// LastSeen is when the last packet was received.
+	LastSeen time.Time
+}
+
+// exporterStatsState is the decoding state of one exporter. The lock of the
+// component only protects the map: the state is updated with atomic operations
+// to not serialize the inputs. Times are stored as nanoseconds since the epoch.
+type exporterStatsState struct {
+	firstSeen time.Time
+	lastSeen  atomic.Int64
+	decoder   atomic.Value // string
+	version   atomic.Uint32
+	packets   atomic.Uint64
+	errors    atomic.Uint64
+}
+
+// recordDecoding updates the decoding statistics of the provided exporter.
+func (c *Component) recordDecoding(exporter netip.Addr, decoder string, payload []byte, ok bool) {
+	exporter = netip.AddrFrom16(exporter.As16())
+	now := c.d.Clock.Now()
+	c.exporterStatsLock.RLock()
+	stats, found := c.exporterStats[exporter]
+	c.exporterStatsLock.RUnlock()
+	if !found {
+		c.exporterStatsLock.Lock()
+		stats, found = c.exporterStats[exporter]
+		if !found {
+			stats = &exporterStatsState{firstSeen: now}
+			c.exporterStats[exporter] = stats
+		}
+		c.exporterStatsLock.Unlock()
+	}
+	stats.lastSeen.Store(now.UnixNano())
+	if current, _ := stats.decoder.Load().(string); current != decoder {
+		stats.decoder.Store(decoder)
+	}
+	stats.version.Store(packetVersion(decoder, payload))
+	if ok {
+		stats.packets.Add(1)
+	} else {
+		stats.errors.Add(1)
+	}
+}
+
+// AllExporterStats returns the decoding statistics for all the exporters.
+func (c *Component) AllExporterStats() map[netip.Addr]ExporterStats {
+	c.exporterStatsLock.RLock()
+	defer c.exporterStatsLock.RUnlock()
+	result := make(map[netip.Addr]ExporterStats, len(c.exporterStats))
+	for exporter, stats := range c.exporterStats {
+		decoder, _ := stats.decoder.Load().(string)
+		result[exporter] = ExporterStats{
+			Decoder:   decoder,
+			Version:   stats.version.Load(),
+			Packets:   stats.packets.Load(),
+			Errors:    stats.errors.Load(),
+			FirstSeen: stats.firstSeen,
+			LastSeen:  time.Unix(0, stats.lastSeen.Load()),
+		}
+	}
+	return result
+}
+
+// ExpireExporterStats removes the decoding statistics of the exporters which
+// did not send any packet during the provided duration.
+func (c *Component) ExpireExporterStats(idle time.Duration) {
+	before := c.d.Clock.Now().Add(-idle).UnixNano()
+	c.exporterStatsLock.Lock()
+	defer c.exporterStatsLock.Unlock()
+	for exporter, stats := range c.exporterStats {
+		if stats.lastSeen.Load() < before {
+			delete(c.exporterStats, exporter)
+		}
+	}
+}
+
+// packetVersion extracts the protocol version from the header of a packet.
+// It returns 0 if the version cannot be extracted.
+func packetVersion(decoder string, payload []byte) uint32 {
+	switch decoder {
+	case "netflow":
+		if len(payload) >= 2 {
+			return uint32(binary.BigEndian.Uint16(payload))
+		}
+	case "sflow":
+		if len(payload) >= 4 {
+			return binary.BigEndian.Uint32(payload)
+		}
+	}
+	return 0
+}
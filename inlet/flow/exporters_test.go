@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"net"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/decoder/netflow"
+	"akvorado/inlet/flow/decoder/protobuf"
+)
+
+func TestExporterStats(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	dec := c.wrapDecoder(
+		netflow.New(r, decoder.Dependencies{Schema: c.d.Schema}, decoder.Option{TimestampSource: decoder.TimestampSourceUDP}),
+		false)
+	source := net.ParseIP("192.0.2.1")
+
+	for _, payload := range [][]byte{
+		helpers.ReadPcapL4(t, filepath.Join("decoder", "netflow", "testdata", "template.pcap")),
+		helpers.ReadPcapL4(t, filepath.Join("decoder", "netflow", "testdata", "data.pcap")),
+		{0, 10, 0, 0},
+	} {
+		dec.Decode(decoder.RawFlow{Payload: payload, Source: source})
+	}
+
+	all := c.AllExporterStats()
+	if len(all) != 1 {
+		t.Fatalf("AllExporterStats() returned %d exporters, expected 1", len(all))
+	}
+	got, ok := all[netip.MustParseAddr("::ffff:192.0.2.1")]
+	if !ok {
+		t.Fatal("AllExporterStats() did not find exporter")
+	}
+	if got.FirstSeen.IsZero() || got.LastSeen.Before(got.FirstSeen) {
+		t.Errorf("AllExporterStats() first seen %v, last seen %v", got.FirstSeen, got.LastSeen)
+	}
+	got.FirstSeen, got.LastSeen = time.Time{}, time.Time{}
+	expected := ExporterStats{
+		Decoder: "netflow",
+		Version: 10,
+		Packets: 2,
+		Errors:  1,
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("AllExporterStats() (-got, +want):\n%s", diff)
+	}
+
+	c.ExpireExporterStats(time.Hour)
+	if all := c.AllExporterStats(); len(all) != 1 {
+		t.Fatal("ExpireExporterStats() removed an active exporter")
+	}
+	time.Sleep(10 * time.Millisecond)
+	c.ExpireExporterStats(5 * time.Millisecond)
+	if all := c.AllExporterStats(); len(all) != 0 {
+		t.Fatal("ExpireExporterStats() did not remove an idle exporter")
+	}
+}
+
+func TestExporterStatsUseExporterAddress(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	dec := c.wrapDecoder(
+		protobuf.New(r, decoder.Dependencies{Schema: c.d.Schema}, decoder.Option{}),
+		false)
+
+	dec.Decode(decoder.RawFlow{
+		Payload: protobuf.EncodeFlowRecord(t, map[string]interface{}{
+			"exporter_address": []byte{198, 51, 100, 1},
+			"bytes":            uint64(100),
+		}),
+		Source: net.ParseIP("192.0.2.1"),
+	})
+	dec.Decode(decoder.RawFlow{
+		Payload: []byte{0x08},
+		Source:  net.ParseIP("192.0.2.1"),
+	})
+
+	got := c.AllExporterStats()
+	for exporter, stats := range got {
+		stats.FirstSeen, stats.LastSeen = time.Time{}, time.Time{}
+		got[exporter] = stats
+	}
+	expected := map[netip.Addr]ExporterStats{
+		// Successfully decoded flows are attributed to their exporter
+		netip.MustParseAddr("::ffff:198.51.100.1"): {Decoder: "protobuf", Packets: 1},
+		// Decoding errors are attributed to the source
+		netip.MustParseAddr("::ffff:192.0.2.1"): {Decoder: "protobuf", Errors: 1},
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("AllExporterStats() (-got, +want):\n%s", diff)
+	}
+}
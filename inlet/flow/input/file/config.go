@@ -9,6 +9,8 @@ import "akvorado/inlet/flow/input"
 type Configuration struct {
 	// Paths to use as input
 	Paths []string `validate:"min=1,dive,required"`
+	// MaxFlows tells how many flows to read before stopping (0 means no limit)
+	MaxFlows uint
 }
 
 // DefaultConfiguration descrives the default configuration for file input.
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"net"
 	"os"
-	"time"
 
 	"gopkg.in/tomb.v2"
 
@@ -48,21 +47,31 @@ func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Comp
 func (in *Input) Start() (<-chan []*schema.FlowMessage, error) {
 	in.r.Info().Msg("file input starting")
 	in.t.Go(func() error {
+		var count uint
 		for idx := 0; true; idx++ {
+			if in.config.MaxFlows > 0 && count >= in.config.MaxFlows {
+				in.r.Info().Uint("count", count).Msg("maximum number of flows reached")
+				<-in.t.Dying()
+				return nil
+			}
 			path := in.config.Paths[idx%len(in.config.Paths)]
 			data, err := os.ReadFile(path)
 			if err != nil {
 				in.r.Err(err).Str("path", path).Msg("unable to read path")
 				return err
 			}
+			// The reception time is set by the flow component
 			flows := in.decoder.Decode(decoder.RawFlow{
-				TimeReceived: time.Now(),
-				Payload:      data,
-				Source:       net.ParseIP("127.0.0.1"),
+				Payload: data,
+				Source:  net.ParseIP("127.0.0.1"),
 			})
 			if len(flows) == 0 {
 				continue
 			}
+			if in.config.MaxFlows > 0 && count+uint(len(flows)) > in.config.MaxFlows {
+				flows = flows[:in.config.MaxFlows-count]
+			}
+			count += uint(len(flows))
 			select {
 			case <-in.t.Dying():
 				return nil
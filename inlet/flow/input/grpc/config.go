@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package grpc
+
+import "akvorado/inlet/flow/input"
+
+// Configuration describes gRPC input configuration.
+type Configuration struct {
+	// Listen tells which port to listen to.
+	Listen string `validate:"required,listen"`
+	// QueueSize defines the size of the channel used to
+	// communicate incoming flows. 0 can be used to disable
+	// buffering.
+	QueueSize uint
+	// TLS defines the TLS configuration of the listener.
+	TLS TLSConfiguration
+	// Tokens is the list of bearer tokens accepted from clients. When not
+	// empty, clients have to provide one of them.
+	Tokens []string `validate:"dive,min=1"`
+}
+
+// TLSConfiguration describes the TLS configuration of the gRPC listener.
+type TLSConfiguration struct {
+	// Enable tells if the listener should use TLS.
+	Enable bool `validate:"required_with=CertFile KeyFile ClientCAFile"`
+	// CertFile is the location of the server certificate.
+	CertFile string `validate:"required_if=Enable true"`
+	// KeyFile is the location of the server key. If empty, the key is
+	// expected to be in the certificate file.
+	KeyFile string
+	// ClientCAFile is the location of the CA certificate used to check
+	// client certificates. If not empty, clients have to present a valid
+	// certificate.
+	ClientCAFile string
+}
+
+// DefaultConfiguration is the default configuration for this input
+func DefaultConfiguration() input.Configuration {
+	return &Configuration{
+		Listen:    ":0",
+		QueueSize: 100000,
+	}
+}
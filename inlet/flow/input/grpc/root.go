@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package grpc handles gRPC listeners.
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gopkg.in/tomb.v2"
+
+	"akvorado/common/daemon"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/input"
+)
+
+// Input represents the state of a gRPC listener.
+type Input struct {
+	r      *reporter.Reporter
+	t      tomb.Tomb
+	config *Configuration
+
+	metrics struct {
+		bytes        *reporter.CounterVec
+		packets      *reporter.CounterVec
+		errors       *reporter.CounterVec
+		decodedFlows *reporter.CounterVec
+	}
+
+	address net.Addr                   // listening address, for testing purpose
+	server  *grpc.Server               // gRPC server
+	ch      chan []*schema.FlowMessage // channel to send flows to
+	decoder decoder.Decoder            // decoder to use
+}
+
+// New instantiate a new gRPC listener from the provided configuration.
+func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Component, dec decoder.Decoder) (input.Input, error) {
+	input := &Input{
+		r:       r,
+		config:  configuration,
+		ch:      make(chan []*schema.FlowMessage, configuration.QueueSize),
+		decoder: dec,
+	}
+
+	input.metrics.bytes = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "bytes_total",
+			Help: "Bytes received by the application.",
+		},
+		[]string{"listener", "exporter"},
+	)
+	input.metrics.packets = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "packets_total",
+			Help: "Packets received by the application.",
+		},
+		[]string{"listener", "exporter"},
+	)
+	input.metrics.errors = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "errors_total",
+			Help: "Errors while receiving packets by the application.",
+		},
+		[]string{"listener"},
+	)
+	input.metrics.decodedFlows = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "decoded_flows_total",
+			Help: "Number of flows decoded and written to the internal queue",
+		},
+		[]string{"listener", "exporter"},
+	)
+
+	daemon.Track(&input.t, "inlet/flow/input/grpc")
+	return input, nil
+}
+
+// Start starts listening to the provided TCP socket and producing flows.
+func (in *Input) Start() (<-chan []*schema.FlowMessage, error) {
+	in.r.Info().Str("listen", in.config.Listen).Msg("starting gRPC input")
+
+	options := []grpc.ServerOption{
+		grpc.WaitForHandlers(true),
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.StreamInterceptor(in.authenticate),
+	}
+	if in.config.TLS.Enable {
+		tlsConfig, err := in.config.TLS.makeTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	listener, err := net.Listen("tcp", in.config.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen to %v: %w", in.config.Listen, err)
+	}
+	in.address = listener.Addr()
+	in.r.Info().Str("listen", in.address.String()).Msg("gRPC input listening")
+
+	in.server = grpc.NewServer(options...)
+	in.server.RegisterService(&serviceDesc, in)
+	in.t.Go(func() error {
+		if err := in.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			return err
+		}
+		return nil
+	})
+
+	// Watch for termination and stop the server on dying
+	in.t.Go(func() error {
+		<-in.t.Dying()
+		in.server.Stop()
+		return nil
+	})
+
+	return in.ch, nil
+}
+
+// send handles a stream of flow records from a client.
+func (in *Input) send(stream grpc.ServerStream) error {
+	listen := in.config.Listen
+	var source net.IP
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		if addr, ok := p.Addr.(*net.TCPAddr); ok {
+			source = addr.IP
+		}
+	}
+	if source == nil {
+		in.metrics.errors.WithLabelValues(listen).Inc()
+		return errors.New("cannot get peer address")
+	}
+	srcIP := source.String()
+	errLogger := in.r.With().
+		Str("listen", listen).
+		Str("exporter", srcIP).
+		Logger().
+		Sample(reporter.BurstSampler(time.Minute, 1))
+
+	for {
+		var payload rawMessage
+		if err := stream.RecvMsg(&payload); err != nil {
+			if err == io.EOF {
+				return stream.SendMsg(&emptypb.Empty{})
+			}
+			errLogger.Err(err).Msg("unable to receive gRPC message")
+			in.metrics.errors.WithLabelValues(listen).Inc()
+			return err
+		}
+
+		in.metrics.bytes.WithLabelValues(listen, srcIP).Add(float64(len(payload)))
+		in.metrics.packets.WithLabelValues(listen, srcIP).Inc()
+		flows := in.decoder.Decode(decoder.RawFlow{
+			TimeReceived: time.Now(),
+			Payload:      payload,
+			Source:       source,
+		})
+		if len(flows) == 0 {
+			continue
+		}
+		// Unlike UDP, we can apply backpressure on the client.
+		select {
+		case <-in.t.Dying():
+			return nil
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case in.ch <- flows:
+			in.metrics.decodedFlows.WithLabelValues(listen, srcIP).
+				Add(float64(len(flows)))
+		}
+	}
+}
+
+// Stop stops the gRPC listener
+func (in *Input) Stop() error {
+	l := in.r.With().Str("listen", in.config.Listen).Logger()
+	defer func() {
+		close(in.ch)
+		l.Info().Msg("gRPC listener stopped")
+	}()
+	in.t.Kill(nil)
+	return in.t.Wait()
+}
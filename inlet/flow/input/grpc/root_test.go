@@ -0,0 +1,272 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/decoder/protobuf"
+	"akvorado/inlet/flow/input"
+)
+
+// startInput starts a gRPC input with the provided configuration.
+func startInput(t *testing.T, r *reporter.Reporter, configuration *Configuration) (input.Input, <-chan []*schema.FlowMessage) {
+	t.Helper()
+	sch := schema.NewMock(t)
+	in, err := configuration.New(r, daemon.NewMock(t), protobuf.New(r, decoder.Dependencies{
+		Schema: sch,
+	}, decoder.Option{}))
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	ch, err := in.Start()
+	if err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	t.Cleanup(func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	})
+	return in, ch
+}
+
+// sendRecords sends the provided flow records on a new stream.
+func sendRecords(ctx context.Context, t *testing.T, conn *grpc.ClientConn, records ...[]byte) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], "/akvorado.inlet.flow.v0.Flows/Send",
+		grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		t.Fatalf("NewStream() error:\n%+v", err)
+	}
+	for _, record := range records {
+		msg := rawMessage(record)
+		if err := stream.SendMsg(&msg); err != nil {
+			// The error is returned by RecvMsg()
+			break
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error:\n%+v", err)
+	}
+	return stream.RecvMsg(&emptypb.Empty{})
+}
+
+func TestGRPCInput(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	in, ch := startInput(t, r, configuration)
+
+	// Connect to it
+	conn, err := grpc.NewClient(in.(*Input).address.String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error:\n%+v", err)
+	}
+	defer conn.Close()
+	if err := sendRecords(context.Background(), t, conn,
+		protobuf.EncodeFlowRecord(t, map[string]interface{}{
+			"time_received": uint64(1715500000),
+			"src_addr":      []byte{192, 0, 2, 1},
+			"bytes":         uint64(1500),
+			"packets":       uint64(1),
+		}),
+		protobuf.EncodeFlowRecord(t, map[string]interface{}{
+			"time_received":    uint64(1715500001),
+			"exporter_address": []byte{198, 51, 100, 1},
+			"bytes":            uint64(100),
+			"packets":          uint64(2),
+		}),
+	); err != nil {
+		t.Fatalf("sendRecords() error:\n%+v", err)
+	}
+
+	// Get it back
+	got := []*schema.FlowMessage{}
+	for range 2 {
+		select {
+		case flows := <-ch:
+			got = append(got, flows...)
+		case <-time.After(time.Second):
+			t.Fatal("no flow received")
+		}
+	}
+	expected := []*schema.FlowMessage{
+		{
+			TimeReceived:    1715500000,
+			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
+			SrcAddr:         netip.MustParseAddr("::ffff:192.0.2.1"),
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   1500,
+				schema.ColumnPackets: 1,
+			},
+		}, {
+			TimeReceived:    1715500001,
+			ExporterAddress: netip.MustParseAddr("::ffff:198.51.100.1"),
+			ProtobufDebug: map[schema.ColumnKey]interface{}{
+				schema.ColumnBytes:   100,
+				schema.ColumnPackets: 2,
+			},
+		},
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Input data (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_input_grpc_", "bytes", "packets", "decoded_flows")
+	expectedMetrics := map[string]string{
+		`bytes_total{exporter="127.0.0.1",listener="127.0.0.1:0"}`:         "37",
+		`packets_total{exporter="127.0.0.1",listener="127.0.0.1:0"}`:       "2",
+		`decoded_flows_total{exporter="127.0.0.1",listener="127.0.0.1:0"}`: "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
+	}
+}
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 in the
+// provided file.
+func writeCertificate(t *testing.T, file string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error:\n%+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "akvorado"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error:\n%+v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error:\n%+v", err)
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	content = append(content, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error:\n%+v", err)
+	}
+	return cert
+}
+
+func TestGRPCInputTLSAndTokens(t *testing.T) {
+	r := reporter.NewMock(t)
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	cert := writeCertificate(t, certFile)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	configuration.TLS = TLSConfiguration{
+		Enable:   true,
+		CertFile: certFile,
+	}
+	configuration.Tokens = []string{"secret1", "secret2"}
+	in, ch := startInput(t, r, configuration)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	conn, err := grpc.NewClient(in.(*Input).address.String(),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error:\n%+v", err)
+	}
+	defer conn.Close()
+	record := protobuf.EncodeFlowRecord(t, map[string]interface{}{
+		"bytes":   uint64(1500),
+		"packets": uint64(1),
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		err := sendRecords(context.Background(), t, conn, record)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("sendRecords() error:\n%+v", err)
+		}
+	})
+	t.Run("bad token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret3")
+		err := sendRecords(ctx, t, conn, record)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("sendRecords() error:\n%+v", err)
+		}
+	})
+	t.Run("good token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret2")
+		if err := sendRecords(ctx, t, conn, record); err != nil {
+			t.Fatalf("sendRecords() error:\n%+v", err)
+		}
+		select {
+		case flows := <-ch:
+			if len(flows) != 1 {
+				t.Fatalf("received %d flows, expected 1", len(flows))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no flow received")
+		}
+	})
+	t.Run("without TLS", func(t *testing.T) {
+		conn, err := grpc.NewClient(in.(*Input).address.String(),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("grpc.NewClient() error:\n%+v", err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := conn.NewStream(ctx, &serviceDesc.Streams[0], "/akvorado.inlet.flow.v0.Flows/Send"); err == nil {
+			t.Fatal("NewStream() did not error")
+		}
+	})
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_input_grpc_", "errors")
+	expectedMetrics := map[string]string{
+		`errors_total{listener="127.0.0.1:0"}`: "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
+	}
+}
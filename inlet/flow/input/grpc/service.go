@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package grpc
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// The service is described in inlet/flow/decoder/protobuf/flow.proto. Each
+// message is a FlowRecord which is not unmarshaled here: it is handed as is to
+// the decoder associated with the input (which should be "protobuf").
+
+// flowsServer is the server API for the Flows service.
+type flowsServer interface {
+	send(grpc.ServerStream) error
+}
+
+func sendHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(flowsServer).send(stream)
+}
+
+// serviceDesc is the description of the Flows service.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "akvorado.inlet.flow.v0.Flows",
+	HandlerType: (*flowsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Send",
+			Handler:       sendHandler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "inlet/flow/decoder/protobuf/flow.proto",
+}
+
+// rawMessage is a message kept in its wire format.
+type rawMessage []byte
+
+// rawCodec is a codec keeping received messages in their wire format when
+// asked to unmarshal them into a rawMessage. Other messages are handled as
+// regular protobuf messages.
+type rawCodec struct{}
+
+// Marshal returns the wire format of v.
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case *rawMessage:
+		return *m, nil
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, fmt.Errorf("cannot marshal %T", v)
+}
+
+// Unmarshal parses the wire format into v.
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case *rawMessage:
+		// The buffer is not reused by gRPC once handed to us.
+		*m = data
+		return nil
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return fmt.Errorf("cannot unmarshal into %T", v)
+}
+
+// Name returns the name of the codec.
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// authenticate is a stream interceptor checking the bearer token provided by
+// the client.
+func (in *Input) authenticate(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if len(in.config.Tokens) == 0 {
+		return handler(srv, stream)
+	}
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		for _, candidate := range in.config.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+				return handler(srv, stream)
+			}
+		}
+	}
+	in.metrics.errors.WithLabelValues(in.config.Listen).Inc()
+	return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
@@ -9,8 +9,16 @@ import "akvorado/inlet/flow/input"
 type Configuration struct {
 	// Listen tells which port to listen to.
 	Listen string `validate:"required,listen"`
+	// AdditionalListen tells which additional ports to listen to. They
+	// share the decoder and the settings of this input.
+	AdditionalListen []string `validate:"dive,listen"`
 	// Workers define the number of workers to use for receiving flows.
+	// Each worker gets its own socket bound to the same address with
+	// SO_REUSEPORT, letting the kernel spread the load between them.
 	Workers int `validate:"required,min=1"`
+	// BindDevice restricts the listening sockets to the provided network
+	// device (SO_BINDTODEVICE). This is only supported on Linux.
+	BindDevice string
 	// QueueSize defines the size of the channel used to
 	// communicate incoming flows. 0 can be used to disable
 	// buffering.
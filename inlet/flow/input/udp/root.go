@@ -34,12 +34,15 @@ type Input struct {
 		errors        *reporter.CounterVec
 		outDrops      *reporter.CounterVec
 		inDrops       *reporter.GaugeVec
+		bufferSize    *reporter.GaugeVec
+		queueLength   *reporter.GaugeVec
 		decodedFlows  *reporter.CounterVec
 	}
 
-	address net.Addr                   // listening address, for testing purpoese
-	ch      chan []*schema.FlowMessage // channel to send flows to
-	decoder decoder.Decoder            // decoder to use
+	address             net.Addr                   // listening address, for testing purpoese
+	additionalAddresses []net.Addr                 // additional listening addresses, for testing purpose
+	ch                  chan []*schema.FlowMessage // channel to send flows to
+	decoder             decoder.Decoder            // decoder to use
 }
 
 // New instantiate a new UDP listener from the provided configuration.
@@ -94,6 +97,20 @@ func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Comp
 		},
 		[]string{"listener", "worker"},
 	)
+	input.metrics.bufferSize = r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "buffer_size_bytes",
+			Help: "Effective size of the receive buffer of the listening socket.",
+		},
+		[]string{"listener", "worker"},
+	)
+	input.metrics.queueLength = r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "queue_length",
+			Help: "Number of messages in the internal queue (Linux only).",
+		},
+		[]string{"listener"},
+	)
 	input.metrics.decodedFlows = r.CounterVec(
 		reporter.CounterOpts{
 			Name: "decoded_flows_total",
@@ -110,56 +127,97 @@ func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Comp
 func (in *Input) Start() (<-chan []*schema.FlowMessage, error) {
 	in.r.Info().Str("listen", in.config.Listen).Msg("starting UDP input")
 
-	// Listen to UDP port
-	conns := []*net.UDPConn{}
-	for i := range in.config.Workers {
-		var listenAddr net.Addr
-		if in.address != nil {
-			// We already are listening on one address, let's
-			// listen to the same (useful when using :0).
-			listenAddr = in.address
-		} else {
-			var err error
-			listenAddr, err = net.ResolveUDPAddr("udp", in.config.Listen)
-			if err != nil {
-				return nil, fmt.Errorf("unable to resolve %v: %w", in.config.Listen, err)
-			}
-		}
-		pconn, err := listenConfig.ListenPacket(in.t.Context(context.Background()), "udp", listenAddr.String())
-		if err != nil {
-			return nil, fmt.Errorf("unable to listen to %v: %w", listenAddr, err)
+	// Listen to UDP ports
+	type listener struct {
+		conn   *net.UDPConn
+		listen string
+		worker string
+	}
+	listeners := []listener{}
+	closeAll := func() {
+		for _, li := range listeners {
+			li.conn.Close()
 		}
-		udpConn := pconn.(*net.UDPConn)
-		in.address = udpConn.LocalAddr()
-		if i == 0 {
-			in.r.Info().Str("listen", in.address.String()).Msg("UDP input listening")
+	}
+	lc := listenConfig(in.config.BindDevice)
+	for idx, listen := range append([]string{in.config.Listen}, in.config.AdditionalListen...) {
+		var boundAddr net.Addr
+		if idx == 0 {
+			boundAddr = in.address
 		}
-		if in.config.ReceiveBuffer > 0 {
-			if err := udpConn.SetReadBuffer(int(in.config.ReceiveBuffer)); err != nil {
-				in.r.Warn().
-					Str("error", err.Error()).
-					Str("listen", in.config.Listen).
-					Msgf("unable to set requested buffer size (%d bytes)", in.config.ReceiveBuffer)
+		for i := range in.config.Workers {
+			var listenAddr net.Addr
+			if boundAddr != nil {
+				// We already are listening on one address, let's
+				// listen to the same (useful when using :0).
+				listenAddr = boundAddr
+			} else {
+				var err error
+				listenAddr, err = net.ResolveUDPAddr("udp", listen)
+				if err != nil {
+					closeAll()
+					return nil, fmt.Errorf("unable to resolve %v: %w", listen, err)
+				}
+			}
+			pconn, err := lc.ListenPacket(in.t.Context(context.Background()), "udp", listenAddr.String())
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("unable to listen to %v: %w", listenAddr, err)
+			}
+			udpConn := pconn.(*net.UDPConn)
+			boundAddr = udpConn.LocalAddr()
+			if idx == 0 {
+				in.address = boundAddr
+			} else if i == 0 {
+				in.additionalAddresses = append(in.additionalAddresses, boundAddr)
+			}
+			if i == 0 {
+				in.r.Info().Str("listen", boundAddr.String()).Msg("UDP input listening")
+			}
+			if in.config.ReceiveBuffer > 0 {
+				if err := udpConn.SetReadBuffer(int(in.config.ReceiveBuffer)); err != nil {
+					in.r.Warn().
+						Str("error", err.Error()).
+						Str("listen", listen).
+						Msgf("unable to set requested buffer size (%d bytes)", in.config.ReceiveBuffer)
+				} else if size, err := getReceiveBuffer(udpConn); err != nil {
+					in.r.Warn().
+						Str("error", err.Error()).
+						Str("listen", listen).
+						Msg("unable to get effective buffer size")
+				} else {
+					in.metrics.bufferSize.WithLabelValues(listen, strconv.Itoa(i)).Set(float64(size))
+					if uint(size) < in.config.ReceiveBuffer {
+						in.r.Warn().
+							Str("listen", listen).
+							Msgf("requested buffer size (%d bytes) clamped by the kernel to %d bytes (check net.core.rmem_max)",
+								in.config.ReceiveBuffer, size)
+					}
+				}
 			}
-		}
 
-		conns = append(conns, udpConn)
+			listeners = append(listeners, listener{
+				conn:   udpConn,
+				listen: listen,
+				worker: strconv.Itoa(i),
+			})
+		}
 	}
 
-	for i := range in.config.Workers {
-		workerID := i
-		worker := strconv.Itoa(i)
+	for _, li := range listeners {
+		conn := li.conn
+		listen := li.listen
+		worker := li.worker
 		in.t.Go(func() error {
 			payload := make([]byte, 9000)
 			oob := make([]byte, oobLength)
-			listen := in.config.Listen
 			l := in.r.With().
 				Str("worker", worker).
 				Str("listen", listen).
 				Logger()
 			errLogger := l.Sample(reporter.BurstSampler(time.Minute, 1))
 			for count := 0; ; count++ {
-				n, oobn, _, source, err := conns[workerID].ReadMsgUDP(payload, oob)
+				n, oobn, _, source, err := conn.ReadMsgUDP(payload, oob)
 				if err != nil {
 					if errors.Is(err, net.ErrClosed) {
 						return nil
@@ -176,6 +234,8 @@ func (in *Input) Start() (<-chan []*schema.FlowMessage, error) {
 					if count < 100 || count%100 == 0 {
 						in.metrics.inDrops.WithLabelValues(listen, worker).Set(
 							float64(oobMsg.Drops))
+						in.metrics.queueLength.WithLabelValues(listen).Set(
+							float64(len(in.ch)))
 					}
 				}
 				if oobMsg.Received.IsZero() {
@@ -204,22 +264,40 @@ func (in *Input) Start() (<-chan []*schema.FlowMessage, error) {
 					in.metrics.decodedFlows.WithLabelValues(listen, worker, srcIP).
 						Add(float64(len((flows))))
 				default:
+					// Drop the oldest message to make room for this one. The
+					// drop is accounted to this listener as the oldest
+					// message may come from another one. As the new
+					// message only takes the place of the dropped one, it
+					// is not counted as decoded.
 					errLogger.Warn().Msgf("dropping flow due to queue full (size %d)",
 						in.config.QueueSize)
-					in.metrics.outDrops.WithLabelValues(listen, worker, srcIP).
-						Inc()
+					evicted := false
+					select {
+					case <-in.ch:
+						evicted = true
+						in.metrics.outDrops.WithLabelValues(listen, worker, srcIP).
+							Inc()
+					default:
+					}
+					select {
+					case in.ch <- flows:
+						if !evicted {
+							in.metrics.decodedFlows.WithLabelValues(listen, worker, srcIP).
+								Add(float64(len((flows))))
+						}
+					default:
+						in.metrics.outDrops.WithLabelValues(listen, worker, srcIP).
+							Inc()
+					}
 				}
 			}
 		})
-
 	}
 
 	// Watch for termination and close on dying
 	in.t.Go(func() error {
 		<-in.t.Dying()
-		for _, conn := range conns {
-			conn.Close()
-		}
+		closeAll()
 		return nil
 	})
 
@@ -6,6 +6,8 @@ package udp
 import (
 	"net"
 	"net/netip"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -82,6 +84,7 @@ func TestUDPInput(t *testing.T) {
 		`decoded_flows_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:                "1",
 		`packets_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:                      "1",
 		`in_dropped_packets_total{listener="127.0.0.1:0",worker="0"}`:                                "0",
+		`queue_length{listener="127.0.0.1:0"}`:                                                       "0",
 		`summary_size_bytes_count{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:           "1",
 		`summary_size_bytes_sum{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:             "12",
 		`summary_size_bytes{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0",quantile="0.5"}`:  "12",
@@ -134,6 +137,7 @@ func TestOverflow(t *testing.T) {
 		`bytes_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:                        "120",
 		`decoded_flows_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:                "1",
 		`in_dropped_packets_total{listener="127.0.0.1:0",worker="0"}`:                                "0",
+		`queue_length{listener="127.0.0.1:0"}`:                                                       "1",
 		`out_dropped_packets_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:          "9",
 		`packets_total{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:                      "10",
 		`summary_size_bytes_count{exporter="127.0.0.1",listener="127.0.0.1:0",worker="0"}`:           "10",
@@ -146,3 +150,155 @@ func TestOverflow(t *testing.T) {
 		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
 	}
 }
+
+func TestReceiveBuffer(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	configuration.ReceiveBuffer = 65536
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{Schema: schema.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if _, err := in.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	defer func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	}()
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_input_udp_", "buffer_size_bytes")
+	expectedMetrics := map[string]string{
+		`buffer_size_bytes{listener="127.0.0.1:0",worker="0"}`: "65536",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestAdditionalListen(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	// Use a different name for the same address to get distinct labels.
+	// Other loopback addresses are not available on all platforms.
+	configuration.AdditionalListen = []string{"localhost:0"}
+	configuration.Workers = 2
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{Schema: schema.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	ch, err := in.Start()
+	if err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	defer func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	}()
+
+	// Send data to both addresses
+	for _, addr := range append([]net.Addr{in.(*Input).address}, in.(*Input).additionalAddresses...) {
+		conn, err := net.Dial("udp", addr.String())
+		if err != nil {
+			t.Fatalf("Dial() error:\n%+v", err)
+		}
+		if _, err := conn.Write([]byte("hello world!")); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+		select {
+		case <-ch:
+		case <-time.After(20 * time.Millisecond):
+			t.Fatalf("no decoded flows received from %s", addr)
+		}
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_input_udp_", "packets_total")
+	if len(gotMetrics) != 2 {
+		t.Fatalf("GetMetrics() should have returned two listeners, got:\n%v", gotMetrics)
+	}
+	gotMetrics = r.GetMetrics("akvorado_inlet_flow_input_udp_", "queue_length")
+	if len(gotMetrics) != 2 {
+		t.Fatalf("GetMetrics() should have returned two queue lengths, got:\n%v", gotMetrics)
+	}
+}
+
+func TestAdditionalListenError(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	configuration.AdditionalListen = []string{"192.0.2.1:0"}
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{Schema: schema.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	_, err = in.Start()
+	if err == nil {
+		in.Stop()
+		t.Fatal("Start() did not error")
+	}
+	if !strings.Contains(err.Error(), "192.0.2.1:0") {
+		t.Fatalf("Start() error should mention the address, got:\n%+v", err)
+	}
+}
+
+func TestBindDevice(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Skip Linux-only test")
+	}
+	t.Run("loopback", func(t *testing.T) {
+		r := reporter.NewMock(t)
+		configuration := DefaultConfiguration().(*Configuration)
+		configuration.Listen = "127.0.0.1:0"
+		configuration.BindDevice = "lo"
+		configuration.Workers = 2
+		in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{Schema: schema.NewMock(t)})
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		ch, err := in.Start()
+		if err != nil {
+			t.Fatalf("Start() error:\n%+v", err)
+		}
+		defer func() {
+			if err := in.Stop(); err != nil {
+				t.Fatalf("Stop() error:\n%+v", err)
+			}
+		}()
+
+		conn, err := net.Dial("udp", in.(*Input).address.String())
+		if err != nil {
+			t.Fatalf("Dial() error:\n%+v", err)
+		}
+		if _, err := conn.Write([]byte("hello world!")); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+		select {
+		case <-ch:
+		case <-time.After(20 * time.Millisecond):
+			t.Fatal("no decoded flows received")
+		}
+	})
+
+	t.Run("unknown device", func(t *testing.T) {
+		r := reporter.NewMock(t)
+		configuration := DefaultConfiguration().(*Configuration)
+		configuration.Listen = "127.0.0.1:0"
+		configuration.BindDevice = "akvorado-none"
+		in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{Schema: schema.NewMock(t)})
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		_, err = in.Start()
+		if err == nil {
+			in.Stop()
+			t.Fatal("Start() did not error")
+		}
+		if !strings.Contains(err.Error(), `"akvorado-none"`) {
+			t.Fatalf("Start() error should mention the device, got:\n%+v", err)
+		}
+	})
+}
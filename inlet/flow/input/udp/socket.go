@@ -16,19 +16,49 @@ type oobMessage struct {
 	Received time.Time
 }
 
-// listenConfig configures a listening socket to reuse port and return overflows
-var listenConfig = net.ListenConfig{
-	Control: func(_, _ string, c syscall.RawConn) error {
-		var err error
-		c.Control(func(fd uintptr) {
-			opts := udpSocketOptions
-			for _, opt := range opts {
-				err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, opt, 1)
-				if err != nil {
-					return
+// listenConfig returns a configuration for listening sockets to reuse port
+// and return overflows. When device is not empty, sockets are bound to this
+// network device.
+func listenConfig(device string) net.ListenConfig {
+	return net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var err error
+			cerr := c.Control(func(fd uintptr) {
+				opts := udpSocketOptions
+				for _, opt := range opts {
+					err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, opt, 1)
+					if err != nil {
+						return
+					}
 				}
+				if device != "" {
+					err = bindToDevice(int(fd), device)
+				}
+			})
+			if cerr != nil {
+				return cerr
 			}
-		})
-		return err
-	},
+			return err
+		},
+	}
+}
+
+// getReceiveBuffer returns the effective receive buffer size of a socket, as
+// it would have been requested with SetReadBuffer.
+func getReceiveBuffer(conn *net.UDPConn) (int, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	cerr := rawConn.Control(func(fd uintptr) {
+		size, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+	})
+	if cerr != nil {
+		return 0, cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return size / receiveBufferOverhead, nil
 }
@@ -6,6 +6,7 @@
 package udp
 
 import (
+	"fmt"
 	"syscall"
 	"time"
 
@@ -21,13 +22,16 @@ var (
 		unix.SO_REUSEADDR, unix.SO_REUSEPORT,
 		// Get the number of dropped packets
 		unix.SO_RXQ_OVFL,
-		// Ask the kernel to timestamp incoming packets
-		unix.SO_TIMESTAMP | unix.SOF_TIMESTAMPING_RX_SOFTWARE,
+		// Ask the kernel to timestamp incoming packets (nanosecond precision)
+		unix.SO_TIMESTAMPNS,
 	}
+	// The kernel doubles the requested receive buffer size to account
+	// for bookkeeping overhead.
+	receiveBufferOverhead = 2
 )
 
 // parseSocketControlMessage parses b and extract the number of drops
-// returned (SO_RXQ_OVFL).
+// returned (SO_RXQ_OVFL) and the reception timestamp (SO_TIMESTAMPNS).
 func parseSocketControlMessage(b []byte) (oobMessage, error) {
 	result := oobMessage{}
 
@@ -39,12 +43,20 @@ func parseSocketControlMessage(b []byte) (oobMessage, error) {
 	for _, cmsg := range cmsgs {
 		if cmsg.Header.Level == unix.SOL_SOCKET && cmsg.Header.Type == unix.SO_RXQ_OVFL {
 			result.Drops = helpers.NativeEndian.Uint32(cmsg.Data)
-		} else if cmsg.Header.Level == unix.SOL_SOCKET && cmsg.Header.Type == unix.SO_TIMESTAMP {
-			// We only are interested in the current second.
+		} else if cmsg.Header.Level == unix.SOL_SOCKET && cmsg.Header.Type == unix.SCM_TIMESTAMPNS {
 			result.Received = time.Unix(
 				int64(helpers.NativeEndian.Uint64(cmsg.Data)),
-				int64(helpers.NativeEndian.Uint64(cmsg.Data[8:]))*1000)
+				int64(helpers.NativeEndian.Uint64(cmsg.Data[8:])))
 		}
 	}
 	return result, nil
 }
+
+// bindToDevice binds the socket to the provided network device
+// (SO_BINDTODEVICE).
+func bindToDevice(fd int, device string) error {
+	if err := unix.BindToDevice(fd, device); err != nil {
+		return fmt.Errorf("unable to bind to device %q: %w", device, err)
+	}
+	return nil
+}
@@ -5,14 +5,24 @@
 
 package udp
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
 
 var (
-	oobLength        = 0
-	udpSocketOptions = []int{unix.SO_REUSEADDR, unix.SO_REUSEPORT}
+	oobLength             = 0
+	udpSocketOptions      = []int{unix.SO_REUSEADDR, unix.SO_REUSEPORT}
+	receiveBufferOverhead = 1
 )
 
 // parseSocketControlMessage always returns 0.
 func parseSocketControlMessage(_ []byte) (oobMessage, error) {
 	return oobMessage{}, nil
 }
+
+// bindToDevice is not supported on this platform.
+func bindToDevice(_ int, _ string) error {
+	return errors.New("binding to a device is not supported on this platform")
+}
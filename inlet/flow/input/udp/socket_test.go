@@ -17,7 +17,8 @@ func TestParseSocketControlMessage(t *testing.T) {
 	if runtime.GOOS != "linux" {
 		t.Skip("Skip Linux-only test")
 	}
-	server, err := listenConfig.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	lc := listenConfig("")
+	server, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
 	if err != nil {
 		t.Fatalf("ListenPacket() error:\n%+v", err)
 	}
@@ -73,4 +74,7 @@ outer:
 	if oobMsg.Drops == 0 {
 		t.Fatal("no drops detected")
 	}
+	if since := time.Since(oobMsg.Received); since < 0 || since > time.Second {
+		t.Fatalf("parseSocketControlMessage() received timestamp %s is not recent", oobMsg.Received)
+	}
 }
@@ -12,10 +12,10 @@ import (
 )
 
 type limiter struct {
-	l           *rate.Limiter
-	dropped     uint64  // dropped during the current second
-	total       uint64  // total during the current second
-	dropRate    float64 // drop rate during the last second
+	l           *rate.Limiter // nil if not limited
+	dropped     uint64        // dropped during the current second
+	total       uint64        // total during the current second
+	dropRate    float64       // drop rate during the last second
 	currentTick time.Time
 }
 
@@ -24,17 +24,23 @@ type limiter struct {
 // rate may be modified to match current drop rate.
 func (c *Component) allowMessages(fmsgs []*schema.FlowMessage) bool {
 	count := len(fmsgs)
-	if c.config.RateLimit == 0 || count == 0 {
+	if count == 0 {
 		return true
 	}
 	exporter := fmsgs[0].ExporterAddress
+	c.limitersLock.Lock()
+	defer c.limitersLock.Unlock()
 	exporterLimiter, ok := c.limiters[exporter]
 	if !ok {
-		exporterLimiter = &limiter{
-			l: rate.NewLimiter(rate.Limit(c.config.RateLimit), int(c.config.RateLimit/10)),
+		exporterLimiter = &limiter{}
+		if limit, ok := c.config.RateLimit.Lookup(exporter); ok && limit > 0 {
+			exporterLimiter.l = rate.NewLimiter(limit, int(limit/10))
 		}
 		c.limiters[exporter] = exporterLimiter
 	}
+	if exporterLimiter.l == nil {
+		return true
+	}
 	now := time.Now()
 	tick := now.Truncate(200 * time.Millisecond) // we use a 200-millisecond resolution
 	if exporterLimiter.currentTick.UnixMilli() != tick.UnixMilli() {
@@ -46,6 +52,7 @@ func (c *Component) allowMessages(fmsgs []*schema.FlowMessage) bool {
 	exporterLimiter.total += uint64(count)
 	if !exporterLimiter.l.AllowN(now, count) {
 		exporterLimiter.dropped += uint64(count)
+		c.metrics.rateLimitedFlows.WithLabelValues(exporter.Unmap().String()).Add(float64(count))
 		return false
 	}
 	if exporterLimiter.dropRate > 0 {
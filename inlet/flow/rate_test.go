@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"net/netip"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestAllowMessages(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.RateLimit = *helpers.MustNewSubnetMap(map[string]rate.Limit{
+		"192.0.2.0/24": 100,
+	})
+	c := NewMock(t, r, config)
+
+	flows := func(exporter string, count int) []*schema.FlowMessage {
+		fmsgs := make([]*schema.FlowMessage, count)
+		for i := range fmsgs {
+			fmsgs[i] = &schema.FlowMessage{
+				ExporterAddress: netip.MustParseAddr(exporter),
+				SamplingRate:    1,
+			}
+		}
+		return fmsgs
+	}
+
+	// The burst is a tenth of the limit.
+	if !c.allowMessages(flows("::ffff:192.0.2.1", 10)) {
+		t.Error("allowMessages() should accept the first burst")
+	}
+	if c.allowMessages(flows("::ffff:192.0.2.1", 10)) {
+		t.Error("allowMessages() should refuse flows above the limit")
+	}
+	// Each exporter has its own limiter.
+	if !c.allowMessages(flows("::ffff:192.0.2.2", 10)) {
+		t.Error("allowMessages() should accept flows from another exporter")
+	}
+	// Exporters without a limit are not limited.
+	for range 10 {
+		if !c.allowMessages(flows("::ffff:198.51.100.1", 1000)) {
+			t.Fatal("allowMessages() should accept flows from an unlimited exporter")
+		}
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_", "rate_limited_")
+	expectedMetrics := map[string]string{
+		`rate_limited_flows_total{exporter="192.0.2.1"}`: "10",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	c := Component{config: Configuration{
+		RateLimit: *helpers.MustNewSubnetMap(map[string]rate.Limit{
+			"192.0.2.0/24": 10,
+		}),
+	}}
+	expected := "rate-limit[192.0.2.0/24]: 10 is lower than 100"
+	if err := c.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("Validate() error %v, expected %q", err, expected)
+	}
+}
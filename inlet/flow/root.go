@@ -5,11 +5,17 @@
 package flow
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"gopkg.in/tomb.v2"
 
 	"akvorado/common/daemon"
@@ -18,28 +24,42 @@ import (
 	"akvorado/common/schema"
 	"akvorado/inlet/flow/decoder"
 	"akvorado/inlet/flow/input"
+	"akvorado/inlet/flow/input/grpc"
+	"akvorado/inlet/flow/input/udp"
 )
 
 // Component represents the flow component.
 type Component struct {
-	r      *reporter.Reporter
-	d      *Dependencies
-	t      tomb.Tomb
-	config Configuration
+	r         *reporter.Reporter
+	d         *Dependencies
+	t         tomb.Tomb
+	config    Configuration
+	errLogger reporter.Logger
 
 	metrics struct {
-		decoderStats  *reporter.CounterVec
-		decoderErrors *reporter.CounterVec
+		decoderStats     *reporter.CounterVec
+		decoderErrors    *reporter.CounterVec
+		rateLimitedFlows *reporter.CounterVec
+		queueWait        reporter.Histogram
 	}
 
 	// Channel for sending flows out of the package.
 	outgoingFlows chan *schema.FlowMessage
 
 	// Per-exporter rate-limiters
-	limiters map[netip.Addr]*limiter
+	limitersLock sync.Mutex
+	limiters     map[netip.Addr]*limiter
+
+	// Per-exporter decoding statistics
+	exporterStatsLock sync.RWMutex
+	exporterStats     map[netip.Addr]*exporterStatsState
+
+	// Recent decoding errors
+	decodeErrors *decodeErrors
 
 	// Inputs
-	inputs []input.Input
+	inputs        []input.Input
+	runningInputs atomic.Int32
 }
 
 // Dependencies are the dependencies of the flow component.
@@ -47,6 +67,7 @@ type Dependencies struct {
 	Daemon daemon.Component
 	HTTP   *httpserver.Component
 	Schema *schema.Component
+	Clock  clock.Clock
 }
 
 // New creates a new flow component.
@@ -54,13 +75,19 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	if len(configuration.Inputs) == 0 {
 		return nil, errors.New("no input configured")
 	}
+	if dependencies.Clock == nil {
+		dependencies.Clock = clock.New()
+	}
 
 	c := Component{
 		r:             r,
 		d:             &dependencies,
 		config:        configuration,
+		errLogger:     r.Sample(reporter.BurstSampler(time.Minute, 1)),
 		outgoingFlows: make(chan *schema.FlowMessage),
 		limiters:      make(map[netip.Addr]*limiter),
+		exporterStats: make(map[netip.Addr]*exporterStatsState),
+		decodeErrors:  newDecodeErrors(configuration.RecentDecodeErrors),
 		inputs:        make([]input.Input, len(configuration.Inputs)),
 	}
 
@@ -68,6 +95,9 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	alreadyInitialized := map[string]decoder.Decoder{}
 	decs := make([]decoder.Decoder, len(configuration.Inputs))
 	for idx, input := range c.config.Inputs {
+		if _, ok := input.Config.(*grpc.Configuration); ok && input.Decoder != "protobuf" {
+			return nil, fmt.Errorf("gRPC input requires the %q decoder", "protobuf")
+		}
 		dec, ok := alreadyInitialized[input.Decoder]
 		if ok {
 			decs[idx] = dec
@@ -77,7 +107,16 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		if !ok {
 			return nil, fmt.Errorf("unknown decoder %q", input.Decoder)
 		}
-		dec = decoderfunc(r, decoder.Dependencies{Schema: c.d.Schema}, decoder.Option{TimestampSource: input.TimestampSource})
+		decoderName := input.Decoder
+		dec = decoderfunc(r, decoder.Dependencies{
+			Schema: c.d.Schema,
+			ErrorCallback: func(in decoder.RawFlow, err error) {
+				c.recordDecodeError(decoderName, in, err)
+			},
+		}, decoder.Option{
+			TimestampSource: input.TimestampSource,
+			SplitBiflows:    c.config.SplitBiflows,
+		})
 		alreadyInitialized[input.Decoder] = dec
 		decs[idx] = c.wrapDecoder(dec, input.UseSrcAddrForExporterAddr)
 	}
@@ -106,6 +145,21 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		},
 		[]string{"name"},
 	)
+	c.metrics.rateLimitedFlows = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "rate_limited_flows_total",
+			Help: "Number of flows dropped by the rate limiter.",
+		},
+		[]string{"exporter"},
+	)
+
+	c.metrics.queueWait = c.r.Histogram(
+		reporter.HistogramOpts{
+			Name:    "queue_wait_seconds",
+			Help:    "Time flows wait in the input queues.",
+			Buckets: []float64{.0001, .001, .01, .1, 1, 10},
+		},
+	)
 
 	c.d.Daemon.Track(&c.t, "inlet/flow")
 
@@ -114,10 +168,42 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 			w.Header().Set("Content-Type", "text/plain")
 			w.Write([]byte(c.d.Schema.ProtobufDefinition()))
 		}))
+	c.d.HTTP.GinRouter.GET("/api/v0/inlet/flow/decode-errors", c.DecodeErrorsHTTPHandler)
 
 	return &c, nil
 }
 
+// Validate checks the configuration of the inputs. Two UDP inputs cannot listen
+// on the same address. Rate limits should be at least 100 flows per second.
+func (c *Component) Validate() error {
+	errs := []error{}
+	for subnet, limit := range c.config.RateLimit.ToMap() {
+		if limit != 0 && limit < 100 {
+			errs = append(errs, fmt.Errorf("rate-limit[%s]: %v is lower than 100", subnet, float64(limit)))
+		}
+	}
+	used := map[string]string{}
+	check := func(listen, key string) {
+		if _, port, err := net.SplitHostPort(listen); err != nil || port == "0" {
+			return
+		}
+		if previous, ok := used[listen]; ok {
+			errs = append(errs, fmt.Errorf("%s: %q already used by %s", key, listen, previous))
+			return
+		}
+		used[listen] = key
+	}
+	for idx, input := range c.config.Inputs {
+		if config, ok := input.Config.(*udp.Configuration); ok {
+			check(config.Listen, fmt.Sprintf("inputs[%d].listen", idx))
+			for idx2, listen := range config.AdditionalListen {
+				check(listen, fmt.Sprintf("inputs[%d].additional-listen[%d]", idx, idx2))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Flows returns a channel to receive flows.
 func (c *Component) Flows() <-chan *schema.FlowMessage {
 	return c.outgoingFlows
@@ -131,7 +217,9 @@ func (c *Component) Start() error {
 		if err != nil {
 			return err
 		}
+		c.runningInputs.Add(1)
 		c.t.Go(func() error {
+			defer c.runningInputs.Add(-1)
 			defer stopper()
 			for {
 				select {
@@ -139,7 +227,12 @@ func (c *Component) Start() error {
 					return nil
 				case fmsgs := <-ch:
 					if c.allowMessages(fmsgs) {
+						now := time.Now()
 						for _, fmsg := range fmsgs {
+							if !fmsg.QueuedAt.IsZero() {
+								c.metrics.queueWait.Observe(now.Sub(fmsg.QueuedAt).Seconds())
+								fmsg.QueuedAt = now
+							}
 							select {
 							case <-c.t.Dying():
 								return nil
@@ -151,9 +244,25 @@ func (c *Component) Start() error {
 			}
 		})
 	}
+	c.r.RegisterReadinessCheck("flow", c.readinessCheck)
 	return nil
 }
 
+// readinessCheck checks all inputs are running.
+func (c *Component) readinessCheck(_ context.Context) reporter.HealthcheckResult {
+	running := int(c.runningInputs.Load())
+	if running < len(c.inputs) {
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckError,
+			Reason: fmt.Sprintf("%d/%d inputs running", running, len(c.inputs)),
+		}
+	}
+	return reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: fmt.Sprintf("%d inputs running", running),
+	}
+}
+
 // Stop stops the flow component
 func (c *Component) Stop() error {
 	defer func() {
@@ -4,6 +4,7 @@
 package flow
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -11,9 +12,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
+	"golang.org/x/time/rate"
+
+	"akvorado/common/daemon"
 	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
 	"akvorado/common/reporter"
+	"akvorado/common/schema"
 	"akvorado/inlet/flow/input/file"
+	"akvorado/inlet/flow/input/grpc"
 )
 
 func TestFlow(t *testing.T) {
@@ -74,7 +82,7 @@ func TestFlow(t *testing.T) {
 		if runtime.GOOS == "Linux" {
 			r := reporter.NewMock(t)
 			config := DefaultConfiguration()
-			config.RateLimit = 1000
+			config.RateLimit = *helpers.MustNewSubnetMap(map[string]rate.Limit{"::/0": 1000})
 			config.Inputs = inputs
 			c := NewMock(t, r, config)
 
@@ -123,3 +131,98 @@ func TestFlow(t *testing.T) {
 		}
 	}
 }
+
+func TestFlowReadiness(t *testing.T) {
+	r := reporter.NewMock(t)
+	NewMock(t, r, DefaultConfiguration())
+	got := r.RunReadinessChecks(context.Background())
+	expected := reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: "2 inputs running",
+	}
+	if diff := helpers.Diff(got.Details["flow"], expected); diff != "" {
+		t.Fatalf("RunReadinessChecks() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestFlowGRPCRequiresProtobuf(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Inputs = []InputConfiguration{{
+		Decoder: "netflow",
+		Config:  grpc.DefaultConfiguration(),
+	}}
+	_, err := New(r, config, Dependencies{
+		Daemon: daemon.NewMock(t),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
+
+func TestFlowReplay(t *testing.T) {
+	_, src, _, _ := runtime.Caller(0)
+	base := path.Join(path.Dir(src), "decoder", "netflow", "testdata")
+	outDir := t.TempDir()
+	outFiles := []string{}
+	for idx, f := range []string{"template.pcap", "data.pcap"} {
+		outFile := path.Join(outDir, fmt.Sprintf("data-%d", idx))
+		err := os.WriteFile(outFile, helpers.ReadPcapL4(t, path.Join(base, f)), 0o666)
+		if err != nil {
+			t.Fatalf("WriteFile(%q) error:\n%+v", outFile, err)
+		}
+		outFiles = append(outFiles, outFile)
+	}
+
+	replay := func(clk clock.Clock) []*schema.FlowMessage {
+		r := reporter.NewMock(t)
+		config := DefaultConfiguration()
+		config.Inputs = []InputConfiguration{
+			{
+				Decoder: "netflow",
+				Config: &file.Configuration{
+					Paths:    outFiles,
+					MaxFlows: 10,
+				},
+			},
+		}
+		c, err := New(r, config, Dependencies{
+			Daemon: daemon.NewMock(t),
+			HTTP:   httpserver.NewMock(t, r),
+			Schema: schema.NewMock(t),
+			Clock:  clk,
+		})
+		if err != nil {
+			t.Fatalf("New() error:\n%+v", err)
+		}
+		helpers.StartStop(t, c)
+		flows := []*schema.FlowMessage{}
+		for range 10 {
+			select {
+			case flow := <-c.Flows():
+				// Latency measurements use the real clock
+				flow.ReceivedAt = time.Time{}
+				flow.QueuedAt = time.Time{}
+				flows = append(flows, flow)
+			case <-time.After(time.Second):
+				t.Fatalf("no flow received")
+			}
+		}
+		return flows
+	}
+
+	clk := clock.NewMock()
+	clk.Set(time.Date(2024, time.March, 10, 14, 0, 0, 0, time.UTC))
+	first := replay(clk)
+	second := replay(clk)
+	if diff := helpers.Diff(first, second); diff != "" {
+		t.Fatalf("replay (-first, +second):\n%s", diff)
+	}
+	for _, flow := range first {
+		if flow.TimeReceived != uint64(clk.Now().Unix()) {
+			t.Fatalf("TimeReceived: got %d, expected %d", flow.TimeReceived, clk.Now().Unix())
+		}
+	}
+}
@@ -6,6 +6,9 @@
 package flow
 
 import (
+	"fmt"
+	"net/netip"
+	"reflect"
 	"testing"
 
 	"akvorado/common/daemon"
@@ -14,8 +17,14 @@ import (
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
 	"akvorado/inlet/flow/input/udp"
+
+	"golang.org/x/time/rate"
 )
 
+func init() {
+	helpers.AddPrettyFormatter(reflect.TypeOf(helpers.SubnetMap[rate.Limit]{}), fmt.Sprint)
+}
+
 // NewMock creates a new flow importer listening on a random port. It
 // is autostarted.
 func NewMock(t *testing.T, r *reporter.Reporter, config Configuration) *Component {
@@ -47,3 +56,17 @@ func NewMock(t *testing.T, r *reporter.Reporter, config Configuration) *Componen
 func (c *Component) Inject(fmsg *schema.FlowMessage) {
 	c.outgoingFlows <- fmsg
 }
+
+// InjectExporterStats sets the decoding statistics of an exporter, as if it
+// sent some packets.
+func (c *Component) InjectExporterStats(exporter netip.Addr, stats ExporterStats) {
+	state := &exporterStatsState{firstSeen: stats.FirstSeen}
+	state.lastSeen.Store(stats.LastSeen.UnixNano())
+	state.decoder.Store(stats.Decoder)
+	state.version.Store(stats.Version)
+	state.packets.Store(stats.Packets)
+	state.errors.Store(stats.Errors)
+	c.exporterStatsLock.Lock()
+	defer c.exporterStatsLock.Unlock()
+	c.exporterStats[netip.AddrFrom16(exporter.As16())] = state
+}
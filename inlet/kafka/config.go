@@ -4,10 +4,12 @@
 package kafka
 
 import (
+	"errors"
 	"time"
 
 	"github.com/IBM/sarama"
 
+	"akvorado/common/helpers/bimap"
 	"akvorado/common/kafka"
 )
 
@@ -18,6 +20,12 @@ type Configuration struct {
 	FlushInterval time.Duration `validate:"min=100ms"`
 	// FlushBytes tells to flush when there are many bytes to write
 	FlushBytes int `validate:"min=1000"`
+	// BatchSize tells to flush when there are many messages to write. 0
+	// means no limit.
+	BatchSize int `validate:"min=0"`
+	// MaxInFlight is the maximum number of unacknowledged requests sent to
+	// a broker.
+	MaxInFlight int `validate:"min=1"`
 	// MaxMessageBytes is the maximum permitted size of a message.
 	// Should be set equal or smaller than broker's
 	// `message.max.bytes`.
@@ -26,6 +34,20 @@ type Configuration struct {
 	CompressionCodec CompressionCodec
 	// QueueSize defines the size of the channel used to send to Kafka.
 	QueueSize int `validate:"min=1"`
+	// QueueFullPolicy defines what to do when the queue to send to Kafka
+	// is full.
+	QueueFullPolicy QueueFullPolicy
+	// PartitionKey defines the key used to select the partition.
+	PartitionKey PartitionKey
+	// RequiredAcks defines the level of acknowledgement required from brokers.
+	RequiredAcks RequiredAcks
+	// Idempotent enables the idempotent producer. RequiredAcks should be
+	// set to "all" and MaxInFlight to 1.
+	Idempotent bool
+	// TopicRoutes defines rules to select the topic of a flow. The first
+	// rule returning a non-empty topic is used. Otherwise, the default topic
+	// is used.
+	TopicRoutes []TopicRoute
 }
 
 // DefaultConfiguration represents the default configuration for the Kafka exporter.
@@ -34,9 +56,13 @@ func DefaultConfiguration() Configuration {
 		Configuration:    kafka.DefaultConfiguration(),
 		FlushInterval:    time.Second,
 		FlushBytes:       int(sarama.MaxRequestSize) - 1,
+		MaxInFlight:      5,
 		MaxMessageBytes:  1000000,
 		CompressionCodec: CompressionCodec(sarama.CompressionNone),
 		QueueSize:        32,
+		QueueFullPolicy:  QueueFullBlock,
+		PartitionKey:     PartitionKeyRandom,
+		RequiredAcks:     RequiredAcks(sarama.WaitForLocal),
 	}
 }
 
@@ -57,3 +83,119 @@ func (cc CompressionCodec) String() string {
 func (cc CompressionCodec) MarshalText() ([]byte, error) {
 	return []byte(cc.String()), nil
 }
+
+// PartitionKey defines how messages are assigned to partitions.
+type PartitionKey int
+
+const (
+	// PartitionKeyRandom spreads messages randomly over partitions.
+	PartitionKeyRandom PartitionKey = iota
+	// PartitionKeyExporter sends messages from the same exporter to the
+	// same partition.
+	PartitionKeyExporter
+)
+
+var partitionKeyMap = bimap.New(map[PartitionKey]string{
+	PartitionKeyRandom:   "random",
+	PartitionKeyExporter: "exporter",
+})
+
+// MarshalText turns a partition key to text.
+func (pk PartitionKey) MarshalText() ([]byte, error) {
+	got, ok := partitionKeyMap.LoadValue(pk)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown partition key")
+}
+
+// String turns a partition key to string.
+func (pk PartitionKey) String() string {
+	got, _ := partitionKeyMap.LoadValue(pk)
+	return got
+}
+
+// UnmarshalText provides a partition key from a string.
+func (pk *PartitionKey) UnmarshalText(input []byte) error {
+	got, ok := partitionKeyMap.LoadKey(string(input))
+	if ok {
+		*pk = got
+		return nil
+	}
+	return errors.New("unknown partition key")
+}
+
+// QueueFullPolicy defines what to do when the producer queue is full.
+type QueueFullPolicy int
+
+const (
+	// QueueFullBlock waits for the queue to have room, slowing down the
+	// core component.
+	QueueFullBlock QueueFullPolicy = iota
+	// QueueFullDrop drops the message.
+	QueueFullDrop
+)
+
+var queueFullPolicyMap = bimap.New(map[QueueFullPolicy]string{
+	QueueFullBlock: "block",
+	QueueFullDrop:  "drop",
+})
+
+// MarshalText turns a queue full policy to text.
+func (qfp QueueFullPolicy) MarshalText() ([]byte, error) {
+	got, ok := queueFullPolicyMap.LoadValue(qfp)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown queue full policy")
+}
+
+// String turns a queue full policy to string.
+func (qfp QueueFullPolicy) String() string {
+	got, _ := queueFullPolicyMap.LoadValue(qfp)
+	return got
+}
+
+// UnmarshalText provides a queue full policy from a string.
+func (qfp *QueueFullPolicy) UnmarshalText(input []byte) error {
+	got, ok := queueFullPolicyMap.LoadKey(string(input))
+	if ok {
+		*qfp = got
+		return nil
+	}
+	return errors.New("unknown queue full policy")
+}
+
+// RequiredAcks represents the level of acknowledgement required from brokers.
+type RequiredAcks sarama.RequiredAcks
+
+var requiredAcksMap = bimap.New(map[RequiredAcks]string{
+	RequiredAcks(sarama.NoResponse):   "none",
+	RequiredAcks(sarama.WaitForLocal): "leader",
+	RequiredAcks(sarama.WaitForAll):   "all",
+})
+
+// MarshalText turns a required acks level to text.
+func (ra RequiredAcks) MarshalText() ([]byte, error) {
+	got, ok := requiredAcksMap.LoadValue(ra)
+	if ok {
+		return []byte(got), nil
+	}
+	return nil, errors.New("unknown required acks")
+}
+
+// String turns a required acks level to string.
+func (ra RequiredAcks) String() string {
+	got, _ := requiredAcksMap.LoadValue(ra)
+	return got
+}
+
+// UnmarshalText provides a required acks level from a string.
+func (ra *RequiredAcks) UnmarshalText(input []byte) error {
+	got, ok := requiredAcksMap.LoadKey(string(input))
+	if ok {
+		*ra = got
+		return nil
+	}
+	return errors.New("unknown required acks")
+}
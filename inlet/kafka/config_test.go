@@ -45,3 +45,46 @@ func TestDefaultConfiguration(t *testing.T) {
 		t.Fatalf("validate.Struct() error:\n%+v", err)
 	}
 }
+
+func TestPartitionKeyMarshalUnmarshal(t *testing.T) {
+	partitionKeyMap.TestMarshalUnmarshal(t)
+}
+
+func TestRequiredAcksMarshalUnmarshal(t *testing.T) {
+	requiredAcksMap.TestMarshalUnmarshal(t)
+}
+
+func TestQueueFullPolicyMarshalUnmarshal(t *testing.T) {
+	queueFullPolicyMap.TestMarshalUnmarshal(t)
+}
+
+func TestValidate(t *testing.T) {
+	withBrokers := func(brokers ...string) Configuration {
+		config := DefaultConfiguration()
+		config.Brokers = brokers
+		return config
+	}
+	cases := []struct {
+		Description   string
+		Configuration Configuration
+		Error         string
+	}{
+		{"default", DefaultConfiguration(), ""},
+		{"distinct", withBrokers("kafka1:9092", "kafka2:9092"), ""},
+		{"duplicate", withBrokers("kafka1:9092", "kafka2:9092", "kafka1:9092"),
+			`brokers[2]: "kafka1:9092" already used by brokers[0]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c := Component{config: tc.Configuration}
+			err := c.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Error); diff != "" {
+				t.Fatalf("Validate() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@
 package kafka
 
 import (
-	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -59,41 +58,11 @@ func TestRealKafka(t *testing.T) {
 	}
 
 	// Try to consume the two messages
-	consumer, err := sarama.NewConsumerFromClient(client)
-	if err != nil {
-		t.Fatalf("NewConsumerGroup() error:\n%+v", err)
-	}
-	defer consumer.Close()
-	var partitions []int32
-	for {
-		partitions, err = consumer.Partitions(expectedTopicName)
-		if err != nil {
-			if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
-				// Wait for topic to be available
-				continue
-			}
-			t.Fatalf("Partitions() error:\n%+v", err)
-		}
-		break
-	}
-	partitionConsumer, err := consumer.ConsumePartition(expectedTopicName, partitions[0], sarama.OffsetOldest)
-	if err != nil {
-		t.Fatalf("ConsumePartitions() error:\n%+v", err)
-	}
-
 	got := []string{}
-	expected := []string{string(msg1), string(msg2)}
-	timeout := time.After(15 * time.Second)
-	for range len(expected) {
-		select {
-		case msg := <-partitionConsumer.Messages():
-			got = append(got, string(msg.Value))
-		case err := <-partitionConsumer.Errors():
-			t.Fatalf("consumer.Errors():\n%+v", err)
-		case <-timeout:
-		}
+	for _, msg := range ConsumeLastMessages(t, client, expectedTopicName, 2) {
+		got = append(got, string(msg))
 	}
-
+	expected := []string{string(msg1), string(msg2)}
 	if diff := helpers.Diff(got, expected); diff != "" {
 		t.Fatalf("Didn't received the expected messages (-got, +want):\n%s", diff)
 	}
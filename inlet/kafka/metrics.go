@@ -15,9 +15,15 @@ import (
 type metrics struct {
 	c *Component
 
-	messagesSent *reporter.CounterVec
-	bytesSent    *reporter.CounterVec
-	errors       *reporter.CounterVec
+	messagesSent     *reporter.CounterVec
+	bytesSent        *reporter.CounterVec
+	errors           *reporter.CounterVec
+	unroutedMessages *reporter.CounterVec
+	droppedMessages  *reporter.CounterVec
+	enqueueWait      reporter.Histogram
+	retries          reporter.Counter
+	tlsEnabled       reporter.Gauge
+	compression      *reporter.GaugeVec
 
 	kafkaIncomingByteRate  *reporter.MetricDesc
 	kafkaOutgoingByteRate  *reporter.MetricDesc
@@ -31,6 +37,7 @@ type metrics struct {
 	kafkaRecordSendRate    *reporter.MetricDesc
 	kafkaRecordsPerRequest *reporter.MetricDesc
 	kafkaCompressionRatio  *reporter.MetricDesc
+	kafkaBatchFillRatio    *reporter.MetricDesc
 }
 
 func (c *Component) initMetrics() {
@@ -50,6 +57,27 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"exporter"},
 	)
+	c.metrics.unroutedMessages = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "unrouted_messages_total",
+			Help: "Number of messages sent to the default topic because no topic route applied.",
+		},
+		[]string{"reason"},
+	)
+	c.metrics.droppedMessages = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "dropped_messages_total",
+			Help: "Number of messages dropped because the producer queue was full.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.enqueueWait = c.r.Histogram(
+		reporter.HistogramOpts{
+			Name:    "enqueue_wait_seconds",
+			Help:    "Time waited to enqueue a message when the producer queue was full.",
+			Buckets: []float64{.0001, .001, .01, .1, 1, 10},
+		},
+	)
 	c.metrics.errors = c.r.CounterVec(
 		reporter.CounterOpts{
 			Name: "errors_total",
@@ -57,6 +85,25 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"error"},
 	)
+	c.metrics.retries = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "retries_total",
+			Help: "Number of retries when sending.",
+		},
+	)
+	c.metrics.tlsEnabled = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "tls_enabled",
+			Help: "Whether the connection to the brokers is encrypted with TLS.",
+		},
+	)
+	c.metrics.compression = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "compression_codec_info",
+			Help: "Compression codec used for messages.",
+		},
+		[]string{"codec"},
+	)
 
 	c.metrics.kafkaIncomingByteRate = c.r.MetricDesc(
 		"brokers_incoming_byte_rate",
@@ -106,6 +153,10 @@ func (c *Component) initMetrics() {
 		"producer_compression_ratio",
 		"Distribution of the compression ratio times 100 of record batches.",
 		nil)
+	c.metrics.kafkaBatchFillRatio = c.r.MetricDesc(
+		"producer_batch_fill_ratio",
+		"Mean number of records per request relative to the configured batch size.",
+		nil)
 
 	c.r.MetricCollector(c.metrics)
 }
@@ -124,6 +175,7 @@ func (m metrics) Describe(ch chan<- *prometheus.Desc) {
 	ch <- m.kafkaRecordSendRate
 	ch <- m.kafkaRecordsPerRequest
 	ch <- m.kafkaCompressionRatio
+	ch <- m.kafkaBatchFillRatio
 }
 
 // Collect metrics
@@ -175,6 +227,11 @@ func (m metrics) Collect(ch chan<- prometheus.Metric) {
 		}
 		if name == "records-per-request" {
 			gomHistogram(ch, m.kafkaRecordsPerRequest, gom)
+			if m.c.config.BatchSize > 0 {
+				snap := gom.(gometrics.Histogram).Snapshot()
+				ch <- prometheus.MustNewConstMetric(m.kafkaBatchFillRatio,
+					prometheus.GaugeValue, snap.Mean()/float64(m.c.config.BatchSize))
+			}
 			return
 		}
 		if name == "compression-ratio" {
@@ -5,10 +5,13 @@
 package kafka
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -28,10 +31,16 @@ type Component struct {
 	config Configuration
 
 	kafkaTopic          string
+	topicHash           string
 	kafkaConfig         *sarama.Config
 	kafkaProducer       sarama.AsyncProducer
 	createKafkaProducer func() (sarama.AsyncProducer, error)
 	metrics             metrics
+	routeErrLogger      reporter.Logger
+
+	// Client used to check if Kafka is ready to accept flows
+	readinessLock   sync.Mutex
+	readinessClient sarama.Client
 }
 
 // Dependencies define the dependencies of the Kafka exporter.
@@ -54,7 +63,19 @@ func New(reporter *reporter.Reporter, configuration Configuration, dependencies
 	kafkaConfig.Producer.Return.Errors = true
 	kafkaConfig.Producer.Flush.Bytes = configuration.FlushBytes
 	kafkaConfig.Producer.Flush.Frequency = configuration.FlushInterval
+	kafkaConfig.Producer.Flush.Messages = configuration.BatchSize
+	kafkaConfig.Net.MaxOpenRequests = configuration.MaxInFlight
 	kafkaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	kafkaConfig.Producer.RequiredAcks = sarama.RequiredAcks(configuration.RequiredAcks)
+	if configuration.Idempotent {
+		if configuration.RequiredAcks != RequiredAcks(sarama.WaitForAll) {
+			return nil, errors.New("idempotent Kafka producer requires required-acks to be set to all")
+		}
+		if configuration.MaxInFlight != 1 {
+			return nil, errors.New("idempotent Kafka producer requires max-in-flight to be set to 1")
+		}
+		kafkaConfig.Producer.Idempotent = true
+	}
 	kafkaConfig.ChannelBufferSize = configuration.QueueSize
 	if err := kafkaConfig.Validate(); err != nil {
 		return nil, fmt.Errorf("cannot validate Kafka configuration: %w", err)
@@ -67,8 +88,17 @@ func New(reporter *reporter.Reporter, configuration Configuration, dependencies
 
 		kafkaConfig: kafkaConfig,
 		kafkaTopic:  fmt.Sprintf("%s-%s", configuration.Topic, dependencies.Schema.ProtobufMessageHash()),
+		topicHash:   dependencies.Schema.ProtobufMessageHash(),
 	}
 	c.initMetrics()
+	kafkaConfig.Producer.Retry.BackoffFunc = func(int, int) time.Duration {
+		c.metrics.retries.Inc()
+		return kafkaConfig.Producer.Retry.Backoff
+	}
+	if kafkaConfig.Net.TLS.Enable {
+		c.metrics.tlsEnabled.Set(1)
+	}
+	c.metrics.compression.WithLabelValues(configuration.CompressionCodec.String()).Set(1)
 	c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
 		return sarama.NewAsyncProducer(c.config.Brokers, c.kafkaConfig)
 	}
@@ -76,6 +106,22 @@ func New(reporter *reporter.Reporter, configuration Configuration, dependencies
 	return &c, nil
 }
 
+// Validate checks the configuration of the Kafka component. A broker cannot be
+// listed twice.
+func (c *Component) Validate() error {
+	errs := []error{}
+	seen := map[string]int{}
+	for idx, broker := range c.config.Brokers {
+		if previous, ok := seen[broker]; ok {
+			errs = append(errs, fmt.Errorf("brokers[%d]: %q already used by brokers[%d]",
+				idx, broker, previous))
+			continue
+		}
+		seen[broker] = idx
+	}
+	return errors.Join(errs...)
+}
+
 // Start starts the Kafka component.
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting Kafka component")
@@ -84,12 +130,15 @@ func (c *Component) Start() error {
 	// Create producer
 	kafkaProducer, err := c.createKafkaProducer()
 	if err != nil {
+		err = kafka.ErrorWithHint(err)
 		c.r.Err(err).
 			Str("brokers", strings.Join(c.config.Brokers, ",")).
 			Msg("unable to create async producer")
 		return fmt.Errorf("unable to create Kafka async producer: %w", err)
 	}
 	c.kafkaProducer = kafkaProducer
+	c.routeErrLogger = c.r.Sample(reporter.BurstSampler(10*time.Second, 3))
+	c.r.RegisterReadinessCheck("kafka", c.readinessCheck)
 
 	// Main loop
 	c.t.Go(func() error {
@@ -124,18 +173,109 @@ func (c *Component) Stop() error {
 	}()
 	c.r.Info().Msg("stopping Kafka component")
 	c.t.Kill(nil)
-	return c.t.Wait()
+	err := c.t.Wait()
+	c.readinessLock.Lock()
+	if c.readinessClient != nil {
+		c.readinessClient.Close()
+		c.readinessClient = nil
+	}
+	c.readinessLock.Unlock()
+	return err
 }
 
-// Send a message to Kafka.
-func (c *Component) Send(exporter string, payload []byte) {
-	c.metrics.bytesSent.WithLabelValues(exporter).Add(float64(len(payload)))
-	c.metrics.messagesSent.WithLabelValues(exporter).Inc()
-	key := make([]byte, 4)
-	binary.BigEndian.PutUint32(key, rand.Uint32())
-	c.kafkaProducer.Input() <- &sarama.ProducerMessage{
-		Topic: c.kafkaTopic,
+// readinessCheck checks that the flow topic has writable partitions. The
+// producer does not tell if it is able to reach Kafka, so we use a separate
+// client. As connecting to Kafka may take a while, the check is done in the
+// background and we give up when the provided context is done.
+func (c *Component) readinessCheck(ctx context.Context) reporter.HealthcheckResult {
+	resultChan := make(chan reporter.HealthcheckResult, 1)
+	go func() {
+		resultChan <- c.checkWritablePartitions()
+	}()
+	select {
+	case <-ctx.Done():
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckError,
+			Reason: "timeout while checking Kafka",
+		}
+	case result := <-resultChan:
+		return result
+	}
+}
+
+// checkWritablePartitions checks the flow topic has writable partitions.
+func (c *Component) checkWritablePartitions() reporter.HealthcheckResult {
+	c.readinessLock.Lock()
+	defer c.readinessLock.Unlock()
+	if c.readinessClient == nil {
+		client, err := sarama.NewClient(c.config.Brokers, c.kafkaConfig)
+		if err != nil {
+			return reporter.HealthcheckResult{
+				Status: reporter.HealthcheckError,
+				Reason: fmt.Sprintf("cannot connect to Kafka: %s", err),
+			}
+		}
+		c.readinessClient = client
+	}
+	if err := c.readinessClient.RefreshMetadata(c.kafkaTopic); err != nil {
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckError,
+			Reason: fmt.Sprintf("cannot get metadata for topic %q: %s", c.kafkaTopic, err),
+		}
+	}
+	partitions, err := c.readinessClient.WritablePartitions(c.kafkaTopic)
+	if err != nil || len(partitions) == 0 {
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckError,
+			Reason: fmt.Sprintf("no writable partition for topic %q", c.kafkaTopic),
+		}
+	}
+	return reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: fmt.Sprintf("%d writable partitions", len(partitions)),
+	}
+}
+
+// Send a message to Kafka using the default topic. It returns false if the
+// message was dropped because the queue is full.
+func (c *Component) Send(exporter string, payload []byte) bool {
+	return c.send(c.kafkaTopic, exporter, payload)
+}
+
+// SendRouted sends a message to Kafka using the topic selected by the topic
+// routes for the provided flow attributes. It returns false if the message was
+// dropped because the queue is full.
+func (c *Component) SendRouted(route Route, payload []byte) bool {
+	return c.send(c.topic(route), route.Exporter.IP, payload)
+}
+
+func (c *Component) send(topic string, exporter string, payload []byte) bool {
+	var key []byte
+	switch c.config.PartitionKey {
+	case PartitionKeyExporter:
+		key = []byte(exporter)
+	default:
+		key = make([]byte, 4)
+		binary.BigEndian.PutUint32(key, rand.Uint32())
+	}
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
 		Key:   sarama.ByteEncoder(key),
 		Value: sarama.ByteEncoder(payload),
 	}
+	select {
+	case c.kafkaProducer.Input() <- msg:
+	default:
+		// The queue is full
+		if c.config.QueueFullPolicy == QueueFullDrop {
+			c.metrics.droppedMessages.WithLabelValues(exporter).Inc()
+			return false
+		}
+		start := time.Now()
+		c.kafkaProducer.Input() <- msg
+		c.metrics.enqueueWait.Observe(time.Since(start).Seconds())
+	}
+	c.metrics.bytesSent.WithLabelValues(exporter).Add(float64(len(payload)))
+	c.metrics.messagesSent.WithLabelValues(exporter).Inc()
+	return true
 }
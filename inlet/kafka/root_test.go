@@ -4,8 +4,11 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/netip"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,11 +52,14 @@ func TestKafka(t *testing.T) {
 	c.Send("127.0.0.1", []byte("goodbye world!"))
 
 	time.Sleep(10 * time.Millisecond)
-	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_")
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "-enqueue_wait_")
 	expectedMetrics := map[string]string{
 		`sent_bytes_total{exporter="127.0.0.1"}`: "26",
 		fmt.Sprintf(`errors_total{error="kafka: Failed to produce message to topic flows-%s: noooo"}`, c.d.Schema.ProtobufMessageHash()): "1",
 		`sent_messages_total{exporter="127.0.0.1"}`: "2",
+		`tls_enabled`:                          "0",
+		`compression_codec_info{codec="none"}`: "1",
+		`retries_total`:                        "0",
 	}
 	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 		t.Fatalf("Metrics (-got, +want):\n%s", diff)
@@ -84,7 +90,7 @@ func TestKafkaMetrics(t *testing.T) {
 	gometrics.GetOrRegisterCounter("requests-in-flight-for-broker-1112", c.kafkaConfig.MetricRegistry).
 		Inc(20)
 
-	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_")
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "brokers_")
 	expectedMetrics := map[string]string{
 		`brokers_incoming_byte_rate{broker="1111"}`:            "0",
 		`brokers_incoming_byte_rate{broker="1112"}`:            "0",
@@ -103,3 +109,279 @@ func TestKafkaMetrics(t *testing.T) {
 		t.Fatalf("Metrics (-got, +want):\n%s", diff)
 	}
 }
+
+func TestKafkaSettingsMetrics(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.TLS.Enable = true
+	config.CompressionCodec = CompressionCodec(sarama.CompressionZSTD)
+	if _, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)}); err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "tls_", "compression_")
+	expectedMetrics := map[string]string{
+		`tls_enabled`:                          "1",
+		`compression_codec_info{codec="zstd"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaPartitionKeyExporter(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.PartitionKey = PartitionKeyExporter
+	c, mockProducer := NewMock(t, r, config)
+
+	for range 2 {
+		received := make(chan bool)
+		mockProducer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(got *sarama.ProducerMessage) error {
+			defer close(received)
+			if diff := helpers.Diff(got.Key, sarama.ByteEncoder("192.0.2.1")); diff != "" {
+				t.Errorf("Send() key (-got, +want):\n%s", diff)
+			}
+			return nil
+		})
+		c.Send("192.0.2.1", []byte("hello world!"))
+		select {
+		case <-received:
+		case <-time.After(1 * time.Second):
+			t.Fatal("Kafka message not received")
+		}
+	}
+}
+
+func TestKafkaIdempotent(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Idempotent = true
+	if _, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)}); err == nil {
+		t.Fatal("New() did not error with idempotent producer and required-acks set to leader")
+	}
+
+	config.RequiredAcks = RequiredAcks(sarama.WaitForAll)
+	if _, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)}); err == nil {
+		t.Fatal("New() did not error with idempotent producer and max-in-flight set to 5")
+	}
+
+	config.MaxInFlight = 1
+	c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if !c.kafkaConfig.Producer.Idempotent || c.kafkaConfig.Net.MaxOpenRequests != 1 {
+		t.Fatal("New() did not configure an idempotent producer")
+	}
+
+	// Retries are counted
+	c.kafkaConfig.Producer.Retry.BackoffFunc(1, c.kafkaConfig.Producer.Retry.Max)
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "retries_")
+	expectedMetrics := map[string]string{
+		`retries_total`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestCheckFlow(t *testing.T) {
+	r := reporter.NewMock(t)
+	c, _ := NewMock(t, r, DefaultConfiguration())
+	sch := c.d.Schema
+
+	flow := &schema.FlowMessage{
+		TimeReceived:    200,
+		SamplingRate:    1000,
+		ExporterAddress: netip.MustParseAddr("192.0.2.142"),
+		InIf:            434,
+		OutIf:           677,
+		SrcAddr:         netip.MustParseAddr("67.43.156.77"),
+		DstAddr:         netip.MustParseAddr("2001:db8::1"),
+		SrcNetMask:      24,
+		ReceivedAt:      time.Now(),
+	}
+	sch.ProtobufAppendVarint(flow, schema.ColumnBytes, 6765)
+	got := sch.ProtobufDecode(t, sch.ProtobufMarshal(flow))
+	c.CheckFlow(t, got, flow)
+}
+
+// blockedProducer is an async producer never accepting messages.
+type blockedProducer struct {
+	sarama.AsyncProducer
+	input chan *sarama.ProducerMessage
+}
+
+func (bp *blockedProducer) Input() chan<- *sarama.ProducerMessage { return bp.input }
+func (bp *blockedProducer) Errors() <-chan *sarama.ProducerError  { return nil }
+func (bp *blockedProducer) Close() error                          { return nil }
+
+func TestKafkaQueueFull(t *testing.T) {
+	for _, policy := range []QueueFullPolicy{QueueFullBlock, QueueFullDrop} {
+		t.Run(policy.String(), func(t *testing.T) {
+			r := reporter.NewMock(t)
+			config := DefaultConfiguration()
+			config.QueueFullPolicy = policy
+			c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)})
+			if err != nil {
+				t.Fatalf("New() error:\n%+v", err)
+			}
+			producer := &blockedProducer{input: make(chan *sarama.ProducerMessage)}
+			c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
+				return producer, nil
+			}
+			helpers.StartStop(t, c)
+
+			sent := make(chan bool)
+			var enqueued bool
+			go func() {
+				defer close(sent)
+				enqueued = c.Send("127.0.0.1", []byte("hello world!"))
+			}()
+			time.Sleep(20 * time.Millisecond)
+			if policy == QueueFullBlock {
+				select {
+				case <-sent:
+					t.Fatal("Send() did not block")
+				case <-producer.input:
+				}
+			}
+			select {
+			case <-sent:
+			case <-time.After(time.Second):
+				t.Fatal("Send() still blocked")
+			}
+
+			if enqueued != (policy == QueueFullBlock) {
+				t.Fatalf("Send() == %v", enqueued)
+			}
+
+			gotMetrics := r.GetMetrics("akvorado_inlet_kafka_",
+				"dropped_", "sent_", "enqueue_wait_seconds_count")
+			expectedMetrics := map[string]string{
+				`enqueue_wait_seconds_count`:                "1",
+				`sent_bytes_total{exporter="127.0.0.1"}`:    "12",
+				`sent_messages_total{exporter="127.0.0.1"}`: "1",
+			}
+			if policy == QueueFullDrop {
+				// Dropped messages are not counted as sent
+				expectedMetrics = map[string]string{
+					`dropped_messages_total{exporter="127.0.0.1"}`: "1",
+					`enqueue_wait_seconds_count`:                   "0",
+				}
+			}
+			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+				t.Fatalf("Metrics (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKafkaBatchFillRatio(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.BatchSize = 100
+	c, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t), Schema: schema.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	gometrics.GetOrRegisterHistogram("records-per-request", c.kafkaConfig.MetricRegistry,
+		gometrics.NewExpDecaySample(10, 1)).
+		Update(50)
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "producer_batch_fill_ratio")
+	expectedMetrics := map[string]string{
+		`producer_batch_fill_ratio`: "0.5",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaReadiness(t *testing.T) {
+	r := reporter.NewMock(t)
+	sch := schema.NewMock(t)
+	topic := fmt.Sprintf("flows-%s", sch.ProtobufMessageHash())
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadataResponse := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID())
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":    metadataResponse,
+	})
+
+	configuration := DefaultConfiguration()
+	configuration.Brokers = []string{broker.Addr()}
+	c, _ := NewMock(t, r, configuration)
+	c.kafkaConfig.Metadata.Retry.Max = 0
+
+	// Unknown topic
+	got := r.RunReadinessChecks(context.Background())
+	if got.Details["kafka"].Status != reporter.HealthcheckError {
+		t.Errorf("RunReadinessChecks() kafka status: got %s, expected error", got.Details["kafka"].Status)
+	}
+
+	// Topic with one partition
+	metadataResponse.SetLeader(topic, 0, broker.BrokerID())
+	got = r.RunReadinessChecks(context.Background())
+	expected := reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: "1 writable partitions",
+	}
+	if diff := helpers.Diff(got.Details["kafka"], expected); diff != "" {
+		t.Errorf("RunReadinessChecks() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaReadinessTimeout(t *testing.T) {
+	r := reporter.NewMock(t)
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetLatency(time.Second)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+
+	configuration := DefaultConfiguration()
+	configuration.Brokers = []string{broker.Addr()}
+	c, _ := NewMock(t, r, configuration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	got := c.readinessCheck(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("readinessCheck() took %s", elapsed)
+	}
+	expected := reporter.HealthcheckResult{
+		Status: reporter.HealthcheckError,
+		Reason: "timeout while checking Kafka",
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Errorf("readinessCheck() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaAuthenticationFailure(t *testing.T) {
+	r := reporter.NewMock(t)
+	c, err := New(r, DefaultConfiguration(), Dependencies{
+		Daemon: daemon.NewMock(t),
+		Schema: schema.NewMock(t),
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
+		return nil, sarama.Wrap(sarama.ErrOutOfBrokers, sarama.ErrSASLAuthenticationFailed)
+	}
+	err = c.Start()
+	if err == nil {
+		t.Fatal("Start() did not error")
+	}
+	if !strings.Contains(err.Error(), "authentication to Kafka failed") {
+		t.Fatalf("Start() error does not mention authentication:\n%+v", err)
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Exporter describes the exporter of a flow. It is used to select the topic a
+// flow is sent to.
+type Exporter struct {
+	IP     string
+	Name   string
+	Group  string
+	Role   string
+	Site   string
+	Region string
+	Tenant string
+}
+
+// Route describes the attributes of a flow used to select its topic.
+type Route struct {
+	Exporter Exporter
+	// Tags are the tags assigned by the flow classifiers.
+	Tags []string
+}
+
+// TopicRoute is a rule to select the topic of a flow. This is an expression
+// returning the name of the topic, or an empty string when the rule does not
+// apply.
+type TopicRoute struct {
+	program *vm.Program
+}
+
+// topicRouteEnvironment defines the environment used by the topic routes.
+type topicRouteEnvironment struct {
+	Exporter Exporter
+	Tags     []string
+	Format   func(string, ...any) string
+}
+
+// validTopicRegex matches the names Kafka accepts for a topic.
+var validTopicRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// maxTopicLength is the maximum length of a topic name for Kafka.
+const maxTopicLength = 249
+
+// exec returns the topic selected by the route for the provided flow
+// attributes.
+func (tr *TopicRoute) exec(route Route) (string, error) {
+	env := topicRouteEnvironment{
+		Exporter: route.Exporter,
+		Tags:     route.Tags,
+		Format: func(format string, a ...any) string {
+			return fmt.Sprintf(format, a...)
+		},
+	}
+	result, err := expr.Run(tr.program, env)
+	if err != nil {
+		return "", fmt.Errorf("unable to execute topic route %q: %w", tr, err)
+	}
+	return result.(string), nil
+}
+
+// UnmarshalText compiles a topic route.
+func (tr *TopicRoute) UnmarshalText(text []byte) error {
+	program, err := expr.Compile(string(text),
+		expr.Env(topicRouteEnvironment{}),
+		expr.AsKind(reflect.String))
+	if err != nil {
+		return fmt.Errorf("cannot compile topic route %q: %w", string(text), err)
+	}
+	tr.program = program
+	return nil
+}
+
+// String turns a topic route into a string
+func (tr TopicRoute) String() string {
+	return tr.program.Source().String()
+}
+
+// MarshalText turns a topic route into a string
+func (tr TopicRoute) MarshalText() ([]byte, error) {
+	return []byte(tr.String()), nil
+}
+
+// topic selects the topic for a flow from the provided attributes. When no
+// route matches or when the selected topic is invalid, the default topic is
+// used.
+func (c *Component) topic(route Route) string {
+	if len(c.config.TopicRoutes) == 0 {
+		return c.kafkaTopic
+	}
+	for idx, tr := range c.config.TopicRoutes {
+		topic, err := tr.exec(route)
+		if err != nil {
+			c.routeErrLogger.Err(err).
+				Int("index", idx).
+				Str("exporter", route.Exporter.IP).
+				Msg("error executing topic route")
+			c.metrics.unroutedMessages.WithLabelValues("route error").Inc()
+			return c.kafkaTopic
+		}
+		if topic == "" {
+			continue
+		}
+		topic = fmt.Sprintf("%s-%s", topic, c.topicHash)
+		if len(topic) > maxTopicLength || !validTopicRegex.MatchString(topic) {
+			c.routeErrLogger.Warn().
+				Int("index", idx).
+				Str("exporter", route.Exporter.IP).
+				Str("topic", topic).
+				Msg("invalid topic selected by topic route")
+			c.metrics.unroutedMessages.WithLabelValues("invalid topic").Inc()
+			return c.kafkaTopic
+		}
+		return topic
+	}
+	c.metrics.unroutedMessages.WithLabelValues("no route").Inc()
+	return c.kafkaTopic
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestTopicRouteUnmarshal(t *testing.T) {
+	var route TopicRoute
+	if err := route.UnmarshalText([]byte(`Exporter.Tenant == "test" ? "flows-test" : ""`)); err != nil {
+		t.Fatalf("UnmarshalText() error:\n%+v", err)
+	}
+	if err := route.UnmarshalText([]byte(`Exporter.Tenant == "test"`)); err == nil {
+		t.Fatal("UnmarshalText() did not error with a boolean expression")
+	}
+	if err := route.UnmarshalText([]byte(`"internal" in Tags ? "flows-internal" : ""`)); err != nil {
+		t.Fatalf("UnmarshalText() error:\n%+v", err)
+	}
+	if err := route.UnmarshalText([]byte(`Exporter.Unknown`)); err == nil {
+		t.Fatal("UnmarshalText() did not error with an unknown field")
+	}
+}
+
+func TestTopicRoutes(t *testing.T) {
+	routes := []string{
+		`"internal" in Tags ? "flows-internal" : ""`,
+		`Exporter.Tenant == "test" ? "flows-test" : ""`,
+		`Exporter.Group == "tenants" ? Format("flows-tenant-%s", Exporter.Tenant) : ""`,
+		`Exporter.Role == "broken" ? split(Exporter.Role, ",")[5] : ""`,
+	}
+	config := DefaultConfiguration()
+	for _, route := range routes {
+		var tr TopicRoute
+		if err := tr.UnmarshalText([]byte(route)); err != nil {
+			t.Fatalf("UnmarshalText(%q) error:\n%+v", route, err)
+		}
+		config.TopicRoutes = append(config.TopicRoutes, tr)
+	}
+	r := reporter.NewMock(t)
+	c, mockProducer := NewMock(t, r, config)
+	hash := c.d.Schema.ProtobufMessageHash()
+
+	cases := []struct {
+		Description string
+		Route       Route
+		Expected    string
+	}{
+		{
+			Description: "tag route",
+			Route: Route{
+				Exporter: Exporter{IP: "192.0.2.1", Tenant: "test"},
+				Tags:     []string{"transit", "internal"},
+			},
+			Expected: fmt.Sprintf("flows-internal-%s", hash),
+		}, {
+			Description: "other tags",
+			Route: Route{
+				Exporter: Exporter{IP: "192.0.2.1", Tenant: "test"},
+				Tags:     []string{"transit"},
+			},
+			Expected: fmt.Sprintf("flows-test-%s", hash),
+		}, {
+			Description: "static route",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1", Tenant: "test"}},
+			Expected:    fmt.Sprintf("flows-test-%s", hash),
+		}, {
+			Description: "derived route",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1", Group: "tenants", Tenant: "customer1"}},
+			Expected:    fmt.Sprintf("flows-tenant-customer1-%s", hash),
+		}, {
+			Description: "invalid derived topic",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1", Group: "tenants", Tenant: "customer 1"}},
+			Expected:    fmt.Sprintf("flows-%s", hash),
+		}, {
+			Description: "too long derived topic",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1", Group: "tenants", Tenant: strings.Repeat("a", 250)}},
+			Expected:    fmt.Sprintf("flows-%s", hash),
+		}, {
+			Description: "route error",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1", Role: "broken"}},
+			Expected:    fmt.Sprintf("flows-%s", hash),
+		}, {
+			Description: "no route",
+			Route:       Route{Exporter: Exporter{IP: "192.0.2.1"}},
+			Expected:    fmt.Sprintf("flows-%s", hash),
+		},
+	}
+	for _, tc := range cases {
+		received := make(chan bool)
+		mockProducer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(got *sarama.ProducerMessage) error {
+			defer close(received)
+			if got.Topic != tc.Expected {
+				t.Errorf("SendRouted(%s) topic (-got, +want):\n-%s\n+%s", tc.Description, got.Topic, tc.Expected)
+			}
+			return nil
+		})
+		c.SendRouted(tc.Route, []byte("hello world!"))
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("SendRouted(%s): Kafka message not received", tc.Description)
+		}
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "unrouted_")
+	expectedMetrics := map[string]string{
+		`unrouted_messages_total{reason="invalid topic"}`: "2",
+		`unrouted_messages_total{reason="route error"}`:   "1",
+		`unrouted_messages_total{reason="no route"}`:      "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
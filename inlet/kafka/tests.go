@@ -6,7 +6,11 @@
 package kafka
 
 import (
+	"errors"
+	"net/netip"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/IBM/sarama/mocks"
@@ -39,3 +43,138 @@ func NewMock(t *testing.T, reporter *reporter.Reporter, configuration Configurat
 	helpers.StartStop(t, c)
 	return c, mockProducer
 }
+
+// ConsumeLastMessages reads back the last n messages published to the
+// provided topic using a client connected to a real Kafka broker. Messages
+// are ordered by timestamp.
+func ConsumeLastMessages(t *testing.T, client sarama.Client, topic string, n int) [][]byte {
+	t.Helper()
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		t.Fatalf("NewConsumerFromClient() error:\n%+v", err)
+	}
+	defer consumer.Close()
+
+	// Wait for topic to be available
+	var partitions []int32
+	for i := 0; ; i++ {
+		partitions, err = consumer.Partitions(topic)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, sarama.ErrUnknownTopicOrPartition) || i >= 50 {
+			t.Fatalf("Partitions() error:\n%+v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		client.RefreshMetadata(topic)
+	}
+
+	messages := []*sarama.ConsumerMessage{}
+	for _, partition := range partitions {
+		newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			t.Fatalf("GetOffset() error:\n%+v", err)
+		}
+		oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			t.Fatalf("GetOffset() error:\n%+v", err)
+		}
+		start := max(oldest, newest-int64(n))
+		if start >= newest {
+			continue
+		}
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, start)
+		if err != nil {
+			t.Fatalf("ConsumePartition() error:\n%+v", err)
+		}
+		timeout := time.After(15 * time.Second)
+		for offset := start; offset < newest; {
+			select {
+			case msg := <-partitionConsumer.Messages():
+				messages = append(messages, msg)
+				offset = msg.Offset + 1
+			case err := <-partitionConsumer.Errors():
+				t.Fatalf("ConsumePartition() error:\n%+v", err)
+			case <-timeout:
+				t.Fatalf("timeout while consuming partition %d of %s", partition, topic)
+			}
+		}
+		partitionConsumer.Close()
+	}
+
+	slices.SortStableFunc(messages, func(a, b *sarama.ConsumerMessage) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	if len(messages) > n {
+		messages = messages[len(messages)-n:]
+	}
+	result := make([][]byte, len(messages))
+	for idx, msg := range messages {
+		result[idx] = msg.Value
+	}
+	return result
+}
+
+// ConsumeLastFlows reads back and decodes the last n flows published by the
+// component to a real Kafka broker.
+func (c *Component) ConsumeLastFlows(t *testing.T, client sarama.Client, n int) []*schema.FlowMessage {
+	t.Helper()
+	messages := ConsumeLastMessages(t, client, c.kafkaTopic, n)
+	flows := make([]*schema.FlowMessage, len(messages))
+	for idx, msg := range messages {
+		flows[idx] = c.d.Schema.ProtobufDecode(t, msg)
+	}
+	return flows
+}
+
+// CheckFlow checks that a flow read back from Kafka matches the expected
+// flow. The expected flow may be the original flow, even after it has been
+// marshaled. Fields which are not serialized are ignored and addresses are
+// compared as IPv6 addresses.
+func (c *Component) CheckFlow(t *testing.T, got, expected *schema.FlowMessage) {
+	t.Helper()
+	want := schema.FlowMessage{
+		TimeReceived:    expected.TimeReceived,
+		SamplingRate:    expected.SamplingRate,
+		ExporterAddress: expected.ExporterAddress,
+		SrcAddr:         expected.SrcAddr,
+		DstAddr:         expected.DstAddr,
+		NextHop:         expected.NextHop,
+		SrcAS:           expected.SrcAS,
+		DstAS:           expected.DstAS,
+		ProtobufDebug:   map[schema.ColumnKey]interface{}{},
+	}
+	for k, v := range expected.ProtobufDebug {
+		want.ProtobufDebug[k] = v
+	}
+	// Columns decoded as fields are not in ProtobufDebug.
+	for _, k := range []schema.ColumnKey{
+		schema.ColumnTimeReceived, schema.ColumnSamplingRate,
+		schema.ColumnExporterAddress, schema.ColumnSrcAddr, schema.ColumnDstAddr, schema.ColumnNextHop,
+		schema.ColumnSrcAS, schema.ColumnDstAS,
+	} {
+		delete(want.ProtobufDebug, k)
+	}
+	// Other fields are only in ProtobufDebug.
+	debugFields := map[schema.ColumnKey]uint64{
+		schema.ColumnSrcNetMask: uint64(expected.SrcNetMask),
+		schema.ColumnDstNetMask: uint64(expected.DstNetMask),
+	}
+	if !c.d.Schema.IsDisabled(schema.ColumnGroupL2) {
+		debugFields[schema.ColumnSrcVlan] = uint64(expected.SrcVlan)
+		debugFields[schema.ColumnDstVlan] = uint64(expected.DstVlan)
+	}
+	for k, v := range debugFields {
+		if _, ok := want.ProtobufDebug[k]; !ok && v != 0 {
+			want.ProtobufDebug[k] = v
+		}
+	}
+	for _, addr := range []*netip.Addr{&want.ExporterAddress, &want.SrcAddr, &want.DstAddr, &want.NextHop} {
+		if addr.IsValid() {
+			*addr = netip.AddrFrom16(addr.As16())
+		}
+	}
+	if diff := helpers.Diff(got, &want); diff != "" {
+		t.Errorf("Kafka flow (-got, +want):\n%s", diff)
+	}
+}
@@ -5,6 +5,7 @@ package metadata
 
 import (
 	"net/netip"
+	"sync"
 	"time"
 
 	"akvorado/common/helpers/cache"
@@ -15,11 +16,22 @@ import (
 // Interface describes an interface.
 type Interface = provider.Interface
 
+// maxRejectedQueries is the maximum number of rejected interfaces to remember.
+// Past this limit, rejected interfaces are polled again on each miss.
+const maxRejectedQueries = 100_000
+
 // metadataCache represents the metadata cache.
 type metadataCache struct {
 	r     *reporter.Reporter
 	cache *cache.Cache[provider.Query, provider.Answer]
 
+	// interfaces is the number of cached interfaces for each exporter.
+	interfacesLock sync.Mutex
+	interfaces     map[netip.Addr]uint
+	// rejected are the interfaces not cached because of this limit. The
+	// last access is the time of the rejection.
+	rejected *cache.Cache[provider.Query, struct{}]
+
 	metrics struct {
 		cacheHit     reporter.Counter
 		cacheMiss    reporter.Counter
@@ -30,8 +42,10 @@ type metadataCache struct {
 
 func newMetadataCache(r *reporter.Reporter) *metadataCache {
 	sc := &metadataCache{
-		r:     r,
-		cache: cache.New[provider.Query, provider.Answer](),
+		r:          r,
+		cache:      cache.New[provider.Query, provider.Answer](),
+		interfaces: map[netip.Addr]uint{},
+		rejected:   cache.New[provider.Query, struct{}](),
 	}
 	sc.metrics.cacheHit = r.Counter(
 		reporter.CounterOpts{
@@ -70,29 +84,95 @@ func (sc *metadataCache) Lookup(t time.Time, query provider.Query) (provider.Ans
 	return result, true
 }
 
-// Put a new entry in the cache.
-func (sc *metadataCache) Put(t time.Time, query provider.Query, answer provider.Answer) {
+// Put a new entry in the cache. It returns true if the entry is new or if it
+// has changed.
+func (sc *metadataCache) Put(t time.Time, query provider.Query, answer provider.Answer) bool {
+	_, changed := sc.PutWithLimit(t, query, answer, 0)
+	return changed
+}
+
+// PutWithLimit puts a new entry in the cache, unless this is a new interface
+// and the exporter already has maxInterfaces cached interfaces (0 means no
+// limit). It returns true if the entry was accepted and true if the entry is
+// new or if it has changed. Rejected entries are remembered to not poll them
+// again until they expire.
+func (sc *metadataCache) PutWithLimit(t time.Time, query provider.Query, answer provider.Answer, maxInterfaces uint) (bool, bool) {
+	sc.interfacesLock.Lock()
+	defer sc.interfacesLock.Unlock()
+	previous, ok := sc.cache.Get(time.Time{}, query)
+	if !ok && maxInterfaces > 0 && sc.interfaces[query.ExporterIP] >= maxInterfaces {
+		if sc.rejected.Size() < maxRejectedQueries {
+			sc.rejected.Put(t, query, struct{}{})
+		}
+		return false, false
+	}
 	sc.cache.Put(t, query, answer)
+	if !ok {
+		sc.interfaces[query.ExporterIP]++
+	}
+	return true, !ok || previous != answer
+}
+
+// Rejected tells if the provided query was rejected by PutWithLimit.
+func (sc *metadataCache) Rejected(query provider.Query) bool {
+	_, ok := sc.rejected.Get(time.Time{}, query)
+	return ok
 }
 
-// Expire expire entries whose last access is before the provided time
+// Interfaces returns the number of cached interfaces for an exporter.
+func (sc *metadataCache) Interfaces(exporterIP netip.Addr) uint {
+	sc.interfacesLock.Lock()
+	defer sc.interfacesLock.Unlock()
+	return sc.interfaces[exporterIP]
+}
+
+// countInterfaces recomputes the number of cached interfaces for each exporter.
+func (sc *metadataCache) countInterfaces() {
+	interfaces := map[netip.Addr]uint{}
+	for k := range sc.cache.Items() {
+		interfaces[k.ExporterIP]++
+	}
+	sc.interfacesLock.Lock()
+	sc.interfaces = interfaces
+	sc.interfacesLock.Unlock()
+}
+
+// Expire expire entries whose last access is before the provided time. It also
+// forgets rejected entries older than the provided time.
 func (sc *metadataCache) Expire(before time.Time) int {
 	expired := sc.cache.DeleteLastAccessedBefore(before)
 	sc.metrics.cacheExpired.Add(float64(expired))
+	if expired > 0 {
+		sc.countInterfaces()
+	}
+	sc.rejected.DeleteLastAccessedBefore(before)
 	return expired
 }
 
 // NeedUpdates returns a map of interface entries that would need to
-// be updated. It relies on last update.
-func (sc *metadataCache) NeedUpdates(before time.Time) map[netip.Addr][]uint {
+// be updated. It relies on last update. Negative entries (interfaces
+// unknown to the provider) are also returned if their last update is
+// before negativeBefore.
+func (sc *metadataCache) NeedUpdates(before, negativeBefore time.Time) map[netip.Addr][]uint {
 	result := map[netip.Addr][]uint{}
-	for k := range sc.cache.ItemsLastUpdatedBefore(before) {
+	add := func(k provider.Query) {
 		interfaces, ok := result[k.ExporterIP]
 		if !ok {
 			interfaces = []uint{}
 		}
 		result[k.ExporterIP] = append(interfaces, k.IfIndex)
 	}
+	items := sc.cache.ItemsLastUpdatedBefore(before)
+	for k := range items {
+		add(k)
+	}
+	if negativeBefore.After(before) {
+		for k, v := range sc.cache.ItemsLastUpdatedBefore(negativeBefore) {
+			if _, ok := items[k]; !ok && v.Interface.Name == "" {
+				add(k)
+			}
+		}
+	}
 	return result
 }
 
@@ -103,5 +183,9 @@ func (sc *metadataCache) Save(cacheFile string) error {
 
 // Load loads the cache from the provided location.
 func (sc *metadataCache) Load(cacheFile string) error {
-	return sc.cache.Load(cacheFile)
+	if err := sc.cache.Load(cacheFile); err != nil {
+		return err
+	}
+	sc.countInterfaces()
+	return nil
 }
@@ -102,6 +102,92 @@ func TestSimpleLookup(t *testing.T) {
 	}
 }
 
+func TestPutChanged(t *testing.T) {
+	_, sc := setupTestCache(t)
+	query := provider.Query{
+		ExporterIP: netip.MustParseAddr("::ffff:127.0.0.1"),
+		IfIndex:    676,
+	}
+	answer := provider.Answer{
+		Exporter:  provider.Exporter{Name: "localhost"},
+		Interface: provider.Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000},
+	}
+	if !sc.Put(time.Now(), query, answer) {
+		t.Error("Put() should report a new entry as changed")
+	}
+	if sc.Put(time.Now(), query, answer) {
+		t.Error("Put() should not report an identical entry as changed")
+	}
+	answer.Interface.Description = "Peering"
+	if !sc.Put(time.Now(), query, answer) {
+		t.Error("Put() should report a different entry as changed")
+	}
+}
+
+func TestInterfacesCount(t *testing.T) {
+	_, sc := setupTestCache(t)
+	now := time.Now()
+	exporter1 := netip.MustParseAddr("::ffff:127.0.0.1")
+	exporter2 := netip.MustParseAddr("::ffff:127.0.0.2")
+	answer := provider.Answer{
+		Exporter:  provider.Exporter{Name: "localhost"},
+		Interface: provider.Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000},
+	}
+	sc.Put(now, provider.Query{ExporterIP: exporter1, IfIndex: 676}, answer)
+	sc.Put(now, provider.Query{ExporterIP: exporter1, IfIndex: 676}, answer)
+	sc.Put(now.Add(10*time.Minute), provider.Query{ExporterIP: exporter1, IfIndex: 677}, answer)
+	sc.Put(now, provider.Query{ExporterIP: exporter2, IfIndex: 676}, answer)
+	if got := sc.Interfaces(exporter1); got != 2 {
+		t.Errorf("Interfaces(%s) == %d, expected 2", exporter1, got)
+	}
+	if got := sc.Interfaces(exporter2); got != 1 {
+		t.Errorf("Interfaces(%s) == %d, expected 1", exporter2, got)
+	}
+	sc.Expire(now.Add(time.Minute))
+	if got := sc.Interfaces(exporter1); got != 1 {
+		t.Errorf("Interfaces(%s) == %d, expected 1", exporter1, got)
+	}
+	if got := sc.Interfaces(exporter2); got != 0 {
+		t.Errorf("Interfaces(%s) == %d, expected 0", exporter2, got)
+	}
+}
+
+func TestPutWithLimit(t *testing.T) {
+	_, sc := setupTestCache(t)
+	now := time.Now()
+	exporter := netip.MustParseAddr("::ffff:127.0.0.1")
+	answer := provider.Answer{
+		Exporter:  provider.Exporter{Name: "localhost"},
+		Interface: provider.Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000},
+	}
+	query1 := provider.Query{ExporterIP: exporter, IfIndex: 676}
+	query2 := provider.Query{ExporterIP: exporter, IfIndex: 677}
+	if accepted, changed := sc.PutWithLimit(now, query1, answer, 1); !accepted || !changed {
+		t.Errorf("PutWithLimit(%d) == %v, %v, expected true, true", query1.IfIndex, accepted, changed)
+	}
+	if accepted, changed := sc.PutWithLimit(now, query2, answer, 1); accepted || changed {
+		t.Errorf("PutWithLimit(%d) == %v, %v, expected false, false", query2.IfIndex, accepted, changed)
+	}
+	if accepted, changed := sc.PutWithLimit(now, query1, answer, 1); !accepted || changed {
+		t.Errorf("PutWithLimit(%d) == %v, %v, expected true, false", query1.IfIndex, accepted, changed)
+	}
+	if !sc.Rejected(query2) {
+		t.Errorf("Rejected(%d) == false, expected true", query2.IfIndex)
+	}
+	if sc.Rejected(query1) {
+		t.Errorf("Rejected(%d) == true, expected false", query1.IfIndex)
+	}
+
+	// Once expired, the interface can be cached again
+	sc.Expire(now.Add(time.Minute))
+	if sc.Rejected(query2) {
+		t.Errorf("Rejected(%d) == true after expiration, expected false", query2.IfIndex)
+	}
+	if accepted, changed := sc.PutWithLimit(now, query2, answer, 1); !accepted || !changed {
+		t.Errorf("PutWithLimit(%d) == %v, %v, expected true, true", query2.IfIndex, accepted, changed)
+	}
+}
+
 func TestExpire(t *testing.T) {
 	r, sc := setupTestCache(t)
 	now := time.Now()
@@ -305,7 +391,7 @@ func TestNeedUpdates(t *testing.T) {
 	}
 	for _, tc := range cases {
 		t.Run(fmt.Sprintf("%d minutes", tc.Minutes), func(t *testing.T) {
-			got := sc.NeedUpdates(now.Add(-tc.Minutes * time.Minute))
+			got := sc.NeedUpdates(now.Add(-tc.Minutes*time.Minute), time.Time{})
 			// sort output for comparison purposes
 			for _, v := range got {
 				slices.Sort(v)
@@ -317,6 +403,56 @@ func TestNeedUpdates(t *testing.T) {
 	}
 }
 
+func TestNeedUpdatesNegative(t *testing.T) {
+	_, sc := setupTestCache(t)
+	now := time.Now()
+	sc.Put(now,
+		provider.Query{
+			ExporterIP: netip.MustParseAddr("::ffff:127.0.0.1"),
+			IfIndex:    676,
+		},
+		provider.Answer{
+			Exporter:  provider.Exporter{Name: "localhost"},
+			Interface: provider.Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
+	sc.Put(now,
+		provider.Query{
+			ExporterIP: netip.MustParseAddr("::ffff:127.0.0.1"),
+			IfIndex:    677,
+		},
+		provider.Answer{
+			Exporter: provider.Exporter{Name: "localhost"}})
+	now = now.Add(10 * time.Minute)
+
+	cases := []struct {
+		Description            string
+		Before, NegativeBefore time.Time
+		Expected               map[string][]uint
+	}{
+		{"nothing to refresh", now.Add(-time.Hour), now.Add(-20 * time.Minute), map[string][]uint{}},
+		{"negative entry", now.Add(-time.Hour), now.Add(-5 * time.Minute), map[string][]uint{
+			"::ffff:127.0.0.1": {677},
+		}},
+		{"negative refresh disabled", now.Add(-time.Hour), time.Time{}, map[string][]uint{}},
+		{"no positive refresh", time.Time{}, now.Add(-5 * time.Minute), map[string][]uint{
+			"::ffff:127.0.0.1": {677},
+		}},
+		{"all entries", now.Add(-5 * time.Minute), now.Add(-5 * time.Minute), map[string][]uint{
+			"::ffff:127.0.0.1": {676, 677},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			got := sc.NeedUpdates(tc.Before, tc.NegativeBefore)
+			for _, v := range got {
+				slices.Sort(v)
+			}
+			if diff := helpers.Diff(got, tc.Expected); diff != "" {
+				t.Fatalf("NeedUpdates() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestLoadNotExist(t *testing.T) {
 	_, sc := setupTestCache(t)
 	err := sc.Load("/i/do/not/exist")
@@ -484,3 +620,23 @@ func TestConcurrentOperations(t *testing.T) {
 		time.Sleep(30 * time.Millisecond)
 	}
 }
+
+func TestPutWithLimitBoundedRejections(t *testing.T) {
+	_, sc := setupTestCache(t)
+	now := time.Now()
+	exporter := netip.MustParseAddr("::ffff:127.0.0.1")
+	answer := provider.Answer{
+		Exporter:  provider.Exporter{Name: "localhost"},
+		Interface: provider.Interface{Name: "Gi0/0/0/1"},
+	}
+	sc.PutWithLimit(now, provider.Query{ExporterIP: exporter, IfIndex: 0}, answer, 1)
+	for ifIndex := range uint(maxRejectedQueries + 10) {
+		sc.PutWithLimit(now, provider.Query{ExporterIP: exporter, IfIndex: ifIndex + 1}, answer, 1)
+	}
+	if got := sc.rejected.Size(); got != maxRejectedQueries {
+		t.Errorf("rejected.Size() == %d, expected %d", got, maxRejectedQueries)
+	}
+	if sc.Rejected(provider.Query{ExporterIP: exporter, IfIndex: maxRejectedQueries + 5}) {
+		t.Error("Rejected() == true for a query past the limit")
+	}
+}
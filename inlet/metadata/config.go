@@ -23,6 +23,9 @@ type Configuration struct {
 	CacheDuration time.Duration `validate:"min=1m"`
 	// CacheRefresh defines how soon to refresh an existing cached entry
 	CacheRefresh time.Duration `validate:"eq=0|min=1m,eq=0|gtefield=CacheDuration"`
+	// CacheNegativeRefresh defines how soon to refresh a cached entry for an
+	// unknown interface (0 means to use CacheRefresh)
+	CacheNegativeRefresh time.Duration `validate:"eq=0|min=1m"`
 	// CacheRefreshInterval defines the interval to check for expiration/refresh
 	CacheCheckInterval time.Duration `validate:"ltefield=CacheRefresh,min=1s"`
 	// CachePersist defines a file to store cache and survive restarts
@@ -35,6 +38,9 @@ type Configuration struct {
 	Workers int `validate:"min=1"`
 	// MaxBatchRequests define how many requests to pass to a worker at once if possible
 	MaxBatchRequests int `validate:"min=0"`
+	// MaxInterfaces defines the maximum number of interfaces to cache for
+	// each exporter (0 means no limit)
+	MaxInterfaces helpers.SubnetMap[uint]
 }
 
 // DefaultConfiguration represents the default configuration for the metadata provider.
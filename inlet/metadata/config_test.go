@@ -5,6 +5,7 @@ package metadata
 
 import (
 	"testing"
+	"time"
 
 	"akvorado/common/helpers"
 )
@@ -14,3 +15,36 @@ func TestDefaultConfiguration(t *testing.T) {
 		t.Fatalf("validate.Struct() error:\n%+v", err)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	withRefresh := func(refresh, negativeRefresh time.Duration) Configuration {
+		config := DefaultConfiguration()
+		config.CacheRefresh = refresh
+		config.CacheNegativeRefresh = negativeRefresh
+		return config
+	}
+	cases := []struct {
+		Description   string
+		Configuration Configuration
+		Error         string
+	}{
+		{"default", DefaultConfiguration(), ""},
+		{"shorter negative refresh", withRefresh(time.Hour, 10*time.Minute), ""},
+		{"no refresh", withRefresh(0, 2*time.Hour), ""},
+		{"longer negative refresh", withRefresh(time.Hour, 2*time.Hour),
+			"cache-negative-refresh: 2h0m0s is longer than cache-refresh (1h0m0s)"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c := Component{config: tc.Configuration}
+			err := c.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Error); diff != "" {
+				t.Fatalf("Validate() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
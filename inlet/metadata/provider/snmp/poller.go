@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/netip"
 	"slices"
 	"time"
@@ -101,15 +102,18 @@ func (p *Provider) Poll(ctx context.Context, exporter, agent netip.Addr, port ui
 		moreRequests := []string{
 			fmt.Sprintf("1.3.6.1.2.1.2.2.1.2.%d", ifIndex),     // ifDescr
 			fmt.Sprintf("1.3.6.1.2.1.31.1.1.1.18.%d", ifIndex), // ifAlias
-			fmt.Sprintf("1.3.6.1.2.1.31.1.1.1.15.%d", ifIndex), // ifSpeed
+			fmt.Sprintf("1.3.6.1.2.1.31.1.1.1.15.%d", ifIndex), // ifHighSpeed
+			fmt.Sprintf("1.3.6.1.2.1.2.2.1.5.%d", ifIndex),     // ifSpeed
 		}
 		requests = append(requests, moreRequests...)
 	}
+	// Do not let GoSNMP refuse large batches, let the agent decide
+	g.MaxOids = max(gosnmp.MaxOids, len(requests))
 	var results []gosnmp.SnmpPDU
 	success := false
 
 	logError := func(err error) error {
-		p.metrics.errors.WithLabelValues(exporterStr, "get").Inc()
+		p.metrics.errors.WithLabelValues(exporterStr, errorLabel(err)).Inc()
 		p.errLogger.Err(err).
 			Str("exporter", exporterStr).
 			Msgf("unable to GET (%d OIDs)", len(requests))
@@ -188,13 +192,13 @@ func (p *Provider) Poll(ctx context.Context, exporter, agent netip.Addr, port ui
 	if !processStr(0, "sysname", &sysNameVal) {
 		return errors.New("unable to get sysName")
 	}
-	for idx := 1; idx < len(requests)-2; idx += 3 {
+	for idx := 1; idx < len(requests)-3; idx += 4 {
 		var (
 			ifDescrVal string
 			ifAliasVal string
 			ifSpeedVal uint
 		)
-		ifIndex := ifIndexes[(idx-1)/3]
+		ifIndex := ifIndexes[(idx-1)/4]
 		ok := true
 		// We do not process results when index is 0 (this can happen for local
 		// traffic, we only care for exporter name).
@@ -205,12 +209,25 @@ func (p *Provider) Poll(ctx context.Context, exporter, agent netip.Addr, port ui
 		if ifIndex > 0 && !processStr(idx+1, "ifalias", &ifAliasVal) {
 			ok = false
 		}
-		if ifIndex > 0 && !processUint(idx+2, "ifspeed", &ifSpeedVal) {
-			ok = false
+		if ifIndex > 0 {
+			// Prefer ifHighSpeed (in Mbps) and fallback to ifSpeed (in bps)
+			// when it is missing or zero. ifSpeed saturates at 4294967295 for
+			// links faster than 4 Gbps and is ignored in this case.
+			if results[idx+2].Type == gosnmp.Gauge32 && results[idx+2].Value.(uint) > 0 {
+				ifSpeedVal = results[idx+2].Value.(uint)
+			} else if results[idx+3].Type == gosnmp.Gauge32 && results[idx+3].Value.(uint) < math.MaxUint32 {
+				ifSpeedVal = results[idx+3].Value.(uint) / 1_000_000
+			} else if !processUint(idx+2, "ifspeed", &ifSpeedVal) {
+				ok = false
+			}
 		}
 		if ok {
 			p.metrics.successes.WithLabelValues(exporterStr).Inc()
 		}
+		if ifAliasVal == "" {
+			// Use interface name when there is no description
+			ifAliasVal = ifDescrVal
+		}
 		put(provider.Update{
 			Query: provider.Query{
 				ExporterIP: exporter,
@@ -247,3 +264,19 @@ func (l *goSNMPLogger) Printf(format string, v ...interface{}) {
 		e.Msg(fmt.Sprintf(format, v...))
 	}
 }
+
+// errorLabel returns the label to use in metrics for an error returned by a
+// GET request. Authentication errors are distinguished from other errors.
+func errorLabel(err error) string {
+	for _, authErr := range []error{
+		gosnmp.ErrUnknownSecurityLevel,
+		gosnmp.ErrUnknownUsername,
+		gosnmp.ErrWrongDigest,
+		gosnmp.ErrDecryption,
+	} {
+		if errors.Is(err, authErr) {
+			return "authentication"
+		}
+	}
+	return "get"
+}
@@ -5,7 +5,9 @@ package snmp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/netip"
 	"strconv"
@@ -175,6 +177,24 @@ func TestPoller(t *testing.T) {
 								OnGet: func() (interface{}, error) {
 									return "Gi0/0/0/2", nil
 								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.2.645",
+								Type: gosnmp.OctetString,
+								OnGet: func() (interface{}, error) {
+									return "Gi0/0/0/4", nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.5.641",
+								Type: gosnmp.Gauge32,
+								OnGet: func() (interface{}, error) {
+									return uint(math.MaxUint32), nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.5.645",
+								Type: gosnmp.Gauge32,
+								OnGet: func() (interface{}, error) {
+									return uint(1000000000), nil
+								},
 							}, {
 								OID:  "1.3.6.1.2.1.31.1.1.1.15.641",
 								Type: gosnmp.Gauge32,
@@ -205,7 +225,14 @@ func TestPoller(t *testing.T) {
 								OnGet: func() (interface{}, error) {
 									return "Peering", nil
 								},
+							}, {
+								OID:  "1.3.6.1.2.1.31.1.1.1.18.645",
+								Type: gosnmp.OctetString,
+								OnGet: func() (interface{}, error) {
+									return "Management", nil
+								},
 							},
+							// ifHighSpeed.645 missing
 							// ifAlias.643 missing
 						},
 					},
@@ -245,14 +272,16 @@ func TestPoller(t *testing.T) {
 			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: []uint{641}})
 			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: []uint{642}})
 			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: []uint{643, 644}})
+			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: []uint{645}})
 			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: []uint{0}})
 			exporterStr := tc.ExporterIP.Unmap().String()
 			time.Sleep(50 * time.Millisecond)
 			if diff := helpers.Diff(got, []string{
 				fmt.Sprintf(`%s exporter62 641 Gi0/0/0/0 Transit 10000`, exporterStr),
 				fmt.Sprintf(`%s exporter62 642 Gi0/0/0/1 Peering 20000`, exporterStr),
-				fmt.Sprintf(`%s exporter62 643 Gi0/0/0/2  10000`, exporterStr), // no ifAlias
-				fmt.Sprintf(`%s exporter62 644   0`, exporterStr),              // negative cache
+				fmt.Sprintf(`%s exporter62 643 Gi0/0/0/2 Gi0/0/0/2 10000`, exporterStr), // no ifAlias
+				fmt.Sprintf(`%s exporter62 644   0`, exporterStr),                       // negative cache
+				fmt.Sprintf(`%s exporter62 645 Gi0/0/0/4 Management 1000`, exporterStr), // no ifHighSpeed
 				fmt.Sprintf(`%s exporter62 0   0`, exporterStr),
 			}); diff != "" {
 				t.Fatalf("Poll() (-got, +want):\n%s", diff)
@@ -264,11 +293,40 @@ func TestPoller(t *testing.T) {
 				fmt.Sprintf(`error_requests_total{error="ifdescr missing",exporter="%s"}`, exporterStr): "1", // 644
 				fmt.Sprintf(`error_requests_total{error="ifspeed missing",exporter="%s"}`, exporterStr): "1", // 644
 				`pending_requests`: "0",
-				fmt.Sprintf(`success_requests_total{exporter="%s"}`, exporterStr): "3", // 641+642+0
+				fmt.Sprintf(`success_requests_total{exporter="%s"}`, exporterStr): "4", // 641+642+645+0
 			}
 			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 				t.Fatalf("Metrics (-got, +want):\n%s", diff)
 			}
+
+			// Query more OIDs than the default limit of GoSNMP (60)
+			got = []string{}
+			ifIndexes := []uint{}
+			for ifIndex := range uint(20) {
+				ifIndexes = append(ifIndexes, 700+ifIndex)
+			}
+			p.Query(context.Background(), provider.BatchQuery{ExporterIP: tc.ExporterIP, IfIndexes: ifIndexes})
+			time.Sleep(50 * time.Millisecond)
+			if len(got) != len(ifIndexes) {
+				t.Fatalf("Poll() got %d updates, expected %d", len(got), len(ifIndexes))
+			}
 		})
 	}
 }
+
+func TestErrorLabel(t *testing.T) {
+	cases := []struct {
+		Error    error
+		Expected string
+	}{
+		{errors.New("request timeout"), "get"},
+		{gosnmp.ErrWrongDigest, "authentication"},
+		{fmt.Errorf("unable to decode: %w", gosnmp.ErrUnknownUsername), "authentication"},
+		{gosnmp.ErrDecryption, "authentication"},
+	}
+	for _, tc := range cases {
+		if got := errorLabel(tc.Error); got != tc.Expected {
+			t.Errorf("errorLabel(%q) == %q, expected %q", tc.Error, got, tc.Expected)
+		}
+	}
+}
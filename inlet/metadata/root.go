@@ -40,13 +40,16 @@ type Component struct {
 	providerBreakerLoggers map[netip.Addr]reporter.Logger
 	providerBreakers       map[netip.Addr]*breaker.Breaker
 	providers              []provider.Provider
+	maxInterfacesLogger    reporter.Logger
 
 	metrics struct {
 		cacheRefreshRuns         reporter.Counter
 		cacheRefresh             reporter.Counter
 		providerBusyCount        *reporter.CounterVec
 		providerBreakerOpenCount *reporter.CounterVec
+		providerBreakerState     *reporter.GaugeVec
 		providerBatchedCount     reporter.Counter
+		providerUpdates          *reporter.CounterVec
 	}
 }
 
@@ -81,13 +84,33 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		providerBreakers:       make(map[netip.Addr]*breaker.Breaker),
 		providerBreakerLoggers: make(map[netip.Addr]reporter.Logger),
 		providers:              make([]provider.Provider, 0, 1),
+		maxInterfacesLogger:    r.Sample(reporter.BurstSampler(time.Minute, 1)),
 	}
 	c.d.Daemon.Track(&c.t, "inlet/metadata")
 
 	// Initialize providers
 	for _, p := range c.config.Providers {
 		selectedProvider, err := p.Config.New(r, func(update provider.Update) {
-			c.sc.Put(c.d.Clock.Now(), update.Query, update.Answer)
+			exporterStr := update.Query.ExporterIP.Unmap().String()
+			maxInterfaces, _ := c.config.MaxInterfaces.Lookup(update.Query.ExporterIP)
+			accepted, changed := c.sc.PutWithLimit(c.d.Clock.Now(), update.Query, update.Answer, maxInterfaces)
+			if !accepted {
+				c.metrics.providerUpdates.WithLabelValues(exporterStr, "dropped").Inc()
+				c.maxInterfacesLogger.Warn().
+					Str("exporter", exporterStr).
+					Uint("max", maxInterfaces).
+					Msg("too many interfaces for exporter, ignoring new ones")
+				return
+			}
+			if !changed {
+				c.metrics.providerUpdates.WithLabelValues(exporterStr, "unchanged").Inc()
+				return
+			}
+			c.metrics.providerUpdates.WithLabelValues(exporterStr, "changed").Inc()
+			c.r.Debug().
+				Str("exporter", exporterStr).
+				Uint("ifindex", update.Query.IfIndex).
+				Msg("metadata updated")
 		})
 		if err != nil {
 			return nil, err
@@ -117,6 +140,18 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 			Help: "Provider breaker was opened due to too many errors.",
 		},
 		[]string{"exporter"})
+	c.metrics.providerBreakerState = r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "provider_breaker_state",
+			Help: "State of the provider breaker (0: closed, 1: open, 2: half-open).",
+		},
+		[]string{"exporter"})
+	c.metrics.providerUpdates = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "provider_updates_total",
+			Help: "Number of updates received from providers, by result (changed, unchanged, or dropped).",
+		},
+		[]string{"exporter", "result"})
 	c.metrics.providerBatchedCount = r.Counter(
 		reporter.CounterOpts{
 			Name: "provider_batched_requests_total",
@@ -126,6 +161,17 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	return &c, nil
 }
 
+// Validate checks the configuration of the metadata component. Unknown
+// interfaces should not be refreshed less often than known ones.
+func (c *Component) Validate() error {
+	if c.config.CacheNegativeRefresh > 0 && c.config.CacheRefresh > 0 &&
+		c.config.CacheNegativeRefresh > c.config.CacheRefresh {
+		return fmt.Errorf("cache-negative-refresh: %s is longer than cache-refresh (%s)",
+			c.config.CacheNegativeRefresh, c.config.CacheRefresh)
+	}
+	return nil
+}
+
 // Start starts the metadata component.
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting metadata component")
@@ -231,7 +277,7 @@ func (c *Component) Stop() error {
 func (c *Component) Lookup(t time.Time, exporterIP netip.Addr, ifIndex uint) (provider.Answer, bool) {
 	query := provider.Query{ExporterIP: exporterIP, IfIndex: ifIndex}
 	answer, ok := c.sc.Lookup(t, query)
-	if !ok {
+	if !ok && !c.sc.Rejected(query) {
 		select {
 		case c.dispatcherChannel <- query:
 		default:
@@ -293,6 +339,10 @@ func (c *Component) providerIncomingRequest(request provider.BatchQuery) {
 	}
 	c.providerBreakersLock.Unlock()
 
+	defer func() {
+		c.metrics.providerBreakerState.WithLabelValues(request.ExporterIP.Unmap().String()).
+			Set(float64(providerBreaker.GetState()))
+	}()
 	if err := providerBreaker.Run(func() error {
 		ctx := c.t.Context(nil)
 		for _, p := range c.providers {
@@ -325,11 +375,19 @@ func (c *Component) providerIncomingRequest(request provider.BatchQuery) {
 // expireCache handles cache expiration and refresh.
 func (c *Component) expireCache() {
 	c.sc.Expire(c.d.Clock.Now().Add(-c.config.CacheDuration))
-	if c.config.CacheRefresh > 0 {
+	if c.config.CacheRefresh > 0 || c.config.CacheNegativeRefresh > 0 {
 		c.r.Debug().Msg("refresh metadata cache")
 		c.metrics.cacheRefreshRuns.Inc()
 		count := 0
-		toRefresh := c.sc.NeedUpdates(c.d.Clock.Now().Add(-c.config.CacheRefresh))
+		now := c.d.Clock.Now()
+		var before, negativeBefore time.Time
+		if c.config.CacheRefresh > 0 {
+			before = now.Add(-c.config.CacheRefresh)
+		}
+		if c.config.CacheNegativeRefresh > 0 {
+			negativeBefore = now.Add(-c.config.CacheNegativeRefresh)
+		}
+		toRefresh := c.sc.NeedUpdates(before, negativeBefore)
 		for exporter, ifaces := range toRefresh {
 			for _, ifIndex := range ifaces {
 				select {
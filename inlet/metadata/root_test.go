@@ -52,6 +52,46 @@ func TestLookup(t *testing.T) {
 	})
 }
 
+func TestMaxInterfaces(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.MaxInterfaces = *helpers.MustNewSubnetMap(map[string]uint{
+		"::ffff:127.0.0.1/128": 1,
+	})
+	c := NewMock(t, r, configuration, Dependencies{Daemon: daemon.NewMock(t)})
+	expectMockLookup(t, c, "127.0.0.1", 765, provider.Answer{})
+	time.Sleep(30 * time.Millisecond)
+	expectMockLookup(t, c, "127.0.0.1", 999, provider.Answer{})
+	expectMockLookup(t, c, "127.0.0.2", 999, provider.Answer{})
+	time.Sleep(30 * time.Millisecond)
+	expectMockLookup(t, c, "127.0.0.1", 765, provider.Answer{
+		Exporter: provider.Exporter{
+			Name: "127_0_0_1",
+		},
+
+		Interface: provider.Interface{Name: "Gi0/0/765",
+			Description: "Interface 765",
+			Speed:       1000,
+		},
+	})
+	expectMockLookup(t, c, "127.0.0.1", 999, provider.Answer{})
+	expectMockLookup(t, c, "127.0.0.2", 999, provider.Answer{
+		Exporter: provider.Exporter{
+			Name: "127_0_0_2",
+		},
+	})
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_metadata_provider_updates_")
+	expectedMetrics := map[string]string{
+		`total{exporter="127.0.0.1",result="changed"}`: "1",
+		`total{exporter="127.0.0.1",result="dropped"}`: "1", // rejected interfaces are not polled again
+		`total{exporter="127.0.0.2",result="changed"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
 func TestComponentSaveLoad(t *testing.T) {
 	configuration := DefaultConfiguration()
 	configuration.CachePersistFile = filepath.Join(t.TempDir(), "cache")
@@ -149,6 +189,16 @@ func TestAutoRefresh(t *testing.T) {
 			break
 		}
 	}
+
+	// The refresh did not change anything
+	gotMetrics = r.GetMetrics("akvorado_inlet_metadata_provider_updates_")
+	expectedMetrics := map[string]string{
+		`total{exporter="127.0.0.1",result="changed"}`:   "1",
+		`total{exporter="127.0.0.1",result="unchanged"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
 }
 
 func TestConfigCheck(t *testing.T) {
@@ -208,9 +258,10 @@ func TestProviderBreaker(t *testing.T) {
 		Name                  string
 		ProviderConfiguration provider.Configuration
 		ExpectedCount         string
+		ExpectedState         string
 	}{
-		{"always successful provider", mockProviderConfiguration{}, "0"},
-		{"never successful provider", errorProviderConfiguration{}, "10"},
+		{"always successful provider", mockProviderConfiguration{}, "0", "0"},
+		{"never successful provider", errorProviderConfiguration{}, "10", "1"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
@@ -236,6 +287,14 @@ func TestProviderBreaker(t *testing.T) {
 			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 				t.Errorf("Metrics (-got, +want):\n%s", diff)
 			}
+			gotMetrics = r.GetMetrics("akvorado_inlet_metadata_provider_", "breaker_state")
+			expectedMetrics = map[string]string{
+				`breaker_state{exporter="127.0.0.1"}`: tc.ExpectedState,
+				`breaker_state{exporter="127.0.0.2"}`: "0",
+			}
+			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+				t.Errorf("Metrics (-got, +want):\n%s", diff)
+			}
 		})
 	}
 }
@@ -37,6 +37,12 @@ type Configuration struct {
 	// ASNs is a mapping from AS numbers to names. It replaces or
 	// extends the builtin list of AS numbers.
 	ASNs map[uint32]string
+	// TCPPorts is a mapping from TCP port numbers to names. It replaces or
+	// extends the builtin list of TCP services.
+	TCPPorts map[uint16]string
+	// UDPPorts is a mapping from UDP port numbers to names. It replaces or
+	// extends the builtin list of UDP services.
+	UDPPorts map[uint16]string
 	// Networks is a mapping from IP networks to attributes. It is used
 	// to instantiate the SrcNet* and DstNet* columns.
 	Networks *helpers.SubnetMap[NetworkAttributes] `validate:"omitempty,dive"`
@@ -72,9 +78,21 @@ type KafkaConfiguration struct {
 	// GroupName defines the Kafka consumers group used to poll data from topic,
 	// shared between all Consumers.
 	GroupName string
+	// MaxBlockSize is the maximum number of messages polled in a batch by the
+	// Kafka engine. 0 keeps the default value of ClickHouse.
+	MaxBlockSize int `validate:"min=0"`
+	// PollTimeout is the timeout for a single poll from Kafka by the Kafka
+	// engine. 0 keeps the default value of ClickHouse.
+	PollTimeout time.Duration `validate:"min=0"`
 	// EngineSettings allows one to set arbitrary settings for Kafka engine in
 	// ClickHouse.
 	EngineSettings []string
+	// MonitorInterval tells how often the Kafka engine consumers are checked.
+	// 0 disables monitoring.
+	MonitorInterval time.Duration `validate:"min=0"`
+	// StallTimeout is the duration after which a consumer without poll is
+	// considered as stalled.
+	StallTimeout time.Duration `validate:"min=1s"`
 }
 
 // DefaultConfiguration represents the default configuration for the ClickHouse configurator.
@@ -82,8 +100,10 @@ func DefaultConfiguration() Configuration {
 	return Configuration{
 		Configuration: clickhousedb.DefaultConfiguration(),
 		Kafka: KafkaConfiguration{
-			Consumers: 1,
-			GroupName: "clickhouse",
+			Consumers:       1,
+			GroupName:       "clickhouse",
+			MonitorInterval: time.Minute,
+			StallTimeout:    2 * time.Minute,
 		},
 		Resolutions: []ResolutionConfiguration{
 			{0, 15 * 24 * time.Hour},                   // 15 days
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -93,3 +94,36 @@ func TestDefaultConfiguration(t *testing.T) {
 		t.Fatalf("validate.Struct() error:\n%+v", err)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	withResolutions := func(resolutions ...ResolutionConfiguration) Configuration {
+		config := DefaultConfiguration()
+		config.Resolutions = resolutions
+		return config
+	}
+	cases := []struct {
+		Description   string
+		Configuration Configuration
+		Error         string
+	}{
+		{"default", DefaultConfiguration(), ""},
+		{"duplicate", withResolutions(
+			ResolutionConfiguration{0, 24 * time.Hour},
+			ResolutionConfiguration{time.Minute, 48 * time.Hour},
+			ResolutionConfiguration{time.Minute, 72 * time.Hour},
+		), "resolutions: interval 1m0s used more than once"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c := Component{config: tc.Configuration}
+			err := c.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Error); diff != "" {
+				t.Fatalf("Validate() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
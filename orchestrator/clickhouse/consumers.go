@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// kafkaIngestionLagWindow is the window used to look for the most recent flow
+// when computing the ingestion lag. The lag is capped to this value.
+const kafkaIngestionLagWindow = time.Hour
+
+// errUnknownTable is the code of the error returned by ClickHouse when a table
+// does not exist.
+const errUnknownTable = 60
+
+// kafkaConsumerStatus is the status of a Kafka engine consumer as reported by
+// ClickHouse.
+type kafkaConsumerStatus struct {
+	ConsumerID        string    `ch:"consumer_id"`
+	LastPoll          time.Time `ch:"last_poll"`
+	LastExceptionTime time.Time `ch:"last_exception_time"`
+	LastException     string    `ch:"last_exception"`
+}
+
+// runKafkaConsumersMonitor periodically checks the Kafka engine consumers and
+// their lag once migrations are done.
+func (c *Component) runKafkaConsumersMonitor() error {
+	select {
+	case <-c.t.Dying():
+		return nil
+	case <-c.migrationsDone:
+	}
+	ticker := time.NewTicker(c.config.Kafka.MonitorInterval)
+	defer ticker.Stop()
+	defer func() {
+		if c.kafkaAdmin != nil {
+			c.kafkaAdmin.Close()
+		}
+	}()
+	for {
+		ctx := c.t.Context(nil)
+		if err := c.monitorKafkaConsumers(ctx); err != nil {
+			c.metrics.kafkaConsumersHealthy.Set(0)
+			c.metrics.kafkaMonitorErrors.Inc()
+			c.r.Err(err).Msg("unable to monitor Kafka consumers")
+		}
+		if err := c.monitorKafkaLag(); err != nil {
+			c.metrics.kafkaMonitorErrors.Inc()
+			c.r.Err(err).Msg("unable to get Kafka consumer lag")
+		}
+		select {
+		case <-c.t.Dying():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitorKafkaConsumers checks the Kafka engine consumers of the raw flows
+// table (when ClickHouse is recent enough), the number of rows sent to the
+// errors table, and the ingestion lag.
+func (c *Component) monitorKafkaConsumers(ctx context.Context) error {
+	now := time.Now()
+
+	// Consumers
+	if !c.kafkaConsumersUnavailable {
+		if err := c.monitorKafkaConsumersStatus(ctx, now); err != nil {
+			var exception *clickhouse.Exception
+			if !errors.As(err, &exception) || exception.Code != errUnknownTable {
+				return err
+			}
+			// system.kafka_consumers is only available since ClickHouse 23.8
+			c.kafkaConsumersUnavailable = true
+			c.r.Warn().Msg("Kafka consumers are not monitored, ClickHouse 23.8 or more recent is needed")
+		}
+	}
+
+	// Rows sent to the errors table
+	var errorRows []struct {
+		Count uint64 `ch:"count"`
+	}
+	if err := c.d.ClickHouse.Select(ctx, &errorRows, fmt.Sprintf(
+		`SELECT count() AS count FROM flows_raw_errors WHERE timestamp > now() - toIntervalSecond(%d)`,
+		uint64(c.config.Kafka.MonitorInterval.Seconds()))); err != nil {
+		return fmt.Errorf("cannot count raw flows errors: %w", err)
+	}
+	if len(errorRows) == 1 {
+		c.metrics.kafkaErrorRows.Set(float64(errorRows[0].Count))
+	}
+
+	// Ingestion lag
+	var last []struct {
+		TimeReceived time.Time `ch:"t"`
+	}
+	if err := c.d.ClickHouse.Select(ctx, &last, fmt.Sprintf(
+		`SELECT max(TimeReceived) AS t FROM flows WHERE TimeReceived > now() - toIntervalSecond(%d)`,
+		uint64(kafkaIngestionLagWindow.Seconds()))); err != nil {
+		return fmt.Errorf("cannot get most recent flow: %w", err)
+	}
+	lag := kafkaIngestionLagWindow
+	if len(last) == 1 && !last[0].TimeReceived.IsZero() && last[0].TimeReceived.Unix() != 0 {
+		lag = min(kafkaIngestionLagWindow, max(0, now.Sub(last[0].TimeReceived)))
+	}
+	c.metrics.kafkaIngestionLag.Set(lag.Seconds())
+
+	return nil
+}
+
+// monitorKafkaConsumersStatus checks the Kafka engine consumers of the raw flows
+// table using the system.kafka_consumers table.
+func (c *Component) monitorKafkaConsumersStatus(ctx context.Context, now time.Time) error {
+	source := "system.kafka_consumers"
+	if c.config.Cluster != "" {
+		source = fmt.Sprintf("clusterAllReplicas('%s', system.kafka_consumers)", c.config.Cluster)
+	}
+	consumersQuery, err := stemplate(`
+SELECT
+ consumer_id,
+ ifNull(last_poll_time, toDateTime(0)) AS last_poll,
+ exceptions.time[-1] AS last_exception_time,
+ exceptions.text[-1] AS last_exception
+FROM {{ .Source }}
+WHERE database = '{{ .Database }}' AND table = '{{ .Table }}'`, gin.H{
+		"Source":   source,
+		"Database": c.config.Database,
+		"Table":    fmt.Sprintf("flows_%s_raw", c.d.Schema.ProtobufMessageHash()),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot build query to get Kafka consumers: %w", err)
+	}
+	var consumers []kafkaConsumerStatus
+	if err := c.d.ClickHouse.Select(ctx, &consumers, consumersQuery); err != nil {
+		return fmt.Errorf("cannot get Kafka consumers: %w", err)
+	}
+	stalled := 0
+	exceptions := 0
+	since := c.kafkaLastException
+	if since.IsZero() {
+		// Do not report errors older than the first check
+		since = now.Add(-c.config.Kafka.MonitorInterval)
+	}
+	lastException := since
+	for _, consumer := range consumers {
+		if now.Sub(consumer.LastPoll) > c.config.Kafka.StallTimeout {
+			stalled++
+		}
+		if consumer.LastExceptionTime.After(since) {
+			exceptions++
+			c.r.Error().
+				Str("consumer", consumer.ConsumerID).
+				Time("time", consumer.LastExceptionTime).
+				Msgf("Kafka consumer error: %s", consumer.LastException)
+			if consumer.LastExceptionTime.After(lastException) {
+				lastException = consumer.LastExceptionTime
+			}
+		}
+	}
+	c.kafkaLastException = lastException
+	c.metrics.kafkaConsumers.Set(float64(len(consumers)))
+	c.metrics.kafkaStalledConsumers.Set(float64(stalled))
+	c.metrics.kafkaConsumerErrors.Add(float64(exceptions))
+	if len(consumers) > 0 && stalled == 0 && exceptions == 0 {
+		c.metrics.kafkaConsumersHealthy.Set(1)
+	} else {
+		c.metrics.kafkaConsumersHealthy.Set(0)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/IBM/sarama"
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/kafka"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/orchestrator/geoip"
+)
+
+func TestMonitorKafkaConsumers(t *testing.T) {
+	r := reporter.NewMock(t)
+	chComponent, mockConn := clickhousedb.NewMock(t, r)
+	sch := schema.NewMock(t)
+	c, err := New(r, DefaultConfiguration(), Dependencies{
+		Daemon:     daemon.NewMock(t),
+		HTTP:       httpserver.NewMock(t, r),
+		Schema:     sch,
+		GeoIP:      geoip.NewMock(t, r, false),
+		ClickHouse: chComponent,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	consumersQuery := fmt.Sprintf(`
+SELECT
+ consumer_id,
+ ifNull(last_poll_time, toDateTime(0)) AS last_poll,
+ exceptions.time[-1] AS last_exception_time,
+ exceptions.text[-1] AS last_exception
+FROM system.kafka_consumers
+WHERE database = 'default' AND table = 'flows_%s_raw'`, sch.ProtobufMessageHash())
+	errorsQuery := `SELECT count() AS count FROM flows_raw_errors WHERE timestamp > now() - toIntervalSecond(60)`
+	lagQuery := `SELECT max(TimeReceived) AS t FROM flows WHERE TimeReceived > now() - toIntervalSecond(3600)`
+	now := time.Now()
+
+	// Healthy consumers, an old error is not reported
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), consumersQuery).
+		Return(nil).
+		SetArg(1, []kafkaConsumerStatus{
+			{"consumer-0", now.Add(-time.Second), now.Add(-time.Hour), "old error"},
+			{"consumer-1", now.Add(-2 * time.Second), time.Unix(0, 0), ""},
+		})
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), errorsQuery).
+		Return(nil).
+		SetArg(1, []struct {
+			Count uint64 `ch:"count"`
+		}{{0}})
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), lagQuery).
+		Return(nil).
+		SetArg(1, []struct {
+			TimeReceived time.Time `ch:"t"`
+		}{{now.Add(-10 * time.Second)}})
+	if err := c.monitorKafkaConsumers(context.Background()); err != nil {
+		t.Fatalf("monitorKafkaConsumers() error:\n%+v", err)
+	}
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_clickhouse_", "-kafka_ingestion_lag_", "kafka_")
+	expectedMetrics := map[string]string{
+		`kafka_consumers`:             "2",
+		`kafka_consumers_healthy`:     "1",
+		`kafka_consumer_errors_total`: "0",
+		`kafka_error_rows`:            "0",
+		`kafka_monitor_errors_total`:  "0",
+		`kafka_stalled_consumers`:     "0",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+
+	// A stalled consumer and a new error
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), consumersQuery).
+		Return(nil).
+		SetArg(1, []kafkaConsumerStatus{
+			{"consumer-0", now.Add(-time.Hour), now.Add(-time.Hour), "old error"},
+			{"consumer-1", now.Add(-2 * time.Second), now.Add(-time.Second), "new error"},
+		})
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), errorsQuery).
+		Return(nil).
+		SetArg(1, []struct {
+			Count uint64 `ch:"count"`
+		}{{12}})
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), lagQuery).
+		Return(nil).
+		SetArg(1, []struct {
+			TimeReceived time.Time `ch:"t"`
+		}{{time.Unix(0, 0)}})
+	if err := c.monitorKafkaConsumers(context.Background()); err != nil {
+		t.Fatalf("monitorKafkaConsumers() error:\n%+v", err)
+	}
+	gotMetrics = r.GetMetrics("akvorado_orchestrator_clickhouse_", "kafka_")
+	expectedMetrics = map[string]string{
+		`kafka_consumers`:             "2",
+		`kafka_consumers_healthy`:     "0",
+		`kafka_consumer_errors_total`: "1",
+		`kafka_error_rows`:            "12",
+		`kafka_ingestion_lag_seconds`: "3600",
+		`kafka_monitor_errors_total`:  "0",
+		`kafka_stalled_consumers`:     "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+
+	// Errors while querying ClickHouse
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), consumersQuery).
+		Return(errors.New("connection refused"))
+	if err := c.monitorKafkaConsumers(context.Background()); err == nil {
+		t.Fatal("monitorKafkaConsumers() did not error")
+	}
+
+	// ClickHouse without system.kafka_consumers, checked only once
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), consumersQuery).
+		Return(&clickhouse.Exception{Code: 60, Message: "Table system.kafka_consumers does not exist"})
+	for range 2 {
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), errorsQuery).
+			Return(nil).
+			SetArg(1, []struct {
+				Count uint64 `ch:"count"`
+			}{{0}})
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), lagQuery).
+			Return(nil).
+			SetArg(1, []struct {
+				TimeReceived time.Time `ch:"t"`
+			}{{now.Add(-10 * time.Second)}})
+		if err := c.monitorKafkaConsumers(context.Background()); err != nil {
+			t.Fatalf("monitorKafkaConsumers() error:\n%+v", err)
+		}
+	}
+}
+
+func TestMonitorKafkaLag(t *testing.T) {
+	r := reporter.NewMock(t)
+	sch := schema.NewMock(t)
+	topic := fmt.Sprintf("flows-%s", sch.ProtobufMessageHash())
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()).
+			SetLeader(topic, 2, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "clickhouse", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("clickhouse", topic, 0, 100, "", sarama.ErrNoError).
+			SetOffset("clickhouse", topic, 1, 1000, "", sarama.ErrNoError).
+			SetOffset("clickhouse", topic, 2, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(topic, 0, sarama.OffsetNewest, 150).
+			SetOffset(topic, 1, sarama.OffsetNewest, 1000).
+			SetOffset(topic, 2, sarama.OffsetNewest, 10).
+			SetOffset(topic, 2, sarama.OffsetOldest, 4),
+	})
+
+	config := DefaultConfiguration()
+	config.Kafka.Configuration = kafka.DefaultConfiguration()
+	config.Kafka.Brokers = []string{broker.Addr()}
+	chComponent, _ := clickhousedb.NewMock(t, r)
+	c, err := New(r, config, Dependencies{
+		Daemon:     daemon.NewMock(t),
+		HTTP:       httpserver.NewMock(t, r),
+		Schema:     sch,
+		GeoIP:      geoip.NewMock(t, r, false),
+		ClickHouse: chComponent,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	defer func() {
+		if c.kafkaAdmin != nil {
+			c.kafkaAdmin.Close()
+		}
+	}()
+
+	if err := c.monitorKafkaLag(); err != nil {
+		t.Fatalf("monitorKafkaLag() error:\n%+v", err)
+	}
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_clickhouse_", "kafka_consumer_lag")
+	expectedMetrics := map[string]string{
+		`kafka_consumer_lag{partition="0"}`: "50",
+		`kafka_consumer_lag{partition="1"}`: "0",
+		`kafka_consumer_lag{partition="2"}`: "6",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
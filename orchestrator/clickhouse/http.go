@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"text/template"
 	"time"
@@ -184,52 +185,17 @@ func (c *Component) registerHTTPHandlers() error {
 			}
 		}))
 
-	// asns.csv (when there are some custom-defined ASNs)
-	if len(c.config.ASNs) != 0 {
-		c.d.HTTP.AddHandler("/api/v0/orchestrator/clickhouse/asns.csv",
-			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-				f, err := data.Open("data/asns.csv")
-				if err != nil {
-					c.r.Err(err).Msg("unable to open data/asns.csv")
-					http.Error(w, "Unable to open ASN file.",
-						http.StatusInternalServerError)
-					return
-				}
-				rd := csv.NewReader(f)
-				rd.ReuseRecord = true
-				rd.FieldsPerRecord = 2
-				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
-				w.WriteHeader(http.StatusOK)
-				wr := csv.NewWriter(w)
-				wr.Write([]string{"asn", "name"})
-				// Custom ASNs
-				for asn, name := range c.config.ASNs {
-					wr.Write([]string{strconv.Itoa(int(asn)), name})
-				}
-				// Other ASNs
-				for count := 0; ; count++ {
-					record, err := rd.Read()
-					if err == io.EOF {
-						break
-					}
-					if err != nil {
-						c.r.Err(err).Msgf("unable to parse data/asns.csv (line %d)", count)
-						continue
-					}
-					if count == 0 {
-						continue
-					}
-					asn, err := strconv.ParseUint(record[0], 10, 32)
-					if err != nil {
-						c.r.Err(err).Msgf("invalid AS number (line %d)", count)
-						continue
-					}
-					if _, ok := c.config.ASNs[uint32(asn)]; !ok {
-						wr.Write(record)
-					}
-				}
-				wr.Flush()
-			}))
+	// CSV files with custom-defined entries
+	overridden := map[string]map[uint64]string{}
+	for file, overrides := range map[string]map[uint64]string{
+		"asns.csv": toOverrides(c.config.ASNs),
+		"tcp.csv":  toOverrides(c.config.TCPPorts),
+		"udp.csv":  toOverrides(c.config.UDPPorts),
+	} {
+		if len(overrides) != 0 {
+			overridden[file] = overrides
+			c.addHandlerOverridden(file, overrides)
+		}
 	}
 
 	// Static CSV files
@@ -241,7 +207,7 @@ func (c *Component) registerHTTPHandlers() error {
 		if entry.IsDir() {
 			continue
 		}
-		if entry.Name() == "asns.csv" && len(c.config.ASNs) != 0 {
+		if _, ok := overridden[entry.Name()]; ok {
 			continue
 		}
 		url := fmt.Sprintf("/api/v0/orchestrator/clickhouse/%s", entry.Name())
@@ -251,3 +217,74 @@ func (c *Component) registerHTTPHandlers() error {
 
 	return nil
 }
+
+// addHandlerOverridden serves an embedded CSV file whose first column is a
+// number, replacing or extending its entries with the provided ones.
+func (c *Component) addHandlerOverridden(file string, overrides map[uint64]string) {
+	url := fmt.Sprintf("/api/v0/orchestrator/clickhouse/%s", file)
+	path := fmt.Sprintf("data/%s", file)
+	c.d.HTTP.AddHandler(url,
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			f, err := data.Open(path)
+			if err != nil {
+				c.r.Err(err).Msgf("unable to open %s", path)
+				http.Error(w, fmt.Sprintf("Unable to open %s.", file),
+					http.StatusInternalServerError)
+				return
+			}
+			defer f.Close()
+			rd := csv.NewReader(f)
+			rd.ReuseRecord = true
+			rd.FieldsPerRecord = 2
+			header, err := rd.Read()
+			if err != nil {
+				c.r.Err(err).Msgf("unable to parse %s header", path)
+				http.Error(w, fmt.Sprintf("Unable to parse %s.", file),
+					http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			wr := csv.NewWriter(w)
+			wr.Write(header)
+			// Custom entries
+			keys := make([]uint64, 0, len(overrides))
+			for key := range overrides {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				wr.Write([]string{strconv.FormatUint(key, 10), overrides[key]})
+			}
+			// Other entries
+			for count := 1; ; count++ {
+				record, err := rd.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					c.r.Err(err).Msgf("unable to parse %s (line %d)", path, count)
+					continue
+				}
+				key, err := strconv.ParseUint(record[0], 10, 32)
+				if err != nil {
+					c.r.Err(err).Msgf("invalid key in %s (line %d)", path, count)
+					continue
+				}
+				if _, ok := overrides[key]; !ok {
+					wr.Write(record)
+				}
+			}
+			wr.Flush()
+		}))
+}
+
+// toOverrides converts a mapping from numbers to names to a mapping usable
+// with addHandlerOverridden.
+func toOverrides[T uint16 | uint32](input map[T]string) map[uint64]string {
+	output := make(map[uint64]string, len(input))
+	for key, name := range input {
+		output[uint64(key)] = name
+	}
+	return output
+}
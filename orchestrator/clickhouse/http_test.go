@@ -143,3 +143,45 @@ func TestAdditionalASNs(t *testing.T) {
 
 	helpers.TestHTTPEndpoints(t, c.d.HTTP.LocalAddr(), cases)
 }
+
+func TestAdditionalPorts(t *testing.T) {
+	r := reporter.NewMock(t)
+	clickhouseComponent := clickhousedb.SetupClickHouse(t, r, false)
+	config := DefaultConfiguration()
+	config.TCPPorts = map[uint16]string{
+		80:   "web",
+		8080: "proxy",
+	}
+	c, err := New(r, config, Dependencies{
+		Daemon:     daemon.NewMock(t),
+		HTTP:       httpserver.NewMock(t, r),
+		Schema:     schema.NewMock(t),
+		GeoIP:      geoip.NewMock(t, r, false),
+		ClickHouse: clickhouseComponent,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	cases := helpers.HTTPEndpointCases{
+		{
+			URL:         "/api/v0/orchestrator/clickhouse/tcp.csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				`port,name`,
+				`80,web`,
+				`8080,proxy`,
+				`22,ssh`,
+				`443,https`,
+			},
+		}, {
+			URL:         "/api/v0/orchestrator/clickhouse/udp.csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				`port,name`,
+			},
+		},
+	}
+
+	helpers.TestHTTPEndpoints(t, c.d.HTTP.LocalAddr(), cases)
+}
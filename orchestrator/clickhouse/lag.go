@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2024 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+)
+
+// monitorKafkaLag compares the offsets committed by the ClickHouse consumer
+// group with the newest offsets of each partition of the flow topic.
+func (c *Component) monitorKafkaLag() error {
+	if c.kafkaAdmin == nil {
+		client, err := sarama.NewClient(c.config.Kafka.Brokers, c.kafkaConfig)
+		if err != nil {
+			return fmt.Errorf("cannot create Kafka client: %w", err)
+		}
+		admin, err := sarama.NewClusterAdminFromClient(client)
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("cannot create Kafka admin client: %w", err)
+		}
+		c.kafkaClient = client
+		c.kafkaAdmin = admin
+	}
+
+	topic := fmt.Sprintf("%s-%s", c.config.Kafka.Topic, c.d.Schema.ProtobufMessageHash())
+	partitions, err := c.kafkaClient.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("cannot get partitions for topic %q: %w", topic, err)
+	}
+	offsets, err := c.kafkaAdmin.ListConsumerGroupOffsets(c.config.Kafka.GroupName,
+		map[string][]int32{topic: partitions})
+	if err != nil {
+		return fmt.Errorf("cannot get offsets for consumer group %q: %w", c.config.Kafka.GroupName, err)
+	}
+	if offsets.Err != sarama.ErrNoError {
+		return fmt.Errorf("cannot get offsets for consumer group %q: %w", c.config.Kafka.GroupName, offsets.Err)
+	}
+	for _, partition := range partitions {
+		block := offsets.GetBlock(topic, partition)
+		if block != nil && block.Err != sarama.ErrNoError {
+			return fmt.Errorf("cannot get committed offset for partition %d: %w", partition, block.Err)
+		}
+		newest, err := c.kafkaClient.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return fmt.Errorf("cannot get newest offset for partition %d: %w", partition, err)
+		}
+		committed := int64(-1)
+		if block != nil {
+			committed = block.Offset
+		}
+		if committed < 0 {
+			// Nothing committed yet for this partition: all the messages
+			// still available are waiting to be consumed.
+			committed, err = c.kafkaClient.GetOffset(topic, partition, sarama.OffsetOldest)
+			if err != nil {
+				return fmt.Errorf("cannot get oldest offset for partition %d: %w", partition, err)
+			}
+		}
+		c.metrics.kafkaConsumerLag.
+			WithLabelValues(strconv.Itoa(int(partition))).
+			Set(float64(max(0, newest-committed)))
+	}
+	return nil
+}
@@ -11,6 +11,15 @@ type metrics struct {
 	migrationsNotApplied reporter.Counter
 
 	networksReload reporter.Counter
+
+	kafkaConsumers        reporter.Gauge
+	kafkaStalledConsumers reporter.Gauge
+	kafkaConsumersHealthy reporter.Gauge
+	kafkaConsumerErrors   reporter.Counter
+	kafkaErrorRows        reporter.Gauge
+	kafkaIngestionLag     reporter.Gauge
+	kafkaConsumerLag      *reporter.GaugeVec
+	kafkaMonitorErrors    reporter.Counter
 }
 
 func (c *Component) initMetrics() {
@@ -38,4 +47,53 @@ func (c *Component) initMetrics() {
 			Help: "Number of reloads triggered for networks dictionary.",
 		},
 	)
+	c.metrics.kafkaConsumers = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "kafka_consumers",
+			Help: "Number of Kafka engine consumers for the raw flows table.",
+		},
+	)
+	c.metrics.kafkaStalledConsumers = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "kafka_stalled_consumers",
+			Help: "Number of Kafka engine consumers without a recent poll.",
+		},
+	)
+	c.metrics.kafkaConsumersHealthy = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "kafka_consumers_healthy",
+			Help: "Whether Kafka engine consumers are running without errors.",
+		},
+	)
+	c.metrics.kafkaConsumerErrors = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "kafka_consumer_errors_total",
+			Help: "Number of new errors reported by Kafka engine consumers.",
+		},
+	)
+	c.metrics.kafkaErrorRows = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "kafka_error_rows",
+			Help: "Number of rows sent to the raw flows errors table during the last interval.",
+		},
+	)
+	c.metrics.kafkaIngestionLag = c.r.Gauge(
+		reporter.GaugeOpts{
+			Name: "kafka_ingestion_lag_seconds",
+			Help: "Time since the most recent flow ingested from Kafka.",
+		},
+	)
+	c.metrics.kafkaConsumerLag = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Number of messages not yet consumed by ClickHouse for each partition.",
+		},
+		[]string{"partition"},
+	)
+	c.metrics.kafkaMonitorErrors = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "kafka_monitor_errors_total",
+			Help: "Number of errors while monitoring Kafka engine consumers.",
+		},
+	)
 }
@@ -122,11 +122,15 @@ func (c *Component) migrateDatabase() error {
 			// This is only an attribute. We only need it in the schema
 			schemaStr = append(schemaStr, fmt.Sprintf("`%s` %s DEFAULT '%s'", a.Name, a.Type, defaultValue))
 		}
+		layout := v.Layout
+		if layout == "iptrie" {
+			layout = "ip_trie"
+		}
 		dictMigrations = append(dictMigrations, func(ctx context.Context) error {
 			return c.createDictionary(
 				ctx,
 				fmt.Sprintf("custom_dict_%s", k),
-				v.Layout,
+				layout,
 				strings.Join(schemaStr[:], ", "),
 				strings.Join(keys[:], ", "))
 		})
@@ -201,7 +205,7 @@ func (c *Component) getHTTPBaseURL(address string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("cannot get HTTP port: %w", err)
 	}
-	base := fmt.Sprintf("http://%s",
+	base := fmt.Sprintf("%s://%s", c.d.HTTP.Scheme(),
 		net.JoinHostPort(localAddr.IP.String(), port))
 	c.r.Debug().Msgf("detected base URL is %s", base)
 	return base, nil
@@ -263,10 +263,10 @@ CREATE MATERIALIZED VIEW exporters_consumer TO %s AS %s
 	return nil
 }
 
-// createRawFlowsTable creates the raw flow table
-func (c *Component) createRawFlowsTable(ctx context.Context) error {
+// kafkaEngineSettings returns the settings for the Kafka engine of the raw
+// flows table.
+func (c *Component) kafkaEngineSettings() []string {
 	hash := c.d.Schema.ProtobufMessageHash()
-	tableName := fmt.Sprintf("flows_%s_raw", hash)
 	kafkaSettings := []string{
 		fmt.Sprintf(`kafka_broker_list = '%s'`,
 			strings.Join(c.config.Kafka.Brokers, ",")),
@@ -279,10 +279,25 @@ func (c *Component) createRawFlowsTable(ctx context.Context) error {
 		`kafka_thread_per_consumer = 1`,
 		`kafka_handle_error_mode = 'stream'`,
 	}
+	if c.config.Kafka.MaxBlockSize > 0 {
+		kafkaSettings = append(kafkaSettings,
+			fmt.Sprintf(`kafka_max_block_size = %d`, c.config.Kafka.MaxBlockSize))
+	}
+	if c.config.Kafka.PollTimeout > 0 {
+		kafkaSettings = append(kafkaSettings,
+			fmt.Sprintf(`kafka_poll_timeout_ms = %d`, c.config.Kafka.PollTimeout.Milliseconds()))
+	}
 	for _, setting := range c.config.Kafka.EngineSettings {
 		kafkaSettings = append(kafkaSettings, setting)
 	}
-	kafkaEngine := fmt.Sprintf("Kafka SETTINGS %s", strings.Join(kafkaSettings, ", "))
+	return kafkaSettings
+}
+
+// createRawFlowsTable creates the raw flow table
+func (c *Component) createRawFlowsTable(ctx context.Context) error {
+	hash := c.d.Schema.ProtobufMessageHash()
+	tableName := fmt.Sprintf("flows_%s_raw", hash)
+	kafkaEngine := fmt.Sprintf("Kafka SETTINGS %s", strings.Join(c.kafkaEngineSettings(), ", "))
 
 	// Build CREATE query
 	createQuery, err := stemplate(
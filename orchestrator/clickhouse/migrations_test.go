@@ -537,3 +537,54 @@ AND name LIKE $3`, "flows", ch.config.Database, "%DimensionAttribute")
 		}
 	})
 }
+
+func TestKafkaEngineSettings(t *testing.T) {
+	r := reporter.NewMock(t)
+	sch := schema.NewMock(t)
+	hash := sch.ProtobufMessageHash()
+	newComponent := func(config Configuration) (*Component, error) {
+		return New(r, config, Dependencies{
+			Daemon: daemon.NewMock(t),
+			HTTP:   httpserver.NewMock(t, r),
+			Schema: sch,
+			GeoIP:  geoip.NewMock(t, r, false),
+		})
+	}
+
+	config := DefaultConfiguration()
+	config.Kafka.Configuration = kafka.DefaultConfiguration()
+	config.Kafka.Consumers = 4
+	config.Kafka.GroupName = "akvorado"
+	config.Kafka.MaxBlockSize = 10000
+	config.Kafka.PollTimeout = 500 * time.Millisecond
+	config.Kafka.EngineSettings = []string{"kafka_flush_interval_ms = 1000"}
+	c, err := newComponent(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	expected := []string{
+		"kafka_broker_list = '127.0.0.1:9092'",
+		fmt.Sprintf("kafka_topic_list = 'flows-%s'", hash),
+		"kafka_group_name = 'akvorado'",
+		"kafka_format = 'Protobuf'",
+		fmt.Sprintf("kafka_schema = 'flow-%s.proto:FlowMessagev%s'", hash, hash),
+		"kafka_num_consumers = 4",
+		"kafka_thread_per_consumer = 1",
+		"kafka_handle_error_mode = 'stream'",
+		"kafka_max_block_size = 10000",
+		"kafka_poll_timeout_ms = 500",
+		"kafka_flush_interval_ms = 1000",
+	}
+	if diff := helpers.Diff(c.kafkaEngineSettings(), expected); diff != "" {
+		t.Fatalf("kafkaEngineSettings() (-got, +want):\n%s", diff)
+	}
+
+	config.Kafka.EngineSettings = []string{"kafka_max_block_size=5000"}
+	if _, err := newComponent(config); err == nil {
+		t.Fatal("New() did not error with a conflicting engine setting")
+	}
+	config.Kafka.MaxBlockSize = 0
+	if _, err := newComponent(config); err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+}
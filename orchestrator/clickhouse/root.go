@@ -5,20 +5,24 @@
 package clickhouse
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"akvorado/common/remotedatasourcefetcher"
 
+	"github.com/IBM/sarama"
 	"github.com/cenkalti/backoff/v4"
 	"gopkg.in/tomb.v2"
 
 	"akvorado/common/clickhousedb"
 	"akvorado/common/daemon"
 	"akvorado/common/httpserver"
+	"akvorado/common/kafka"
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
 	"akvorado/orchestrator/geoip"
@@ -34,6 +38,12 @@ type Component struct {
 
 	shards int // number of shards if in a cluster
 
+	kafkaLastException        time.Time // most recent Kafka consumer error seen
+	kafkaConsumersUnavailable bool      // system.kafka_consumers does not exist
+	kafkaConfig               *sarama.Config
+	kafkaClient               sarama.Client
+	kafkaAdmin                sarama.ClusterAdmin
+
 	migrationsDone        chan bool // closed when migrations are done
 	migrationsOnce        chan bool // closed after first attempt to migrate
 	networkSourcesFetcher *remotedatasourcefetcher.Component[externalNetworkAttributes]
@@ -67,7 +77,18 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		networksCSVReady:      make(chan bool),
 		networksCSVUpdateChan: make(chan bool, 1),
 	}
+	for _, setting := range configuration.Kafka.EngineSettings {
+		name := strings.TrimSpace(strings.SplitN(setting, "=", 2)[0])
+		if (name == "kafka_max_block_size" && configuration.Kafka.MaxBlockSize > 0) ||
+			(name == "kafka_poll_timeout_ms" && configuration.Kafka.PollTimeout > 0) {
+			return nil, fmt.Errorf("engine setting %q conflicts with Kafka configuration", name)
+		}
+	}
 	var err error
+	c.kafkaConfig, err = kafka.NewConfig(configuration.Kafka.Configuration)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build Kafka configuration: %w", err)
+	}
 	c.networkSourcesFetcher, err = remotedatasourcefetcher.New[externalNetworkAttributes](
 		r, c.UpdateRemoteDataSource, "network_source", configuration.NetworkSources)
 	if err != nil {
@@ -92,6 +113,23 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	return &c, nil
 }
 
+// Validate checks the configuration of the ClickHouse component. Two
+// resolutions cannot share the same interval as they would use the same table.
+// Resolutions are sorted by New(), so they are not referenced by index.
+func (c *Component) Validate() error {
+	errs := []error{}
+	seen := map[time.Duration]bool{}
+	for _, resolution := range c.config.Resolutions {
+		if seen[resolution.Interval] {
+			errs = append(errs, fmt.Errorf("resolutions: interval %s used more than once",
+				resolution.Interval))
+			continue
+		}
+		seen[resolution.Interval] = true
+	}
+	return errors.Join(errs...)
+}
+
 // Start the ClickHouse component.
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting ClickHouse component")
@@ -132,6 +170,11 @@ func (c *Component) Start() error {
 		}
 	})
 
+	// Kafka consumers monitoring
+	if c.config.Kafka.MonitorInterval > 0 {
+		c.t.Go(c.runKafkaConsumersMonitor)
+	}
+
 	// Network sources update
 	if err := c.networkSourcesFetcher.Start(); err != nil {
 		return fmt.Errorf("unable to start network sources fetcher component: %w", err)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/oschwald/maxminddb-golang"
 )
@@ -53,6 +54,11 @@ func (c *Component) openDatabase(which string, path string, notifySubscribers bo
 		c.db.geo[path] = newOne
 	}
 	c.metrics.databaseRefresh.WithLabelValues(which).Inc()
+	c.metrics.databaseBuildTimestamp.WithLabelValues(which, path).Set(float64(db.Metadata.BuildEpoch))
+	c.r.Info().
+		Str("database", path).
+		Time("build", time.Unix(int64(db.Metadata.BuildEpoch), 0)).
+		Msgf("%s database loaded", which)
 	if oldOne != nil {
 		c.r.Debug().
 			Str("database", path).
@@ -5,6 +5,7 @@
 package geoip
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -32,7 +33,8 @@ type Component struct {
 	}
 
 	metrics struct {
-		databaseRefresh *reporter.CounterVec
+		databaseRefresh        *reporter.CounterVec
+		databaseBuildTimestamp *reporter.GaugeVec
 	}
 
 	onOpenChan        chan struct{}   // input notification channel
@@ -72,6 +74,13 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		},
 		[]string{"database"},
 	)
+	c.metrics.databaseBuildTimestamp = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "db_build_timestamp_seconds",
+			Help: "Build time of the loaded GeoIP database.",
+		},
+		[]string{"database", "path"},
+	)
 	return &c, nil
 }
 
@@ -95,6 +104,7 @@ func (c *Component) Start() error {
 		c.r.Warn().Msg("skipping GeoIP component: no database specified")
 	}
 	c.r.Info().Msg("starting GeoIP component")
+	c.r.RegisterHealthcheck("geoip", c.healthcheck)
 
 	c.t.Go(func() error {
 		for range c.onOpenChan {
@@ -179,6 +189,38 @@ func (c *Component) Start() error {
 	return nil
 }
 
+// healthcheck reports an error until all configured databases are loaded. When
+// databases are optional, a missing database is only a warning.
+func (c *Component) healthcheck(_ context.Context) reporter.HealthcheckResult {
+	c.db.lock.RLock()
+	defer c.db.lock.RUnlock()
+	missing := 0
+	for _, path := range c.config.GeoDatabase {
+		if c.db.geo[path] == nil {
+			missing++
+		}
+	}
+	for _, path := range c.config.ASNDatabase {
+		if c.db.asn[path] == nil {
+			missing++
+		}
+	}
+	switch {
+	case missing == 0:
+		return reporter.HealthcheckResult{Status: reporter.HealthcheckOK, Reason: "databases loaded"}
+	case c.config.Optional:
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckOK,
+			Reason: fmt.Sprintf("%d optional database(s) not loaded", missing),
+		}
+	default:
+		return reporter.HealthcheckResult{
+			Status: reporter.HealthcheckError,
+			Reason: fmt.Sprintf("%d database(s) not loaded", missing),
+		}
+	}
+}
+
 // Stop stops the GeoIP component.
 func (c *Component) Stop() error {
 	c.r.Info().Msg("stopping GeoIP component")
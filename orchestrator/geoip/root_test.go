@@ -4,6 +4,8 @@
 package geoip
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -57,7 +59,13 @@ func TestDatabaseRefresh(t *testing.T) {
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
+	if got := c.healthcheck(context.Background()); got.Status != reporter.HealthcheckError {
+		t.Errorf("healthcheck() before start = %s, expected error", got.Status)
+	}
 	helpers.StartStop(t, c)
+	if got := c.healthcheck(context.Background()); got.Status != reporter.HealthcheckOK {
+		t.Errorf("healthcheck() after start = %s, expected ok", got.Status)
+	}
 
 	count := atomic.Uint32{}
 	notify := c.Notify()
@@ -68,7 +76,7 @@ func TestDatabaseRefresh(t *testing.T) {
 	}()
 
 	// Check we did load both databases
-	gotMetrics := r.GetMetrics("akvorado_orchestrator_geoip_db_")
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_geoip_db_", "refresh_")
 	expectedMetrics := map[string]string{
 		`refresh_total{database="asn"}`: "1",
 		`refresh_total{database="geo"}`: "1",
@@ -85,7 +93,7 @@ func TestDatabaseRefresh(t *testing.T) {
 	// Check we can reload country database
 	copyFile(t, filepath.Join("testdata", "GeoLite2-Country-Test.mmdb"), countryFile)
 	time.Sleep(20 * time.Millisecond)
-	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_")
+	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_", "refresh_")
 	expectedMetrics = map[string]string{
 		`refresh_total{database="asn"}`: "1",
 		`refresh_total{database="geo"}`: "2",
@@ -101,7 +109,7 @@ func TestDatabaseRefresh(t *testing.T) {
 	// Check we can reload ASN database
 	copyFile(t, filepath.Join("testdata", "GeoLite2-ASN-Test.mmdb"), asnFile)
 	time.Sleep(20 * time.Millisecond)
-	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_")
+	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_", "refresh_")
 	expectedMetrics = map[string]string{
 		`refresh_total{database="asn"}`: "2",
 		`refresh_total{database="geo"}`: "2",
@@ -113,6 +121,16 @@ func TestDatabaseRefresh(t *testing.T) {
 	if current := count.Load(); current != 3 {
 		t.Errorf("Notified %d times instead of %d", current, 3)
 	}
+
+	// Check build timestamps
+	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_", "build_")
+	expectedMetrics = map[string]string{
+		fmt.Sprintf(`build_timestamp_seconds{database="asn",path="%s"}`, asnFile):     "1.63710205e+09",
+		fmt.Sprintf(`build_timestamp_seconds{database="geo",path="%s"}`, countryFile): "1.63710205e+09",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
 }
 
 func TestStartWithoutDatabase(t *testing.T) {
@@ -163,7 +181,7 @@ func TestStartDatabaseOptional(t *testing.T) {
 	}()
 
 	// Check we did not load anything
-	gotMetrics := r.GetMetrics("akvorado_orchestrator_geoip_db_")
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_geoip_db_", "refresh_")
 	expectedMetrics := map[string]string{}
 	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 		t.Fatalf("Metrics (-got, +want):\n%s", diff)
@@ -173,6 +191,14 @@ func TestStartDatabaseOptional(t *testing.T) {
 	if current := count.Load(); current != 1 {
 		t.Errorf("Notified %d times instead of %d", current, 1)
 	}
+	got := c.healthcheck(context.Background())
+	expected := reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: "2 optional database(s) not loaded",
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("healthcheck() (-got, +want):\n%s", diff)
+	}
 
 	copyFile(t, filepath.Join("testdata", "GeoLite2-Country-Test.mmdb"),
 		countryFile)
@@ -181,7 +207,15 @@ func TestStartDatabaseOptional(t *testing.T) {
 
 	// Check databases were loaded
 	time.Sleep(50 * time.Millisecond)
-	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_")
+	got = c.healthcheck(context.Background())
+	expected = reporter.HealthcheckResult{
+		Status: reporter.HealthcheckOK,
+		Reason: "databases loaded",
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("healthcheck() (-got, +want):\n%s", diff)
+	}
+	gotMetrics = r.GetMetrics("akvorado_orchestrator_geoip_db_", "refresh_")
 	expectedMetrics = map[string]string{
 		`refresh_total{database="asn"}`: "1",
 		`refresh_total{database="geo"}`: "1",
@@ -65,3 +65,32 @@ func TestConfigurationEndpoint(t *testing.T) {
 		},
 	})
 }
+
+func TestConfigurationEndpointReplace(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+	c, err := New(r, DefaultConfiguration(), Dependencies{
+		HTTP: h,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	c.RegisterConfiguration(InletService, map[string]string{
+		"hello": "Hello world!",
+	})
+	c.ReplaceConfigurations(InletService, []interface{}{
+		map[string]string{
+			"hello": "Hello pal!",
+		},
+	})
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:         "/api/v0/orchestrator/configuration/inlet",
+			ContentType: "application/yaml; charset=utf-8",
+			FirstLines: []string{
+				`hello: Hello pal!`,
+			},
+		},
+	})
+}
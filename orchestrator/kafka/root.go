@@ -62,6 +62,7 @@ func (c *Component) Start() error {
 	// Create topic
 	admin, err := sarama.NewClusterAdmin(c.config.Brokers, c.kafkaConfig)
 	if err != nil {
+		err = kafka.ErrorWithHint(err)
 		c.r.Err(err).
 			Str("brokers", strings.Join(c.config.Brokers, ",")).
 			Msg("unable to get admin client for topic creation")
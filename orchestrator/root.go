@@ -65,3 +65,11 @@ func (c *Component) RegisterConfiguration(service ServiceType, configuration int
 	c.serviceConfigurations[service] = append(c.serviceConfigurations[service], configuration)
 	c.serviceLock.Unlock()
 }
+
+// ReplaceConfigurations replaces the configurations for a service. This is
+// used when the configuration of the orchestrator is reloaded.
+func (c *Component) ReplaceConfigurations(service ServiceType, configurations []interface{}) {
+	c.serviceLock.Lock()
+	c.serviceConfigurations[service] = configurations
+	c.serviceLock.Unlock()
+}